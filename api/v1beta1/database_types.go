@@ -20,8 +20,10 @@ package v1beta1
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/db-operator/db-operator/pkg/consts"
+	"github.com/db-operator/db-operator/pkg/utils/engines"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -34,10 +36,12 @@ type DatabaseSpec struct {
 	Backup            DatabaseBackup    `json:"backup"`
 	SecretsTemplates  map[string]string `json:"secretsTemplates,omitempty"`
 	Postgres          Postgres          `json:"postgres,omitempty"`
+	Mysql             Mysql             `json:"mysql,omitempty"`
 	MongoDB           MongoDB           `json:"mongodb,omitempty"`
 	Clickhouse        Clickhouse        `json:"clickhouse,omitempty"`
 	Oracle            Oracle            `json:"oracle,omitempty"`
 	SQLServer         SQLServer         `json:"sqlserver,omitempty"`
+	Redis             Redis             `json:"redis,omitempty"`
 	Cleanup           bool              `json:"cleanup,omitempty"`
 	Credentials       Credentials       `json:"credentials,omitempty"`
 	DatabaseName      string            `json:"database,omitempty"`
@@ -45,15 +49,74 @@ type DatabaseSpec struct {
 	AllowedNamespaces []string          `json:"allowedNamespaces,omitempty"`
 }
 
+// Mysql struct should be used to provide resource that only applicable to mysql
+type Mysql struct {
+	// Accounts and limits of the main user
+	MysqlUser `json:",inline"`
+	// The default character set of the database, e.g. utf8mb4 or latin1
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_]+$`
+	// +optional
+	Charset string `json:"charset,omitempty"`
+	// The default collation of the database, e.g. utf8mb4_0900_ai_ci
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_]+$`
+	// +optional
+	Collation string `json:"collation,omitempty"`
+}
+
 // Postgres struct should be used to provide resource that only applicable to postgres
 type Postgres struct {
+	// Names of extensions that are created in the database
 	Extensions []string `json:"extensions,omitempty"`
+	// Extensions with a version or a target schema, they're
+	// taking precedence over extensions with the same name
+	ExtensionConfigs []PostgresExtension `json:"extensionConfigs,omitempty"`
+	// If set to true, extensions that are removed from the spec are dropped
+	PruneExtensions bool `json:"pruneExtensions,omitempty"`
 	// If set to true, the public schema will be dropped after the database creation
 	DropPublicSchema bool `json:"dropPublicSchema,omitempty"`
 	// Specify schemas to be created. The user created by db-operator will have all access on them.
 	Schemas []string `json:"schemas,omitempty"`
+	// What happens to schemas that were created by db-operator, when they're removed from the spec:
+	// grants on them are revoked, and they're either retained or dropped with all their objects
+	// +kubebuilder:validation:Enum=retain;drop
+	// +optional
+	SchemaPrunePolicy string `json:"schemaPrunePolicy,omitempty"`
 	// Let user create database from template
 	Template string `json:"template,omitempty"`
+	// Roles that create objects in the database besides the main user, e.g. a DbUser
+	// that runs migrations. Default privileges of DbUsers are set for objects created
+	// by the main user and by all of these roles
+	ObjectOwners []string `json:"objectOwners,omitempty"`
+	// If set to true, DbUsers that can create objects are granted the main user role,
+	// and their sessions in the database are switched to it, so all objects
+	// are owned by the main user
+	EnforceMainUserOwnership bool `json:"enforceMainUserOwnership,omitempty"`
+	// What happens to objects that are owned by users, when they're removed:
+	// reassign them to the main user, drop them, or fail until they're removed manually.
+	// Can be overridden per DbUser, by default objects are reassigned, so they're never lost
+	// +kubebuilder:validation:Enum=reassign;drop;fail
+	// +optional
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+	// Encoding of the database, it can only be set when the database is created
+	Encoding string `json:"encoding,omitempty"`
+	// Collation order (LC_COLLATE), it can only be set when the database is created
+	LcCollate string `json:"lcCollate,omitempty"`
+	// Character classification (LC_CTYPE), it can only be set when the database is created
+	LcCtype string `json:"lcCtype,omitempty"`
+	// ICU locale of the database, the ICU locale provider is used when it's set.
+	// It can only be set when the database is created
+	IcuLocale string `json:"icuLocale,omitempty"`
+	// How many concurrent connections can be made to the database, -1 means no limit
+	// +kubebuilder:validation:Minimum=-1
+	// +optional
+	ConnectionLimit *int `json:"connectionLimit,omitempty"`
+	// A role that owns the database, e.g. the main user.
+	// It's set when the role exists, by default the database is owned by the admin
+	Owner string `json:"owner,omitempty"`
+	// Parameters that are set for the database with ALTER DATABASE ... SET,
+	// e.g. statement_timeout or search_path, elements of lists are separated by commas.
+	// When it's set, parameters that are not listed here are reset
+	Settings map[string]string `json:"settings,omitempty"`
 }
 
 // MongoDB struct should be used to provide resource that only applicable to MongoDB
@@ -65,17 +128,30 @@ type MongoDB struct {
 // Clickhouse struct should be used to provide resource that only applicable to Clickhouse
 type Clickhouse struct {
 	Cluster string `json:"clusterName,omitempty"`
-	// Shard name for distributed tables
+	// Shard name for distributed tables,
+	// it's the shard_num of the cluster that is checked against the replication factor,
+	// and the shard of the Replicated engine, the {shard} macro is used when it's empty
 	Shard string `json:"shard,omitempty"`
 
-	// Replication factor for tables
+	// Replica name of the Replicated engine, the {replica} macro is used when it's empty
+	Replica string `json:"replica,omitempty"`
+
+	// Replication factor for tables, the cluster must have enough replicas,
+	// and inserts are confirmed after they're written to this number of replicas
 	ReplicationFactor int `json:"replicationFactor,omitempty"`
 
-	// Engine type for the ClickHouse database (e.g., MergeTree, ReplicatedMergeTree)
+	// Engine type for the ClickHouse database (e.g., MergeTree, ReplicatedMergeTree),
+	// it's used as the default table engine for users of the database.
+	// Replicated is the database engine, that replicates the database to all
+	// hosts of the cluster, it can only be set on creation and requires clusterName
 	Engine string `json:"engine,omitempty"`
 
-	// Additional settings that might be necessary for ClickHouse configuration
+	// Additional settings that might be necessary for ClickHouse configuration,
+	// they're added to the settings profile of the database
 	Settings map[string]string `json:"settings,omitempty"`
+
+	// Limits of the main user
+	ClickhouseUser `json:",inline"`
 }
 
 // Oracle struct should be used to provide resource that only applicable to Oracle
@@ -90,6 +166,15 @@ type SQLServer struct {
 	Roles   []string `json:"roles,omitempty"`
 }
 
+// Redis struct should be used to provide resource that only applicable to Redis and Valkey,
+// the database is a key prefix, users can only access keys and channels that start with it
+type Redis struct {
+	// The logical database that users are connected to, it can only be set on creation
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DB int `json:"db,omitempty"`
+}
+
 // DatabaseStatus defines the observed state of Database
 type DatabaseStatus struct {
 	// Important: Run "make generate" to regenerate code after modifying this file
@@ -101,6 +186,66 @@ type DatabaseStatus struct {
 	UserName              string              `json:"user"`
 	Engine                string              `json:"engine"`
 	OperatorVersion       string              `json:"operatorVersion,omitempty"`
+	// Observed versions of extensions from the spec
+	Extensions []DatabaseExtensionStatus `json:"extensions,omitempty"`
+	// Names of database parameters from the spec, they're reset,
+	// when they're removed from the spec
+	Settings []string `json:"settings,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// PostgresExtension defines an extension that is created in the database
+type PostgresExtension struct {
+	Name string `json:"name"`
+	// The version of the extension, the extension is updated when it's changed.
+	// The default version is installed when it's not set
+	Version string `json:"version,omitempty"`
+	// A schema that the extension is created in, relocatable extensions are moved,
+	// when it's changed
+	Schema string `json:"schema,omitempty"`
+}
+
+// ValidateClickhouseEngine checks that replicated databases are created on a cluster
+func (db *Database) ValidateClickhouseEngine() error {
+	if db.Spec.Clickhouse.Engine == engines.CLICKHOUSE_ENGINE_REPLICATED && db.Spec.Clickhouse.Cluster == "" {
+		return fmt.Errorf("spec.clickhouse.clusterName is required by the %s engine", engines.CLICKHOUSE_ENGINE_REPLICATED)
+	}
+	return nil
+}
+
+// CharsetChangeWarnings returns warnings about changes of the character set or the collation of a mysql
+// database, because they're only defaults of new tables, and existing ones are not converted
+func (db *Database) CharsetChangeWarnings(old *Database) []string {
+	warnings := []string{}
+	if db.Spec.Mysql.Charset != old.Spec.Mysql.Charset {
+		warnings = append(warnings, fmt.Sprintf("spec.mysql.charset is changed from %q to %q, it only affects new tables, "+
+			"existing tables must be converted with ALTER TABLE ... CONVERT TO CHARACTER SET", old.Spec.Mysql.Charset, db.Spec.Mysql.Charset))
+	}
+	if db.Spec.Mysql.Collation != old.Spec.Mysql.Collation {
+		warnings = append(warnings, fmt.Sprintf("spec.mysql.collation is changed from %q to %q, it only affects new tables, "+
+			"existing tables must be converted with ALTER TABLE ... CONVERT TO CHARACTER SET", old.Spec.Mysql.Collation, db.Spec.Mysql.Collation))
+	}
+	return warnings
+}
+
+// ExtensionNames returns names of all extensions from the spec
+func (p Postgres) ExtensionNames() []string {
+	names := slices.Clone(p.Extensions)
+	for _, ext := range p.ExtensionConfigs {
+		if !slices.Contains(names, ext.Name) {
+			names = append(names, ext.Name)
+		}
+	}
+	return names
+}
+
+// DatabaseExtensionStatus is an extension that is installed in the database
+type DatabaseExtensionStatus struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
 }
 
 // DatabaseProxyStatus defines whether proxy for database is enabled or not
@@ -201,24 +346,22 @@ func (db *Database) ValidateDbUserNamespace(userNamespace string) error {
 	return fmt.Errorf("namespace %s is not allowed for the user", userNamespace)
 }
 
-// GetProtocol returns the protocol that is required for connection (postgresql, mysql, mongodb, clickhouse, oracle, sqlserver)
+// GetProtocol returns the protocol that is required for connection (e.g. postgresql for postgres)
 func (db *Database) GetProtocol() (string, error) {
-	switch db.Status.Engine {
-	case consts.ENGINE_POSTGRES:
-		return "postgresql", nil
-	case consts.ENGINE_MYSQL:
-		return db.Status.Engine, nil
-	case consts.ENGINE_MONGODB:
-		return "mongodb", nil
-	case consts.ENGINE_CLICKHOUSE:
-		return "clickhouse", nil
-	case consts.ENGINE_ORACLE:
-		return "oracle", nil
-	case consts.ENGINE_SQLSERVER:
-		return "sqlserver", nil
-	default:
+	engine, err := engines.Get(db.Status.Engine)
+	if err != nil {
 		return "", fmt.Errorf("unknown engine %s", db.Status.Engine)
 	}
+	return engine.Protocol, nil
+}
+
+// DefaultTemplate returns the template of the connection string for the engine,
+// redis clients expect the number of the logical database instead of the key prefix
+func (db *Database) DefaultTemplate(engine string) string {
+	if engine == consts.ENGINE_REDIS {
+		return fmt.Sprintf("{{ .Protocol }}://{{ .Username }}:{{ .Password }}@{{ .Hostname }}:{{ .Port }}/%d", db.Spec.Redis.DB)
+	}
+	return DEFAULT_TEMPLATE_VALUE
 }
 
 func (db *Database) IsCleanup() bool {
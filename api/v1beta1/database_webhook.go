@@ -23,7 +23,9 @@ import (
 	"fmt"
 	"regexp"
 
+	"github.com/db-operator/db-operator/pkg/utils/engines"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/strings/slices"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -60,7 +62,7 @@ func (r *Database) Default(ctx context.Context, obj runtime.Object) error {
 			Templates: Templates{
 				&Template{
 					Name:     DEFAULT_TEMPLATE_NAME,
-					Template: DEFAULT_TEMPLATE_VALUE,
+					Template: r.DefaultTemplate(r.instanceEngine(ctx)),
 					Secret:   true,
 				},
 			},
@@ -69,6 +71,19 @@ func (r *Database) Default(ctx context.Context, obj runtime.Object) error {
 	return nil
 }
 
+// instanceEngine returns the engine of the instance, it's empty when the instance can't be read
+func (r *Database) instanceEngine(ctx context.Context) string {
+	if databaseMgr == nil {
+		return ""
+	}
+	dbin := &DbInstance{}
+	if err := databaseMgr.GetClient().Get(ctx, types.NamespacedName{Name: r.Spec.Instance}, dbin); err != nil {
+		databaselog.Info("can't read the instance, using the default template", "instance", r.Spec.Instance, "error", err.Error())
+		return ""
+	}
+	return dbin.Spec.Engine
+}
+
 //+kubebuilder:webhook:path=/validate-kinda-rocks-v1beta1-database,mutating=false,failurePolicy=fail,sideEffects=None,groups=kinda.rocks,resources=databases,verbs=create;update,versions=v1beta1,name=vdatabase.kb.io,admissionReviewVersions=v1
 
 var _ webhook.CustomValidator = &Database{}
@@ -94,6 +109,14 @@ func (r *Database) ValidateCreate(ctx context.Context, obj runtime.Object) (admi
 		}
 	}
 
+	if err := engines.ValidatePostgresSettings(r.Spec.Postgres.Settings); err != nil {
+		return nil, err
+	}
+
+	if err := r.ValidateClickhouseEngine(); err != nil {
+		return nil, err
+	}
+
 	if err := r.ValidateNamespace(); err != nil {
 		return nil, err
 	}
@@ -140,6 +163,40 @@ func (r *Database) ValidateUpdate(ctx context.Context, obj runtime.Object, old r
 		return nil, fmt.Errorf(immutableErr, "spec.postgres.template")
 	}
 
+	if r.Spec.Postgres.Encoding != oldDatabase.Spec.Postgres.Encoding {
+		return nil, fmt.Errorf(immutableErr, "spec.postgres.encoding")
+	}
+
+	if r.Spec.Postgres.LcCollate != oldDatabase.Spec.Postgres.LcCollate {
+		return nil, fmt.Errorf(immutableErr, "spec.postgres.lcCollate")
+	}
+
+	if r.Spec.Postgres.LcCtype != oldDatabase.Spec.Postgres.LcCtype {
+		return nil, fmt.Errorf(immutableErr, "spec.postgres.lcCtype")
+	}
+
+	if r.Spec.Postgres.IcuLocale != oldDatabase.Spec.Postgres.IcuLocale {
+		return nil, fmt.Errorf(immutableErr, "spec.postgres.icuLocale")
+	}
+
+	// Table engines can be changed, but the database engine can't
+	if (r.Spec.Clickhouse.Engine == engines.CLICKHOUSE_ENGINE_REPLICATED) != (oldDatabase.Spec.Clickhouse.Engine == engines.CLICKHOUSE_ENGINE_REPLICATED) {
+		return nil, fmt.Errorf(immutableErr, "spec.clickhouse.engine")
+	}
+
+	// Keys are not moved to another logical database
+	if r.Spec.Redis.DB != oldDatabase.Spec.Redis.DB {
+		return nil, fmt.Errorf(immutableErr, "spec.redis.db")
+	}
+
+	if err := engines.ValidatePostgresSettings(r.Spec.Postgres.Settings); err != nil {
+		return nil, err
+	}
+
+	if err := r.ValidateClickhouseEngine(); err != nil {
+		return nil, err
+	}
+
 	if err := r.ValidateNamespace(); err != nil {
 		return nil, err
 	}
@@ -147,7 +204,7 @@ func (r *Database) ValidateUpdate(ctx context.Context, obj runtime.Object, old r
 		return nil, err
 	}
 
-	return nil, nil
+	return r.CharsetChangeWarnings(oldDatabase), nil
 }
 
 func ValidateSecretTemplates(templates map[string]string) error {
@@ -1,6 +1,7 @@
 package v1beta1_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -33,3 +34,68 @@ func TestUnitSecretTemplatesValidator(t *testing.T) {
 		"the error doesn't contain expected substring",
 	)
 }
+
+func TestUnitDatabasePostgresOptionsImmutable(t *testing.T) {
+	old := &v1beta1.Database{Spec: v1beta1.DatabaseSpec{Postgres: v1beta1.Postgres{Encoding: "UTF8", LcCollate: "C"}}}
+	for field, update := range map[string]func(*v1beta1.Postgres){
+		"spec.postgres.encoding":  func(p *v1beta1.Postgres) { p.Encoding = "LATIN1" },
+		"spec.postgres.lcCollate": func(p *v1beta1.Postgres) { p.LcCollate = "" },
+		"spec.postgres.lcCtype":   func(p *v1beta1.Postgres) { p.LcCtype = "en_US.UTF-8" },
+		"spec.postgres.icuLocale": func(p *v1beta1.Postgres) { p.IcuLocale = "de-DE" },
+	} {
+		updated := old.DeepCopy()
+		update(&updated.Spec.Postgres)
+		_, err := updated.ValidateUpdate(context.TODO(), updated, old)
+		assert.ErrorContains(t, err, fmt.Sprintf("cannot change %s", field))
+	}
+
+	updated := old.DeepCopy()
+	updated.Spec.Postgres.Settings = map[string]string{"statement_timeout = 0; DROP DATABASE app; --": "1"}
+	_, err := updated.ValidateUpdate(context.TODO(), updated, old)
+	assert.ErrorContains(t, err, "invalid setting name")
+}
+
+func TestUnitDatabaseCharsetChangeWarnings(t *testing.T) {
+	old := &v1beta1.Database{Spec: v1beta1.DatabaseSpec{Mysql: v1beta1.Mysql{Charset: "latin1"}}}
+	assert.Empty(t, old.DeepCopy().CharsetChangeWarnings(old))
+
+	updated := old.DeepCopy()
+	updated.Spec.Mysql.Charset = "utf8mb4"
+	updated.Spec.Mysql.Collation = "utf8mb4_unicode_ci"
+	warnings := updated.CharsetChangeWarnings(old)
+	assert.Len(t, warnings, 2)
+	assert.Contains(t, warnings[0], `spec.mysql.charset is changed from "latin1" to "utf8mb4"`)
+	assert.Contains(t, warnings[1], "only affects new tables")
+}
+
+func TestUnitDatabaseClickhouseEngine(t *testing.T) {
+	replicated := &v1beta1.Database{Spec: v1beta1.DatabaseSpec{Clickhouse: v1beta1.Clickhouse{Engine: "Replicated"}}}
+	_, err := replicated.ValidateCreate(context.TODO(), replicated)
+	assert.ErrorContains(t, err, "spec.clickhouse.clusterName is required")
+
+	replicated.Spec.Clickhouse.Cluster = "analytics"
+	assert.NoError(t, replicated.ValidateClickhouseEngine())
+
+	// Only the default table engine can be changed
+	updated := replicated.DeepCopy()
+	updated.Spec.Clickhouse.Engine = "ReplicatedMergeTree"
+	_, err = updated.ValidateUpdate(context.TODO(), updated, replicated)
+	assert.ErrorContains(t, err, "cannot change spec.clickhouse.engine")
+
+	old := updated.DeepCopy()
+	updated.Spec.Clickhouse.Engine = "Replicated"
+	_, err = updated.ValidateUpdate(context.TODO(), updated, old)
+	assert.ErrorContains(t, err, "cannot change spec.clickhouse.engine")
+}
+
+func TestUnitDatabaseRedisTemplate(t *testing.T) {
+	db := &v1beta1.Database{Spec: v1beta1.DatabaseSpec{Redis: v1beta1.Redis{DB: 3}}}
+	assert.Equal(t, "{{ .Protocol }}://{{ .Username }}:{{ .Password }}@{{ .Hostname }}:{{ .Port }}/3", db.DefaultTemplate("redis"))
+	assert.Equal(t, v1beta1.DEFAULT_TEMPLATE_VALUE, db.DefaultTemplate("postgres"))
+
+	// Keys are not moved to another logical database
+	updated := db.DeepCopy()
+	updated.Spec.Redis.DB = 4
+	_, err := updated.ValidateUpdate(context.TODO(), updated, db)
+	assert.ErrorContains(t, err, "cannot change spec.redis.db")
+}
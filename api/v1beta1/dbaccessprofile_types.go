@@ -0,0 +1,120 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1beta1
+
+import (
+	"fmt"
+
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DbAccessProfileSpec defines privileges that are granted to DbUsers
+// that are using the profile as the access type
+type DbAccessProfileSpec struct {
+	// Privileges per engine, the profile can only be used on
+	// instances of engines that are listed here
+	// +kubebuilder:validation:MinItems=1
+	Engines []DbAccessProfileEngine `json:"engines"`
+}
+
+// DbAccessProfileEngine defines privileges per kind of database objects for an engine.
+// Privileges are SQL keywords, e.g. SELECT or CREATE TEMPORARY TABLES, kinds of objects
+// that don't exist in an engine are ignored by it
+type DbAccessProfileEngine struct {
+	Engine string `json:"engine"`
+	// Privileges on the database itself
+	// +kubebuilder:validation:items:Pattern=`^[A-Za-z][A-Za-z ]*$`
+	Database []string `json:"database,omitempty"`
+	// Privileges on every schema of the database
+	// +kubebuilder:validation:items:Pattern=`^[A-Za-z][A-Za-z ]*$`
+	Schemas []string `json:"schemas,omitempty"`
+	// Privileges on all existing tables
+	// +kubebuilder:validation:items:Pattern=`^[A-Za-z][A-Za-z ]*$`
+	Tables []string `json:"tables,omitempty"`
+	// Privileges on all existing sequences
+	// +kubebuilder:validation:items:Pattern=`^[A-Za-z][A-Za-z ]*$`
+	Sequences []string `json:"sequences,omitempty"`
+	// Privileges on all existing functions
+	// +kubebuilder:validation:items:Pattern=`^[A-Za-z][A-Za-z ]*$`
+	Functions []string `json:"functions,omitempty"`
+	// Privileges on objects that will be created by the main user
+	DefaultPrivileges DbAccessProfileDefaultPrivileges `json:"defaultPrivileges,omitempty"`
+}
+
+// DbAccessProfileDefaultPrivileges defines privileges on objects that don't exist yet
+type DbAccessProfileDefaultPrivileges struct {
+	// +kubebuilder:validation:items:Pattern=`^[A-Za-z][A-Za-z ]*$`
+	Tables []string `json:"tables,omitempty"`
+	// +kubebuilder:validation:items:Pattern=`^[A-Za-z][A-Za-z ]*$`
+	Sequences []string `json:"sequences,omitempty"`
+	// +kubebuilder:validation:items:Pattern=`^[A-Za-z][A-Za-z ]*$`
+	Functions []string `json:"functions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,shortName=dbap
+//+kubebuilder:printcolumn:name="Engines",type=string,JSONPath=`.spec.engines[*].engine`,description="engines that are supported by the profile"
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`,description="time since creation of resource"
+
+// DbAccessProfile is the Schema for the dbaccessprofiles API
+type DbAccessProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DbAccessProfileSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DbAccessProfileList contains a list of DbAccessProfile
+type DbAccessProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DbAccessProfile `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DbAccessProfile{}, &DbAccessProfileList{})
+}
+
+// AccessProfile returns privileges of the profile for an engine
+func (p *DbAccessProfile) AccessProfile(engine string) (*engines.AccessProfile, error) {
+	for _, privileges := range p.Spec.Engines {
+		if privileges.Engine != engine {
+			continue
+		}
+		profile := &engines.AccessProfile{
+			Name:      p.Name,
+			Database:  privileges.Database,
+			Schemas:   privileges.Schemas,
+			Tables:    privileges.Tables,
+			Sequences: privileges.Sequences,
+			Functions: privileges.Functions,
+			DefaultPrivileges: engines.DefaultPrivileges{
+				Tables:    privileges.DefaultPrivileges.Tables,
+				Sequences: privileges.DefaultPrivileges.Sequences,
+				Functions: privileges.DefaultPrivileges.Functions,
+			},
+		}
+		if err := profile.Validate(); err != nil {
+			return nil, err
+		}
+		return profile, nil
+	}
+	return nil, fmt.Errorf("access profile %s doesn't define privileges for the engine %s", p.Name, engine)
+}
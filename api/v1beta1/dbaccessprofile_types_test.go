@@ -0,0 +1,57 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1beta1_test
+
+import (
+	"testing"
+
+	"github.com/db-operator/db-operator/api/v1beta1"
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUnitAccessProfileForEngine(t *testing.T) {
+	profile := &v1beta1.DbAccessProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+		Spec: v1beta1.DbAccessProfileSpec{
+			Engines: []v1beta1.DbAccessProfileEngine{
+				{
+					Engine:            "postgres",
+					Schemas:           []string{"USAGE"},
+					Sequences:         []string{"USAGE", "SELECT"},
+					DefaultPrivileges: v1beta1.DbAccessProfileDefaultPrivileges{Sequences: []string{"USAGE"}},
+				},
+				{Engine: "mysql", Database: []string{"SELECT; DROP DATABASE mysql"}},
+			},
+		},
+	}
+
+	privileges, err := profile.AccessProfile("postgres")
+	assert.NoError(t, err)
+	assert.Equal(t, &engines.AccessProfile{
+		Name:              "app",
+		Schemas:           []string{"USAGE"},
+		Sequences:         []string{"USAGE", "SELECT"},
+		DefaultPrivileges: engines.DefaultPrivileges{Sequences: []string{"USAGE"}},
+	}, privileges)
+
+	_, err = profile.AccessProfile("mysql")
+	assert.ErrorContains(t, err, "invalid privilege")
+	_, err = profile.AccessProfile("clickhouse")
+	assert.ErrorContains(t, err, "doesn't define privileges for the engine clickhouse")
+}
@@ -20,7 +20,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
 
+	"github.com/db-operator/db-operator/pkg/consts"
+	"github.com/db-operator/db-operator/pkg/utils/engines"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -37,7 +42,17 @@ type DbInstanceSpec struct {
 	SSLConnection   DbInstanceSSLConnection `json:"sslConnection,omitempty"`
 	// A list of privileges that are allowed to be set as Dbuser's extra privileges
 	AllowedPrivileges []string `json:"allowedPrivileges,omitempty"`
-	DbInstanceSource  `json:",inline"`
+	// A list of DbAccessProfiles that are allowed to be set as DbUser's access types,
+	// built-in access types (readOnly and readWrite) are always allowed
+	AllowedAccessProfiles []string `json:"allowedAccessProfiles,omitempty"`
+	// A list of extensions that can be created by Databases on the instance,
+	// all extensions are allowed when it's empty
+	AllowedExtensions []string `json:"allowedExtensions,omitempty"`
+	// Limits of DbUsers that are created on the instance
+	UserPolicy DbInstanceUserPolicy `json:"userPolicy,omitempty"`
+	// Plugin must be set when the engine is "plugin"
+	Plugin           *DbInstancePlugin `json:"plugin,omitempty"`
+	DbInstanceSource `json:",inline"`
 }
 
 // DbInstanceSource represents the source of an instance.
@@ -50,6 +65,23 @@ type DbInstanceSource struct {
 	AWS       *AWSInstance       `json:"aws,omitempty" protobuf:"bytes,5,opt,name=aws"`
 }
 
+// DbInstanceUserPolicy caps settings of DbUsers per engine, users
+// that don't set a capped value are getting the maximum one
+type DbInstanceUserPolicy struct {
+	Postgres DbInstancePostgresUserPolicy `json:"postgres,omitempty"`
+}
+
+// DbInstancePostgresUserPolicy caps settings of postgres users
+type DbInstancePostgresUserPolicy struct {
+	// The maximum connection limit of a user
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConnectionLimit *int `json:"maxConnectionLimit,omitempty"`
+	// Maximum values of parameters, e.g. statement_timeout: 5min or work_mem: 64MB,
+	// values of users must be set in the same kind of units to be compared
+	MaxSettings map[string]string `json:"maxSettings,omitempty"`
+}
+
 // DbInstanceStatus defines the observed state of DbInstance
 type DbInstanceStatus struct {
 	// Important: Run "make generate" to regenerate code after modifying this file
@@ -140,6 +172,25 @@ type DbInstanceSSLConnection struct {
 	SkipVerify bool `json:"skip-verify"`
 }
 
+// DbInstancePlugin defines an out-of-process engine that is driven over gRPC
+type DbInstancePlugin struct {
+	// Endpoint of the plugin gRPC server, e.g. my-plugin.db-operator.svc:9090
+	Endpoint string `json:"endpoint"`
+	// TLS of the connection to the plugin, it must be set unless insecure is true
+	TLS *DbInstancePluginTLS `json:"tls,omitempty"`
+	// Insecure allows a plain text connection to the plugin, admin credentials
+	// are sent with every request, so it should only be used for local plugins
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// DbInstancePluginTLS references a secret with the CA of the plugin server in ca.crt,
+// and optionally with a client certificate in tls.crt and tls.key, that is used for mTLS
+type DbInstancePluginTLS struct {
+	SecretRef NamespacedName `json:"secretRef"`
+	// ServerName is verified in the server certificate instead of the endpoint host
+	ServerName string `json:"serverName,omitempty"`
+}
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster,shortName=dbin
@@ -171,13 +222,37 @@ func init() {
 
 // ValidateEngine checks if defined engine by DbInstance object is supported by db-operator
 func (dbin *DbInstance) ValidateEngine() error {
-	if (dbin.Spec.Engine == "mysql") || (dbin.Spec.Engine == "postgres") ||
-		(dbin.Spec.Engine == "mongodb") || (dbin.Spec.Engine == "clickhouse") ||
-		(dbin.Spec.Engine == "oracle") || (dbin.Spec.Engine == "sqlserver") {
-		return nil
+	if _, err := engines.Get(dbin.Spec.Engine); err != nil {
+		return errors.New("not supported engine type")
 	}
 
-	return errors.New("not supported engine type")
+	return nil
+}
+
+// ValidatePlugin checks if the plugin is only and always configured for the plugin engine
+func (dbin *DbInstance) ValidatePlugin() error {
+	if dbin.Spec.Engine == consts.ENGINE_PLUGIN {
+		if dbin.Spec.Plugin == nil || len(dbin.Spec.Plugin.Endpoint) == 0 {
+			return errors.New("plugin endpoint must be set when the plugin engine is used")
+		}
+		if dbin.Spec.Plugin.TLS == nil && !dbin.Spec.Plugin.Insecure {
+			return errors.New("plugin tls must be set, unless the plugin is insecure")
+		}
+		if dbin.Spec.Plugin.TLS != nil && dbin.Spec.Plugin.Insecure {
+			return errors.New("plugin tls can't be set for an insecure plugin")
+		}
+	} else if dbin.Spec.Plugin != nil {
+		return fmt.Errorf("plugin can't be set for the %s engine", dbin.Spec.Engine)
+	}
+	return nil
+}
+
+// GetPluginEndpoint returns the endpoint of the engine plugin, or an empty string if it's not set
+func (dbin *DbInstance) GetPluginEndpoint() string {
+	if dbin.Spec.Plugin == nil {
+		return ""
+	}
+	return dbin.Spec.Plugin.Endpoint
 }
 
 // ValidateExistingDatabase checks if there's an existing database for the same instance in any namespace
@@ -289,6 +364,106 @@ func (dbin *DbInstance) GetSecretName() string {
 	return ""
 }
 
+// ValidateExtensions returns an error if one of extensions is not allowed on the instance
+func (dbin *DbInstance) ValidateExtensions(extensions []string) error {
+	if len(dbin.Spec.AllowedExtensions) == 0 {
+		return nil
+	}
+	for _, ext := range extensions {
+		if !slices.Contains(dbin.Spec.AllowedExtensions, ext) {
+			return fmt.Errorf("extension %s is not allowed on the instance %s", ext, dbin.Name)
+		}
+	}
+	return nil
+}
+
+// Apply returns settings of the user that are capped by the policy,
+// it fails if the user requests more than the policy allows
+func (p DbInstancePostgresUserPolicy) Apply(user DbUserPostgres) (DbUserPostgres, error) {
+	result := *user.DeepCopy()
+	if p.MaxConnectionLimit != nil {
+		switch {
+		case result.ConnectionLimit == nil || *result.ConnectionLimit < 0:
+			limit := *p.MaxConnectionLimit
+			result.ConnectionLimit = &limit
+		case *result.ConnectionLimit > *p.MaxConnectionLimit:
+			return result, fmt.Errorf("connection limit %d exceeds the maximum of the instance: %d", *result.ConnectionLimit, *p.MaxConnectionLimit)
+		}
+	}
+
+	for name, max := range p.MaxSettings {
+		value, ok := result.Settings[name]
+		if !ok {
+			if result.Settings == nil {
+				result.Settings = map[string]string{}
+			}
+			result.Settings[name] = max
+			continue
+		}
+		exceeds, err := postgresSettingExceeds(value, max)
+		if err != nil {
+			return result, fmt.Errorf("can't compare %s with the maximum of the instance: %v", name, err)
+		}
+		if exceeds {
+			return result, fmt.Errorf("%s %s exceeds the maximum of the instance: %s", name, value, max)
+		}
+	}
+	return result, nil
+}
+
+// Units of postgres parameters, converted to bytes and milliseconds
+var postgresSettingUnits = map[string]struct {
+	kind       string
+	multiplier float64
+}{
+	"":    {kind: "number", multiplier: 1},
+	"B":   {kind: "memory", multiplier: 1},
+	"kB":  {kind: "memory", multiplier: 1 << 10},
+	"MB":  {kind: "memory", multiplier: 1 << 20},
+	"GB":  {kind: "memory", multiplier: 1 << 30},
+	"TB":  {kind: "memory", multiplier: 1 << 40},
+	"us":  {kind: "time", multiplier: 0.001},
+	"ms":  {kind: "time", multiplier: 1},
+	"s":   {kind: "time", multiplier: 1000},
+	"min": {kind: "time", multiplier: 60 * 1000},
+	"h":   {kind: "time", multiplier: 60 * 60 * 1000},
+	"d":   {kind: "time", multiplier: 24 * 60 * 60 * 1000},
+}
+
+var postgresSettingRegexp = regexp.MustCompile(`^\s*(-?[0-9]+(?:\.[0-9]+)?)\s*([A-Za-z]*)\s*$`)
+
+// postgresSettingExceeds returns true if the value is greater than the maximum,
+// values that are not positive are disabling limits, so they're always greater
+func postgresSettingExceeds(value, max string) (bool, error) {
+	parse := func(setting string) (float64, string, error) {
+		match := postgresSettingRegexp.FindStringSubmatch(setting)
+		if match == nil {
+			return 0, "", fmt.Errorf("not a number: %s", setting)
+		}
+		unit, ok := postgresSettingUnits[match[2]]
+		if !ok {
+			return 0, "", fmt.Errorf("unknown unit: %s", match[2])
+		}
+		number, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return 0, "", err
+		}
+		return number * unit.multiplier, unit.kind, nil
+	}
+	valueNumber, valueKind, err := parse(value)
+	if err != nil {
+		return false, err
+	}
+	maxNumber, maxKind, err := parse(max)
+	if err != nil {
+		return false, err
+	}
+	if valueKind != maxKind {
+		return false, fmt.Errorf("%s and %s are set in different units", value, max)
+	}
+	return valueNumber <= 0 || valueNumber > maxNumber, nil
+}
+
 func (db *DbInstance) Hub() {
 	// Function to mark the DbInstance as a hub
 }
@@ -25,8 +25,8 @@ import (
 
 	"github.com/db-operator/db-operator/pkg/consts"
 	"github.com/db-operator/db-operator/pkg/helpers/kube"
+	"github.com/db-operator/db-operator/pkg/utils/engines"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/utils/strings/slices"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -84,6 +84,9 @@ func (r *DbInstance) ValidateCreate(ctx context.Context, obj runtime.Object) (ad
 	if err := ValidateEngine(r.Spec.Engine); err != nil {
 		return nil, err
 	}
+	if err := r.ValidatePlugin(); err != nil {
+		return nil, err
+	}
 	if err := r.ValidateExistingDatabase(context.Background(), dbInstanceMgr.GetClient()); err != nil {
 		return nil, err
 	}
@@ -105,6 +108,10 @@ func (r *DbInstance) ValidateUpdate(ctx context.Context, obj runtime.Object, old
 		return nil, fmt.Errorf(immutableErr, "engine")
 	}
 
+	if err := r.ValidatePlugin(); err != nil {
+		return nil, err
+	}
+
 	if err := ValidateConfigVsConfigFrom(r.Spec.Generic); err != nil {
 		return nil, err
 	}
@@ -147,8 +154,8 @@ func ValidateConfigFrom(dbin *GenericInstance) error {
 }
 
 func ValidateEngine(engine string) error {
-	if !slices.Contains([]string{"postgres", "mysql", "mongodb", "clickhouse", "oracle", "sqlserver"}, engine) {
-		return fmt.Errorf("unsupported engine: %s. please use one of: postgres, mysql, mongodb, clickhouse, oracle, sqlserver", engine)
+	if _, err := engines.Get(engine); err != nil {
+		return fmt.Errorf("unsupported engine: %s. please use one of: %s", engine, strings.Join(engines.Names(), ", "))
 	}
 	return nil
 }
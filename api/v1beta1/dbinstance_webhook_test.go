@@ -176,3 +176,78 @@ func TestAllowedPrivileges(t *testing.T) {
 	err := v1beta1.TestAllowedPrivileges(privileges)
 	assert.NoError(t, err)
 }
+
+func TestUnitPluginValidation(t *testing.T) {
+	dbin := &v1beta1.DbInstance{Spec: v1beta1.DbInstanceSpec{Engine: consts.ENGINE_PLUGIN}}
+	assert.Error(t, dbin.ValidatePlugin())
+
+	dbin.Spec.Plugin = &v1beta1.DbInstancePlugin{Endpoint: "plugin:9090"}
+	assert.Error(t, dbin.ValidatePlugin(), "plain text must be allowed explicitly")
+
+	dbin.Spec.Plugin.Insecure = true
+	assert.NoError(t, dbin.ValidatePlugin())
+	assert.Equal(t, "plugin:9090", dbin.GetPluginEndpoint())
+
+	dbin.Spec.Plugin.TLS = &v1beta1.DbInstancePluginTLS{SecretRef: v1beta1.NamespacedName{Namespace: "plugins", Name: "plugin-tls"}}
+	assert.Error(t, dbin.ValidatePlugin())
+
+	dbin.Spec.Plugin.Insecure = false
+	assert.NoError(t, dbin.ValidatePlugin())
+
+	dbin.Spec.Engine = "postgres"
+	assert.Error(t, dbin.ValidatePlugin())
+}
+
+func TestUnitPostgresUserPolicy(t *testing.T) {
+	maxConnections := 10
+	policy := v1beta1.DbInstancePostgresUserPolicy{
+		MaxConnectionLimit: &maxConnections,
+		MaxSettings:        map[string]string{"statement_timeout": "5min", "work_mem": "64MB"},
+	}
+
+	// Users without limits are getting the maximum ones
+	user, err := policy.Apply(v1beta1.DbUserPostgres{Settings: map[string]string{"search_path": "app"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, *user.ConnectionLimit)
+	assert.Equal(t, map[string]string{"search_path": "app", "statement_timeout": "5min", "work_mem": "64MB"}, user.Settings)
+
+	limit := 5
+	user, err = policy.Apply(v1beta1.DbUserPostgres{ConnectionLimit: &limit, Settings: map[string]string{"statement_timeout": "30s", "work_mem": "1GB"}})
+	assert.ErrorContains(t, err, "work_mem 1GB exceeds the maximum of the instance")
+
+	for _, settings := range []map[string]string{
+		{"statement_timeout": "0"},
+		{"statement_timeout": "6min"},
+		{"statement_timeout": "64MB"},
+	} {
+		_, err = policy.Apply(v1beta1.DbUserPostgres{Settings: settings})
+		assert.Error(t, err, settings)
+	}
+
+	limit = 11
+	_, err = policy.Apply(v1beta1.DbUserPostgres{ConnectionLimit: &limit})
+	assert.ErrorContains(t, err, "connection limit 11 exceeds")
+
+	// Users are not changed without a policy
+	limit = -1
+	user, err = v1beta1.DbInstancePostgresUserPolicy{}.Apply(v1beta1.DbUserPostgres{ConnectionLimit: &limit})
+	assert.NoError(t, err)
+	assert.Equal(t, -1, *user.ConnectionLimit)
+}
+
+func TestUnitAllowedExtensions(t *testing.T) {
+	postgres := v1beta1.Postgres{
+		Extensions:       []string{"uuid-ossp", "postgis"},
+		ExtensionConfigs: []v1beta1.PostgresExtension{{Name: "postgis", Version: "3.4.0"}, {Name: "pgcrypto"}},
+	}
+	assert.Equal(t, []string{"uuid-ossp", "postgis", "pgcrypto"}, postgres.ExtensionNames())
+
+	instance := &v1beta1.DbInstance{}
+	instance.Name = "postgres"
+	assert.NoError(t, instance.ValidateExtensions(postgres.ExtensionNames()), "all extensions are allowed by default")
+
+	instance.Spec.AllowedExtensions = []string{"uuid-ossp", "postgis"}
+	assert.ErrorContains(t, instance.ValidateExtensions(postgres.ExtensionNames()), "extension pgcrypto is not allowed")
+	instance.Spec.AllowedExtensions = append(instance.Spec.AllowedExtensions, "pgcrypto")
+	assert.NoError(t, instance.ValidateExtensions(postgres.ExtensionNames()))
+}
@@ -21,7 +21,9 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/db-operator/db-operator/pkg/utils/engines"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -33,8 +35,9 @@ type DbUserSpec struct {
 	DatabaseRefs []string `json:"databaseRefs,omitempty"`
 	NamespaceRef string   `json:"namespaceRef,omitempty"`
 	UserName     string   `json:"user,omitempty"`
-	// AccessType that should be given to a user
-	// Currently only readOnly and readWrite are supported by the operator
+	// AccessType that should be given to a user, it's either
+	// one of built-in types: readOnly and readWrite, or a name
+	// of a DbAccessProfile that is allowed on the instance
 	AccessType string `json:"accessType"`
 	// SecretName name that should be used to save user's credentials
 	SecretName string `json:"secretName"`
@@ -55,6 +58,116 @@ type DbUserSpec struct {
 	// +kubebuilder:default=true
 	// +optional
 	GrantToAdmin bool `json:"grantToAdmin"`
+	// Postgres specific settings of the user
+	Postgres DbUserPostgres `json:"postgres,omitempty"`
+	// Mysql specific settings of the user
+	Mysql MysqlUser `json:"mysql,omitempty"`
+	// ClickHouse specific settings of the user
+	Clickhouse ClickhouseUser `json:"clickhouse,omitempty"`
+}
+
+// DbUserPostgres defines settings that are only applicable to postgres users
+type DbUserPostgres struct {
+	// Overrides the deletion policy of the database for the user,
+	// objects are reassigned to the main user, when neither of them is set
+	// +kubebuilder:validation:Enum=reassign;drop;fail
+	// +optional
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+	// How many concurrent connections the user can make, -1 means no limit
+	// +kubebuilder:validation:Minimum=-1
+	// +optional
+	ConnectionLimit *int `json:"connectionLimit,omitempty"`
+	// Time after which the password of the user is not valid anymore
+	// +optional
+	ValidUntil *metav1.Time `json:"validUntil,omitempty"`
+	// Parameters that are set for the user with ALTER ROLE ... SET, e.g. statement_timeout
+	// or work_mem, elements of lists are separated by commas. Parameters that are
+	// not listed here are reset
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+// MysqlUser defines accounts of a mysql user and their limits. Each host
+// gets its own account, accounts of other hosts are removed
+type MysqlUser struct {
+	// Hosts that the user can connect from: host names, patterns with % and _ wildcards,
+	// or IPv4 CIDRs, e.g. 10.0.0.0/8. By default the user can connect from any host
+	// +kubebuilder:validation:items:Pattern=`^[A-Za-z0-9%_.:/-]+$`
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+	// An authentication plugin of the user, the default plugin of the server is used when it's not set
+	// +kubebuilder:validation:Enum=caching_sha2_password;mysql_native_password
+	// +optional
+	AuthPlugin string `json:"authPlugin,omitempty"`
+	// A TLS requirement for connections of the user
+	// +kubebuilder:validation:Enum=none;ssl;x509
+	// +optional
+	RequireTLS string `json:"requireTLS,omitempty"`
+	// How many concurrent connections the user can make, 0 means no limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxUserConnections *int `json:"maxUserConnections,omitempty"`
+	// How many queries the user can run per hour, 0 means no limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxQueriesPerHour *int `json:"maxQueriesPerHour,omitempty"`
+}
+
+// ClickhouseUser defines limits of a clickhouse user. They're applied through a settings profile
+// and a quota of the user, that are removed together with the user
+type ClickhouseUser struct {
+	// The maximum amount of memory in bytes that a query of the user can use
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxMemoryUsage *int64 `json:"maxMemoryUsage,omitempty"`
+	// The maximum execution time of a query in seconds
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxExecutionTime *int64 `json:"maxExecutionTime,omitempty"`
+	// Restricts queries of the user, 1 allows only reading, 2 also allows changing settings
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=2
+	// +optional
+	Readonly *int `json:"readonly,omitempty"`
+	// Limits of the user per interval, intervals that are not listed here are removed
+	// +optional
+	Quotas []ClickhouseQuota `json:"quotas,omitempty"`
+	// Filters of rows that the user can read, policies of tables
+	// that are not listed here are removed
+	// +optional
+	RowPolicies []ClickhouseRowPolicy `json:"rowPolicies,omitempty"`
+}
+
+// ClickhouseQuota limits resources that the user can consume during an interval
+type ClickhouseQuota struct {
+	// The length of the interval, e.g. "1 hour" or "30 minutes"
+	// +kubebuilder:validation:Pattern=`^[0-9]+ (second|minute|hour|day|week)s?$`
+	Interval string `json:"interval"`
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Queries *int64 `json:"queries,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Errors *int64 `json:"errors,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ResultRows *int64 `json:"resultRows,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ReadRows *int64 `json:"readRows,omitempty"`
+	// Execution time of all queries in seconds
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ExecutionTime *int64 `json:"executionTime,omitempty"`
+}
+
+// ClickhouseRowPolicy allows the user to read only rows of a table that match the condition
+type ClickhouseRowPolicy struct {
+	// A table of the database, or * for all tables
+	// +kubebuilder:validation:Pattern=`^([A-Za-z0-9_]+|\*)$`
+	Table string `json:"table"`
+	// A filter expression, e.g. tenant_id = 42
+	// +kubebuilder:validation:MinLength=1
+	Condition string `json:"condition"`
 }
 
 // DbUserStatus defines the observed state of DbUser
@@ -64,6 +177,10 @@ type DbUserStatus struct {
 	// It's required to let the operator update users
 	Created         bool   `json:"created"`
 	OperatorVersion string `json:"operatorVersion,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -103,17 +220,24 @@ const (
 	READWRITE = "readWrite"
 )
 
-// IsAccessTypeSupported returns an error if access type is not supported
+// IsBuiltinAccessType returns true if the access type doesn't reference a DbAccessProfile
+func IsBuiltinAccessType(accessType string) bool {
+	return accessType == READONLY || accessType == READWRITE
+}
+
+// IsAccessTypeSupported returns an error if access type is neither
+// a built-in one nor can be a name of a DbAccessProfile
 func IsAccessTypeSupported(wantedAccessType string) error {
-	supportedAccessTypes := []string{READONLY, READWRITE}
-	for _, supportedAccessType := range supportedAccessTypes {
-		if supportedAccessType == wantedAccessType {
-			return nil
-		}
+	if IsBuiltinAccessType(wantedAccessType) {
+		return nil
+	}
+	// The main user access type is reserved for users that are created by Databases
+	if wantedAccessType != engines.ACCESS_TYPE_MAINUSER && len(validation.IsDNS1123Subdomain(wantedAccessType)) == 0 {
+		return nil
 	}
-	return fmt.Errorf("the provided access type is not supported by the operator: %s - please choose one of these: %v",
+	return fmt.Errorf("the provided access type is not supported by the operator: %s - please choose one of these: %v, or a name of a DbAccessProfile",
 		wantedAccessType,
-		supportedAccessTypes,
+		[]string{READONLY, READWRITE},
 	)
 }
 
@@ -125,11 +125,12 @@ func (r *DbUser) ValidateUpdate(ctx context.Context, obj runtime.Object, old run
 	if old.(*DbUser).Spec.GrantToAdmin != r.Spec.GrantToAdmin {
 		return nil, errors.New("grantToAdmin is an immutable field")
 	}
+	// The webhook doesn't know the engine of the user, so the warning names the ones that revoke roles
 	for _, role := range old.(*DbUser).Spec.ExtraPrivileges {
 		if !slices.Contains(r.Spec.ExtraPrivileges, role) {
 			warnings = append(
 				warnings,
-				fmt.Sprintf("extra privileges can't be removed by the operator, please manualy revoke %s from the user %s",
+				fmt.Sprintf("extra privileges are only revoked by the operator on MySQL and MariaDB, on other engines please manually revoke %s from the user %s",
 					role, r.Name),
 			)
 		}
@@ -17,11 +17,13 @@
 package v1beta1_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/db-operator/db-operator/api/v1beta1"
 	"github.com/db-operator/db-operator/pkg/consts"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestExtraPrivilegesFail1(t *testing.T) {
@@ -47,3 +49,29 @@ func TestExtraPrivileges(t *testing.T) {
 	err := v1beta1.TestExtraPrivileges(privileges)
 	assert.NoError(t, err)
 }
+
+func TestUnitAccessTypeSupported(t *testing.T) {
+	assert.NoError(t, v1beta1.IsAccessTypeSupported(v1beta1.READONLY))
+	assert.NoError(t, v1beta1.IsAccessTypeSupported(v1beta1.READWRITE))
+	// Names of access profiles
+	assert.NoError(t, v1beta1.IsAccessTypeSupported("app-readwrite"))
+	assert.Error(t, v1beta1.IsAccessTypeSupported("main"))
+	assert.Error(t, v1beta1.IsAccessTypeSupported("Not A Profile"))
+	assert.Error(t, v1beta1.IsAccessTypeSupported(""))
+}
+
+func TestUnitRemovedExtraPrivilegesWarning(t *testing.T) {
+	old := &v1beta1.DbUser{
+		ObjectMeta: metav1.ObjectMeta{Name: "analyst"},
+		Spec: v1beta1.DbUserSpec{
+			DatabaseRef:     "db",
+			AccessType:      v1beta1.READONLY,
+			ExtraPrivileges: []string{"reporting", "auditing"},
+		},
+	}
+	updated := old.DeepCopy()
+	updated.Spec.ExtraPrivileges = []string{"reporting"}
+	warnings, err := updated.ValidateUpdate(context.TODO(), updated, old)
+	assert.NoError(t, err)
+	assert.Contains(t, warnings, "extra privileges are only revoked by the operator on MySQL and MariaDB, on other engines please manually revoke auditing from the user analyst")
+}
@@ -19,6 +19,7 @@
 package v1beta1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -81,6 +82,7 @@ func (in *Clickhouse) DeepCopyInto(out *Clickhouse) {
 			(*out)[key] = val
 		}
 	}
+	in.ClickhouseUser.DeepCopyInto(&out.ClickhouseUser)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Clickhouse.
@@ -93,6 +95,103 @@ func (in *Clickhouse) DeepCopy() *Clickhouse {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClickhouseQuota) DeepCopyInto(out *ClickhouseQuota) {
+	*out = *in
+	if in.Queries != nil {
+		in, out := &in.Queries, &out.Queries
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Errors != nil {
+		in, out := &in.Errors, &out.Errors
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ResultRows != nil {
+		in, out := &in.ResultRows, &out.ResultRows
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ReadRows != nil {
+		in, out := &in.ReadRows, &out.ReadRows
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ExecutionTime != nil {
+		in, out := &in.ExecutionTime, &out.ExecutionTime
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClickhouseQuota.
+func (in *ClickhouseQuota) DeepCopy() *ClickhouseQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(ClickhouseQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClickhouseRowPolicy) DeepCopyInto(out *ClickhouseRowPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClickhouseRowPolicy.
+func (in *ClickhouseRowPolicy) DeepCopy() *ClickhouseRowPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClickhouseRowPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClickhouseUser) DeepCopyInto(out *ClickhouseUser) {
+	*out = *in
+	if in.MaxMemoryUsage != nil {
+		in, out := &in.MaxMemoryUsage, &out.MaxMemoryUsage
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxExecutionTime != nil {
+		in, out := &in.MaxExecutionTime, &out.MaxExecutionTime
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Readonly != nil {
+		in, out := &in.Readonly, &out.Readonly
+		*out = new(int)
+		**out = **in
+	}
+	if in.Quotas != nil {
+		in, out := &in.Quotas, &out.Quotas
+		*out = make([]ClickhouseQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RowPolicies != nil {
+		in, out := &in.RowPolicies, &out.RowPolicies
+		*out = make([]ClickhouseRowPolicy, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClickhouseUser.
+func (in *ClickhouseUser) DeepCopy() *ClickhouseUser {
+	if in == nil {
+		return nil
+	}
+	out := new(ClickhouseUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Credentials) DeepCopyInto(out *Credentials) {
 	*out = *in
@@ -125,7 +224,7 @@ func (in *Database) DeepCopyInto(out *Database) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Database.
@@ -161,6 +260,21 @@ func (in *DatabaseBackup) DeepCopy() *DatabaseBackup {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseExtensionStatus) DeepCopyInto(out *DatabaseExtensionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseExtensionStatus.
+func (in *DatabaseExtensionStatus) DeepCopy() *DatabaseExtensionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseExtensionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseList) DeepCopyInto(out *DatabaseList) {
 	*out = *in
@@ -220,10 +334,12 @@ func (in *DatabaseSpec) DeepCopyInto(out *DatabaseSpec) {
 		}
 	}
 	in.Postgres.DeepCopyInto(&out.Postgres)
+	in.Mysql.DeepCopyInto(&out.Mysql)
 	in.MongoDB.DeepCopyInto(&out.MongoDB)
 	in.Clickhouse.DeepCopyInto(&out.Clickhouse)
 	in.Oracle.DeepCopyInto(&out.Oracle)
 	in.SQLServer.DeepCopyInto(&out.SQLServer)
+	out.Redis = in.Redis
 	in.Credentials.DeepCopyInto(&out.Credentials)
 	if in.AllowedNamespaces != nil {
 		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
@@ -246,6 +362,23 @@ func (in *DatabaseSpec) DeepCopy() *DatabaseSpec {
 func (in *DatabaseStatus) DeepCopyInto(out *DatabaseStatus) {
 	*out = *in
 	out.ProxyStatus = in.ProxyStatus
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make([]DatabaseExtensionStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseStatus.
@@ -258,6 +391,157 @@ func (in *DatabaseStatus) DeepCopy() *DatabaseStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DbAccessProfile) DeepCopyInto(out *DbAccessProfile) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbAccessProfile.
+func (in *DbAccessProfile) DeepCopy() *DbAccessProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(DbAccessProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DbAccessProfile) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DbAccessProfileDefaultPrivileges) DeepCopyInto(out *DbAccessProfileDefaultPrivileges) {
+	*out = *in
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Sequences != nil {
+		in, out := &in.Sequences, &out.Sequences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Functions != nil {
+		in, out := &in.Functions, &out.Functions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbAccessProfileDefaultPrivileges.
+func (in *DbAccessProfileDefaultPrivileges) DeepCopy() *DbAccessProfileDefaultPrivileges {
+	if in == nil {
+		return nil
+	}
+	out := new(DbAccessProfileDefaultPrivileges)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DbAccessProfileEngine) DeepCopyInto(out *DbAccessProfileEngine) {
+	*out = *in
+	if in.Database != nil {
+		in, out := &in.Database, &out.Database
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Sequences != nil {
+		in, out := &in.Sequences, &out.Sequences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Functions != nil {
+		in, out := &in.Functions, &out.Functions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.DefaultPrivileges.DeepCopyInto(&out.DefaultPrivileges)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbAccessProfileEngine.
+func (in *DbAccessProfileEngine) DeepCopy() *DbAccessProfileEngine {
+	if in == nil {
+		return nil
+	}
+	out := new(DbAccessProfileEngine)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DbAccessProfileList) DeepCopyInto(out *DbAccessProfileList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DbAccessProfile, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbAccessProfileList.
+func (in *DbAccessProfileList) DeepCopy() *DbAccessProfileList {
+	if in == nil {
+		return nil
+	}
+	out := new(DbAccessProfileList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DbAccessProfileList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DbAccessProfileSpec) DeepCopyInto(out *DbAccessProfileSpec) {
+	*out = *in
+	if in.Engines != nil {
+		in, out := &in.Engines, &out.Engines
+		*out = make([]DbAccessProfileEngine, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbAccessProfileSpec.
+func (in *DbAccessProfileSpec) DeepCopy() *DbAccessProfileSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DbAccessProfileSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DbInstance) DeepCopyInto(out *DbInstance) {
 	*out = *in
@@ -347,6 +631,69 @@ func (in *DbInstanceMonitoring) DeepCopy() *DbInstanceMonitoring {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DbInstancePlugin) DeepCopyInto(out *DbInstancePlugin) {
+	*out = *in
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(DbInstancePluginTLS)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbInstancePlugin.
+func (in *DbInstancePlugin) DeepCopy() *DbInstancePlugin {
+	if in == nil {
+		return nil
+	}
+	out := new(DbInstancePlugin)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DbInstancePluginTLS) DeepCopyInto(out *DbInstancePluginTLS) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbInstancePluginTLS.
+func (in *DbInstancePluginTLS) DeepCopy() *DbInstancePluginTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(DbInstancePluginTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DbInstancePostgresUserPolicy) DeepCopyInto(out *DbInstancePostgresUserPolicy) {
+	*out = *in
+	if in.MaxConnectionLimit != nil {
+		in, out := &in.MaxConnectionLimit, &out.MaxConnectionLimit
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxSettings != nil {
+		in, out := &in.MaxSettings, &out.MaxSettings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbInstancePostgresUserPolicy.
+func (in *DbInstancePostgresUserPolicy) DeepCopy() *DbInstancePostgresUserPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DbInstancePostgresUserPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DbInstanceSSLConnection) DeepCopyInto(out *DbInstanceSSLConnection) {
 	*out = *in
@@ -414,6 +761,22 @@ func (in *DbInstanceSpec) DeepCopyInto(out *DbInstanceSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.AllowedAccessProfiles != nil {
+		in, out := &in.AllowedAccessProfiles, &out.AllowedAccessProfiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedExtensions != nil {
+		in, out := &in.AllowedExtensions, &out.AllowedExtensions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.UserPolicy.DeepCopyInto(&out.UserPolicy)
+	if in.Plugin != nil {
+		in, out := &in.Plugin, &out.Plugin
+		*out = new(DbInstancePlugin)
+		(*in).DeepCopyInto(*out)
+	}
 	in.DbInstanceSource.DeepCopyInto(&out.DbInstanceSource)
 }
 
@@ -456,13 +819,29 @@ func (in *DbInstanceStatus) DeepCopy() *DbInstanceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DbInstanceUserPolicy) DeepCopyInto(out *DbInstanceUserPolicy) {
+	*out = *in
+	in.Postgres.DeepCopyInto(&out.Postgres)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbInstanceUserPolicy.
+func (in *DbInstanceUserPolicy) DeepCopy() *DbInstanceUserPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DbInstanceUserPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DbUser) DeepCopyInto(out *DbUser) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbUser.
@@ -515,6 +894,37 @@ func (in *DbUserList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DbUserPostgres) DeepCopyInto(out *DbUserPostgres) {
+	*out = *in
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int)
+		**out = **in
+	}
+	if in.ValidUntil != nil {
+		in, out := &in.ValidUntil, &out.ValidUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbUserPostgres.
+func (in *DbUserPostgres) DeepCopy() *DbUserPostgres {
+	if in == nil {
+		return nil
+	}
+	out := new(DbUserPostgres)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DbUserSpec) DeepCopyInto(out *DbUserSpec) {
 	*out = *in
@@ -529,6 +939,9 @@ func (in *DbUserSpec) DeepCopyInto(out *DbUserSpec) {
 		copy(*out, *in)
 	}
 	in.Credentials.DeepCopyInto(&out.Credentials)
+	in.Postgres.DeepCopyInto(&out.Postgres)
+	in.Mysql.DeepCopyInto(&out.Mysql)
+	in.Clickhouse.DeepCopyInto(&out.Clickhouse)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbUserSpec.
@@ -544,6 +957,13 @@ func (in *DbUserSpec) DeepCopy() *DbUserSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DbUserStatus) DeepCopyInto(out *DbUserStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbUserStatus.
@@ -638,6 +1058,52 @@ func (in *MongoDB) DeepCopy() *MongoDB {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Mysql) DeepCopyInto(out *Mysql) {
+	*out = *in
+	in.MysqlUser.DeepCopyInto(&out.MysqlUser)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Mysql.
+func (in *Mysql) DeepCopy() *Mysql {
+	if in == nil {
+		return nil
+	}
+	out := new(Mysql)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlUser) DeepCopyInto(out *MysqlUser) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxUserConnections != nil {
+		in, out := &in.MaxUserConnections, &out.MaxUserConnections
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxQueriesPerHour != nil {
+		in, out := &in.MaxQueriesPerHour, &out.MaxQueriesPerHour
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlUser.
+func (in *MysqlUser) DeepCopy() *MysqlUser {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NamespacedName) DeepCopyInto(out *NamespacedName) {
 	*out = *in
@@ -703,11 +1169,33 @@ func (in *Postgres) DeepCopyInto(out *Postgres) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExtensionConfigs != nil {
+		in, out := &in.ExtensionConfigs, &out.ExtensionConfigs
+		*out = make([]PostgresExtension, len(*in))
+		copy(*out, *in)
+	}
 	if in.Schemas != nil {
 		in, out := &in.Schemas, &out.Schemas
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ObjectOwners != nil {
+		in, out := &in.ObjectOwners, &out.ObjectOwners
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int)
+		**out = **in
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Postgres.
@@ -720,6 +1208,36 @@ func (in *Postgres) DeepCopy() *Postgres {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresExtension) DeepCopyInto(out *PostgresExtension) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresExtension.
+func (in *PostgresExtension) DeepCopy() *PostgresExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresExtension)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Redis) DeepCopyInto(out *Redis) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Redis.
+func (in *Redis) DeepCopy() *Redis {
+	if in == nil {
+		return nil
+	}
+	out := new(Redis)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SQLServer) DeepCopyInto(out *SQLServer) {
 	*out = *in
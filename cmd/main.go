@@ -31,6 +31,7 @@ import (
 	kindarocksv1beta1 "github.com/db-operator/db-operator/api/v1beta1"
 	controllers "github.com/db-operator/db-operator/internal/controller"
 	"github.com/db-operator/db-operator/pkg/config"
+	"github.com/db-operator/db-operator/pkg/utils/database"
 	"github.com/db-operator/db-operator/pkg/utils/thirdpartyapi"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.) to ensure that exec-entrypoint and run can make use of them.
@@ -117,6 +118,10 @@ func main() {
 			setupLog.Error(err, "an error occured when reading the config")
 			os.Exit(1)
 		}
+		database.Connections.Configure(conf.Connections.PoolConfig())
+		database.ConfigureTimeouts(conf.Timeouts.TimeoutConfig())
+		database.ConfigurePluginSecrets(mgr.GetClient())
+		defer database.Connections.Close()
 
 		interval := os.Getenv("RECONCILE_INTERVAL")
 		i, err := strconv.ParseInt(interval, 10, 64)
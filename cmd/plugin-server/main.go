@@ -0,0 +1,128 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// A reference implementation of an engine plugin, it serves one of
+// the engines that are built into db-operator over the plugin protocol.
+// In-house engines are expected to be served the same way, with
+// their own constructor passed to database.NewPluginServer
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"net"
+	"os"
+
+	"github.com/db-operator/db-operator/pkg/consts"
+	"github.com/db-operator/db-operator/pkg/utils/database"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var setupLog = ctrl.Log.WithName("setup")
+
+func main() {
+	var listenAddr string
+	var engine string
+	var certFile, keyFile, clientCAFile string
+	flag.StringVar(&listenAddr, "listen-address", ":9090", "The address the gRPC server binds to.")
+	flag.StringVar(&engine, "engine", "dummy", "The engine that is served by the plugin.")
+	flag.StringVar(&certFile, "tls-cert-file", "", "The server certificate, plain text is served when it's not set.")
+	flag.StringVar(&keyFile, "tls-key-file", "", "The key of the server certificate.")
+	flag.StringVar(&clientCAFile, "client-ca-file", "", "The CA of client certificates, they're required when it's set.")
+	opts := zap.Options{
+		Development: true,
+	}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	newDatabase, err := engineConstructor(engine)
+	if err != nil {
+		setupLog.Error(err, "unable to serve the engine", "engine", engine)
+		os.Exit(1)
+	}
+
+	lis, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		setupLog.Error(err, "unable to listen", "address", listenAddr)
+		os.Exit(1)
+	}
+
+	serverOpts, err := tlsOptions(certFile, keyFile, clientCAFile)
+	if err != nil {
+		setupLog.Error(err, "unable to configure tls")
+		os.Exit(1)
+	}
+	srv := grpc.NewServer(serverOpts...)
+	database.NewPluginServer(srv, newDatabase)
+
+	setupLog.Info("Starting plugin server", "engine", engine, "address", listenAddr)
+	if err := srv.Serve(lis); err != nil {
+		setupLog.Error(err, "problem running plugin server")
+		os.Exit(1)
+	}
+}
+
+// tlsOptions returns options of a server with the certificate, client certificates
+// are verified, when the client CA is set
+func tlsOptions(certFile, keyFile, clientCAFile string) ([]grpc.ServerOption, error) {
+	if certFile == "" {
+		if clientCAFile != "" {
+			return nil, errors.New("client ca can't be set without the server certificate")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCAFile != "" {
+		ca, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.New("invalid client ca")
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(config))}, nil
+}
+
+func engineConstructor(engine string) (func(ctx context.Context, cfg database.EngineConfig) (database.Database, error), error) {
+	switch engine {
+	case "dummy":
+		return func(ctx context.Context, cfg database.EngineConfig) (database.Database, error) {
+			return database.Dummy{}, nil
+		}, nil
+	case consts.ENGINE_PLUGIN:
+		return nil, errors.New("plugin can't serve the plugin engine")
+	default:
+		e, err := database.GetEngine(engine)
+		if err != nil {
+			return nil, err
+		}
+		return e.New, nil
+	}
+}
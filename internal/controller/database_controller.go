@@ -21,8 +21,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -46,6 +49,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
@@ -371,6 +376,7 @@ func (r *DatabaseReconciler) handleDbDelete(ctx context.Context, dbcr *kindav1be
 
 	if commonhelper.ContainsString(dbcr.ObjectMeta.Finalizers, "db."+dbcr.Name) {
 		err := r.deleteDatabase(ctx, dbcr)
+		setDeletionCondition(&dbcr.Status.Conditions, dbcr.Generation, err)
 		if err != nil {
 			log.Error(err, "failed deleting database")
 			// when database deletion failed, don't requeue request. to prevent exceeding api limit (ex: against google api)
@@ -511,6 +517,12 @@ func (r *DatabaseReconciler) createDatabase(ctx context.Context, dbcr *kindav1be
 		return err
 	}
 
+	if instance.Spec.Engine == consts.ENGINE_POSTGRES {
+		if err := instance.ValidateExtensions(dbcr.Spec.Postgres.ExtensionNames()); err != nil {
+			return err
+		}
+	}
+
 	err = database.CreateDatabase(ctx, db, adminCred)
 	if err != nil {
 		return err
@@ -521,6 +533,28 @@ func (r *DatabaseReconciler) createDatabase(ctx context.Context, dbcr *kindav1be
 		return err
 	}
 
+	managedExtensions := []string{}
+	for _, ext := range dbcr.Status.Extensions {
+		managedExtensions = append(managedExtensions, ext.Name)
+	}
+	extensions, err := database.ReconcileExtensions(ctx, db, managedExtensions, adminCred)
+	if err != nil {
+		return err
+	}
+
+	if err := database.PruneSettings(ctx, db, dbcr.Status.Settings, adminCred); err != nil {
+		return err
+	}
+
+	retainedSchemas, err := database.PruneSchemas(ctx, db, adminCred)
+	if err != nil {
+		return err
+	}
+	if len(retainedSchemas) > 0 {
+		r.Recorder.Event(dbcr, "Warning", consts.CONDITION_SCHEMA_DRIFT,
+			fmt.Sprintf("schemas are removed from the spec, but retained in the database: %s", strings.Join(retainedSchemas, ", ")))
+	}
+
 	kci.AddFinalizer(&dbcr.ObjectMeta, "db."+dbcr.Name)
 
 	commonhelper.AddDBChecksum(dbcr, dbSecret)
@@ -538,10 +572,24 @@ func (r *DatabaseReconciler) createDatabase(ctx context.Context, dbcr *kindav1be
 	dbcr.Status.OperatorVersion = commonhelper.OperatorVersion
 	dbcr.Status.DatabaseName = databaseCred.Name
 	dbcr.Status.UserName = databaseCred.Username
+	dbcr.Status.Extensions = extensionStatus(extensions)
+	if instance.Spec.Engine == consts.ENGINE_POSTGRES {
+		dbcr.Status.Settings = slices.Sorted(maps.Keys(dbcr.Spec.Postgres.Settings))
+		setSchemaDriftCondition(&dbcr.Status.Conditions, dbcr.Generation, retainedSchemas)
+	}
 	log.Info("successfully created")
 	return nil
 }
 
+// extensionStatus turns versions of extensions into the status, sorted by names
+func extensionStatus(versions map[string]string) []kindav1beta1.DatabaseExtensionStatus {
+	var status []kindav1beta1.DatabaseExtensionStatus
+	for _, name := range slices.Sorted(maps.Keys(versions)) {
+		status = append(status, kindav1beta1.DatabaseExtensionStatus{Name: name, Version: versions[name]})
+	}
+	return status
+}
+
 func (r *DatabaseReconciler) deleteDatabase(ctx context.Context, dbcr *kindav1beta1.Database) error {
 	log := log.FromContext(ctx)
 	if dbcr.Spec.DeletionProtected {
@@ -937,10 +985,12 @@ func (r *DatabaseReconciler) manageError(ctx context.Context, dbcr *kindav1beta1
 	err := r.Status().Update(ctx, dbcr)
 	if err != nil {
 		log.Error(err, "unable to update status")
-		return reconcile.Result{
-			RequeueAfter: retryInterval,
-			Requeue:      requeue,
-		}, nil
+	}
+
+	// Timeouts are returned, so the request is requeued with an exponential
+	// backoff, even if it's not requeued on other errors
+	if database.IsTimeout(issue) {
+		return reconcile.Result{}, issue
 	}
 
 	// TODO: implementing reschedule calculation based on last updated time
@@ -950,6 +1000,44 @@ func (r *DatabaseReconciler) manageError(ctx context.Context, dbcr *kindav1beta1
 	}, nil
 }
 
+// setDeletionCondition reports the result of removing a resource from the database server,
+// so it can be seen why a resource is stuck, e.g. because its user still owns objects
+func setDeletionCondition(conditions *[]metav1.Condition, generation int64, err error) {
+	condition := metav1.Condition{
+		Type:               consts.CONDITION_DELETED,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             consts.REASON_DELETED,
+		Message:            "the deletion is finished",
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = consts.REASON_DELETION_FAILED
+		if errors.Is(err, database.ErrOwnedObjects) {
+			condition.Reason = consts.REASON_OWNED_OBJECTS
+		}
+		condition.Message = err.Error()
+	}
+	meta.SetStatusCondition(conditions, condition)
+}
+
+// setSchemaDriftCondition reports schemas that are left in the database after they were removed from the spec
+func setSchemaDriftCondition(conditions *[]metav1.Condition, generation int64, retained []string) {
+	condition := metav1.Condition{
+		Type:               consts.CONDITION_SCHEMA_DRIFT,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             consts.REASON_SCHEMAS_IN_SYNC,
+		Message:            "schemas of the database match the spec",
+	}
+	if len(retained) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = consts.REASON_SCHEMAS_RETAINED
+		condition.Message = fmt.Sprintf("schemas are removed from the spec, but retained in the database: %s", strings.Join(retained, ", "))
+	}
+	meta.SetStatusCondition(conditions, condition)
+}
+
 func (r *DatabaseReconciler) createSecret(ctx context.Context, dbcr *kindav1beta1.Database) (*corev1.Secret, error) {
 	log := log.FromContext(ctx)
 	secretData, err := dbhelper.GenerateDatabaseSecretData(dbcr.ObjectMeta, dbcr.Status.Engine, dbcr.Spec.DatabaseName, dbcr.Spec.UserName)
@@ -0,0 +1,251 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	kindav1beta1 "github.com/db-operator/db-operator/api/v1beta1"
+	"github.com/db-operator/db-operator/pkg/config"
+	"github.com/db-operator/db-operator/pkg/consts"
+	"github.com/db-operator/db-operator/pkg/utils/database"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// testInstance is a ready generic instance that uses the recorder engine,
+// it's named after a new namespace, so every spec gets its own recorder
+type testInstance struct {
+	namespace string
+	name      string
+	recorder  database.Recorder
+}
+
+// newTestInstance creates a namespace with the admin secret and the instance,
+// the state of the recorder is released after the spec
+func newTestInstance() *testInstance {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "recorder-"}}
+	Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+	ti := &testInstance{namespace: ns.Name, name: ns.Name, recorder: database.RecorderOf(ns.Name)}
+	DeferCleanup(database.ReleaseRecorder, ti.name)
+
+	adminSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ti.namespace, Name: "recorder-admin"},
+		Data:       map[string][]byte{"user": []byte("admin"), "password": []byte("adminpwd")},
+	}
+	Expect(k8sClient.Create(ctx, adminSecret)).To(Succeed())
+
+	instance := &kindav1beta1.DbInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: ti.name},
+		Spec: kindav1beta1.DbInstanceSpec{
+			Engine: consts.ENGINE_RECORDER,
+			AdminUserSecret: kindav1beta1.NamespacedName{
+				Namespace: ti.namespace,
+				Name:      adminSecret.Name,
+			},
+			DbInstanceSource: kindav1beta1.DbInstanceSource{
+				Generic: &kindav1beta1.GenericInstance{Host: "recorder"},
+			},
+			AllowedPrivileges:     []string{"extra"},
+			AllowedAccessProfiles: []string{ti.accessProfileName("app")},
+		},
+	}
+	Expect(k8sClient.Create(ctx, instance)).To(Succeed())
+	instance.Status = kindav1beta1.DbInstanceStatus{
+		Phase:  dbInstancePhaseRunning,
+		Status: true,
+		Info:   map[string]string{"DB_CONN": "recorder", "DB_PORT": "5432"},
+	}
+	Expect(k8sClient.Status().Update(ctx, instance)).To(Succeed())
+	return ti
+}
+
+// dbName returns the name of a database or a user on the server, that is generated for a resource
+func (ti *testInstance) dbName(name string) string {
+	return ti.namespace + "-" + name
+}
+
+// accessProfileName returns the name of a profile of the instance,
+// profiles are cluster-scoped, so they're prefixed with the namespace
+func (ti *testInstance) accessProfileName(name string) string {
+	return ti.namespace + "-" + name
+}
+
+func (ti *testInstance) database(name string) *kindav1beta1.Database {
+	return &kindav1beta1.Database{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ti.namespace, Name: name},
+		Spec: kindav1beta1.DatabaseSpec{
+			SecretName: name + "-creds",
+			Instance:   ti.name,
+		},
+	}
+}
+
+func testDatabaseReconciler() *DatabaseReconciler {
+	return &DatabaseReconciler{
+		Client:   k8sClient,
+		Scheme:   k8sClient.Scheme(),
+		Recorder: &record.FakeRecorder{},
+		Conf:     &config.Config{},
+	}
+}
+
+// reconcileObject runs a reconciliation of the object and reads it again,
+// the object is reset, when it's already removed
+func reconcileObject(r interface {
+	Reconcile(context.Context, ctrl.Request) (ctrl.Result, error)
+}, obj client.Object,
+) {
+	GinkgoHelper()
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(client.IgnoreNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj))).To(Succeed())
+}
+
+// isRemoved checks that the object doesn't exist anymore
+func isRemoved(obj client.Object) bool {
+	GinkgoHelper()
+	err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj.DeepCopyObject().(client.Object))
+	return k8serrors.IsNotFound(err)
+}
+
+var _ = Describe("DatabaseReconciler with the recorder engine", func() {
+	var (
+		ti   *testInstance
+		dbcr *kindav1beta1.Database
+		r    *DatabaseReconciler
+	)
+
+	BeforeEach(func() {
+		ti = newTestInstance()
+		dbcr = ti.database("test")
+		r = testDatabaseReconciler()
+	})
+
+	It("creates the database, the main user and the credentials", func() {
+		Expect(k8sClient.Create(ctx, dbcr)).To(Succeed())
+		reconcileObject(r, dbcr)
+		Expect(dbcr.Status.Status).To(BeTrue())
+		Expect(dbcr.Status.Engine).To(Equal(consts.ENGINE_RECORDER))
+		Expect(dbcr.Finalizers).To(ContainElement("db.test"))
+		Expect(ti.recorder.Databases()).To(Equal([]string{ti.dbName("test")}))
+
+		user, ok := ti.recorder.User(ti.dbName("test"))
+		Expect(ok).To(BeTrue())
+		Expect(user.Grants[ti.dbName("test")].AccessType).To(Equal(database.ACCESS_TYPE_MAINUSER))
+
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: ti.namespace, Name: "test-creds"}, secret)).To(Succeed())
+		Expect(string(secret.Data[consts.RECORDER_PASSWORD])).To(Equal(user.Password))
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: ti.namespace, Name: "test-creds"}, &corev1.ConfigMap{})).To(Succeed())
+
+		By("checking the status and updating the user on the second reconciliation")
+		reconcileObject(r, dbcr)
+		Expect(ti.recorder.Methods()).To(Equal([]string{
+			"createDatabase",
+			"createOrUpdateUser", "createUser", "setUserPermission",
+			"CheckStatus",
+			"createDatabase",
+			"createOrUpdateUser", "updateUser", "setUserPermission",
+		}))
+	})
+
+	It("retries a failed creation", func() {
+		Expect(k8sClient.Create(ctx, dbcr)).To(Succeed())
+		ti.recorder.FailNext("createDatabase", errors.New("injected error"))
+		reconcileObject(r, dbcr)
+		Expect(dbcr.Status.Status).To(BeFalse())
+		Expect(dbcr.Finalizers).NotTo(ContainElement("db.test"))
+		Expect(ti.recorder.Databases()).To(BeEmpty())
+
+		reconcileObject(r, dbcr)
+		Expect(dbcr.Status.Status).To(BeTrue())
+		Expect(ti.recorder.Databases()).To(Equal([]string{ti.dbName("test")}))
+	})
+
+	It("removes the database and the main user with the finalizer", func() {
+		Expect(k8sClient.Create(ctx, dbcr)).To(Succeed())
+		reconcileObject(r, dbcr)
+		Expect(k8sClient.Delete(ctx, dbcr)).To(Succeed())
+
+		reconcileObject(r, dbcr)
+		Expect(ti.recorder.Databases()).To(BeEmpty())
+		_, ok := ti.recorder.User(ti.dbName("test"))
+		Expect(ok).To(BeFalse())
+		methods := ti.recorder.Methods()
+		Expect(methods[len(methods)-2:]).To(Equal([]string{"deleteDatabase", "deleteUser"}))
+		Expect(isRemoved(dbcr)).To(BeTrue())
+	})
+
+	It("keeps a protected database on the server", func() {
+		dbcr.Spec.DeletionProtected = true
+		Expect(k8sClient.Create(ctx, dbcr)).To(Succeed())
+		reconcileObject(r, dbcr)
+		Expect(k8sClient.Delete(ctx, dbcr)).To(Succeed())
+
+		reconcileObject(r, dbcr)
+		Expect(ti.recorder.Databases()).To(Equal([]string{ti.dbName("test")}))
+		Expect(ti.recorder.Methods()).NotTo(ContainElement("deleteDatabase"))
+		Expect(isRemoved(dbcr)).To(BeTrue())
+	})
+})
+
+func TestUnitSetDeletionCondition(t *testing.T) {
+	conditions := []metav1.Condition{}
+	err := fmt.Errorf("%w: app owns objects in databases: app", database.ErrOwnedObjects)
+	setDeletionCondition(&conditions, 2, err)
+	assert.Len(t, conditions, 1)
+	assert.Equal(t, consts.CONDITION_DELETED, conditions[0].Type)
+	assert.Equal(t, metav1.ConditionFalse, conditions[0].Status)
+	assert.Equal(t, consts.REASON_OWNED_OBJECTS, conditions[0].Reason)
+	assert.Equal(t, err.Error(), conditions[0].Message)
+
+	setDeletionCondition(&conditions, 2, errors.New("connection refused"))
+	assert.Equal(t, consts.REASON_DELETION_FAILED, conditions[0].Reason)
+
+	setDeletionCondition(&conditions, 3, nil)
+	assert.Len(t, conditions, 1)
+	assert.Equal(t, metav1.ConditionTrue, conditions[0].Status)
+	assert.Equal(t, int64(3), conditions[0].ObservedGeneration)
+}
+
+func TestUnitSetSchemaDriftCondition(t *testing.T) {
+	conditions := []metav1.Condition{}
+	setSchemaDriftCondition(&conditions, 1, []string{"reports", "staging"})
+	assert.Len(t, conditions, 1)
+	assert.Equal(t, consts.CONDITION_SCHEMA_DRIFT, conditions[0].Type)
+	assert.Equal(t, metav1.ConditionTrue, conditions[0].Status)
+	assert.Equal(t, consts.REASON_SCHEMAS_RETAINED, conditions[0].Reason)
+	assert.Contains(t, conditions[0].Message, "reports, staging")
+
+	setSchemaDriftCondition(&conditions, 2, nil)
+	assert.Len(t, conditions, 1)
+	assert.Equal(t, metav1.ConditionFalse, conditions[0].Status)
+	assert.Equal(t, consts.REASON_SCHEMAS_IN_SYNC, conditions[0].Reason)
+	assert.Equal(t, int64(2), conditions[0].ObservedGeneration)
+}
@@ -25,6 +25,7 @@ import (
 	kindav1beta1 "github.com/db-operator/db-operator/api/v1beta1"
 	"github.com/db-operator/db-operator/pkg/config"
 	commonhelper "github.com/db-operator/db-operator/pkg/helpers/common"
+	dbhelper "github.com/db-operator/db-operator/pkg/helpers/database"
 	kubehelper "github.com/db-operator/db-operator/pkg/helpers/kube"
 	proxyhelper "github.com/db-operator/db-operator/pkg/helpers/proxy"
 	"github.com/db-operator/db-operator/pkg/utils/database"
@@ -117,6 +118,8 @@ func (r *DbInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		commonhelper.AddDBInstanceChecksumStatus(ctx, dbin)
 		dbin.Status.Phase = dbInstancePhaseCreate
 		dbin.Status.Info = map[string]string{}
+		// Cached admin connections might point to the previous address
+		database.Connections.Invalidate(dbin.Name)
 
 		err = r.create(ctx, dbin)
 		if err != nil {
@@ -230,14 +233,17 @@ func (r *DbInstanceReconciler) create(ctx context.Context, dbin *kindav1beta1.Db
 			publicIP = dbin.Spec.Generic.PublicIP
 		}
 		instance = &dbinstance.Generic{
-			Host:         host,
-			Port:         port,
-			PublicIP:     publicIP,
-			Engine:       dbin.Spec.Engine,
-			User:         cred.Username,
-			Password:     cred.Password,
-			SSLEnabled:   dbin.Spec.SSLConnection.Enabled,
-			SkipCAVerify: dbin.Spec.SSLConnection.SkipVerify,
+			Host:           host,
+			Port:           port,
+			PublicIP:       publicIP,
+			Engine:         dbin.Spec.Engine,
+			Annotations:    dbin.Annotations,
+			PluginEndpoint: dbin.GetPluginEndpoint(),
+			PluginTLS:      dbhelper.PluginTLS(dbin),
+			User:           cred.Username,
+			Password:       cred.Password,
+			SSLEnabled:     dbin.Spec.SSLConnection.Enabled,
+			SkipCAVerify:   dbin.Spec.SSLConnection.SkipVerify,
 		}
 	default:
 		return errors.New("not supported backend type")
@@ -18,7 +18,6 @@ package controllers
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"slices"
 	"strconv"
@@ -34,12 +33,17 @@ import (
 	"github.com/db-operator/db-operator/pkg/utils/kci"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -56,6 +60,7 @@ type DbUserReconciler struct {
 // +kubebuilder:rbac:groups=kinda.rocks,resources=dbusers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kinda.rocks,resources=dbusers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=kinda.rocks,resources=dbusers/finalizers,verbs=update
+// +kubebuilder:rbac:groups=kinda.rocks,resources=dbaccessprofiles,verbs=get;list;watch
 
 // Reconcile a DbUser object
 func (r *DbUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -146,11 +151,33 @@ func (r *DbUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 				return r.manageError(ctx, dbusercr, err, false)
 			}
 		}
-		db, dbuser, err := dbhelper.FetchDatabaseData(ctx, dbcr, creds, instance)
+		accessProfile, err := r.getAccessProfile(ctx, dbusercr, instance)
+		if err != nil {
+			// A profile is only used to revoke default privileges, so users can be
+			// removed even if the profile is not there or not allowed anymore
+			if !dbusercr.IsDeleted() {
+				return r.manageError(ctx, dbusercr, err, false)
+			}
+			log.Info("can't get the access profile of a user that is being removed, ignoring", "error", err)
+		}
+		// Engines are getting the main user of the database, because
+		// default privileges of users are set for objects of the main user
+		mainCreds, err := r.getMainUserCredentials(ctx, dbcr)
+		if err != nil {
+			// The secret of the database can be removed before its users,
+			// then the admin is acting instead of the main user
+			if !dbusercr.IsDeleted() {
+				return r.manageError(ctx, dbusercr, err, false)
+			}
+			log.Info("can't get the main user of a database, using the admin instead", "error", err)
+			mainCreds = database.Credentials{Name: creds.Name}
+		}
+		db, _, err := dbhelper.FetchDatabaseData(ctx, dbcr, mainCreds, instance)
 		if err != nil {
 			// failed to determine database type
 			return r.manageError(ctx, dbusercr, err, false)
 		}
+		dbuser := &database.DatabaseUser{}
 
 		val, ok := dbusercr.Annotations[consts.GRANT_TO_ADMIN_ON_DELETE]
 		if ok {
@@ -174,6 +201,18 @@ func (r *DbUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		dbuser.ExtraPrivileges = dbusercr.Spec.ExtraPrivileges
 
 		dbuser.GrantToAdmin = dbusercr.Spec.GrantToAdmin
+		dbuser.DeletionPolicy = dbusercr.Spec.Postgres.DeletionPolicy
+
+		// Settings are capped by the instance, users can't get more than it allows
+		spec := dbusercr.Spec.DeepCopy()
+		spec.Postgres, err = instance.Spec.UserPolicy.Postgres.Apply(spec.Postgres)
+		if err != nil && !dbusercr.IsDeleted() {
+			return r.manageError(ctx, dbusercr, err, false)
+		}
+		dbuser.Spec, err = dbhelper.DbUserEngineSpec(*spec, dbcr.Status.Engine)
+		if err != nil {
+			return r.manageError(ctx, dbusercr, err, false)
+		}
 
 		adminSecretResource, err := r.getAdminSecret(ctx, dbcr)
 		if err != nil {
@@ -186,8 +225,17 @@ func (r *DbUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			// failed to parse database admin secret
 			return r.manageError(ctx, dbusercr, err, false)
 		}
+		if mainCreds.Username == "" {
+			mainCreds.Username = adminCred.Username
+			mainCreds.Password = adminCred.Password
+			db, _, err = dbhelper.FetchDatabaseData(ctx, dbcr, mainCreds, instance)
+			if err != nil {
+				return r.manageError(ctx, dbusercr, err, false)
+			}
+		}
 
 		dbuser.AccessType = dbusercr.Spec.AccessType
+		dbuser.AccessProfile = accessProfile
 		dbuser.Password = creds.Password
 		dbuser.Username = creds.Username
 
@@ -198,8 +246,10 @@ func (r *DbUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 				}
 				if err := database.DeleteUser(ctx, db, dbuser, adminCred); err != nil {
 					log.Error(err, "failed deleting a user")
+					setDeletionCondition(&dbusercr.Status.Conditions, dbusercr.Generation, err)
 					return r.manageError(ctx, dbusercr, err, false)
 				}
+				setDeletionCondition(&dbusercr.Status.Conditions, dbusercr.Generation, nil)
 				kci.RemoveFinalizer(&dbusercr.ObjectMeta, "dbuser."+dbusercr.Name)
 				err = r.Update(ctx, dbusercr)
 				if err != nil {
@@ -262,9 +312,80 @@ func (r *DbUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 func (r *DbUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kindav1beta1.DbUser{}).
+		Watches(&kindav1beta1.Database{},
+			handler.EnqueueRequestsFromMapFunc(r.dbUsersOfDatabase),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(event.CreateEvent) bool { return false },
+				DeleteFunc:  func(event.DeleteEvent) bool { return false },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+				UpdateFunc:  isSchemaSetReconciled,
+			}),
+		).
+		Watches(&kindav1beta1.DbAccessProfile{},
+			handler.EnqueueRequestsFromMapFunc(r.dbUsersOfAccessProfile),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
 		Complete(r)
 }
 
+// isSchemaSetReconciled returns true when schemas of a Database were reconciled for a new generation,
+// grants of DbUsers must be recomputed then, because schemas could've been added or removed
+func isSchemaSetReconciled(e event.UpdateEvent) bool {
+	oldDb, ok := e.ObjectOld.(*kindav1beta1.Database)
+	if !ok {
+		return false
+	}
+	newDb, ok := e.ObjectNew.(*kindav1beta1.Database)
+	if !ok {
+		return false
+	}
+	newCondition := meta.FindStatusCondition(newDb.Status.Conditions, consts.CONDITION_SCHEMA_DRIFT)
+	if newCondition == nil {
+		return false
+	}
+	oldCondition := meta.FindStatusCondition(oldDb.Status.Conditions, consts.CONDITION_SCHEMA_DRIFT)
+	return oldCondition == nil || oldCondition.ObservedGeneration != newCondition.ObservedGeneration
+}
+
+// dbUsersOfDatabase returns requests for all DbUsers that are referencing the Database
+func (r *DbUserReconciler) dbUsersOfDatabase(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+	dbusers := &kindav1beta1.DbUserList{}
+	if err := r.List(ctx, dbusers, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.Error(err, "couldn't list DbUsers of a database", "database", obj.GetName())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, dbuser := range dbusers.Items {
+		if dbuser.Spec.DatabaseRef == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: dbuser.Namespace, Name: dbuser.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// dbUsersOfAccessProfile returns requests for all DbUsers that are using the DbAccessProfile
+// as their access type, privileges of users must be recomputed when the profile is changed
+func (r *DbUserReconciler) dbUsersOfAccessProfile(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+	dbusers := &kindav1beta1.DbUserList{}
+	if err := r.List(ctx, dbusers); err != nil {
+		log.Error(err, "couldn't list DbUsers of an access profile", "profile", obj.GetName())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, dbuser := range dbusers.Items {
+		if dbuser.Spec.AccessType == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: dbuser.Namespace, Name: dbuser.Name},
+			})
+		}
+	}
+	return requests
+}
+
 func isDbUserChanged(dbucr *kindav1beta1.DbUser, userSecret *corev1.Secret) bool {
 	annotations := dbucr.ObjectMeta.GetAnnotations()
 
@@ -297,10 +418,12 @@ func (r *DbUserReconciler) manageError(ctx context.Context, dbucr *kindav1beta1.
 	err := r.Status().Update(ctx, dbucr)
 	if err != nil {
 		log.Error(err, "unable to update status")
-		return reconcile.Result{
-			RequeueAfter: retryInterval,
-			Requeue:      requeue,
-		}, nil
+	}
+
+	// Timeouts are returned, so the request is requeued with an exponential
+	// backoff, even if it's not requeued on other errors
+	if database.IsTimeout(issue) {
+		return reconcile.Result{}, issue
 	}
 
 	// TODO: implementing reschedule calculation based on last updated time
@@ -310,53 +433,54 @@ func (r *DbUserReconciler) manageError(ctx context.Context, dbucr *kindav1beta1.
 	}, nil
 }
 
-func parseDbUserSecretData(engine string, data map[string][]byte) (database.Credentials, error) {
-	cred := database.Credentials{}
-
-	switch engine {
-	case "postgres":
-		if name, ok := data["POSTGRES_DB"]; ok {
-			cred.Name = string(name)
-		} else {
-			return cred, errors.New("POSTGRES_DB key does not exist in secret data")
-		}
-
-		if user, ok := data["POSTGRES_USER"]; ok {
-			cred.Username = string(user)
-		} else {
-			return cred, errors.New("POSTGRES_USER key does not exist in secret data")
-		}
-
-		if pass, ok := data["POSTGRES_PASSWORD"]; ok {
-			cred.Password = string(pass)
-		} else {
-			return cred, errors.New("POSTGRES_PASSWORD key does not exist in secret data")
-		}
+// getMainUserCredentials returns credentials of the main user of the database
+func (r *DbUserReconciler) getMainUserCredentials(ctx context.Context, dbcr *kindav1beta1.Database) (database.Credentials, error) {
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{
+		Namespace: dbcr.Namespace,
+		Name:      dbcr.Spec.SecretName,
+	}
+	if err := r.Get(ctx, key, secret); err != nil {
+		return database.Credentials{}, err
+	}
+	return dbhelper.ParseDatabaseSecretData(dbcr, secret.Data)
+}
 
-		return cred, nil
-	case "mysql":
-		if name, ok := data["DB"]; ok {
-			cred.Name = string(name)
-		} else {
-			return cred, errors.New("DB key does not exist in secret data")
-		}
+func parseDbUserSecretData(engine string, data map[string][]byte) (database.Credentials, error) {
+	e, err := database.GetEngine(engine)
+	if err != nil {
+		return database.Credentials{}, err
+	}
 
-		if user, ok := data["USER"]; ok {
-			cred.Username = string(user)
-		} else {
-			return cred, errors.New("USER key does not exist in secret data")
-		}
+	return e.ParseSecretData(data)
+}
 
-		if pass, ok := data["PASSWORD"]; ok {
-			cred.Password = string(pass)
-		} else {
-			return cred, errors.New("PASSWORD key does not exist in secret data")
-		}
+// getAccessProfile returns a custom access profile that is referenced by the access type
+// of the user, it's nil for built-in access types that are handled by engines
+func (r *DbUserReconciler) getAccessProfile(ctx context.Context, dbusercr *kindav1beta1.DbUser, instance *kindav1beta1.DbInstance) (*database.AccessProfile, error) {
+	accessType := dbusercr.Spec.AccessType
+	if kindav1beta1.IsBuiltinAccessType(accessType) {
+		return nil, nil
+	}
+	if err := kindav1beta1.IsAccessTypeSupported(accessType); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(instance.Spec.AllowedAccessProfiles, accessType) {
+		return nil, fmt.Errorf("access profile %s is not allowed on the instance %s", accessType, instance.Name)
+	}
+	engine, err := database.GetEngine(instance.Spec.Engine)
+	if err != nil {
+		return nil, err
+	}
+	if !engine.AccessProfiles {
+		return nil, fmt.Errorf("access profiles are not supported by the engine %s", engine.Name)
+	}
 
-		return cred, nil
-	default:
-		return cred, errors.New("not supported engine type")
+	profile := &kindav1beta1.DbAccessProfile{}
+	if err := r.Get(ctx, types.NamespacedName{Name: accessType}, profile); err != nil {
+		return nil, err
 	}
+	return profile.AccessProfile(engine.Name)
 }
 
 func (r *DbUserReconciler) getAdminSecret(ctx context.Context, dbcr *kindav1beta1.Database) (*corev1.Secret, error) {
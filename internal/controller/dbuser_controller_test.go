@@ -0,0 +1,293 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controllers
+
+import (
+	"errors"
+	"testing"
+
+	kindav1beta1 "github.com/db-operator/db-operator/api/v1beta1"
+	"github.com/db-operator/db-operator/pkg/consts"
+	"github.com/db-operator/db-operator/pkg/utils/database"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func (ti *testInstance) dbUser(name string, extraPrivileges ...string) *kindav1beta1.DbUser {
+	return &kindav1beta1.DbUser{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ti.namespace, Name: name},
+		Spec: kindav1beta1.DbUserSpec{
+			DatabaseRef:     "test",
+			AccessType:      database.ACCESS_TYPE_READONLY,
+			SecretName:      name + "-creds",
+			ExtraPrivileges: extraPrivileges,
+		},
+	}
+}
+
+// accessProfile creates a profile of the recorder engine, that is removed after the spec
+func (ti *testInstance) accessProfile(name string) *kindav1beta1.DbAccessProfile {
+	profile := &kindav1beta1.DbAccessProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: ti.accessProfileName(name)},
+		Spec: kindav1beta1.DbAccessProfileSpec{
+			Engines: []kindav1beta1.DbAccessProfileEngine{
+				{Engine: consts.ENGINE_RECORDER, Tables: []string{"SELECT", "INSERT"}},
+			},
+		},
+	}
+	Expect(k8sClient.Create(ctx, profile)).To(Succeed())
+	DeferCleanup(k8sClient.Delete, ctx, profile)
+	return profile
+}
+
+// readyDatabase creates and reconciles the "test" database, so users can be created in it
+func (ti *testInstance) readyDatabase() *kindav1beta1.Database {
+	dbcr := ti.database("test")
+	Expect(k8sClient.Create(ctx, dbcr)).To(Succeed())
+	reconcileObject(testDatabaseReconciler(), dbcr)
+	Expect(dbcr.Status.Status).To(BeTrue())
+	return dbcr
+}
+
+func testDbUserReconciler() *DbUserReconciler {
+	return &DbUserReconciler{
+		Client:   k8sClient,
+		Scheme:   k8sClient.Scheme(),
+		Recorder: &record.FakeRecorder{},
+	}
+}
+
+// requestNames returns namespaced names of requests
+func requestNames(requests []reconcile.Request) []string {
+	names := []string{}
+	for _, request := range requests {
+		names = append(names, request.String())
+	}
+	return names
+}
+
+var _ = Describe("DbUserReconciler with the recorder engine", func() {
+	var (
+		ti *testInstance
+		r  *DbUserReconciler
+	)
+
+	BeforeEach(func() {
+		ti = newTestInstance()
+		r = testDbUserReconciler()
+	})
+
+	It("creates the user with extra privileges", func() {
+		dbusercr := ti.dbUser("user", "extra")
+		Expect(k8sClient.Create(ctx, dbusercr)).To(Succeed())
+		ti.readyDatabase()
+		ti.recorder.Reset()
+
+		By("failing to grant the permission, while the database is removed from the recorder")
+		reconcileObject(r, dbusercr)
+		Expect(dbusercr.Status.Status).To(BeFalse())
+		Expect(dbusercr.Status.Created).To(BeFalse())
+
+		Expect(database.CreateDatabase(ctx, ti.recorder.ForDatabase(ti.dbName("test")), &database.DatabaseUser{Username: "admin"})).To(Succeed())
+		reconcileObject(r, dbusercr)
+		Expect(dbusercr.Status.Status).To(BeTrue())
+		Expect(dbusercr.Status.Created).To(BeTrue())
+		Expect(dbusercr.Finalizers).To(ContainElement("dbuser.user"))
+
+		dbcr := ti.database("test")
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dbcr), dbcr)).To(Succeed())
+		Expect(dbcr.Finalizers).To(ContainElement("dbuser.user"))
+
+		user, ok := ti.recorder.User(ti.dbName("user"))
+		Expect(ok).To(BeTrue())
+		Expect(user.Grants[ti.dbName("test")]).To(Equal(database.RecordedGrant{
+			AccessType:      database.ACCESS_TYPE_READONLY,
+			ExtraPrivileges: []string{"extra"},
+		}))
+
+		reconcileObject(r, dbusercr)
+		Expect(ti.recorder.Methods()).To(Equal([]string{
+			"createUser", "setUserPermission",
+			"createDatabase",
+			"createUser", "setUserPermission",
+			"createOrUpdateUser", "updateUser", "setUserPermission",
+		}))
+	})
+
+	It("creates the user again after a failure", func() {
+		dbusercr := ti.dbUser("user")
+		Expect(k8sClient.Create(ctx, dbusercr)).To(Succeed())
+		ti.readyDatabase()
+
+		ti.recorder.FailNext("setUserPermission", errors.New("injected error"))
+		reconcileObject(r, dbusercr)
+		Expect(dbusercr.Status.Status).To(BeFalse())
+		Expect(dbusercr.Finalizers).NotTo(ContainElement("dbuser.user"))
+
+		By("creating the user again, because it's not marked as created yet")
+		reconcileObject(r, dbusercr)
+		Expect(dbusercr.Status.Status).To(BeTrue())
+		methods := ti.recorder.Methods()
+		Expect(methods[len(methods)-4:]).To(Equal([]string{"createUser", "setUserPermission", "createUser", "setUserPermission"}))
+	})
+
+	It("doesn't create a user with a privilege that is not allowed", func() {
+		dbusercr := ti.dbUser("user", "superuser")
+		Expect(k8sClient.Create(ctx, dbusercr)).To(Succeed())
+		ti.readyDatabase()
+		ti.recorder.Reset()
+
+		reconcileObject(r, dbusercr)
+		Expect(dbusercr.Status.Status).To(BeFalse())
+		Expect(ti.recorder.Operations()).To(BeEmpty())
+	})
+
+	It("removes the user before the database", func() {
+		dbusercr := ti.dbUser("user")
+		Expect(k8sClient.Create(ctx, dbusercr)).To(Succeed())
+		dbcr := ti.readyDatabase()
+		reconcileObject(r, dbusercr)
+
+		By("keeping the database, while it's referenced by the user")
+		Expect(k8sClient.Delete(ctx, dbcr)).To(Succeed())
+		reconcileObject(testDatabaseReconciler(), dbcr)
+		Expect(ti.recorder.Databases()).To(Equal([]string{ti.dbName("test")}))
+
+		Expect(k8sClient.Delete(ctx, dbusercr)).To(Succeed())
+		reconcileObject(r, dbusercr)
+		_, ok := ti.recorder.User(ti.dbName("user"))
+		Expect(ok).To(BeFalse())
+		Expect(isRemoved(dbusercr)).To(BeTrue())
+
+		By("removing the database, once the user is gone")
+		reconcileObject(testDatabaseReconciler(), dbcr)
+		Expect(ti.recorder.Databases()).To(BeEmpty())
+		Expect(isRemoved(dbcr)).To(BeTrue())
+		methods := ti.recorder.Methods()
+		Expect(methods[len(methods)-3:]).To(Equal([]string{"deleteUser", "deleteDatabase", "deleteUser"}))
+	})
+
+	It("removes the user as the admin, when the database secret is already removed", func() {
+		dbusercr := ti.dbUser("user")
+		Expect(k8sClient.Create(ctx, dbusercr)).To(Succeed())
+		ti.readyDatabase()
+		reconcileObject(r, dbusercr)
+
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: ti.namespace, Name: "test-creds"}}
+		Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, dbusercr)).To(Succeed())
+		reconcileObject(r, dbusercr)
+		_, ok := ti.recorder.User(ti.dbName("user"))
+		Expect(ok).To(BeFalse())
+		Expect(isRemoved(dbusercr)).To(BeTrue())
+	})
+
+	It("grants privileges of an access profile", func() {
+		profile := ti.accessProfile("app")
+		dbusercr := ti.dbUser("user")
+		dbusercr.Spec.AccessType = profile.Name
+		Expect(k8sClient.Create(ctx, dbusercr)).To(Succeed())
+		ti.readyDatabase()
+
+		reconcileObject(r, dbusercr)
+		Expect(dbusercr.Status.Status).To(BeTrue())
+
+		user, ok := ti.recorder.User(ti.dbName("user"))
+		Expect(ok).To(BeTrue())
+		Expect(user.Grants[ti.dbName("test")]).To(Equal(database.RecordedGrant{
+			AccessType: profile.Name,
+			Profile:    &database.AccessProfile{Name: profile.Name, Tables: []string{"SELECT", "INSERT"}},
+		}))
+
+		By("letting the user write, but not create tables, like a readWrite user")
+		db := ti.recorder.ForDatabase(ti.dbName("test"))
+		dbuser := &database.DatabaseUser{Username: ti.dbName("user"), Password: user.Password}
+		_, err := db.QueryAsUser(ctx, "INSERT INTO test VALUES (1)", dbuser)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = db.QueryAsUser(ctx, "CREATE TABLE test (id int)", dbuser)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("doesn't create a user with a profile that is not allowed or doesn't exist", func() {
+		profile := ti.accessProfile("other")
+		dbusercr := ti.dbUser("user")
+		dbusercr.Spec.AccessType = profile.Name
+		Expect(k8sClient.Create(ctx, dbusercr)).To(Succeed())
+		ti.readyDatabase()
+		ti.recorder.Reset()
+
+		reconcileObject(r, dbusercr)
+		Expect(dbusercr.Status.Status).To(BeFalse())
+		Expect(ti.recorder.Operations()).To(BeEmpty())
+
+		By("using a profile that is allowed, but doesn't exist")
+		dbusercr.Spec.AccessType = ti.accessProfileName("app")
+		Expect(k8sClient.Update(ctx, dbusercr)).To(Succeed())
+		reconcileObject(r, dbusercr)
+		Expect(dbusercr.Status.Status).To(BeFalse())
+		Expect(ti.recorder.Operations()).To(BeEmpty())
+	})
+
+	It("maps a database to its users", func() {
+		other := ti.dbUser("other")
+		other.Spec.DatabaseRef = "other"
+		for _, dbusercr := range []*kindav1beta1.DbUser{ti.dbUser("reader"), ti.dbUser("writer"), other} {
+			Expect(k8sClient.Create(ctx, dbusercr)).To(Succeed())
+		}
+
+		requests := r.dbUsersOfDatabase(ctx, ti.database("test"))
+		Expect(requestNames(requests)).To(ConsistOf(ti.namespace+"/reader", ti.namespace+"/writer"))
+	})
+
+	It("maps an access profile to its users in all namespaces", func() {
+		profile := ti.accessProfile("app")
+		otherInstance := newTestInstance()
+		app := ti.dbUser("app")
+		app.Spec.AccessType = profile.Name
+		other := otherInstance.dbUser("other")
+		other.Spec.AccessType = profile.Name
+		for _, dbusercr := range []*kindav1beta1.DbUser{ti.dbUser("reader"), app, other} {
+			Expect(k8sClient.Create(ctx, dbusercr)).To(Succeed())
+		}
+
+		requests := r.dbUsersOfAccessProfile(ctx, profile)
+		Expect(requestNames(requests)).To(ConsistOf(ti.namespace+"/app", otherInstance.namespace+"/other"))
+	})
+})
+
+func TestUnitIsSchemaSetReconciled(t *testing.T) {
+	oldDb := &kindav1beta1.Database{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"}}
+	newDb := oldDb.DeepCopy()
+	assert.False(t, isSchemaSetReconciled(event.UpdateEvent{ObjectOld: oldDb, ObjectNew: newDb}), "schemas are not reconciled yet")
+
+	setSchemaDriftCondition(&newDb.Status.Conditions, 1, nil)
+	assert.True(t, isSchemaSetReconciled(event.UpdateEvent{ObjectOld: oldDb, ObjectNew: newDb}))
+
+	oldDb = newDb.DeepCopy()
+	setSchemaDriftCondition(&newDb.Status.Conditions, 1, []string{"reports"})
+	assert.False(t, isSchemaSetReconciled(event.UpdateEvent{ObjectOld: oldDb, ObjectNew: newDb}), "the generation is already reconciled")
+
+	setSchemaDriftCondition(&newDb.Status.Conditions, 2, []string{"reports"})
+	assert.True(t, isSchemaSetReconciled(event.UpdateEvent{ObjectOld: oldDb, ObjectNew: newDb}))
+}
@@ -20,9 +20,10 @@
 package controllers
 
 import (
+	"github.com/db-operator/db-operator/pkg/utils/database"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
 func init() {
-	metrics.Registry.MustRegister(promDBsPhaseTime, promDBsStatus, promDBsPhaseError, promDBInstancesPhase, promDBInstancesPhaseTime)
+	metrics.Registry.MustRegister(promDBsPhaseTime, promDBsStatus, promDBsPhaseError, promDBInstancesPhase, promDBInstancesPhaseTime, database.Connections)
 }
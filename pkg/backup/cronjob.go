@@ -22,6 +22,7 @@ import (
 
 	kindav1beta1 "github.com/db-operator/db-operator/api/v1beta1"
 	"github.com/db-operator/db-operator/pkg/config"
+	"github.com/db-operator/db-operator/pkg/utils/database"
 	"github.com/db-operator/db-operator/pkg/utils/kci"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
@@ -29,6 +30,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// credentialsPath is where the database secret is mounted in backup containers
+const credentialsPath = "/srv/k8s/db-cred/"
+
 // GCSBackupCron builds kubernetes cronjob object
 // to create database backup regularly with defined schedule from dbcr
 // this job will database dump and upload to google bucket storage for backup
@@ -68,23 +72,9 @@ func buildJobTemplate(conf *config.Config, dbcr *kindav1beta1.Database, instance
 	ActiveDeadlineSeconds := int64(conf.Backup.ActiveDeadlineSeconds)
 	BackoffLimit := int32(3)
 
-	var backupContainer v1.Container
-	var err error
-
-	engine := instance.Spec.Engine
-	switch engine {
-	case "postgres":
-		backupContainer, err = postgresBackupContainer(conf, dbcr, instance)
-		if err != nil {
-			return batchv1.JobTemplateSpec{}, err
-		}
-	case "mysql":
-		backupContainer, err = mysqlBackupContainer(conf, dbcr, instance)
-		if err != nil {
-			return batchv1.JobTemplateSpec{}, err
-		}
-	default:
-		return batchv1.JobTemplateSpec{}, errors.New("unknown engine type")
+	container, err := backupContainer(conf, dbcr, instance)
+	if err != nil {
+		return batchv1.JobTemplateSpec{}, err
 	}
 
 	return batchv1.JobTemplateSpec{
@@ -99,7 +89,7 @@ func buildJobTemplate(conf *config.Config, dbcr *kindav1beta1.Database, instance
 					Labels: kci.BaseLabelBuilder(),
 				},
 				Spec: v1.PodSpec{
-					Containers:    []v1.Container{backupContainer},
+					Containers:    []v1.Container{container},
 					NodeSelector:  conf.Backup.NodeSelector,
 					RestartPolicy: v1.RestartPolicyNever,
 					Volumes:       volumes(dbcr),
@@ -141,36 +131,33 @@ func getResourceRequirements(conf *config.Config) v1.ResourceRequirements {
 	return resourceRequirements
 }
 
-func postgresBackupContainer(conf *config.Config, dbcr *kindav1beta1.Database, instance *kindav1beta1.DbInstance) (v1.Container, error) {
-	env, err := postgresEnvVars(conf, dbcr, instance)
+func backupContainer(conf *config.Config, dbcr *kindav1beta1.Database, instance *kindav1beta1.DbInstance) (v1.Container, error) {
+	engine, err := database.GetEngine(instance.Spec.Engine)
 	if err != nil {
 		return v1.Container{}, err
 	}
-
-	return v1.Container{
-		Name:            "postgres-dump",
-		Image:           conf.Backup.Postgres.Image,
-		ImagePullPolicy: v1.PullAlways,
-		VolumeMounts:    volumeMounts(),
-		Env:             env,
-		Resources:       getResourceRequirements(conf),
-	}, nil
-}
-
-func mysqlBackupContainer(conf *config.Config, dbcr *kindav1beta1.Database, instance *kindav1beta1.DbInstance) (v1.Container, error) {
-	env, err := mysqlEnvVars(dbcr, instance)
-	if err != nil {
-		return v1.Container{}, err
+	if engine.BackupContainer == nil {
+		return v1.Container{}, fmt.Errorf("backups are not supported by the %s engine", engine.Name)
 	}
 
-	return v1.Container{
-		Name:            "mysql-dump",
-		Image:           conf.Backup.Mysql.Image,
-		ImagePullPolicy: v1.PullAlways,
-		VolumeMounts:    volumeMounts(),
-		Env:             env,
+	host, err := getBackupHost(dbcr, instance)
+	if err != nil {
+		return v1.Container{}, fmt.Errorf("can not build %s backup job environment variables - %s", engine.Name, err)
+	}
+
+	return engine.BackupContainer(database.BackupConfig{
+		Image:           conf.Backup.Image(engine.Name),
+		Host:            host,
+		Port:            instance.Status.Info["DB_PORT"],
+		SecretName:      dbcr.Spec.SecretName,
+		CredentialsPath: credentialsPath,
+		Bucket:          instance.Spec.Backup.Bucket,
+		SSLEnabled:      instance.Spec.SSLConnection.Enabled,
+		Monitoring:      instance.IsMonitoringEnabled(),
+		PromPushGateway: conf.Monitoring.PromPushGateway,
 		Resources:       getResourceRequirements(conf),
-	}, nil
+		VolumeMounts:    volumeMounts(),
+	})
 }
 
 func volumeMounts() []v1.VolumeMount {
@@ -181,7 +168,7 @@ func volumeMounts() []v1.VolumeMount {
 		},
 		{
 			Name:      "db-cred",
-			MountPath: "/srv/k8s/db-cred/",
+			MountPath: credentialsPath,
 		},
 	}
 }
@@ -207,88 +194,6 @@ func volumes(dbcr *kindav1beta1.Database) []v1.Volume {
 	}
 }
 
-func postgresEnvVars(conf *config.Config, dbcr *kindav1beta1.Database, instance *kindav1beta1.DbInstance) ([]v1.EnvVar, error) {
-	host, err := getBackupHost(dbcr, instance)
-	if err != nil {
-		return []v1.EnvVar{}, fmt.Errorf("can not build postgres backup job environment variables - %s", err)
-	}
-
-	port := instance.Status.Info["DB_PORT"]
-
-	envList := []v1.EnvVar{
-		{
-			Name: "DB_HOST", Value: host,
-		},
-		{
-			Name: "DB_PORT", Value: port,
-		},
-		{
-			Name: "DB_NAME", ValueFrom: &v1.EnvVarSource{
-				SecretKeyRef: &v1.SecretKeySelector{
-					LocalObjectReference: v1.LocalObjectReference{Name: dbcr.Spec.SecretName},
-					Key:                  "POSTGRES_DB",
-				},
-			},
-		},
-		{
-			Name: "DB_PASSWORD_FILE", Value: "/srv/k8s/db-cred/POSTGRES_PASSWORD",
-		},
-		{
-			Name: "DB_USERNAME_FILE", Value: "/srv/k8s/db-cred/POSTGRES_USER",
-		},
-		{
-			Name: "GCS_BUCKET", Value: instance.Spec.Backup.Bucket,
-		},
-	}
-
-	if instance.IsMonitoringEnabled() {
-		envList = append(envList, v1.EnvVar{
-			Name: "PROMETHEUS_PUSH_GATEWAY", Value: conf.Monitoring.PromPushGateway,
-		})
-	}
-
-	return envList, nil
-}
-
-func mysqlEnvVars(dbcr *kindav1beta1.Database, instance *kindav1beta1.DbInstance) ([]v1.EnvVar, error) {
-	host, err := getBackupHost(dbcr, instance)
-	if err != nil {
-		return []v1.EnvVar{}, fmt.Errorf("can not build mysql backup job environment variables - %s", err)
-	}
-	port := instance.Status.Info["DB_PORT"]
-
-	return []v1.EnvVar{
-		{
-			Name: "DB_HOST", Value: host,
-		},
-		{
-			Name: "DB_PORT", Value: port,
-		},
-		{
-			Name: "DB_NAME", ValueFrom: &v1.EnvVarSource{
-				SecretKeyRef: &v1.SecretKeySelector{
-					LocalObjectReference: v1.LocalObjectReference{Name: dbcr.Spec.SecretName},
-					Key:                  "DB",
-				},
-			},
-		},
-		{
-			Name: "DB_USER", ValueFrom: &v1.EnvVarSource{
-				SecretKeyRef: &v1.SecretKeySelector{
-					LocalObjectReference: v1.LocalObjectReference{Name: dbcr.Spec.SecretName},
-					Key:                  "USER",
-				},
-			},
-		},
-		{
-			Name: "DB_PASSWORD_FILE", Value: "/srv/k8s/db-cred/PASSWORD",
-		},
-		{
-			Name: "GCS_BUCKET", Value: instance.Spec.Backup.Bucket,
-		},
-	}, nil
-}
-
 func getBackupHost(dbcr *kindav1beta1.Database, instance *kindav1beta1.DbInstance) (string, error) {
 	host := ""
 
@@ -90,6 +90,14 @@ func TestUnitGCSBackupCronGeneric(t *testing.T) {
 
 	assert.Equal(t, "mysqlbackupimage:latest", funcCronObject.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image)
 
+	instance.Spec.Engine = "mongodb"
+	funcCronObject, err = GCSBackupCron(conf, dbcr, instance)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	assert.Equal(t, "mongodbbackupimage:latest", funcCronObject.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image)
+
 	assert.Equal(t, "TestNS", funcCronObject.Namespace)
 	assert.Equal(t, "TestNS-TestDB-backup", funcCronObject.Name)
 	assert.Equal(t, "* * * * *", funcCronObject.Spec.Schedule)
@@ -19,7 +19,9 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
+	"github.com/db-operator/db-operator/pkg/utils/database"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -53,3 +55,37 @@ func TestUnitBackupResourceConfig(t *testing.T) {
 	assert.Equal(t, conf.Backup.Resource.Limits.Cpu, "100m")
 	assert.Equal(t, conf.Backup.Resource.Limits.Memory, "100Mi")
 }
+
+func TestUnitConnectionsConfig(t *testing.T) {
+	os.Setenv("CONFIG_PATH", "./test/config_ok.yaml")
+	conf, err := LoadConfig()
+	assert.NoError(t, err)
+
+	pool := conf.Connections.PoolConfig()
+	assert.Equal(t, 8, pool.MaxOpenConns)
+	assert.Equal(t, time.Minute, pool.ConnMaxIdleTime)
+	assert.Equal(t, 4, pool.MaxPoolsPerInstance)
+	assert.Equal(t, database.DefaultPoolConfig.MaxIdleConns, pool.MaxIdleConns)
+	assert.Equal(t, database.DefaultPoolConfig.ConnMaxLifetime, pool.ConnMaxLifetime)
+
+	os.Setenv("CONFIG_PATH", "./test/config_backup.yaml")
+	conf, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, database.DefaultPoolConfig, conf.Connections.PoolConfig())
+}
+
+func TestUnitTimeoutsConfig(t *testing.T) {
+	os.Setenv("CONFIG_PATH", "./test/config_ok.yaml")
+	conf, err := LoadConfig()
+	assert.NoError(t, err)
+
+	timeouts := conf.Timeouts.TimeoutConfig()
+	assert.Equal(t, 30*time.Second, timeouts.Statement)
+	assert.Equal(t, database.DefaultTimeoutConfig.Connect, timeouts.Connect)
+	assert.Equal(t, database.DefaultTimeoutConfig.Lock, timeouts.Lock)
+
+	os.Setenv("CONFIG_PATH", "./test/config_backup.yaml")
+	conf, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, database.DefaultTimeoutConfig, conf.Timeouts.TimeoutConfig())
+}
@@ -16,11 +16,72 @@
 
 package config
 
+import (
+	"time"
+
+	"github.com/db-operator/db-operator/pkg/utils/database"
+)
+
 // Config defines configurations needed by db-operator
 type Config struct {
 	Instances  instanceConfig   `yaml:"instance"`
 	Backup     backupConfig     `yaml:"backup"`
 	Monitoring monitoringConfig `yaml:"monitoring"`
+	// Limits of pooled admin connections, defaults are used if they're not set
+	Connections connectionsConfig `yaml:"connections"`
+	// Timeouts of database operations, defaults are used if they're not set
+	Timeouts timeoutsConfig `yaml:"timeouts"`
+}
+
+type connectionsConfig struct {
+	MaxOpen     int           `yaml:"maxOpen"`
+	MaxIdle     int           `yaml:"maxIdle"`
+	MaxIdleTime time.Duration `yaml:"maxIdleTime"`
+	MaxLifetime time.Duration `yaml:"maxLifetime"`
+	// Pools of an instance, each pool has up to maxOpen connections
+	MaxPoolsPerInstance int `yaml:"maxPoolsPerInstance"`
+}
+
+// PoolConfig returns the connection pool limits, unset values are taken from the defaults
+func (c connectionsConfig) PoolConfig() database.PoolConfig {
+	config := database.DefaultPoolConfig
+	if c.MaxOpen > 0 {
+		config.MaxOpenConns = c.MaxOpen
+	}
+	if c.MaxIdle > 0 {
+		config.MaxIdleConns = c.MaxIdle
+	}
+	if c.MaxIdleTime > 0 {
+		config.ConnMaxIdleTime = c.MaxIdleTime
+	}
+	if c.MaxLifetime > 0 {
+		config.ConnMaxLifetime = c.MaxLifetime
+	}
+	if c.MaxPoolsPerInstance > 0 {
+		config.MaxPoolsPerInstance = c.MaxPoolsPerInstance
+	}
+	return config
+}
+
+type timeoutsConfig struct {
+	Connect   time.Duration `yaml:"connect"`
+	Statement time.Duration `yaml:"statement"`
+	Lock      time.Duration `yaml:"lock"`
+}
+
+// TimeoutConfig returns timeouts of database operations, unset values are taken from the defaults
+func (c timeoutsConfig) TimeoutConfig() database.TimeoutConfig {
+	config := database.DefaultTimeoutConfig
+	if c.Connect > 0 {
+		config.Connect = c.Connect
+	}
+	if c.Statement > 0 {
+		config.Statement = c.Statement
+	}
+	if c.Lock > 0 {
+		config.Lock = c.Lock
+	}
+	return config
 }
 
 type instanceConfig struct {
@@ -50,19 +111,20 @@ type proxyConfig struct {
 // backupConfig defines docker image for creating database dump by backup cronjob
 // backup cronjob will be created by db-operator when backup is enabled
 type backupConfig struct {
-	Postgres              postgresBackupConfig `yaml:"postgres"`
-	Mysql                 mysqlBackupConfig    `yaml:"mysql"`
-	NodeSelector          map[string]string    `yaml:"nodeSelector"`
-	ActiveDeadlineSeconds int64                `yaml:"activeDeadlineSeconds"`
-	Resource              ResourceRequirements `yaml:"resources"`
+	// Images are configured per engine, e.g. `postgres: {image: ...}`
+	Engines               map[string]engineBackupConfig `yaml:",inline"`
+	NodeSelector          map[string]string             `yaml:"nodeSelector"`
+	ActiveDeadlineSeconds int64                         `yaml:"activeDeadlineSeconds"`
+	Resource              ResourceRequirements          `yaml:"resources"`
 }
 
-type postgresBackupConfig struct {
+type engineBackupConfig struct {
 	Image string `yaml:"image"`
 }
 
-type mysqlBackupConfig struct {
-	Image string `yaml:"image"`
+// Image returns the backup image of an engine
+func (c backupConfig) Image(engine string) string {
+	return c.Engines[engine].Image
 }
 
 type ResourceRequirements struct {
@@ -38,6 +38,15 @@ const (
 	SQLSERVER_DB        = "SQLSERVER_DB"
 	SQLSERVER_USER      = "SQLSERVER_USER"
 	SQLSERVER_PASSWORD  = "SQLSERVER_PASSWORD"
+	REDIS_DB            = "REDIS_KEY_PREFIX"
+	REDIS_USER          = "REDIS_USER"
+	REDIS_PASSWORD      = "REDIS_PASSWORD"
+	PLUGIN_DB           = "PLUGIN_DB"
+	PLUGIN_USER         = "PLUGIN_USER"
+	PLUGIN_PASSWORD     = "PLUGIN_PASSWORD"
+	RECORDER_DB         = "RECORDER_DB"
+	RECORDER_USER       = "RECORDER_USER"
+	RECORDER_PASSWORD   = "RECORDER_PASSWORD"
 )
 
 // Database engines
@@ -48,6 +57,10 @@ const (
 	ENGINE_CLICKHOUSE = "clickhouse"
 	ENGINE_ORACLE     = "oracle"
 	ENGINE_SQLSERVER  = "sqlserver"
+	ENGINE_REDIS      = "redis"
+	ENGINE_PLUGIN     = "plugin"
+	// The recorder is a hidden engine for testing
+	ENGINE_RECORDER = "recorder"
 )
 
 // SSL modes
@@ -71,6 +84,12 @@ const (
 	// But it's not possible on the AWS instances with the rds_iam role,
 	// because then admins are not able to log in with a password anymore
 	GRANT_TO_ADMIN_ON_DELETE = "kinda.rocks/grant-to-admin-on-delete"
+	// Oracle is reached through a service, it's set on the DbInstance,
+	// because there is no default one that works everywhere
+	ORACLE_SERVICE_NAME = "kinda.rocks/oracle-service-name"
+	// When set to "true" on an Oracle DbInstance, databases are created
+	// as PDBs, the service must then point to the CDB root
+	ORACLE_PLUGGABLE_DATABASE = "kinda.rocks/oracle-pluggable-database"
 )
 
 // Kubernetes Labels
@@ -81,6 +100,20 @@ const (
 	USED_BY_NAME_LABEL_KEY = "kinda.rocks/used-by-name"
 )
 
+// Status conditions
+const (
+	// Reports why a resource can't be removed from the database server
+	CONDITION_DELETED = "Deleted"
+	// A user still owns objects, and the deletion policy doesn't let them go
+	REASON_OWNED_OBJECTS   = "OwnedObjects"
+	REASON_DELETION_FAILED = "DeletionFailed"
+	REASON_DELETED         = "Deleted"
+	// Reports schemas that are removed from the spec, but retained in the database
+	CONDITION_SCHEMA_DRIFT  = "SchemaDrift"
+	REASON_SCHEMAS_RETAINED = "SchemasRetained"
+	REASON_SCHEMAS_IN_SYNC  = "SchemasInSync"
+)
+
 // Privileges
 
 const ALL_PRIVILEGES = "ALL PRIVILEGES"
@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/db-operator/db-operator/api/v1beta1"
+	dbhelper "github.com/db-operator/db-operator/pkg/helpers/database"
 	kubehelper "github.com/db-operator/db-operator/pkg/helpers/kube"
 	"github.com/db-operator/db-operator/pkg/utils/database"
 	"github.com/db-operator/db-operator/pkg/utils/dbinstance"
@@ -67,14 +68,17 @@ func (g *GenericBackend) GetInstance(ctx context.Context, dbin *v1beta1.DbInstan
 	}
 
 	instance := &dbinstance.Generic{
-		Host:         host,
-		Port:         port,
-		PublicIP:     publicIP,
-		Engine:       dbin.Spec.Engine,
-		User:         cred.Username,
-		Password:     cred.Password,
-		SSLEnabled:   dbin.Spec.SSLConnection.Enabled,
-		SkipCAVerify: dbin.Spec.SSLConnection.SkipVerify,
+		Host:           host,
+		Port:           port,
+		PublicIP:       publicIP,
+		Engine:         dbin.Spec.Engine,
+		Annotations:    dbin.Annotations,
+		PluginEndpoint: dbin.GetPluginEndpoint(),
+		PluginTLS:      dbhelper.PluginTLS(dbin),
+		User:           cred.Username,
+		Password:       cred.Password,
+		SSLEnabled:     dbin.Spec.SSLConnection.Enabled,
+		SkipCAVerify:   dbin.Spec.SSLConnection.SkipVerify,
 	}
 	// Assume instance creation logic here
 	return instance, nil
@@ -18,7 +18,7 @@ package database
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"strconv"
 
@@ -45,7 +45,16 @@ func FetchDatabaseData(ctx context.Context, dbcr *kindav1beta1.Database, dbCred
 		return nil, nil, err
 	}
 
-	monitoringEnabled := instance.IsMonitoringEnabled()
+	engine, err := database.GetEngine(dbcr.Status.Engine)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	spec, err := engineSpec(dbcr)
+	if err != nil {
+		log.Error(err, "could not get the engine spec")
+		return nil, nil, err
+	}
 
 	dbuser := &database.DatabaseUser{
 		Username: dbCred.Username,
@@ -55,121 +64,88 @@ func FetchDatabaseData(ctx context.Context, dbcr *kindav1beta1.Database, dbCred
 		GrantToAdmin: true,
 	}
 
-	enableRdsIamImpersonate := false
-	val, ok := instance.Annotations[consts.RDS_IAM_IMPERSONATE_WORKAROUND]
-	if ok {
-		boolVal, err := strconv.ParseBool(val)
-		if err != nil {
-			log.Info(
-				"can't parse a value of an annotation into a bool, ignoring",
-				"annotation",
-				consts.RDS_IAM_IMPERSONATE_WORKAROUND,
-				"value",
-				val,
-				"error",
-				err,
-			)
-		} else {
-			enableRdsIamImpersonate = boolVal
-		}
+	db, err := engine.New(ctx, database.EngineConfig{
+		Instance:       instance.Name,
+		Backend:        backend,
+		Host:           host,
+		Port:           uint16(port),
+		Database:       dbCred.Name,
+		SSLEnabled:     instance.Spec.SSLConnection.Enabled,
+		SkipCAVerify:   instance.Spec.SSLConnection.SkipVerify,
+		Monitoring:     instance.IsMonitoringEnabled(),
+		MainUser:       dbuser,
+		Annotations:    instance.Annotations,
+		Spec:           spec,
+		PluginEndpoint: instance.GetPluginEndpoint(),
+		PluginTLS:      PluginTLS(instance),
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	switch dbcr.Status.Engine {
-	case "postgres":
-		extList := dbcr.Spec.Postgres.Extensions
-		db := database.Postgres{
-			Backend:                     backend,
-			Host:                        host,
-			Port:                        uint16(port),
-			Database:                    dbCred.Name,
-			Monitoring:                  monitoringEnabled,
-			Extensions:                  extList,
-			SSLEnabled:                  instance.Spec.SSLConnection.Enabled,
-			SkipCAVerify:                instance.Spec.SSLConnection.SkipVerify,
-			DropPublicSchema:            dbcr.Spec.Postgres.DropPublicSchema,
-			Schemas:                     dbcr.Spec.Postgres.Schemas,
-			Template:                    dbcr.Spec.Postgres.Template,
-			MainUser:                    dbuser,
-			RDSIAMImpersonateWorkaround: enableRdsIamImpersonate,
-		}
-		return db, dbuser, nil
-
-	case "mysql":
-		db := database.Mysql{
-			Backend:      backend,
-			Host:         host,
-			Port:         uint16(port),
-			Database:     dbCred.Name,
-			SSLEnabled:   instance.Spec.SSLConnection.Enabled,
-			SkipCAVerify: instance.Spec.SSLConnection.SkipVerify,
-		}
+	return db, dbuser, nil
+}
 
-		return db, dbuser, nil
+// PluginTLS returns the security of the connection to the plugin of the instance,
+// or nil if the instance doesn't use the plugin engine
+func PluginTLS(instance *kindav1beta1.DbInstance) *database.PluginTLS {
+	plugin := instance.Spec.Plugin
+	switch {
+	case plugin == nil:
+		return nil
+	case plugin.TLS != nil:
+		return &database.PluginTLS{
+			SecretNamespace: plugin.TLS.SecretRef.Namespace,
+			SecretName:      plugin.TLS.SecretRef.Name,
+			ServerName:      plugin.TLS.ServerName,
+		}
 	default:
-		err := errors.New("not supported engine type")
-		return nil, nil, err
+		return &database.PluginTLS{Insecure: plugin.Insecure}
 	}
 }
 
-func ParseDatabaseSecretData(dbcr *kindav1beta1.Database, data map[string][]byte) (database.Credentials, error) {
-	cred := database.Credentials{}
-
-	switch dbcr.Status.Engine {
-	case "postgres":
-		if name, ok := data[consts.POSTGRES_DB]; ok {
-			cred.Name = string(name)
-		} else {
-			return cred, errors.New("POSTGRES_DB key does not exist in secret data")
-		}
-
-		if user, ok := data[consts.POSTGRES_USER]; ok {
-			cred.Username = string(user)
-		} else {
-			return cred, errors.New("POSTGRES_USER key does not exist in secret data")
-		}
-
-		if pass, ok := data[consts.POSTGRES_PASSWORD]; ok {
-			cred.Password = string(pass)
-		} else {
-			return cred, errors.New("POSTGRES_PASSWORD key does not exist in secret data")
-		}
-
-		return cred, nil
-	case "mysql":
-		if name, ok := data[consts.MYSQL_DB]; ok {
-			cred.Name = string(name)
-		} else {
-			return cred, errors.New("DB key does not exist in secret data")
-		}
+// engineSpec returns the part of the Database spec that is named after the engine
+// (e.g. spec.postgres) as JSON, or nil if there is no such part
+func engineSpec(dbcr *kindav1beta1.Database) ([]byte, error) {
+	return specSection(dbcr.Spec, dbcr.Status.Engine)
+}
 
-		if user, ok := data[consts.MYSQL_USER]; ok {
-			cred.Username = string(user)
-		} else {
-			return cred, errors.New("USER key does not exist in secret data")
-		}
+// DbUserEngineSpec returns the part of the DbUser spec that is named after the engine
+// (e.g. spec.postgres) as JSON, or nil if there is no such part
+func DbUserEngineSpec(spec kindav1beta1.DbUserSpec, engine string) ([]byte, error) {
+	return specSection(spec, engine)
+}
 
-		if pass, ok := data[consts.MYSQL_PASSWORD]; ok {
-			cred.Password = string(pass)
-		} else {
-			return cred, errors.New("PASSWORD key does not exist in secret data")
-		}
+func specSection(spec any, engine string) ([]byte, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	sections := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, err
+	}
+	return sections[engine], nil
+}
 
-		return cred, nil
-	default:
-		return cred, errors.New("not supported engine type")
+func ParseDatabaseSecretData(dbcr *kindav1beta1.Database, data map[string][]byte) (database.Credentials, error) {
+	engine, err := database.GetEngine(dbcr.Status.Engine)
+	if err != nil {
+		return database.Credentials{}, err
 	}
+
+	return engine.ParseSecretData(data)
 }
 
 // If dbName is empty, it will be generated, that should be used for database resources.
 // In case this function is called by dbuser controller, dbName should be taken from the
 // `Spec.DatabaseRef` field, so it will ba passed as the last argument
 func GenerateDatabaseSecretData(objectMeta metav1.ObjectMeta, engine, dbName string, dbUser string) (map[string][]byte, error) {
-	const (
-		// https://dev.mysql.com/doc/refman/5.7/en/identifier-length.html
-		mysqlDBNameLengthLimit = 63
-		// https://dev.mysql.com/doc/refman/5.7/en/replication-features-user-names.html
-		mysqlUserLengthLimit = 32
-	)
+	e, err := database.GetEngine(engine)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(dbName) == 0 {
 		dbName = objectMeta.Namespace + "-" + objectMeta.Name
 	}
@@ -178,24 +154,7 @@ func GenerateDatabaseSecretData(objectMeta metav1.ObjectMeta, engine, dbName str
 	}
 	dbPassword := kci.GeneratePass()
 
-	switch engine {
-	case "postgres":
-		data := map[string][]byte{
-			consts.POSTGRES_DB:       []byte(dbName),
-			consts.POSTGRES_USER:     []byte(dbUser),
-			consts.POSTGRES_PASSWORD: []byte(dbPassword),
-		}
-		return data, nil
-	case "mysql":
-		data := map[string][]byte{
-			consts.MYSQL_DB:       []byte(kci.StringSanitize(dbName, mysqlDBNameLengthLimit)),
-			consts.MYSQL_USER:     []byte(kci.StringSanitize(dbUser, mysqlUserLengthLimit)),
-			consts.MYSQL_PASSWORD: []byte(dbPassword),
-		}
-		return data, nil
-	default:
-		return nil, errors.New("not supported engine type")
-	}
+	return e.SecretData(dbName, dbUser, dbPassword), nil
 }
 
 func GetSSLMode(dbcr *kindav1beta1.Database, instance *kindav1beta1.DbInstance) (string, error) {
@@ -204,29 +163,12 @@ func GetSSLMode(dbcr *kindav1beta1.Database, instance *kindav1beta1.DbInstance)
 		return "", err
 	}
 
-	if dbcr.Status.Engine == "postgres" {
-		switch genericSSL {
-		case consts.SSL_DISABLED:
-			return "disable", nil
-		case consts.SSL_REQUIRED:
-			return "require", nil
-		case consts.SSL_VERIFY_CA:
-			return "verify-ca", nil
-		}
-	}
-
-	if dbcr.Status.Engine == "mysql" {
-		switch genericSSL {
-		case consts.SSL_DISABLED:
-			return "disabled", nil
-		case consts.SSL_REQUIRED:
-			return "required", nil
-		case consts.SSL_VERIFY_CA:
-			return "verify_ca", nil
-		}
+	engine, err := database.GetEngine(dbcr.Status.Engine)
+	if err != nil {
+		return "", fmt.Errorf("unknown database engine: %s", dbcr.Status.Engine)
 	}
 
-	return "", fmt.Errorf("unknown database engine: %s", dbcr.Status.Engine)
+	return engine.SSLMode(genericSSL)
 }
 
 func GetGenericSSLMode(dbcr *kindav1beta1.Database, instance *kindav1beta1.DbInstance) (string, error) {
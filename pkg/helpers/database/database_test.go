@@ -52,6 +52,53 @@ func TestUnitDeterminMysqlType(t *testing.T) {
 	assert.Equal(t, ok, true, "expected true")
 }
 
+func TestUnitDeterminMongodbType(t *testing.T) {
+	mongodbDbCr := testutils.NewMongodbTestDbCr()
+	instance := testutils.NewPostgresTestDbInstanceCr()
+	db, _, _ := dbhelper.FetchDatabaseData(ctx, mongodbDbCr, testDbcred, &instance)
+	_, ok := db.(database.MongoDB)
+	assert.Equal(t, ok, true, "expected true")
+}
+
+func TestUnitDeterminClickhouseType(t *testing.T) {
+	clickhouseDbCr := testutils.NewClickhouseTestDbCr()
+	instance := testutils.NewPostgresTestDbInstanceCr()
+	db, _, _ := dbhelper.FetchDatabaseData(ctx, clickhouseDbCr, testDbcred, &instance)
+	clickhouseInterface, ok := db.(database.ClickHouse)
+	assert.Equal(t, ok, true, "expected true")
+	assert.Equal(t, "test_cluster", clickhouseInterface.ClusterName)
+	assert.Equal(t, "1", clickhouseInterface.Shard)
+	assert.Equal(t, 2, clickhouseInterface.ReplicationFactor)
+	assert.Equal(t, "ReplicatedMergeTree", clickhouseInterface.Engine)
+	assert.Equal(t, map[string]string{"max_threads": "8"}, clickhouseInterface.Settings)
+}
+
+func TestUnitDeterminOracleType(t *testing.T) {
+	oracleDbCr := testutils.NewOracleTestDbCr()
+	instance := testutils.NewPostgresTestDbInstanceCr()
+	instance.Annotations = map[string]string{
+		consts.ORACLE_SERVICE_NAME:       "FREE",
+		consts.ORACLE_PLUGGABLE_DATABASE: "true",
+	}
+	db, _, _ := dbhelper.FetchDatabaseData(ctx, oracleDbCr, testDbcred, &instance)
+	oracleInterface, ok := db.(database.Oracle)
+	assert.Equal(t, ok, true, "expected true")
+	assert.Equal(t, "FREE", oracleInterface.Service)
+	assert.True(t, oracleInterface.PluggableDatabase)
+	assert.Equal(t, []string{"APP_DATA"}, oracleInterface.Tablespaces)
+	assert.Equal(t, []string{"APP_PROFILE"}, oracleInterface.Profiles)
+}
+
+func TestUnitDeterminSqlserverType(t *testing.T) {
+	sqlserverDbCr := testutils.NewSqlserverTestDbCr()
+	instance := testutils.NewPostgresTestDbInstanceCr()
+	db, _, _ := dbhelper.FetchDatabaseData(ctx, sqlserverDbCr, testDbcred, &instance)
+	sqlserverInterface, ok := db.(database.SQLServer)
+	assert.Equal(t, ok, true, "expected true")
+	assert.Equal(t, []string{"app"}, sqlserverInterface.Schemas)
+	assert.Equal(t, []string{"db_ddladmin"}, sqlserverInterface.Roles)
+}
+
 func TestUnitParsePostgresSecretData(t *testing.T) {
 	instance := testutils.NewPostgresTestDbInstanceCr()
 	postgresDbCr := testutils.NewPostgresTestDbCr(instance)
@@ -95,6 +142,135 @@ func TestUnitParseMysqlSecretData(t *testing.T) {
 	assert.Equal(t, string(validData["PASSWORD"]), cred.Password, "expect same values")
 }
 
+func TestUnitParseMongodbSecretData(t *testing.T) {
+	mongodbDbCr := testutils.NewMongodbTestDbCr()
+
+	invalidData := make(map[string][]byte)
+	invalidData["DB"] = []byte("testdb")
+
+	_, err := dbhelper.ParseDatabaseSecretData(mongodbDbCr, invalidData)
+	assert.Errorf(t, err, "should get error %v", err)
+
+	validData := make(map[string][]byte)
+	validData["MONGODB_DB"] = []byte("testdb")
+	validData["MONGODB_USER"] = []byte("testuser")
+	validData["MONGODB_PASSWORD"] = []byte("testpassword")
+
+	cred, err := dbhelper.ParseDatabaseSecretData(mongodbDbCr, validData)
+	assert.NoErrorf(t, err, "expected no error %v", err)
+	assert.Equal(t, string(validData["MONGODB_DB"]), cred.Name, "expect same values")
+	assert.Equal(t, string(validData["MONGODB_USER"]), cred.Username, "expect same values")
+	assert.Equal(t, string(validData["MONGODB_PASSWORD"]), cred.Password, "expect same values")
+}
+
+func TestUnitGenerateMongodbSecretData(t *testing.T) {
+	mongodbDbCr := testutils.NewMongodbTestDbCr()
+	mongodbDbCr.Name = "test.db"
+
+	data, err := dbhelper.GenerateDatabaseSecretData(mongodbDbCr.ObjectMeta, consts.ENGINE_MONGODB, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "testns_test_db", string(data[consts.MONGODB_DB]))
+	assert.Equal(t, "TestNS-test.db", string(data[consts.MONGODB_USER]))
+	assert.NotEmpty(t, data[consts.MONGODB_PASSWORD])
+}
+
+func TestUnitParseClickhouseSecretData(t *testing.T) {
+	clickhouseDbCr := testutils.NewClickhouseTestDbCr()
+
+	invalidData := make(map[string][]byte)
+	invalidData["DB"] = []byte("testdb")
+
+	_, err := dbhelper.ParseDatabaseSecretData(clickhouseDbCr, invalidData)
+	assert.Errorf(t, err, "should get error %v", err)
+
+	validData := make(map[string][]byte)
+	validData["CLICKHOUSE_DB"] = []byte("testdb")
+	validData["CLICKHOUSE_USER"] = []byte("testuser")
+	validData["CLICKHOUSE_PASSWORD"] = []byte("testpassword")
+
+	cred, err := dbhelper.ParseDatabaseSecretData(clickhouseDbCr, validData)
+	assert.NoErrorf(t, err, "expected no error %v", err)
+	assert.Equal(t, string(validData["CLICKHOUSE_DB"]), cred.Name, "expect same values")
+	assert.Equal(t, string(validData["CLICKHOUSE_USER"]), cred.Username, "expect same values")
+	assert.Equal(t, string(validData["CLICKHOUSE_PASSWORD"]), cred.Password, "expect same values")
+}
+
+func TestUnitGenerateClickhouseSecretData(t *testing.T) {
+	clickhouseDbCr := testutils.NewClickhouseTestDbCr()
+	clickhouseDbCr.Name = "test.db"
+
+	data, err := dbhelper.GenerateDatabaseSecretData(clickhouseDbCr.ObjectMeta, consts.ENGINE_CLICKHOUSE, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "testns_test_db", string(data[consts.CLICKHOUSE_DB]))
+	assert.Equal(t, "TestNS-test.db", string(data[consts.CLICKHOUSE_USER]))
+	assert.NotEmpty(t, data[consts.CLICKHOUSE_PASSWORD])
+}
+
+func TestUnitParseOracleSecretData(t *testing.T) {
+	oracleDbCr := testutils.NewOracleTestDbCr()
+
+	invalidData := make(map[string][]byte)
+	invalidData["DB"] = []byte("testdb")
+
+	_, err := dbhelper.ParseDatabaseSecretData(oracleDbCr, invalidData)
+	assert.Errorf(t, err, "should get error %v", err)
+
+	validData := make(map[string][]byte)
+	validData["ORACLE_DB"] = []byte("TESTDB")
+	validData["ORACLE_USER"] = []byte("TESTUSER")
+	validData["ORACLE_PASSWORD"] = []byte("testpassword")
+
+	cred, err := dbhelper.ParseDatabaseSecretData(oracleDbCr, validData)
+	assert.NoErrorf(t, err, "expected no error %v", err)
+	assert.Equal(t, string(validData["ORACLE_DB"]), cred.Name, "expect same values")
+	assert.Equal(t, string(validData["ORACLE_USER"]), cred.Username, "expect same values")
+	assert.Equal(t, string(validData["ORACLE_PASSWORD"]), cred.Password, "expect same values")
+}
+
+func TestUnitGenerateOracleSecretData(t *testing.T) {
+	oracleDbCr := testutils.NewOracleTestDbCr()
+	oracleDbCr.Name = "test.db"
+
+	data, err := dbhelper.GenerateDatabaseSecretData(oracleDbCr.ObjectMeta, consts.ENGINE_ORACLE, "", "")
+	assert.NoError(t, err)
+	// The main user is the schema owner, so names must be equal
+	assert.Equal(t, "TESTNS_TEST_DB", string(data[consts.ORACLE_DB]))
+	assert.Equal(t, "TESTNS_TEST_DB", string(data[consts.ORACLE_USER]))
+	assert.NotEmpty(t, data[consts.ORACLE_PASSWORD])
+}
+
+func TestUnitParseSqlserverSecretData(t *testing.T) {
+	sqlserverDbCr := testutils.NewSqlserverTestDbCr()
+
+	invalidData := make(map[string][]byte)
+	invalidData["DB"] = []byte("testdb")
+
+	_, err := dbhelper.ParseDatabaseSecretData(sqlserverDbCr, invalidData)
+	assert.Errorf(t, err, "should get error %v", err)
+
+	validData := make(map[string][]byte)
+	validData["SQLSERVER_DB"] = []byte("testdb")
+	validData["SQLSERVER_USER"] = []byte("testuser")
+	validData["SQLSERVER_PASSWORD"] = []byte("testpassword")
+
+	cred, err := dbhelper.ParseDatabaseSecretData(sqlserverDbCr, validData)
+	assert.NoErrorf(t, err, "expected no error %v", err)
+	assert.Equal(t, string(validData["SQLSERVER_DB"]), cred.Name, "expect same values")
+	assert.Equal(t, string(validData["SQLSERVER_USER"]), cred.Username, "expect same values")
+	assert.Equal(t, string(validData["SQLSERVER_PASSWORD"]), cred.Password, "expect same values")
+}
+
+func TestUnitGenerateSqlserverSecretData(t *testing.T) {
+	sqlserverDbCr := testutils.NewSqlserverTestDbCr()
+	sqlserverDbCr.Name = "test.db"
+
+	data, err := dbhelper.GenerateDatabaseSecretData(sqlserverDbCr.ObjectMeta, consts.ENGINE_SQLSERVER, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "testns_test_db", string(data[consts.SQLSERVER_DB]))
+	assert.Equal(t, "testns_test_db", string(data[consts.SQLSERVER_USER]))
+	assert.NotEmpty(t, data[consts.SQLSERVER_PASSWORD])
+}
+
 func TestUnitMonitoringNotEnabled(t *testing.T) {
 	instance := testutils.NewPostgresTestDbInstanceCr()
 	instance.Spec.Monitoring.Enabled = false
@@ -401,3 +577,111 @@ func TestUnitGetSSLModeMysql(t *testing.T) {
 	}
 	assert.Equal(t, "verify_ca", mode)
 }
+
+func TestUnitGetSSLModeMongodb(t *testing.T) {
+	mongodbDbCR := testutils.NewMongodbTestDbCr()
+	instance := testutils.NewPostgresTestDbInstanceCr()
+
+	instance.Spec.SSLConnection.Enabled = false
+	mode, err := dbhelper.GetSSLMode(mongodbDbCR, &instance)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "false", mode)
+
+	instance.Spec.SSLConnection.Enabled = true
+	instance.Spec.SSLConnection.SkipVerify = true
+	mode, err = dbhelper.GetSSLMode(mongodbDbCR, &instance)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "insecure", mode)
+
+	instance.Spec.SSLConnection.SkipVerify = false
+	mode, err = dbhelper.GetSSLMode(mongodbDbCR, &instance)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "true", mode)
+}
+
+func TestUnitGetSSLModeSqlserver(t *testing.T) {
+	sqlserverDbCR := testutils.NewSqlserverTestDbCr()
+	instance := testutils.NewPostgresTestDbInstanceCr()
+
+	instance.Spec.SSLConnection.Enabled = false
+	mode, err := dbhelper.GetSSLMode(sqlserverDbCR, &instance)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "encrypt=disable", mode)
+
+	instance.Spec.SSLConnection.Enabled = true
+	instance.Spec.SSLConnection.SkipVerify = true
+	mode, err = dbhelper.GetSSLMode(sqlserverDbCR, &instance)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "encrypt=true;TrustServerCertificate=true", mode)
+
+	instance.Spec.SSLConnection.SkipVerify = false
+	mode, err = dbhelper.GetSSLMode(sqlserverDbCR, &instance)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "encrypt=true", mode)
+}
+
+func TestUnitGetSSLModeOracle(t *testing.T) {
+	oracleDbCR := testutils.NewOracleTestDbCr()
+	instance := testutils.NewPostgresTestDbInstanceCr()
+
+	instance.Spec.SSLConnection.Enabled = false
+	mode, err := dbhelper.GetSSLMode(oracleDbCR, &instance)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "SSL=false", mode)
+
+	instance.Spec.SSLConnection.Enabled = true
+	instance.Spec.SSLConnection.SkipVerify = true
+	mode, err = dbhelper.GetSSLMode(oracleDbCR, &instance)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "SSL=true&SSL VERIFY=false", mode)
+
+	instance.Spec.SSLConnection.SkipVerify = false
+	mode, err = dbhelper.GetSSLMode(oracleDbCR, &instance)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "SSL=true", mode)
+}
+
+func TestUnitGetSSLModeClickhouse(t *testing.T) {
+	clickhouseDbCR := testutils.NewClickhouseTestDbCr()
+	instance := testutils.NewPostgresTestDbInstanceCr()
+
+	instance.Spec.SSLConnection.Enabled = false
+	mode, err := dbhelper.GetSSLMode(clickhouseDbCR, &instance)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "secure=false", mode)
+
+	instance.Spec.SSLConnection.Enabled = true
+	instance.Spec.SSLConnection.SkipVerify = true
+	mode, err = dbhelper.GetSSLMode(clickhouseDbCR, &instance)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "secure=true&skip_verify=true", mode)
+
+	instance.Spec.SSLConnection.SkipVerify = false
+	mode, err = dbhelper.GetSSLMode(clickhouseDbCR, &instance)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "secure=true", mode)
+}
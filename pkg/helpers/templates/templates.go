@@ -28,6 +28,7 @@ import (
 	"github.com/db-operator/db-operator/pkg/consts"
 	"github.com/db-operator/db-operator/pkg/types"
 	"github.com/db-operator/db-operator/pkg/utils/database"
+	"github.com/db-operator/db-operator/pkg/utils/engines"
 	corev1 "k8s.io/api/core/v1"
 
 	"k8s.io/utils/strings/slices"
@@ -234,38 +235,37 @@ func (tds *TemplateDataSources) Protocol() (string, error) {
 // Username return the main user username, if dbuser is nil,
 // otherwise it returns a name of a DbUser
 func (tds *TemplateDataSources) Username() (string, error) {
-	switch tds.DatabaseK8sObj.Status.Engine {
-	case "postgres":
-		return tds.Secret(consts.POSTGRES_USER)
-	case "mysql":
-		return tds.Secret(consts.MYSQL_USER)
-	default:
-		return "", fmt.Errorf("unknown engine: %s", tds.DatabaseK8sObj.Status.Engine)
+	keys, err := tds.secretKeys()
+	if err != nil {
+		return "", err
 	}
+	return tds.Secret(keys.User)
 }
 
 // Password return the main user password, if dbuser is nil,
 // otherwise it returns a password of a DbUser
 func (tds *TemplateDataSources) Password() (string, error) {
-	switch tds.DatabaseK8sObj.Status.Engine {
-	case "postgres":
-		return tds.Secret(consts.POSTGRES_PASSWORD)
-	case "mysql":
-		return tds.Secret(consts.MYSQL_PASSWORD)
-	default:
-		return "", fmt.Errorf("unknown engine: %s", tds.DatabaseK8sObj.Status.Engine)
+	keys, err := tds.secretKeys()
+	if err != nil {
+		return "", err
 	}
+	return tds.Secret(keys.Password)
 }
 
 func (tds *TemplateDataSources) Database() (string, error) {
-	switch tds.DatabaseK8sObj.Status.Engine {
-	case "postgres":
-		return tds.Secret(consts.POSTGRES_DB)
-	case "mysql":
-		return tds.Secret(consts.MYSQL_DB)
-	default:
-		return "", fmt.Errorf("unknown engine: %s", tds.DatabaseK8sObj.Status.Engine)
+	keys, err := tds.secretKeys()
+	if err != nil {
+		return "", err
+	}
+	return tds.Secret(keys.Database)
+}
+
+func (tds *TemplateDataSources) secretKeys() (engines.SecretKeys, error) {
+	engine, err := engines.Get(tds.DatabaseK8sObj.Status.Engine)
+	if err != nil {
+		return engines.SecretKeys{}, fmt.Errorf("unknown engine: %s", tds.DatabaseK8sObj.Status.Engine)
 	}
+	return engine.SecretKeys, nil
 }
 
 // Hostname
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+)
+
+// AccessProfile and DefaultPrivileges are defined by the engines package,
+// so API types can build them without importing drivers
+type (
+	AccessProfile     = engines.AccessProfile
+	DefaultPrivileges = engines.DefaultPrivileges
+)
+
+// accessProfile returns the access profile of a user, it's either a custom
+// profile that is set on the user or one of the built-in profiles of an engine
+func (user *DatabaseUser) accessProfile(builtin map[string]AccessProfile) (AccessProfile, error) {
+	if user.AccessProfile != nil {
+		if err := user.AccessProfile.Validate(); err != nil {
+			return AccessProfile{}, err
+		}
+		return *user.AccessProfile, nil
+	}
+	profile, ok := builtin[user.AccessType]
+	if !ok {
+		return AccessProfile{}, fmt.Errorf("unknown access type: %s", user.AccessType)
+	}
+	return profile, nil
+}
+
+// joinPrivileges joins privileges of several kinds of objects, when they're
+// granted on the same level, duplicates are removed and the order is kept
+func joinPrivileges(privileges ...[]string) string {
+	result := []string{}
+	for _, list := range privileges {
+		for _, privilege := range list {
+			privilege = strings.ToUpper(privilege)
+			if !slices.Contains(result, privilege) {
+				result = append(result, privilege)
+			}
+		}
+	}
+	return strings.Join(result, ", ")
+}
@@ -0,0 +1,248 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessProfileValidate(t *testing.T) {
+	assert.NoError(t, AccessProfile{Database: []string{"SELECT", "create temporary tables"}}.Validate())
+	assert.Error(t, AccessProfile{Tables: []string{"SELECT; DROP TABLE users"}}.Validate())
+	assert.Error(t, AccessProfile{DefaultPrivileges: DefaultPrivileges{Functions: []string{"EXECUTE\n"}}}.Validate())
+
+	user := &DatabaseUser{AccessType: ACCESS_TYPE_READONLY}
+	profile, err := user.accessProfile(postgresAccessProfiles)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SELECT"}, profile.Tables)
+
+	user.AccessType = "custom"
+	_, err = user.accessProfile(postgresAccessProfiles)
+	assert.Error(t, err)
+	user.AccessProfile = &AccessProfile{Name: "custom", Tables: []string{"SELECT"}}
+	profile, err = user.accessProfile(postgresAccessProfiles)
+	assert.NoError(t, err)
+	assert.Equal(t, "custom", profile.Name)
+
+	assert.Equal(t, "SELECT, INSERT, EXECUTE", joinPrivileges([]string{"select", "insert"}, []string{"SELECT", "execute"}))
+}
+
+// testAccessProfileEngine creates a database with a main user on a fake server
+func testAccessProfileEngine(t *testing.T, engine string, dialect fakeSQLDialect, port uint16) (Database, *fakeSQLServer, *DatabaseUser) {
+	t.Helper()
+	admin := &DatabaseUser{Username: "admin", Password: "adminpwd"}
+	server := newFakeSQLServer(dialect, admin)
+	useFakeSQLServer(t, server)
+
+	e, err := GetEngine(engine)
+	require.NoError(t, err)
+	mainUser := &DatabaseUser{Username: "profile_main", Password: "mainpwd", AccessType: ACCESS_TYPE_MAINUSER}
+	cfg := EngineConfig{Instance: "profile-" + engine, Host: engine, Port: port, Database: "profile", MainUser: mainUser}
+	db, err := e.New(context.TODO(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		Connections.Invalidate(poolInstance(cfg.Instance, cfg.Host, cfg.Port))
+	})
+
+	require.NoError(t, CreateDatabase(context.TODO(), db, admin))
+	require.NoError(t, CreateOrUpdateUser(context.TODO(), db, mainUser, admin))
+	return db, server, admin
+}
+
+// profileObject returns objects of the public schema in the database of testAccessProfileEngine,
+// default privileges on objects of the owner are returned, when it's set
+func profileObject(owner, kind string) fakeSQLObject {
+	return fakeSQLObject{database: "profile", owner: owner, kind: kind, schema: "public"}
+}
+
+func TestPostgresAccessProfile(t *testing.T) {
+	ctx := context.TODO()
+	db, server, admin := testAccessProfileEngine(t, "postgres", fakePostgresDialect, 5432)
+	user := &DatabaseUser{
+		Username:   "profile_user",
+		Password:   "userpwd",
+		AccessType: "app",
+		AccessProfile: &AccessProfile{
+			Name:      "app",
+			Database:  []string{"TEMPORARY"},
+			Schemas:   []string{"USAGE"},
+			Tables:    []string{"SELECT", "INSERT"},
+			Sequences: []string{"USAGE", "SELECT"},
+			Functions: []string{"EXECUTE"},
+			DefaultPrivileges: DefaultPrivileges{
+				Tables:    []string{"SELECT", "INSERT"},
+				Functions: []string{"EXECUTE"},
+			},
+		},
+	}
+
+	require.NoError(t, CreateUser(ctx, db, user, admin))
+	assert.Equal(t, map[fakeSQLObject][]string{
+		{database: "profile", kind: "DATABASE"}: {"TEMPORARY"},
+		profileObject("", "SCHEMA"):             {"USAGE"},
+		profileObject("", "TABLES"):             {"INSERT", "SELECT"},
+		profileObject("", "SEQUENCES"):          {"SELECT", "USAGE"},
+		profileObject("", "FUNCTIONS"):          {"EXECUTE"},
+		profileObject("profile_main", "TABLES"): {"INSERT", "SELECT"},
+		// Default privileges on sequences are not a part of the profile
+		profileObject("profile_main", "FUNCTIONS"): {"EXECUTE"},
+	}, server.users["profile_user"].objects)
+
+	// Privileges that are removed from the profile are revoked from the user
+	user.AccessProfile.Database = nil
+	user.AccessProfile.Tables = []string{"SELECT"}
+	user.AccessProfile.Sequences = nil
+	user.AccessProfile.DefaultPrivileges.Tables = []string{"SELECT"}
+	require.NoError(t, UpdateUser(ctx, db, user, admin))
+	assert.Equal(t, map[fakeSQLObject][]string{
+		profileObject("", "SCHEMA"):                {"USAGE"},
+		profileObject("", "TABLES"):                {"SELECT"},
+		profileObject("", "FUNCTIONS"):             {"EXECUTE"},
+		profileObject("profile_main", "TABLES"):    {"SELECT"},
+		profileObject("profile_main", "FUNCTIONS"): {"EXECUTE"},
+	}, server.users["profile_user"].objects)
+	assert.Equal(t, ACCESS_TYPE_READONLY, server.users["profile_user"].grants["profile"])
+
+	// Privileges are revoked for every kind of objects, even if they're not a part of the profile
+	require.NoError(t, server.grantObject("profile_user", profileObject("profile_main", "SEQUENCES"), "USAGE"))
+	require.NoError(t, DeleteUser(ctx, db, user, admin))
+	assert.NotContains(t, server.users, "profile_user")
+
+	// Privileges that can't be put into a statement are rejected
+	user.AccessProfile.Tables = []string{"SELECT ON ALL TABLES IN SCHEMA public TO PUBLIC; --"}
+	assert.Error(t, CreateUser(ctx, db, user, admin))
+}
+
+func TestPostgresBuiltinAccessProfiles(t *testing.T) {
+	ctx := context.TODO()
+	db, server, admin := testAccessProfileEngine(t, "postgres", fakePostgresDialect, 5432)
+	user := &DatabaseUser{Username: "profile_user", Password: "userpwd", AccessType: ACCESS_TYPE_READWRITE}
+
+	require.NoError(t, CreateUser(ctx, db, user, admin))
+	assert.Equal(t, map[fakeSQLObject][]string{
+		profileObject("", "SCHEMA"):                {"USAGE"},
+		profileObject("", "TABLES"):                {"DELETE", "INSERT", "SELECT", "UPDATE"},
+		profileObject("", "SEQUENCES"):             {"SELECT", "UPDATE", "USAGE"},
+		profileObject("", "FUNCTIONS"):             {"EXECUTE"},
+		profileObject("profile_main", "TABLES"):    {"DELETE", "INSERT", "SELECT", "UPDATE"},
+		profileObject("profile_main", "SEQUENCES"): {"SELECT", "UPDATE", "USAGE"},
+		profileObject("profile_main", "FUNCTIONS"): {"EXECUTE"},
+	}, server.users["profile_user"].objects)
+
+	// Read-only users can read sequences, but can't change them
+	readonly := &DatabaseUser{Username: "profile_ro", Password: "ropwd", AccessType: ACCESS_TYPE_READONLY}
+	require.NoError(t, CreateUser(ctx, db, readonly, admin))
+	objects := server.users["profile_ro"].objects
+	assert.Equal(t, []string{"SELECT", "USAGE"}, objects[profileObject("", "SEQUENCES")])
+	assert.Equal(t, []string{"SELECT", "USAGE"}, objects[profileObject("profile_main", "SEQUENCES")])
+	assert.Equal(t, []string{"EXECUTE"}, objects[profileObject("", "FUNCTIONS")])
+
+	// Privileges of the previous access type are revoked, when it's changed
+	user.AccessType = ACCESS_TYPE_READONLY
+	require.NoError(t, UpdateUser(ctx, db, user, admin))
+	assert.Equal(t, server.users["profile_ro"].objects, server.users["profile_user"].objects)
+}
+
+func TestPostgresObjectOwners(t *testing.T) {
+	ctx := context.TODO()
+	admin := &DatabaseUser{Username: "admin", Password: "adminpwd"}
+	server := newFakeSQLServer(fakePostgresDialect, admin)
+	useFakeSQLServer(t, server)
+
+	e, err := GetEngine("postgres")
+	require.NoError(t, err)
+	mainUser := &DatabaseUser{Username: "owners_main", Password: "mainpwd", AccessType: ACCESS_TYPE_MAINUSER}
+	migrator := &DatabaseUser{Username: "owners_migrator", Password: "migratorpwd", AccessType: "migrator", AccessProfile: &AccessProfile{
+		Name:    "migrator",
+		Schemas: []string{"USAGE", "CREATE"},
+		Tables:  []string{"ALL"},
+	}}
+	cfg := EngineConfig{
+		Instance: "owners-postgres", Host: "postgres", Port: 5432, Database: "owners", MainUser: mainUser,
+		Spec: []byte(`{"objectOwners": ["owners_migrator", "owners_missing"], "enforceMainUserOwnership": true}`),
+	}
+	db, err := e.New(ctx, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		Connections.Invalidate(poolInstance(cfg.Instance, cfg.Host, cfg.Port))
+	})
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	require.NoError(t, CreateOrUpdateUser(ctx, db, mainUser, admin))
+
+	// The migrator can create objects, so it's acting as the main user
+	require.NoError(t, CreateUser(ctx, db, migrator, admin))
+	role := server.users["owners_migrator"]
+	assert.Equal(t, []string{"owners_main"}, role.memberOf)
+	assert.Equal(t, map[string]string{"role": "'owners_main'"}, role.databaseSettings["owners"])
+	for object := range role.objects {
+		assert.NotEqual(t, "owners_migrator", object.owner, "the migrator doesn't need default privileges on its own objects")
+	}
+
+	// Default privileges are set for objects of every existing owner,
+	// the fake server fails on owners that don't exist
+	reader := &DatabaseUser{Username: "owners_ro", Password: "ropwd", AccessType: ACCESS_TYPE_READONLY}
+	require.NoError(t, CreateUser(ctx, db, reader, admin))
+	objects := server.users["owners_ro"].objects
+	for _, owner := range []string{"owners_main", "owners_migrator"} {
+		assert.Equal(t, []string{"SELECT"}, objects[fakeSQLObject{database: "owners", owner: owner, kind: "TABLES", schema: "public"}], owner)
+	}
+	assert.Empty(t, server.users["owners_ro"].memberOf, "read-only users can't create objects")
+	assert.Empty(t, server.users["owners_ro"].databaseSettings, "read-only users can't create objects")
+
+	// Default privileges on objects of other owners are revoked by the admin
+	require.NoError(t, DeleteUser(ctx, db, reader, admin))
+	assert.NotContains(t, server.users, "owners_ro")
+}
+
+func TestMysqlAccessProfile(t *testing.T) {
+	ctx := context.TODO()
+	db, server, admin := testAccessProfileEngine(t, "mysql", fakeMysqlDialect, 3306)
+	user := &DatabaseUser{
+		Username:   "profile_user",
+		Password:   "userpwd",
+		AccessType: "app",
+		AccessProfile: &AccessProfile{
+			Name:      "app",
+			Database:  []string{"SELECT", "CREATE TEMPORARY TABLES"},
+			Tables:    []string{"SELECT", "INSERT"},
+			Sequences: []string{"USAGE"},
+			Functions: []string{"EXECUTE"},
+		},
+	}
+
+	require.NoError(t, CreateUser(ctx, db, user, admin))
+	assert.Equal(t, []string{"SELECT", "CREATE TEMPORARY TABLES", "INSERT", "EXECUTE"}, server.users["profile_user"].privileges["profile@%"])
+
+	// Privileges that are removed from the profile are revoked from the user
+	user.AccessProfile.Tables = []string{"SELECT"}
+	require.NoError(t, UpdateUser(ctx, db, user, admin))
+	assert.Equal(t, []string{"SELECT", "CREATE TEMPORARY TABLES", "EXECUTE"}, server.users["profile_user"].privileges["profile@%"])
+	assert.Equal(t, ACCESS_TYPE_READONLY, server.users["profile_user"].grants["profile"])
+
+	// And so are privileges of the previous access type
+	user.AccessType = ACCESS_TYPE_READONLY
+	user.AccessProfile = nil
+	require.NoError(t, UpdateUser(ctx, db, user, admin))
+	assert.Equal(t, []string{"SELECT"}, server.users["profile_user"].privileges["profile@%"])
+
+	user.AccessProfile = &AccessProfile{Name: "app", Sequences: []string{"USAGE"}}
+	assert.Error(t, CreateUser(ctx, db, user, admin), "profiles without privileges for mysql must be rejected")
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2021 kloeckner.i GmbH
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"path"
+	"strconv"
+
+	"github.com/db-operator/db-operator/pkg/consts"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func postgresBackupContainer(cfg BackupConfig) (corev1.Container, error) {
+	envList := []corev1.EnvVar{
+		{
+			Name: "DB_HOST", Value: cfg.Host,
+		},
+		{
+			Name: "DB_PORT", Value: cfg.Port,
+		},
+		{
+			Name: "DB_NAME", ValueFrom: cfg.secretKeyRef(consts.POSTGRES_DB),
+		},
+		{
+			Name: "DB_PASSWORD_FILE", Value: path.Join(cfg.CredentialsPath, consts.POSTGRES_PASSWORD),
+		},
+		{
+			Name: "DB_USERNAME_FILE", Value: path.Join(cfg.CredentialsPath, consts.POSTGRES_USER),
+		},
+		{
+			Name: "GCS_BUCKET", Value: cfg.Bucket,
+		},
+	}
+
+	if cfg.Monitoring {
+		envList = append(envList, corev1.EnvVar{
+			Name: "PROMETHEUS_PUSH_GATEWAY", Value: cfg.PromPushGateway,
+		})
+	}
+
+	return cfg.container("postgres-dump", envList), nil
+}
+
+func mysqlBackupContainer(cfg BackupConfig) (corev1.Container, error) {
+	envList := []corev1.EnvVar{
+		{
+			Name: "DB_HOST", Value: cfg.Host,
+		},
+		{
+			Name: "DB_PORT", Value: cfg.Port,
+		},
+		{
+			Name: "DB_NAME", ValueFrom: cfg.secretKeyRef(consts.MYSQL_DB),
+		},
+		{
+			Name: "DB_USER", ValueFrom: cfg.secretKeyRef(consts.MYSQL_USER),
+		},
+		{
+			Name: "DB_PASSWORD_FILE", Value: path.Join(cfg.CredentialsPath, consts.MYSQL_PASSWORD),
+		},
+		{
+			Name: "GCS_BUCKET", Value: cfg.Bucket,
+		},
+	}
+
+	return cfg.container("mysql-dump", envList), nil
+}
+
+// mongodbBackupContainer builds a container that is expected to run mongodump,
+// users are created in the database they belong to, so DB_NAME should be
+// also used as the authentication database
+func mongodbBackupContainer(cfg BackupConfig) (corev1.Container, error) {
+	envList := []corev1.EnvVar{
+		{
+			Name: "DB_HOST", Value: cfg.Host,
+		},
+		{
+			Name: "DB_PORT", Value: cfg.Port,
+		},
+		{
+			Name: "DB_NAME", ValueFrom: cfg.secretKeyRef(consts.MONGODB_DB),
+		},
+		{
+			Name: "DB_PASSWORD_FILE", Value: path.Join(cfg.CredentialsPath, consts.MONGODB_PASSWORD),
+		},
+		{
+			Name: "DB_USERNAME_FILE", Value: path.Join(cfg.CredentialsPath, consts.MONGODB_USER),
+		},
+		{
+			Name: "DB_SSL", Value: strconv.FormatBool(cfg.SSLEnabled),
+		},
+		{
+			Name: "GCS_BUCKET", Value: cfg.Bucket,
+		},
+	}
+
+	if cfg.Monitoring {
+		envList = append(envList, corev1.EnvVar{
+			Name: "PROMETHEUS_PUSH_GATEWAY", Value: cfg.PromPushGateway,
+		})
+	}
+
+	return cfg.container("mongodb-dump", envList), nil
+}
+
+func (cfg BackupConfig) container(name string, env []corev1.EnvVar) corev1.Container {
+	return corev1.Container{
+		Name:            name,
+		Image:           cfg.Image,
+		ImagePullPolicy: corev1.PullAlways,
+		VolumeMounts:    cfg.VolumeMounts,
+		Env:             env,
+		Resources:       cfg.Resources,
+	}
+}
+
+func (cfg BackupConfig) secretKeyRef(key string) *corev1.EnvVarSource {
+	return &corev1.EnvVarSource{
+		SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: cfg.SecretName},
+			Key:                  key,
+		},
+	}
+}
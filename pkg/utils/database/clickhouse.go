@@ -21,26 +21,546 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/db-operator/db-operator/pkg/utils/engines"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
 	// Don't delete below package. Used for driver "clickhouse"
+	_ "github.com/ClickHouse/clickhouse-go/v2"
 )
 
 // ClickHouse is a database interface, abstracted object
 // represents a database on ClickHouse instance
 // can be used to execute queries to ClickHouse database
 type ClickHouse struct {
+	// Name of the DbInstance, admin connections are pooled per instance
+	Instance    string `json:"-"`
+	Backend     string
 	Host        string
 	Port        uint16
 	Database    string
-	ClusterName string
+	ClusterName string `json:"clusterName"`
+	// Shard of the cluster that must have enough replicas
+	// to satisfy the ReplicationFactor
+	Shard             string `json:"shard"`
+	Replica           string `json:"replica"`
+	ReplicationFactor int    `json:"replicationFactor"`
+	// Default table engine for users of the database
+	Engine       string            `json:"engine"`
+	Settings     map[string]string `json:"settings"`
+	SSLEnabled   bool
+	SkipCAVerify bool
+	// Limits of the main user
+	MainUser clickhouseUserSpec `json:"-"`
+}
+
+// clickhouseUserSpec is the clickhouse specific part of a user spec, it's coming
+// from the Database for main users, and from DbUsers for other ones
+type clickhouseUserSpec struct {
+	MaxMemoryUsage   *int64                `json:"maxMemoryUsage"`
+	MaxExecutionTime *int64                `json:"maxExecutionTime"`
+	Readonly         *int                  `json:"readonly"`
+	Quotas           []clickhouseQuota     `json:"quotas"`
+	RowPolicies      []clickhouseRowPolicy `json:"rowPolicies"`
+}
+
+type clickhouseQuota struct {
+	Interval      string `json:"interval"`
+	Queries       *int64 `json:"queries"`
+	Errors        *int64 `json:"errors"`
+	ResultRows    *int64 `json:"resultRows"`
+	ReadRows      *int64 `json:"readRows"`
+	ExecutionTime *int64 `json:"executionTime"`
+}
+
+type clickhouseRowPolicy struct {
+	Table     string `json:"table"`
+	Condition string `json:"condition"`
+}
+
+// Quota intervals are converted to seconds, so they can be compared with
+// durations of existing limits, months and years are not supported,
+// because clickhouse doesn't count them in seconds
+var clickhouseIntervalRegexp = regexp.MustCompile(`^([0-9]+) (second|minute|hour|day|week)s?$`)
+
+var clickhouseIntervalUnits = map[string]int64{
+	"second": 1,
+	"minute": 60,
+	"hour":   60 * 60,
+	"day":    24 * 60 * 60,
+	"week":   7 * 24 * 60 * 60,
+}
+
+// Tables of row policies are not quoted, * is a policy of all tables
+var clickhouseTableRegexp = regexp.MustCompile(`^([A-Za-z0-9_]+|\*)$`)
+
+// Built-in access types of clickhouse users
+var clickhouseAccessProfiles = map[string]AccessProfile{
+	ACCESS_TYPE_READONLY: {
+		Name:     ACCESS_TYPE_READONLY,
+		Database: []string{"SELECT"},
+	},
+	ACCESS_TYPE_READWRITE: {
+		Name:     ACCESS_TYPE_READWRITE,
+		Database: []string{"SELECT", "INSERT", "ALTER UPDATE", "ALTER DELETE"},
+	},
+}
+
+func init() {
+	Register(Engine{
+		Engine: engines.ClickHouse,
+		New:    newClickHouse,
+	})
+}
+
+func newClickHouse(ctx context.Context, cfg EngineConfig) (Database, error) {
+	ch := ClickHouse{}
+	if err := cfg.decodeSpec(&ch); err != nil {
+		return nil, err
+	}
+	ch.Instance = cfg.Instance
+	ch.Backend = cfg.Backend
+	ch.Host = cfg.Host
+	ch.Port = cfg.Port
+	ch.Database = cfg.Database
+	ch.SSLEnabled = cfg.SSLEnabled
+	ch.SkipCAVerify = cfg.SkipCAVerify
+	if err := cfg.decodeSpec(&ch.MainUser); err != nil {
+		return nil, err
+	}
+	return ch, nil
 }
 
 // Internal helpers, these functions are not part for the `Database` interface
 
+// dsn builds a clickhouse-go connection string
+func (ch ClickHouse) dsn(dbname, user, password string) string {
+	params := url.Values{}
+	if ch.SSLEnabled {
+		params.Set("secure", "true")
+		if ch.SkipCAVerify {
+			params.Set("skip_verify", "true")
+		}
+	}
+	if timeout := currentTimeouts().Connect; timeout > 0 {
+		params.Set("dial_timeout", timeout.String())
+	}
+	dataSourceName := url.URL{
+		Scheme:   "clickhouse",
+		User:     url.UserPassword(user, password),
+		Host:     fmt.Sprintf("%s:%d", ch.Host, ch.Port),
+		Path:     "/" + dbname,
+		RawQuery: params.Encode(),
+	}
+	return dataSourceName.String()
+}
+
+// clickhouseReplicatedPath is the path of replicated databases in the keeper,
+// {uuid} is substituted by the UUID of the database that is shared by all replicas
+const clickhouseReplicatedPath = "/clickhouse/databases/{uuid}"
+
+// databaseEngine returns the ENGINE clause of the database, shards and replicas
+// of the Replicated engine are taken from the spec, or from macros of hosts if they're not set
+func (ch ClickHouse) databaseEngine() (string, error) {
+	if ch.Engine != CLICKHOUSE_ENGINE_REPLICATED {
+		return "", nil
+	}
+	if ch.ClusterName == "" {
+		return "", fmt.Errorf("the %s engine requires a cluster", ch.Engine)
+	}
+	shard, replica := ch.Shard, ch.Replica
+	if shard == "" {
+		shard = "{shard}"
+	}
+	if replica == "" {
+		replica = "{replica}"
+	}
+	return fmt.Sprintf(" ENGINE = Replicated(%s, %s, %s)",
+		clickhouseQuoteLiteral(clickhouseReplicatedPath), clickhouseQuoteLiteral(shard), clickhouseQuoteLiteral(replica)), nil
+}
+
+// onCluster returns the ON CLUSTER clause if the database is distributed
+func (ch ClickHouse) onCluster() string {
+	if ch.ClusterName != "" {
+		return fmt.Sprintf(" ON CLUSTER %s", clickhouseQuoteLiteral(ch.ClusterName))
+	}
+	return ""
+}
+
+// settingsProfile returns the name of the profile that is assigned to users of the database
+func (ch ClickHouse) settingsProfile() string {
+	return ch.Database + "_profile"
+}
+
+// clickhouseValidCondition checks that a row policy condition can be used
+// in parentheses as is. The condition is an expression, so it can't be quoted,
+// but outside of quotes it must not end the statement, start a comment
+// or close more parentheses than it opens
+func clickhouseValidCondition(condition string) bool {
+	if len(strings.TrimSpace(condition)) == 0 {
+		return false
+	}
+	depth := 0
+	var quote rune
+	escaped := false
+	runes := []rune(condition)
+	for i, r := range runes {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+			continue
+		}
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		case r == ';' || r == '#':
+			return false
+		case r == '-' && next == '-', r == '/' && next == '*':
+			return false
+		}
+	}
+	return quote == 0 && depth == 0
+}
+
+// profileSettings returns settings of the database profile in the SETTINGS clause format,
+// they're sorted to keep queries stable between reconciliations
+func (ch ClickHouse) profileSettings() []string {
+	settings := []string{}
+	if ch.Engine != "" && ch.Engine != CLICKHOUSE_ENGINE_REPLICATED {
+		settings = append(settings, fmt.Sprintf("default_table_engine = %s", clickhouseQuoteLiteral(ch.Engine)))
+	}
+	// Inserts are only confirmed after they're written to all replicas
+	if ch.ReplicationFactor > 1 {
+		settings = append(settings, fmt.Sprintf("insert_quorum = %d", ch.ReplicationFactor))
+	}
+	keys := make([]string, 0, len(ch.Settings))
+	for key := range ch.Settings {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		settings = append(settings, fmt.Sprintf("%s = %s", clickhouseSettingName(key), clickhouseSettingValue(ch.Settings[key])))
+	}
+	return settings
+}
+
+// clickhouseSettingName quotes a setting name unless it's a plain identifier
+func clickhouseSettingName(name string) string {
+	if clickhousePlainIdentifierRegexp.MatchString(name) {
+		return name
+	}
+	return clickhouseQuoteIdentifier(name)
+}
+
+// clickhouseSettingValue quotes a setting value unless it's a number
+func clickhouseSettingValue(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	return clickhouseQuoteLiteral(value)
+}
+
+// accessGrant returns privileges that are granted to the user on the database
+func (ch ClickHouse) accessGrant(user *DatabaseUser) (string, error) {
+	switch user.AccessType {
+	case ACCESS_TYPE_MAINUSER:
+		return "ALL", nil
+	default:
+		profile, err := user.accessProfile(clickhouseAccessProfiles)
+		if err != nil {
+			return "", err
+		}
+		// Schemas, sequences and functions don't exist in clickhouse
+		privileges := joinPrivileges(profile.Database, profile.Tables)
+		if len(privileges) == 0 {
+			return "", fmt.Errorf("access profile %s doesn't have any privileges for clickhouse", profile.Name)
+		}
+		return privileges, nil
+	}
+}
+
+func (ch ClickHouse) checkReplicationFactor(ctx context.Context, admin *DatabaseUser) error {
+	if ch.ClusterName == "" || ch.ReplicationFactor < 2 {
+		return nil
+	}
+	check := fmt.Sprintf("SELECT toString(min(replicas)) FROM (SELECT count() AS replicas FROM system.clusters WHERE cluster = %s", clickhouseQuoteLiteral(ch.ClusterName))
+	if ch.Shard != "" {
+		check += fmt.Sprintf(" AND toString(shard_num) = %s", clickhouseQuoteLiteral(ch.Shard))
+	}
+	check += " GROUP BY shard_num)"
+
+	replicas, err := ch.QueryAsUser(ctx, check, admin)
+	if err != nil {
+		return err
+	}
+	count, err := strconv.Atoi(replicas)
+	if err != nil || count < ch.ReplicationFactor {
+		return fmt.Errorf("cluster %s doesn't have %d replicas for the shard %s", ch.ClusterName, ch.ReplicationFactor, ch.Shard)
+	}
+	return nil
+}
+
+func (ch ClickHouse) createSettingsProfile(ctx context.Context, admin *DatabaseUser) error {
+	settings := ch.profileSettings()
+	// The profile is removed from users together with it, so they're not limited anymore
+	if len(settings) == 0 {
+		drop := fmt.Sprintf("DROP SETTINGS PROFILE IF EXISTS %s%s", clickhouseQuoteLiteral(ch.settingsProfile()), ch.onCluster())
+		return ch.executeExec(ctx, "default", drop, admin)
+	}
+	// ALTER is used to keep the profile up to date with the spec
+	create := fmt.Sprintf("CREATE SETTINGS PROFILE IF NOT EXISTS %s%s", clickhouseQuoteLiteral(ch.settingsProfile()), ch.onCluster())
+	alter := fmt.Sprintf("ALTER SETTINGS PROFILE %s%s SETTINGS %s", clickhouseQuoteLiteral(ch.settingsProfile()), ch.onCluster(), strings.Join(settings, ", "))
+	for _, query := range []string{create, alter} {
+		if err := ch.executeExec(ctx, "default", query, admin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// userSpec returns limits of the user, main users are configured by the database
+func (ch ClickHouse) userSpec(user *DatabaseUser) (clickhouseUserSpec, error) {
+	spec := clickhouseUserSpec{}
+	if user.AccessType == ACCESS_TYPE_MAINUSER {
+		spec = ch.MainUser
+	} else if err := user.decodeSpec(&spec); err != nil {
+		return spec, err
+	}
+	for _, quota := range spec.Quotas {
+		if _, err := quota.seconds(); err != nil {
+			return spec, err
+		}
+	}
+	for _, policy := range spec.RowPolicies {
+		if !clickhouseTableRegexp.MatchString(policy.Table) {
+			return spec, fmt.Errorf("invalid row policy table: %s", policy.Table)
+		}
+		if !clickhouseValidCondition(policy.Condition) {
+			return spec, fmt.Errorf("invalid row policy condition of the table %s: %s", policy.Table, policy.Condition)
+		}
+	}
+	return spec, nil
+}
+
+// profileSettings returns settings of the user profile in the SETTINGS clause format
+func (s clickhouseUserSpec) profileSettings() []string {
+	settings := []string{}
+	if s.MaxMemoryUsage != nil {
+		settings = append(settings, fmt.Sprintf("max_memory_usage = %d", *s.MaxMemoryUsage))
+	}
+	if s.MaxExecutionTime != nil {
+		settings = append(settings, fmt.Sprintf("max_execution_time = %d", *s.MaxExecutionTime))
+	}
+	if s.Readonly != nil {
+		settings = append(settings, fmt.Sprintf("readonly = %d", *s.Readonly))
+	}
+	return settings
+}
+
+// seconds returns the length of the quota interval
+func (q clickhouseQuota) seconds() (int64, error) {
+	match := clickhouseIntervalRegexp.FindStringSubmatch(q.Interval)
+	if match == nil {
+		return 0, fmt.Errorf("invalid quota interval: %s", q.Interval)
+	}
+	count, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil || count == 0 {
+		return 0, fmt.Errorf("invalid quota interval: %s", q.Interval)
+	}
+	return count * clickhouseIntervalUnits[match[2]], nil
+}
+
+// limits returns the MAX clause of the quota interval
+func (q clickhouseQuota) limits() string {
+	limits := []string{}
+	for _, limit := range []struct {
+		name  string
+		value *int64
+	}{
+		{"queries", q.Queries},
+		{"errors", q.Errors},
+		{"result_rows", q.ResultRows},
+		{"read_rows", q.ReadRows},
+		{"execution_time", q.ExecutionTime},
+	} {
+		if limit.value != nil {
+			limits = append(limits, fmt.Sprintf("%s = %d", limit.name, *limit.value))
+		}
+	}
+	if len(limits) == 0 {
+		return "TRACKING ONLY"
+	}
+	return "MAX " + strings.Join(limits, ", ")
+}
+
+// Names of access entities that are created for the user
+func clickhouseUserProfile(username string) string {
+	return username + "_user_profile"
+}
+
+func clickhouseUserQuota(username string) string {
+	return username + "_quota"
+}
+
+// queryValue runs the query as admin in the default database and returns the first value
+func (ch ClickHouse) queryValue(ctx context.Context, query string, admin *DatabaseUser) (string, error) {
+	db, err := ch.getPooledConn("default", admin.Username, admin.Password)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	var result string
+	if err := db.QueryRowContext(ctx, query).Scan(&result); err != nil {
+		return "", timeoutError(err)
+	}
+	return result, nil
+}
+
+// queryList runs a query that returns values joined by commas, because
+// clickhouse aggregates them into one row with arrayStringConcat
+func (ch ClickHouse) queryList(ctx context.Context, query string, admin *DatabaseUser) ([]string, error) {
+	result, err := ch.queryValue(ctx, query, admin)
+	if err != nil || result == "" {
+		return nil, err
+	}
+	return strings.Split(result, ","), nil
+}
+
+// syncUserProfile creates the settings profile of the user, or drops it when there are no limits,
+// and assigns it to the user together with the profile of the database
+func (ch ClickHouse) syncUserProfile(ctx context.Context, admin *DatabaseUser, user *DatabaseUser, spec clickhouseUserSpec) error {
+	profile := clickhouseQuoteLiteral(clickhouseUserProfile(user.Username))
+	queries := []string{}
+	profiles := []string{}
+	if len(ch.profileSettings()) > 0 {
+		profiles = append(profiles, "PROFILE "+clickhouseQuoteLiteral(ch.settingsProfile()))
+	}
+	if settings := spec.profileSettings(); len(settings) > 0 {
+		queries = append(queries,
+			fmt.Sprintf("CREATE SETTINGS PROFILE IF NOT EXISTS %s%s", profile, ch.onCluster()),
+			fmt.Sprintf("ALTER SETTINGS PROFILE %s%s SETTINGS %s", profile, ch.onCluster(), strings.Join(settings, ", ")),
+		)
+		// Settings of the user profile are applied after the database ones, so they take precedence
+		profiles = append(profiles, "PROFILE "+profile)
+	} else {
+		queries = append(queries, fmt.Sprintf("DROP SETTINGS PROFILE IF EXISTS %s%s", profile, ch.onCluster()))
+	}
+	if len(profiles) > 0 {
+		queries = append(queries, fmt.Sprintf("ALTER USER %s%s SETTINGS %s", clickhouseQuoteLiteral(user.Username), ch.onCluster(), strings.Join(profiles, ", ")))
+	}
+	for _, query := range queries {
+		if err := ch.executeExec(ctx, "default", query, admin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncUserQuota creates the quota of the user with limits from the spec, intervals that are
+// not in the spec anymore are left without limits, and the quota is dropped when there are no intervals
+func (ch ClickHouse) syncUserQuota(ctx context.Context, admin *DatabaseUser, user *DatabaseUser, spec clickhouseUserSpec) error {
+	quota := clickhouseQuoteLiteral(clickhouseUserQuota(user.Username))
+	if len(spec.Quotas) == 0 {
+		return ch.executeExec(ctx, "default", fmt.Sprintf("DROP QUOTA IF EXISTS %s%s", quota, ch.onCluster()), admin)
+	}
+
+	create := fmt.Sprintf("CREATE QUOTA IF NOT EXISTS %s%s", quota, ch.onCluster())
+	if err := ch.executeExec(ctx, "default", create, admin); err != nil {
+		return err
+	}
+	query := fmt.Sprintf("SELECT arrayStringConcat(groupArray(toString(duration)), ',') FROM system.quota_limits WHERE quota_name = %s",
+		clickhouseQuoteLiteral(clickhouseUserQuota(user.Username)))
+	existing, err := ch.queryList(ctx, query, admin)
+	if err != nil {
+		return err
+	}
+	intervals := []string{}
+	durations := []string{}
+	for _, q := range spec.Quotas {
+		seconds, err := q.seconds()
+		if err != nil {
+			return err
+		}
+		duration := strconv.FormatInt(seconds, 10)
+		durations = append(durations, duration)
+		intervals = append(intervals, fmt.Sprintf("FOR INTERVAL %s second %s", duration, q.limits()))
+	}
+	for _, duration := range existing {
+		if !slices.Contains(durations, duration) {
+			intervals = append(intervals, fmt.Sprintf("FOR INTERVAL %s second NO LIMITS", duration))
+		}
+	}
+	alter := fmt.Sprintf("ALTER QUOTA %s%s %s TO %s", quota, ch.onCluster(), strings.Join(intervals, ", "), clickhouseQuoteLiteral(user.Username))
+	return ch.executeExec(ctx, "default", alter, admin)
+}
+
+// syncRowPolicies creates row policies of the user in the database,
+// and drops policies of tables that are not in the spec anymore
+func (ch ClickHouse) syncRowPolicies(ctx context.Context, admin *DatabaseUser, user *DatabaseUser, policies []clickhouseRowPolicy) error {
+	name := clickhouseQuoteLiteral(user.Username)
+	tables := []string{}
+	for _, policy := range policies {
+		tables = append(tables, policy.Table)
+		create := fmt.Sprintf("CREATE ROW POLICY OR REPLACE %s%s ON %s FOR SELECT USING (%s) TO %s",
+			name, ch.onCluster(), ch.policyTable(policy.Table), policy.Condition, name)
+		if err := ch.executeExec(ctx, "default", create, admin); err != nil {
+			return err
+		}
+	}
+
+	// Policies of all tables are shown without a table
+	query := fmt.Sprintf("SELECT arrayStringConcat(groupArray(if(table = '', '*', table)), ',') FROM system.row_policies WHERE short_name = %s AND database = %s",
+		name, clickhouseQuoteLiteral(ch.Database))
+	existing, err := ch.queryList(ctx, query, admin)
+	if err != nil {
+		return err
+	}
+	for _, table := range existing {
+		if slices.Contains(tables, table) {
+			continue
+		}
+		drop := fmt.Sprintf("DROP ROW POLICY IF EXISTS %s ON %s%s", name, ch.policyTable(table), ch.onCluster())
+		if err := ch.executeExec(ctx, "default", drop, admin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// policyTable returns the qualified table of a row policy
+func (ch ClickHouse) policyTable(table string) string {
+	if table == "*" {
+		return clickhouseQuoteIdentifier(ch.Database) + ".*"
+	}
+	return clickhouseQuoteIdentifier(ch.Database) + "." + clickhouseQuoteIdentifier(table)
+}
+
 func (ch ClickHouse) getDbConn(dbname, user, password string) (*sql.DB, error) {
-	dataSourceName := fmt.Sprintf("tcp://%s:%d?database=%s&username=%s&password=%s", ch.Host, ch.Port, dbname, user, password)
-	db, err := sql.Open("clickhouse", dataSourceName)
+	db, err := sqlOpen("clickhouse", ch.dsn(dbname, user, password))
 	if err != nil {
 		return nil, fmt.Errorf("sql.Open: %v", err)
 	}
@@ -48,18 +568,28 @@ func (ch ClickHouse) getDbConn(dbname, user, password string) (*sql.DB, error) {
 	return db, err
 }
 
+// getPooledConn returns a connection pool that is shared between reconciliations,
+// it must not be closed by the caller
+func (ch ClickHouse) getPooledConn(dbname, user, password string) (*sql.DB, error) {
+	key := connKey{Instance: poolInstance(ch.Instance, ch.Host, ch.Port), Database: dbname, User: user}
+	return Connections.get(key, ch.dsn(dbname, user, password), func() (*sql.DB, error) {
+		return ch.getDbConn(dbname, user, password)
+	})
+}
+
 func (ch ClickHouse) executeExec(ctx context.Context, database, query string, admin *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	db, err := ch.getDbConn(database, admin.Username, admin.Password)
+	db, err := ch.getPooledConn(database, admin.Username, admin.Password)
 	if err != nil {
 		log.Error(err, "failed to open a db connection")
 		return err
 	}
 
-	defer db.Close()
-	_, err = db.Exec(query)
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	_, err = db.ExecContext(ctx, query)
 
-	return err
+	return timeoutError(err)
 }
 
 func (ch ClickHouse) execAsUser(ctx context.Context, query string, user *DatabaseUser) error {
@@ -71,34 +601,37 @@ func (ch ClickHouse) execAsUser(ctx context.Context, query string, user *Databas
 	}
 
 	defer db.Close()
-	_, err = db.Exec(query)
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	_, err = db.ExecContext(ctx, query)
 
-	return err
+	return timeoutError(err)
 }
 
 func (ch ClickHouse) isDbExist(ctx context.Context, admin *DatabaseUser) bool {
-	check := fmt.Sprintf("SELECT name FROM system.databases WHERE name = '%s'", ch.Database)
+	check := fmt.Sprintf("SELECT name FROM system.databases WHERE name = %s", clickhouseQuoteLiteral(ch.Database))
 
 	return ch.isRowExist(ctx, "default", check, admin.Username, admin.Password)
 }
 
 func (ch ClickHouse) isUserExist(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) bool {
-	check := fmt.Sprintf("SELECT name FROM system.users WHERE name = '%s'", user.Username)
+	check := fmt.Sprintf("SELECT name FROM system.users WHERE name = %s", clickhouseQuoteLiteral(user.Username))
 
 	return ch.isRowExist(ctx, "default", check, admin.Username, admin.Password)
 }
 
 func (ch ClickHouse) isRowExist(ctx context.Context, database, query, user, password string) bool {
 	log := log.FromContext(ctx)
-	db, err := ch.getDbConn(database, user, password)
+	db, err := ch.getPooledConn(database, user, password)
 	if err != nil {
 		log.Error(err, "failed to open a db connection")
 		return false
 	}
-	defer db.Close()
 
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
 	var name string
-	err = db.QueryRow(query).Scan(&name)
+	err = db.QueryRowContext(ctx, query).Scan(&name)
 	if err != nil {
 		log.V(2).Info("failed executing query", "error", err)
 		return false
@@ -116,12 +649,36 @@ func (ch ClickHouse) CheckStatus(ctx context.Context, user *DatabaseUser) error
 		return fmt.Errorf("db conn test failed - couldn't get db conn: %s", err)
 	}
 	defer db.Close()
-	res, err := db.Query("SELECT 1")
+	queryCtx, cancel := statementContext(ctx)
+	defer cancel()
+	res, err := db.QueryContext(queryCtx, "SELECT 1")
 	if err != nil {
-		return fmt.Errorf("db conn test failed - failed to execute query: %s", err)
+		return timeoutError(fmt.Errorf("db conn test failed - failed to execute query: %w", err))
 	}
 	res.Close()
 
+	if ch.ClusterName != "" && ch.Database != "" {
+		return ch.checkClusterHosts(ctx, db)
+	}
+	return nil
+}
+
+// checkClusterHosts returns an error with hosts of the cluster that don't have the database,
+// hosts are listed by system.one, because only databases of the user are visible
+func (ch ClickHouse) checkClusterHosts(ctx context.Context, db *sql.DB) error {
+	query := fmt.Sprintf("SELECT arrayStringConcat(groupArray(host), ', ') FROM (SELECT hostName() AS host FROM clusterAllReplicas(%s, system.one)) "+
+		"WHERE host NOT IN (SELECT hostName() FROM clusterAllReplicas(%s, system.databases) WHERE name = %s)",
+		clickhouseQuoteLiteral(ch.ClusterName), clickhouseQuoteLiteral(ch.ClusterName), clickhouseQuoteLiteral(ch.Database))
+
+	queryCtx, cancel := statementContext(ctx)
+	defer cancel()
+	var missing string
+	if err := db.QueryRowContext(queryCtx, query).Scan(&missing); err != nil {
+		return timeoutError(fmt.Errorf("db conn test failed - failed to check hosts of the cluster %s: %w", ch.ClusterName, err))
+	}
+	if missing != "" {
+		return fmt.Errorf("database %s is missing on hosts of the cluster %s: %s", ch.Database, ch.ClusterName, missing)
+	}
 	return nil
 }
 
@@ -135,17 +692,23 @@ func (ch ClickHouse) GetCredentials(ctx context.Context, user *DatabaseUser) Cre
 }
 
 // ParseAdminCredentials parse admin username and password of ClickHouse database from secret data
+// If "user" key is not defined, take "default" as admin user by default
 func (ch ClickHouse) ParseAdminCredentials(ctx context.Context, data map[string][]byte) (*DatabaseUser, error) {
 	admin := &DatabaseUser{}
 
 	if user, ok := data["user"]; ok {
 		admin.Username = string(user)
+	} else if user, ok := data["CLICKHOUSE_USER"]; ok {
+		// the variable that is used by the official ClickHouse container image
+		admin.Username = string(user)
 	} else {
-		return nil, errors.New("no admin user found")
+		admin.Username = "default"
 	}
 
 	if password, ok := data["password"]; ok {
 		admin.Password = string(password)
+	} else if password, ok := data["CLICKHOUSE_PASSWORD"]; ok {
+		admin.Password = string(password)
 	} else {
 		return nil, errors.New("no admin password found")
 	}
@@ -169,24 +732,28 @@ func (ch ClickHouse) QueryAsUser(ctx context.Context, query string, user *Databa
 	}
 	defer db.Close()
 
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
 	var result string
-	if err := db.QueryRow(query).Scan(&result); err != nil {
+	if err := db.QueryRowContext(ctx, query).Scan(&result); err != nil {
 		log.Error(err, "failed executing query", "query", query)
-		return "", err
+		return "", timeoutError(err)
 	}
 	return result, nil
 }
 
 func (ch ClickHouse) createDatabase(ctx context.Context, admin *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	var create string
+	engine, err := ch.databaseEngine()
+	if err != nil {
+		return err
+	}
+	// Create database on cluster, if it's set, or a standalone database
+	create := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s%s%s", clickhouseQuoteIdentifier(ch.Database), ch.onCluster(), engine)
 
-	if ch.ClusterName != "" {
-		// Create database on cluster
-		create = fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` ON CLUSTER '%s'", ch.Database, ch.ClusterName)
-	} else {
-		// Create standalone database
-		create = fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", ch.Database)
+	if err := ch.checkReplicationFactor(ctx, admin); err != nil {
+		log.Error(err, "ClickHouse cluster doesn't satisfy the replication factor")
+		return err
 	}
 
 	if !ch.isDbExist(ctx, admin) {
@@ -197,12 +764,18 @@ func (ch ClickHouse) createDatabase(ctx context.Context, admin *DatabaseUser) er
 		}
 	}
 
+	if err := ch.createSettingsProfile(ctx, admin); err != nil {
+		log.Error(err, "failed creating ClickHouse settings profile")
+		return err
+	}
+
 	return nil
 }
 
 func (ch ClickHouse) deleteDatabase(ctx context.Context, admin *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	drop := fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", ch.Database)
+	drop := fmt.Sprintf("DROP DATABASE IF EXISTS %s%s", clickhouseQuoteIdentifier(ch.Database), ch.onCluster())
+	dropProfile := fmt.Sprintf("DROP SETTINGS PROFILE IF EXISTS %s%s", clickhouseQuoteLiteral(ch.settingsProfile()), ch.onCluster())
 
 	if ch.isDbExist(ctx, admin) {
 		err := ch.executeExec(ctx, "default", drop, admin)
@@ -211,6 +784,11 @@ func (ch ClickHouse) deleteDatabase(ctx context.Context, admin *DatabaseUser) er
 			return err
 		}
 	}
+
+	if err := ch.executeExec(ctx, "default", dropProfile, admin); err != nil {
+		log.Error(err, "failed dropping ClickHouse settings profile")
+		return err
+	}
 	return nil
 }
 
@@ -226,37 +804,33 @@ func (ch ClickHouse) createOrUpdateUser(ctx context.Context, admin *DatabaseUser
 			log.Error(err, "failed updating ClickHouse user")
 			return err
 		}
-	}
-
-	if err := ch.setUserPermission(ctx, admin, user); err != nil {
-		return err
+		// Permissions of new users are set by createUser
+		if err := ch.setUserPermission(ctx, admin, user); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 func (ch ClickHouse) createUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	create := fmt.Sprintf("CREATE USER IF NOT EXISTS '%s' IDENTIFIED BY '%s'", user.Username, user.Password)
-
-	if ch.ClusterName != "" {
-		create += fmt.Sprintf(" ON CLUSTER '%s'", ch.ClusterName)
-	}
+	create := fmt.Sprintf("CREATE USER IF NOT EXISTS %s%s IDENTIFIED BY %s", clickhouseQuoteLiteral(user.Username), ch.onCluster(), clickhouseQuoteLiteral(user.Password))
 
 	if err := ch.executeExec(ctx, "default", create, admin); err != nil {
 		log.Error(err, "failed creating ClickHouse user")
 		return err
 	}
 
+	if err := ch.setUserPermission(ctx, admin, user); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (ch ClickHouse) updateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	update := fmt.Sprintf("ALTER USER '%s' IDENTIFIED BY '%s'", user.Username, user.Password)
-
-	if ch.ClusterName != "" {
-		update += fmt.Sprintf(" ON CLUSTER '%s'", ch.ClusterName)
-	}
+	update := fmt.Sprintf("ALTER USER %s%s IDENTIFIED BY %s", clickhouseQuoteLiteral(user.Username), ch.onCluster(), clickhouseQuoteLiteral(user.Password))
 
 	if err := ch.executeExec(ctx, "default", update, admin); err != nil {
 		log.Error(err, "failed updating ClickHouse user")
@@ -268,14 +842,70 @@ func (ch ClickHouse) updateUser(ctx context.Context, admin *DatabaseUser, user *
 
 func (ch ClickHouse) setUserPermission(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	grant := fmt.Sprintf("GRANT ALL ON `%s`.* TO '%s'", ch.Database, user.Username)
+	privileges, err := ch.accessGrant(user)
+	if err != nil {
+		return err
+	}
+	spec, err := ch.userSpec(user)
+	if err != nil {
+		return err
+	}
 
-	if ch.ClusterName != "" {
-		grant += fmt.Sprintf(" ON CLUSTER '%s'", ch.ClusterName)
+	// Only privileges that are not in the access type anymore are revoked,
+	// so users don't lose access to the database while they're reconciled
+	granted, err := ch.queryList(ctx, fmt.Sprintf("SELECT arrayStringConcat(groupArray(toString(access_type)), ',') FROM system.grants WHERE user_name = %s AND database = %s AND table IS NULL AND is_partial_revoke = 0",
+		clickhouseQuoteLiteral(user.Username), clickhouseQuoteLiteral(ch.Database)), admin)
+	if err != nil {
+		log.Error(err, "failed getting privileges of ClickHouse user")
+		return err
+	}
+	wanted := strings.Split(privileges, ", ")
+	revoke := []string{}
+	for _, privilege := range granted {
+		if !slices.Contains(wanted, privilege) {
+			revoke = append(revoke, privilege)
+		}
+	}
+	grant := []string{}
+	for _, privilege := range wanted {
+		if !slices.Contains(granted, privilege) {
+			grant = append(grant, privilege)
+		}
+	}
+
+	queries := []string{}
+	if len(revoke) > 0 {
+		queries = append(queries, fmt.Sprintf("REVOKE%s %s ON %s.* FROM %s", ch.onCluster(), strings.Join(revoke, ", "), clickhouseQuoteIdentifier(ch.Database), clickhouseQuoteLiteral(user.Username)))
+	}
+	if len(grant) > 0 {
+		queries = append(queries, fmt.Sprintf("GRANT%s %s ON %s.* TO %s", ch.onCluster(), strings.Join(grant, ", "), clickhouseQuoteIdentifier(ch.Database), clickhouseQuoteLiteral(user.Username)))
+	}
+	// The main user checks the status of the database on all hosts with clusterAllReplicas
+	if user.AccessType == ACCESS_TYPE_MAINUSER && ch.ClusterName != "" {
+		queries = append(queries, fmt.Sprintf("GRANT%s REMOTE ON *.* TO %s", ch.onCluster(), clickhouseQuoteLiteral(user.Username)))
+	}
+	// Extra privileges are treated as roles
+	for _, role := range user.ExtraPrivileges {
+		queries = append(queries, fmt.Sprintf("GRANT%s %s TO %s", ch.onCluster(), clickhouseQuoteIdentifier(role), clickhouseQuoteLiteral(user.Username)))
 	}
 
-	if err := ch.executeExec(ctx, ch.Database, grant, admin); err != nil {
-		log.Error(err, "failed granting privileges to ClickHouse user")
+	for _, query := range queries {
+		if err := ch.executeExec(ctx, "default", query, admin); err != nil {
+			log.Error(err, "failed granting privileges to ClickHouse user", "query", query)
+			return err
+		}
+	}
+
+	if err := ch.syncUserProfile(ctx, admin, user, spec); err != nil {
+		log.Error(err, "failed setting the settings profile of ClickHouse user")
+		return err
+	}
+	if err := ch.syncUserQuota(ctx, admin, user, spec); err != nil {
+		log.Error(err, "failed setting the quota of ClickHouse user")
+		return err
+	}
+	if err := ch.syncRowPolicies(ctx, admin, user, spec.RowPolicies); err != nil {
+		log.Error(err, "failed setting row policies of ClickHouse user")
 		return err
 	}
 
@@ -284,12 +914,28 @@ func (ch ClickHouse) setUserPermission(ctx context.Context, admin *DatabaseUser,
 
 func (ch ClickHouse) deleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	drop := fmt.Sprintf("DROP USER IF EXISTS '%s'", user.Username)
+	drop := fmt.Sprintf("DROP USER IF EXISTS %s", clickhouseQuoteLiteral(user.Username))
 
 	if ch.ClusterName != "" {
-		drop += fmt.Sprintf(" ON CLUSTER '%s'", ch.ClusterName)
+		drop += fmt.Sprintf(" ON CLUSTER %s", clickhouseQuoteLiteral(ch.ClusterName))
+	}
+
+	// Limits of the user are removed with an empty spec
+	if err := ch.syncRowPolicies(ctx, admin, user, nil); err != nil {
+		log.Error(err, "failed dropping row policies of ClickHouse user")
+		return err
+	}
+	for _, query := range []string{
+		fmt.Sprintf("DROP QUOTA IF EXISTS %s%s", clickhouseQuoteLiteral(clickhouseUserQuota(user.Username)), ch.onCluster()),
+		fmt.Sprintf("DROP SETTINGS PROFILE IF EXISTS %s%s", clickhouseQuoteLiteral(clickhouseUserProfile(user.Username)), ch.onCluster()),
+	} {
+		if err := ch.executeExec(ctx, "default", query, admin); err != nil {
+			log.Error(err, "failed dropping limits of ClickHouse user")
+			return err
+		}
 	}
 
+	Connections.invalidateUser(poolInstance(ch.Instance, ch.Host, ch.Port), user.Username)
 	if err := ch.executeExec(ctx, "default", drop, admin); err != nil {
 		log.Error(err, "failed deleting ClickHouse user")
 		return err
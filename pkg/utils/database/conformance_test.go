@@ -0,0 +1,220 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/db-operator/db-operator/pkg/consts"
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// conformanceTarget is an engine that is tested by the conformance suite
+type conformanceTarget struct {
+	engine string
+	// config is used to create the engine, the database
+	// and the main user are set by the suite
+	config EngineConfig
+	admin  *DatabaseUser
+	// table returns the name of the table that is used in queries of users,
+	// it must be qualified if the engine doesn't connect users to the database
+	table func(database string) string
+	// results is set when queries return results of a server,
+	// the recorder engine only returns queries back
+	results bool
+}
+
+const conformanceDatabase = "conformance"
+
+// testEngineConformance checks that an engine behaves like all the other engines, it should be run
+// against a local stand-in of the server, so it doesn't require anything to be running.
+// Users are created and removed in the same order as they are by controllers
+func testEngineConformance(t *testing.T, target conformanceTarget) {
+	ctx := context.TODO()
+	admin := target.admin
+	mainUser := &DatabaseUser{Username: "conformance_main", Password: "mainpwd", AccessType: ACCESS_TYPE_MAINUSER}
+	readonly := &DatabaseUser{Username: "conformance_ro", Password: "ropwd", AccessType: ACCESS_TYPE_READONLY}
+	readwrite := &DatabaseUser{Username: "conformance_rw", Password: "rwpwd", AccessType: ACCESS_TYPE_READWRITE}
+	users := []*DatabaseUser{mainUser, readonly, readwrite}
+
+	engine, err := GetEngine(target.engine)
+	require.NoError(t, err)
+	cfg := target.config
+	cfg.Database = conformanceDatabase
+	cfg.MainUser = mainUser
+	db, err := engine.New(ctx, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		Connections.Invalidate(poolInstance(cfg.Instance, cfg.Host, cfg.Port))
+	})
+
+	table := "conformance_table"
+	if target.table != nil {
+		table = target.table(conformanceDatabase)
+	}
+
+	t.Run("IdempotentCreate", func(t *testing.T) {
+		assert.Error(t, db.CheckStatus(ctx, mainUser), "the database must not exist yet")
+		// Every call is repeated, because reconciliations can be
+		// retried after any of them, or the user can already exist
+		for i := 0; i < 2; i++ {
+			require.NoError(t, CreateDatabase(ctx, db, admin))
+			require.NoError(t, CreateOrUpdateUser(ctx, db, mainUser, admin))
+			require.NoError(t, CreateUser(ctx, db, readonly, admin))
+			require.NoError(t, CreateUser(ctx, db, readwrite, admin))
+		}
+		for _, user := range users {
+			assert.NoError(t, db.CheckStatus(ctx, user), user.Username)
+		}
+	})
+
+	t.Run("PasswordUpdate", func(t *testing.T) {
+		old := *readwrite
+		readwrite.Password = "rwpwd-new"
+		require.NoError(t, UpdateUser(ctx, db, readwrite, admin))
+		assert.Error(t, db.CheckStatus(ctx, &old), "the old password must not work anymore")
+		assert.NoError(t, db.CheckStatus(ctx, readwrite))
+	})
+
+	t.Run("AccessTypes", func(t *testing.T) {
+		create := "CREATE TABLE " + table + " (id int)"
+		insert := "INSERT INTO " + table + " VALUES (1)"
+		count := "SELECT count(*) FROM " + table
+
+		assert.Error(t, db.execAsUser(ctx, create, readonly), "only the main user can create tables")
+		assert.Error(t, db.execAsUser(ctx, create, readwrite), "only the main user can create tables")
+		require.NoError(t, db.execAsUser(ctx, create, mainUser))
+
+		require.NoError(t, db.execAsUser(ctx, insert, mainUser))
+		require.NoError(t, db.execAsUser(ctx, insert, readwrite))
+		_, err := db.QueryAsUser(ctx, insert, readonly)
+		assert.Error(t, err, "the readonly user must not write")
+
+		for _, user := range users {
+			result, err := db.QueryAsUser(ctx, count, user)
+			assert.NoError(t, err, user.Username)
+			if target.results {
+				assert.Equal(t, "2", result, user.Username)
+			}
+		}
+	})
+
+	t.Run("DeleteWithDependentObjects", func(t *testing.T) {
+		// Users are granted access to the table, and the database isn't empty
+		for i := 0; i < 2; i++ {
+			require.NoError(t, DeleteUser(ctx, db, readonly, admin))
+			require.NoError(t, DeleteUser(ctx, db, readwrite, admin))
+		}
+		assert.NoError(t, db.CheckStatus(ctx, mainUser), "removed users must not affect the main user")
+		for i := 0; i < 2; i++ {
+			require.NoError(t, DeleteDatabase(ctx, db, admin))
+			require.NoError(t, DeleteUser(ctx, db, mainUser, admin))
+		}
+	})
+
+	t.Run("StatusAfterDeletion", func(t *testing.T) {
+		for _, user := range users {
+			assert.Error(t, db.CheckStatus(ctx, user), user.Username)
+		}
+
+		// A database that is created with the same name again doesn't have old users
+		require.NoError(t, CreateDatabase(ctx, db, admin))
+		assert.Error(t, db.CheckStatus(ctx, readonly))
+		require.NoError(t, DeleteDatabase(ctx, db, admin))
+	})
+}
+
+func TestRecorderConformance(t *testing.T) {
+	engines.AllowHidden(true)
+	defer engines.AllowHidden(false)
+	recorder := New(consts.ENGINE_RECORDER).(Recorder)
+	recorder.Reset()
+	defer recorder.Reset()
+
+	testEngineConformance(t, conformanceTarget{
+		engine: consts.ENGINE_RECORDER,
+		admin:  &DatabaseUser{Username: "admin", Password: "adminpwd"},
+	})
+}
+
+func TestPluginEngineConformance(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	recorder := NewRecorder("")
+	srv := grpc.NewServer()
+	NewPluginServer(srv, func(ctx context.Context, cfg EngineConfig) (Database, error) {
+		return recorder.ForDatabase(cfg.Database), nil
+	})
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	defer srv.Stop()
+
+	testEngineConformance(t, conformanceTarget{
+		engine: consts.ENGINE_PLUGIN,
+		config: EngineConfig{PluginEndpoint: lis.Addr().String(), PluginTLS: &PluginTLS{Insecure: true}},
+		admin:  &DatabaseUser{Username: "admin", Password: "adminpwd"},
+	})
+}
+
+func TestMysqlConformance(t *testing.T) {
+	admin := &DatabaseUser{Username: "root", Password: "rootpwd"}
+	server := newFakeSQLServer(fakeMysqlDialect, admin)
+	useFakeSQLServer(t, server)
+
+	testEngineConformance(t, conformanceTarget{
+		engine:  consts.ENGINE_MYSQL,
+		config:  EngineConfig{Instance: "conformance-mysql", Host: "mysql", Port: 3306},
+		admin:   admin,
+		results: true,
+		// Users are not connected to the database
+		table: func(database string) string {
+			return database + ".conformance_table"
+		},
+	})
+}
+
+func TestPostgresConformance(t *testing.T) {
+	admin := &DatabaseUser{Username: "postgres", Password: "postgrespwd"}
+	server := newFakeSQLServer(fakePostgresDialect, admin)
+	useFakeSQLServer(t, server)
+
+	testEngineConformance(t, conformanceTarget{
+		engine:  consts.ENGINE_POSTGRES,
+		config:  EngineConfig{Instance: "conformance-postgres", Host: "postgres", Port: 5432},
+		admin:   admin,
+		results: true,
+	})
+}
+
+func TestClickhouseConformance(t *testing.T) {
+	admin := &DatabaseUser{Username: "default", Password: "defaultpwd"}
+	server := newFakeSQLServer(fakeClickhouseDialect, admin)
+	useFakeSQLServer(t, server)
+
+	testEngineConformance(t, conformanceTarget{
+		engine:  consts.ENGINE_CLICKHOUSE,
+		config:  EngineConfig{Instance: "conformance-clickhouse", Host: "clickhouse", Port: 9000},
+		admin:   admin,
+		results: true,
+	})
+}
@@ -16,7 +16,11 @@
 
 package database
 
-import "context"
+import (
+	"context"
+
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+)
 
 const (
 	DB_DUMMY_HOSTNAME     = "hostname"
@@ -33,11 +37,21 @@ func NewDummyUser(access string) *DatabaseUser {
 	}
 }
 
-// Dummy is a database interface implementation for unit testing
+// Dummy is a database interface implementation for unit testing,
+// it doesn't do anything, but every call fails if Error is set
 type Dummy struct {
 	Error error
 }
 
+func init() {
+	Register(Engine{
+		Engine: engines.Dummy,
+		New: func(ctx context.Context, cfg EngineConfig) (Database, error) {
+			return Dummy{}, nil
+		},
+	})
+}
+
 // QueryAsUser implements Database.
 func (d Dummy) QueryAsUser(ctx context.Context, query string, user *DatabaseUser) (string, error) {
 	if d.Error != nil {
@@ -47,13 +61,16 @@ func (d Dummy) QueryAsUser(ctx context.Context, query string, user *DatabaseUser
 }
 
 // CheckStatus implements Database.
-func (Dummy) CheckStatus(ctx context.Context, user *DatabaseUser) error {
-	panic("unimplemented")
+func (d Dummy) CheckStatus(ctx context.Context, user *DatabaseUser) error {
+	return d.Error
 }
 
 // GetCredentials implements Database.
 func (Dummy) GetCredentials(ctx context.Context, user *DatabaseUser) Credentials {
-	panic("unimplemented")
+	return Credentials{
+		Username: user.Username,
+		Password: user.Password,
+	}
 }
 
 // GetDatabaseAddress implements Database.
@@ -65,46 +82,52 @@ func (Dummy) GetDatabaseAddress(ctx context.Context) DatabaseAddress {
 }
 
 // ParseAdminCredentials implements Database.
-func (Dummy) ParseAdminCredentials(ctx context.Context, data map[string][]byte) (*DatabaseUser, error) {
-	panic("unimplemented")
+func (d Dummy) ParseAdminCredentials(ctx context.Context, data map[string][]byte) (*DatabaseUser, error) {
+	if d.Error != nil {
+		return nil, d.Error
+	}
+	return &DatabaseUser{
+		Username: string(data["user"]),
+		Password: string(data["password"]),
+	}, nil
 }
 
 // createDatabase implements Database.
-func (Dummy) createDatabase(ctx context.Context, admin *DatabaseUser) error {
-	panic("unimplemented")
+func (d Dummy) createDatabase(ctx context.Context, admin *DatabaseUser) error {
+	return d.Error
 }
 
 // createOrUpdateUser implements Database.
-func (Dummy) createOrUpdateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
-	panic("unimplemented")
+func (d Dummy) createOrUpdateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return d.Error
 }
 
 // createUser implements Database.
-func (Dummy) createUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
-	panic("unimplemented")
+func (d Dummy) createUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return d.Error
 }
 
 // deleteDatabase implements Database.
-func (Dummy) deleteDatabase(ctx context.Context, admin *DatabaseUser) error {
-	panic("unimplemented")
+func (d Dummy) deleteDatabase(ctx context.Context, admin *DatabaseUser) error {
+	return d.Error
 }
 
 // deleteUser implements Database.
-func (Dummy) deleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
-	panic("unimplemented")
+func (d Dummy) deleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return d.Error
 }
 
 // execAsUser implements Database.
-func (Dummy) execAsUser(ctx context.Context, query string, user *DatabaseUser) error {
-	panic("unimplemented")
+func (d Dummy) execAsUser(ctx context.Context, query string, user *DatabaseUser) error {
+	return d.Error
 }
 
 // setUserPermission implements Database.
-func (Dummy) setUserPermission(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
-	panic("unimplemented")
+func (d Dummy) setUserPermission(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return d.Error
 }
 
 // updateUser implements Database.
-func (Dummy) updateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
-	panic("unimplemented")
+func (d Dummy) updateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return d.Error
 }
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import "context"
+
+// ExternalDatabase has the same methods as Database, but all of them are exported,
+// so it can be implemented by engines that are maintained outside of this module
+type ExternalDatabase interface {
+	CheckStatus(ctx context.Context, user *DatabaseUser) error
+	GetCredentials(ctx context.Context, user *DatabaseUser) Credentials
+	ParseAdminCredentials(ctx context.Context, data map[string][]byte) (*DatabaseUser, error)
+	GetDatabaseAddress(ctx context.Context) DatabaseAddress
+	QueryAsUser(ctx context.Context, query string, user *DatabaseUser) (string, error)
+	CreateDatabase(ctx context.Context, admin *DatabaseUser) error
+	DeleteDatabase(ctx context.Context, admin *DatabaseUser) error
+	CreateOrUpdateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error
+	CreateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error
+	UpdateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error
+	DeleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error
+	SetUserPermission(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error
+	ExecAsUser(ctx context.Context, query string, user *DatabaseUser) error
+}
+
+// FromExternal wraps an ExternalDatabase, so it can be returned by an engine constructor
+func FromExternal(db ExternalDatabase) Database {
+	return external{db}
+}
+
+type external struct {
+	ExternalDatabase
+}
+
+func (e external) createDatabase(ctx context.Context, admin *DatabaseUser) error {
+	return e.CreateDatabase(ctx, admin)
+}
+
+func (e external) deleteDatabase(ctx context.Context, admin *DatabaseUser) error {
+	return e.DeleteDatabase(ctx, admin)
+}
+
+func (e external) createOrUpdateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return e.CreateOrUpdateUser(ctx, admin, user)
+}
+
+func (e external) createUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return e.CreateUser(ctx, admin, user)
+}
+
+func (e external) updateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return e.UpdateUser(ctx, admin, user)
+}
+
+func (e external) deleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return e.DeleteUser(ctx, admin, user)
+}
+
+func (e external) setUserPermission(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return e.SetUserPermission(ctx, admin, user)
+}
+
+func (e external) execAsUser(ctx context.Context, query string, user *DatabaseUser) error {
+	return e.ExecAsUser(ctx, query, user)
+}
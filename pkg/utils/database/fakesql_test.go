@@ -0,0 +1,2006 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// fakeSQLServer is an embedded stand-in for sql database servers, it only understands
+// statements that are sent by engines, and keeps databases, users and grants in memory.
+// Every statement that is not known by the dialect fails, so new statements of engines
+// must be added here as well. Tests check the state of the server, and not the statements,
+// so the fake must apply every statement like the server does
+type fakeSQLServer struct {
+	mu      sync.Mutex
+	dialect fakeSQLDialect
+	// Row counts of tables per database
+	databases map[string]map[string]int
+	// Parameters that are set per database
+	settings map[string]map[string]string
+	// Options of postgres databases by their keywords, e.g. ENCODING or OWNER
+	options map[string]map[string]string
+	// Installed extensions per database
+	extensions map[string]map[string]*fakeSQLExtension
+	// Comments of schemas per database
+	schemas map[string]map[string]string
+	users   map[string]*fakeSQLUser
+	// Open sessions, they're removed when they're closed or terminated
+	sessions []*fakeSQLSession
+	// ClickHouse settings profiles, quotas with limits per interval in seconds,
+	// and row policies with conditions per qualified table
+	profiles    map[string]string
+	quotas      map[string]map[string]string
+	rowPolicies map[string]map[string]string
+	// Databases per host of the ClickHouse cluster, hosts are set by tests
+	clusterHosts map[string][]string
+	// Clusters of ClickHouse entities per kind and name, e.g. "USER name",
+	// entities that are not created ON CLUSTER are on the cluster ""
+	clusters map[string]string
+	// Schemas that are locked by transactions of tests, as database.schema,
+	// and mysql databases by their names
+	locks map[string]bool
+	// Returned by SELECT VERSION()
+	version string
+}
+
+type fakeSQLUser struct {
+	password string
+	admin    bool
+	// Access types per database
+	grants map[string]string
+	// Databases with tables that are owned by the user
+	owns map[string]bool
+	// Parameters that are set for the role
+	settings map[string]string
+	// Attributes of the postgres role, they're empty until they're changed
+	connectionLimit string
+	validUntil      string
+	// Options of mysql accounts per host
+	accounts map[string]string
+	// Roles of mysql accounts per host, MySQL roles are kept as name@host
+	roles map[string][]string
+	// Privileges of clickhouse users per database, they're kept like in system.grants,
+	// and privileges of mysql accounts per database@host in the order they're granted
+	privileges map[string][]string
+	// Privileges of postgres roles per object, including default privileges
+	objects map[fakeSQLObject][]string
+	// Roles that the postgres role is a member of, and its parameters per database
+	memberOf         []string
+	databaseSettings map[string]map[string]string
+	// Every GRANT and REVOKE of a clickhouse user rewrites it in the access storage,
+	// even if its privileges are not changed by the statement
+	privilegeUpdates int
+}
+
+// fakeSQLObject is a postgres object that privileges are granted on, kinds are DATABASE,
+// SCHEMA, or TABLES, SEQUENCES and FUNCTIONS, that are all objects of the kind in the schema.
+// The owner is only set for default privileges on objects that are created by the owner
+type fakeSQLObject struct {
+	database string
+	owner    string
+	kind     string
+	schema   string
+}
+
+type fakeSQLExtension struct {
+	version string
+	schema  string
+}
+
+// fakeSQLDialect describes how an engine talks to the server
+type fakeSQLDialect struct {
+	// parseDSN returns the user, the password and the database of a connection string
+	parseDSN func(dsn string) (user, password, database string, err error)
+	// Databases that always exist, they're used by admins to connect
+	systemDatabases []string
+	statements      []fakeSQLStatement
+}
+
+type fakeSQLStatement struct {
+	re *regexp.Regexp
+	// Statements that can be executed by any user, and not only by admins
+	public bool
+	// run executes the statement with submatches of the regexp and returns
+	// values of the first column, the server is locked while it's running
+	run func(s *fakeSQLSession, args []string) ([]string, error)
+}
+
+type fakeSQLSession struct {
+	server   *fakeSQLServer
+	user     string
+	database string
+	// The statement that is running
+	query string
+	// A transaction is open, settings of it are reset by the end of it
+	inTx        bool
+	lockTimeout string
+	// The session is terminated by another one, the connection is broken
+	terminated bool
+}
+
+func newFakeSQLServer(dialect fakeSQLDialect, admin *DatabaseUser) *fakeSQLServer {
+	return &fakeSQLServer{
+		dialect:    dialect,
+		databases:  map[string]map[string]int{},
+		settings:   map[string]map[string]string{},
+		options:    map[string]map[string]string{},
+		extensions: map[string]map[string]*fakeSQLExtension{},
+		schemas:    map[string]map[string]string{},
+		locks:      map[string]bool{},
+		users: map[string]*fakeSQLUser{
+			admin.Username: {password: admin.Password, admin: true, grants: map[string]string{}, owns: map[string]bool{}, settings: map[string]string{}},
+		},
+		profiles:    map[string]string{},
+		quotas:      map[string]map[string]string{},
+		rowPolicies: map[string]map[string]string{},
+		clusters:    map[string]string{},
+	}
+}
+
+// useFakeSQLServer makes sql engines connect to the server until the test is finished
+func useFakeSQLServer(t *testing.T, server *fakeSQLServer) {
+	sqlOpen = func(driverName, dsn string) (*sql.DB, error) {
+		return sql.OpenDB(fakeSQLConnector{server: server, dsn: dsn}), nil
+	}
+	t.Cleanup(func() {
+		sqlOpen = sql.Open
+	})
+}
+
+// openSession connects to the fake server like a client of a user would, the session
+// is kept open, until it's closed by the test or terminated by the server
+func (s *fakeSQLServer) openSession(t *testing.T, dsn string) *sql.Conn {
+	t.Helper()
+	db := sql.OpenDB(fakeSQLConnector{server: s, dsn: dsn})
+	t.Cleanup(func() {
+		db.Close()
+	})
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to open a session: %v", err)
+	}
+	return conn
+}
+
+func (s *fakeSQLServer) connect(dsn string) (*fakeSQLSession, error) {
+	user, password, database, err := s.dialect.parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[user]; !ok || u.password != password {
+		return nil, fmt.Errorf("authentication failed for user %s", user)
+	}
+	if database != "" && !slices.Contains(s.dialect.systemDatabases, database) {
+		if _, ok := s.databases[database]; !ok {
+			return nil, fmt.Errorf("database %s does not exist", database)
+		}
+	}
+	session := &fakeSQLSession{server: s, user: user, database: database}
+	s.sessions = append(s.sessions, session)
+	return session, nil
+}
+
+// disconnect removes the session, when it's closed or terminated
+func (s *fakeSQLServer) disconnect(session *fakeSQLSession) {
+	s.sessions = slices.DeleteFunc(s.sessions, func(open *fakeSQLSession) bool { return open == session })
+}
+
+func (s *fakeSQLServer) run(session *fakeSQLSession, query string) ([]string, error) {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session.terminated {
+		return nil, driver.ErrBadConn
+	}
+	user, ok := s.users[session.user]
+	if !ok {
+		return nil, fmt.Errorf("user %s does not exist anymore", session.user)
+	}
+	for _, statement := range append(slices.Clone(s.dialect.statements), fakeSQLUserStatements...) {
+		args := statement.re.FindStringSubmatch(query)
+		if args == nil {
+			continue
+		}
+		if !statement.public && !user.admin {
+			return nil, fmt.Errorf("permission denied for user %s to run: %s", session.user, query)
+		}
+		session.query = query
+		return statement.run(session, args[1:])
+	}
+	return nil, fmt.Errorf("the fake server doesn't support the statement: %s", query)
+}
+
+// Helpers that are used by dialects, the server must be locked by the caller
+
+func (s *fakeSQLServer) createDatabase(name string, ifNotExists bool) error {
+	if _, ok := s.databases[name]; ok {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("database %s already exists", name)
+	}
+	s.databases[name] = map[string]int{}
+	s.schemas[name] = map[string]string{"public": ""}
+	return nil
+}
+
+// dropDatabase removes the database, grants on it are kept, unless revoke is set
+func (s *fakeSQLServer) dropDatabase(name string, ifExists, revoke bool) error {
+	if _, ok := s.databases[name]; !ok {
+		if ifExists {
+			return nil
+		}
+		return fmt.Errorf("database %s does not exist", name)
+	}
+	delete(s.databases, name)
+	delete(s.settings, name)
+	delete(s.options, name)
+	delete(s.extensions, name)
+	delete(s.schemas, name)
+	if revoke {
+		for _, user := range s.users {
+			delete(user.grants, name)
+			delete(user.owns, name)
+			maps.DeleteFunc(user.objects, func(object fakeSQLObject, _ []string) bool {
+				return object.database == name
+			})
+		}
+	}
+	return nil
+}
+
+// setMysqlCharset keeps the character set and the collation of a mysql database in its settings,
+// the other one is derived like the server does, when only one of them is set
+func (s *fakeSQLServer) setMysqlCharset(name, charset, collation string) {
+	if charset == "" && collation == "" {
+		return
+	}
+	if charset == "" {
+		charset, _, _ = strings.Cut(collation, "_")
+	}
+	if collation == "" {
+		collation = charset + "_general_ci"
+	}
+	if _, ok := s.settings[name]; !ok {
+		s.settings[name] = map[string]string{}
+	}
+	s.settings[name]["character_set"] = charset
+	s.settings[name]["collation"] = collation
+}
+
+func (s *fakeSQLServer) userExists(name string) []string {
+	if _, ok := s.users[name]; ok {
+		return []string{name}
+	}
+	return nil
+}
+
+func (s *fakeSQLServer) databaseExists(name string) []string {
+	if _, ok := s.databases[name]; ok {
+		return []string{name}
+	}
+	return nil
+}
+
+func (s *fakeSQLServer) createUser(name, password string, ifNotExists bool) error {
+	if _, ok := s.users[name]; ok {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("user %s already exists", name)
+	}
+	s.users[name] = &fakeSQLUser{password: password, grants: map[string]string{}, owns: map[string]bool{}, settings: map[string]string{}}
+	return nil
+}
+
+func (s *fakeSQLServer) alterUser(name, password string) error {
+	user, ok := s.users[name]
+	if !ok {
+		return fmt.Errorf("user %s does not exist", name)
+	}
+	user.password = password
+	return nil
+}
+
+func (s *fakeSQLServer) dropUser(name string, ifExists bool) error {
+	if _, ok := s.users[name]; !ok {
+		if ifExists {
+			return nil
+		}
+		return fmt.Errorf("user %s does not exist", name)
+	}
+	delete(s.users, name)
+	return nil
+}
+
+// grant gives the user access to the database, grants are only extended, like on real servers
+func (s *fakeSQLServer) grant(name, database, privileges string) error {
+	user, ok := s.users[name]
+	if !ok {
+		return fmt.Errorf("user %s does not exist", name)
+	}
+	accessType := fakeSQLAccessType(privileges)
+	levels := []string{"", ACCESS_TYPE_READONLY, ACCESS_TYPE_READWRITE, ACCESS_TYPE_MAINUSER}
+	if slices.Index(levels, accessType) > slices.Index(levels, user.grants[database]) {
+		user.grants[database] = accessType
+	}
+	return nil
+}
+
+func (s *fakeSQLServer) revoke(name, database string) error {
+	user, ok := s.users[name]
+	if !ok {
+		return fmt.Errorf("user %s does not exist", name)
+	}
+	delete(user.grants, database)
+	return nil
+}
+
+// grantObject extends privileges of the postgres role on the object
+func (s *fakeSQLServer) grantObject(name string, object fakeSQLObject, privileges string) error {
+	user, ok := s.users[name]
+	if !ok {
+		return fmt.Errorf("role %s does not exist", name)
+	}
+	if user.objects == nil {
+		user.objects = map[fakeSQLObject][]string{}
+	}
+	for _, privilege := range strings.Split(privileges, ",") {
+		privilege = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(privilege)), " PRIVILEGES")
+		if !slices.Contains(user.objects[object], privilege) {
+			user.objects[object] = append(user.objects[object], privilege)
+		}
+	}
+	slices.Sort(user.objects[object])
+	return nil
+}
+
+// revokeObject removes privileges of the postgres role on the object, ALL are removed
+// at once, otherwise ALL is replaced by privileges of the kind, like in the acl
+func (s *fakeSQLServer) revokeObject(name string, object fakeSQLObject, privileges string) error {
+	user, ok := s.users[name]
+	if !ok {
+		return fmt.Errorf("role %s does not exist", name)
+	}
+	revoked := []string{}
+	for _, privilege := range strings.Split(privileges, ",") {
+		revoked = append(revoked, strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(privilege)), " PRIVILEGES"))
+	}
+	if slices.Contains(revoked, "ALL") {
+		delete(user.objects, object)
+		return nil
+	}
+	kept := slices.DeleteFunc(user.grantedObject(object), func(privilege string) bool {
+		return slices.Contains(revoked, privilege)
+	})
+	if len(kept) == 0 {
+		delete(user.objects, object)
+		return nil
+	}
+	user.objects[object] = kept
+	return nil
+}
+
+// Privileges of postgres objects per kind, that are granted by ALL
+var fakePostgresPrivileges = map[string][]string{
+	"DATABASE":  {"CONNECT", "CREATE", "TEMPORARY"},
+	"SCHEMA":    {"CREATE", "USAGE"},
+	"TABLES":    {"DELETE", "INSERT", "REFERENCES", "SELECT", "TRIGGER", "TRUNCATE", "UPDATE"},
+	"SEQUENCES": {"SELECT", "UPDATE", "USAGE"},
+	"FUNCTIONS": {"EXECUTE"},
+}
+
+// grantedObject returns privileges of the postgres role on the object like aclexplode does
+func (user *fakeSQLUser) grantedObject(object fakeSQLObject) []string {
+	privileges := []string{}
+	for _, privilege := range user.objects[object] {
+		switch privilege {
+		case "ALL":
+			privileges = append(privileges, fakePostgresPrivileges[object.kind]...)
+		case "TEMP":
+			privileges = append(privileges, "TEMPORARY")
+		default:
+			privileges = append(privileges, privilege)
+		}
+	}
+	slices.Sort(privileges)
+	return slices.Compact(privileges)
+}
+
+// grantedTables returns the access type of the postgres role on tables of the database
+func (user *fakeSQLUser) grantedTables(database string) string {
+	privileges := []string{}
+	for object := range user.objects {
+		if object.database == database && object.owner == "" && object.kind == "TABLES" {
+			privileges = append(privileges, user.grantedObject(object)...)
+		}
+	}
+	if len(privileges) == 0 {
+		return ""
+	}
+	return fakeSQLAccessType(strings.Join(privileges, ", "))
+}
+
+// setPrivileges replaces privileges of the clickhouse user on the database,
+// the access type of the database is derived from them
+func (s *fakeSQLServer) setPrivileges(name, database string, privileges []string) error {
+	user, ok := s.users[name]
+	if !ok {
+		return fmt.Errorf("user %s does not exist", name)
+	}
+	if user.privileges == nil {
+		user.privileges = map[string][]string{}
+	}
+	user.privilegeUpdates++
+	if len(privileges) == 0 {
+		delete(user.privileges, database)
+		delete(user.grants, database)
+		return nil
+	}
+	user.privileges[database] = privileges
+	user.grants[database] = fakeSQLAccessType(strings.Join(privileges, ", "))
+	return nil
+}
+
+// onCluster checks that the clickhouse statement runs on the cluster of the entity, entities
+// that are created ON CLUSTER must be changed and dropped on the whole cluster, otherwise
+// replicas are diverging. The cluster of the entity is set, when it's created by the statement
+func (session *fakeSQLSession) onCluster(entity string, create bool) error {
+	cluster := ""
+	if match := fakeClickhouseClusterRegexp.FindStringSubmatch(session.query); match != nil {
+		cluster = fakeSQLUnquote(match[1])
+	}
+	current, ok := session.server.clusters[entity]
+	if !ok {
+		if create {
+			session.server.clusters[entity] = cluster
+		}
+		return nil
+	}
+	if current != cluster {
+		return fmt.Errorf("%s is on the cluster '%s', but the statement runs on '%s'", entity, current, cluster)
+	}
+	return nil
+}
+
+var (
+	fakePostgresACLDatabaseRegexp = regexp.MustCompile(`^o\.datname = (\S+)$`)
+	fakePostgresACLSchemaRegexp   = regexp.MustCompile(`^n\.nspname = (\S+)$`)
+	fakePostgresACLObjectsRegexp  = regexp.MustCompile(`^n\.nspname = (\S+) AND o\.(relkind = 'S'|relkind IN .+|prokind <> 'p')$`)
+	fakePostgresACLDefaultRegexp  = regexp.MustCompile(`^n\.nspname = (\S+) AND o\.defaclobjtype = '(\w)' AND o\.defaclrole = \(SELECT oid FROM pg_roles WHERE rolname = (\S+)\)$`)
+)
+
+// fakePostgresACLObject returns the object, privileges on which are selected from the catalog by the condition
+func fakePostgresACLObject(database, catalog, condition string) (fakeSQLObject, error) {
+	switch catalog {
+	case "pg_database":
+		if match := fakePostgresACLDatabaseRegexp.FindStringSubmatch(condition); match != nil {
+			return fakeSQLObject{database: fakeSQLUnquote(match[1]), kind: "DATABASE"}, nil
+		}
+	case "pg_namespace":
+		if match := fakePostgresACLSchemaRegexp.FindStringSubmatch(condition); match != nil {
+			return fakeSQLObject{database: database, kind: "SCHEMA", schema: fakeSQLUnquote(match[1])}, nil
+		}
+	case "pg_class", "pg_proc":
+		if match := fakePostgresACLObjectsRegexp.FindStringSubmatch(condition); match != nil {
+			kind := map[string]string{"relkind = 'S'": "SEQUENCES", "prokind <> 'p'": "FUNCTIONS"}[match[2]]
+			if kind == "" {
+				kind = "TABLES"
+			}
+			return fakeSQLObject{database: database, kind: kind, schema: fakeSQLUnquote(match[1])}, nil
+		}
+	case "pg_default_acl":
+		if match := fakePostgresACLDefaultRegexp.FindStringSubmatch(condition); match != nil {
+			kind := map[string]string{"r": "TABLES", "S": "SEQUENCES", "f": "FUNCTIONS"}[match[2]]
+			return fakeSQLObject{database: database, owner: fakeSQLUnquote(match[3]), kind: kind, schema: fakeSQLUnquote(match[1])}, nil
+		}
+	}
+	return fakeSQLObject{}, fmt.Errorf("unsupported condition of %s: %s", catalog, condition)
+}
+
+// fakeSQLAccessType maps granted privileges to an access type
+func fakeSQLAccessType(privileges string) string {
+	privileges = strings.ToUpper(privileges)
+	switch {
+	case strings.HasPrefix(privileges, "ALL"):
+		return ACCESS_TYPE_MAINUSER
+	case strings.Contains(privileges, "INSERT"):
+		return ACCESS_TYPE_READWRITE
+	default:
+		return ACCESS_TYPE_READONLY
+	}
+}
+
+// fakeSQLUnquote removes quotes of identifiers and literals of all dialects
+func fakeSQLUnquote(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	quote := value[0]
+	if !strings.ContainsRune("'\"`", rune(quote)) || value[len(value)-1] != quote {
+		return value
+	}
+	value = value[1 : len(value)-1]
+	value = strings.ReplaceAll(value, string([]byte{quote, quote}), string(quote))
+	value = strings.ReplaceAll(value, `\`+string(quote), string(quote))
+	return strings.ReplaceAll(value, `\\`, `\`)
+}
+
+func fakeSQLRegexp(expr string) *regexp.Regexp {
+	return regexp.MustCompile("(?is)^" + expr + "$")
+}
+
+// lock takes the lock of the schema, that is needed by DDL. The fake can't wait for
+// locks that are held by tests, so if the lock timeout is set, the statement is canceled
+// right away, like it would be after the timeout, otherwise it would wait forever
+func (session *fakeSQLSession) lock(schema string) error {
+	name := session.database + "." + fakeSQLUnquote(schema)
+	if !session.server.locks[name] {
+		return nil
+	}
+	if session.lockTimeout == "" {
+		return fmt.Errorf("the statement waits for the lock of %s forever", name)
+	}
+	return &pq.Error{Code: "55P03", Message: "canceling statement due to lock timeout"}
+}
+
+// fakeSQLTable returns the database and the table of a name that may be qualified
+func (session *fakeSQLSession) table(name string) (string, string) {
+	if database, table, ok := strings.Cut(name, "."); ok {
+		return fakeSQLUnquote(database), fakeSQLUnquote(table)
+	}
+	return session.database, fakeSQLUnquote(name)
+}
+
+// checkQuery returns tables of the database if the user can run the query
+func (session *fakeSQLSession) checkQuery(database, query string) (map[string]int, error) {
+	server := session.server
+	tables, ok := server.databases[database]
+	if !ok {
+		return nil, fmt.Errorf("database %s does not exist", database)
+	}
+	user := server.users[session.user]
+	if !user.admin && !slices.Contains(allowedAccessTypes(query), user.grants[database]) {
+		return nil, fmt.Errorf("permission denied for user %s to run: %s", session.user, query)
+	}
+	return tables, nil
+}
+
+// fakeSQLUserStatements are executed by users in tests, they're the same in all dialects
+var fakeSQLUserStatements = []fakeSQLStatement{
+	{
+		re:     fakeSQLRegexp(`SELECT 1`),
+		public: true,
+		run: func(s *fakeSQLSession, args []string) ([]string, error) {
+			return []string{"1"}, nil
+		},
+	},
+	{
+		re:     fakeSQLRegexp(`CREATE TABLE (\S+) .*`),
+		public: true,
+		run: func(s *fakeSQLSession, args []string) ([]string, error) {
+			database, table := s.table(args[0])
+			tables, err := s.checkQuery(database, "CREATE")
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := tables[table]; ok {
+				return nil, fmt.Errorf("table %s already exists", table)
+			}
+			tables[table] = 0
+			s.server.users[s.user].owns[database] = true
+			return nil, nil
+		},
+	},
+	{
+		re:     fakeSQLRegexp(`INSERT INTO (\S+) .*`),
+		public: true,
+		run: func(s *fakeSQLSession, args []string) ([]string, error) {
+			database, table := s.table(args[0])
+			tables, err := s.checkQuery(database, "INSERT")
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := tables[table]; !ok {
+				return nil, fmt.Errorf("table %s does not exist", table)
+			}
+			tables[table]++
+			return nil, nil
+		},
+	},
+	{
+		re:     fakeSQLRegexp(`SELECT count\(\*\) FROM (\S+)`),
+		public: true,
+		run: func(s *fakeSQLSession, args []string) ([]string, error) {
+			database, table := s.table(args[0])
+			tables, err := s.checkQuery(database, "SELECT")
+			if err != nil {
+				return nil, err
+			}
+			count, ok := tables[table]
+			if !ok {
+				return nil, fmt.Errorf("table %s does not exist", table)
+			}
+			return []string{strconv.Itoa(count)}, nil
+		},
+	},
+}
+
+// noop is used for statements that don't change the in-memory model
+func noop(s *fakeSQLSession, args []string) ([]string, error) {
+	return nil, nil
+}
+
+var fakeMysqlDialect = fakeSQLDialect{
+	parseDSN: func(dsn string) (string, string, string, error) {
+		cfg, err := mysqldriver.ParseDSN(dsn)
+		if err != nil {
+			return "", "", "", err
+		}
+		return cfg.User, cfg.Passwd, cfg.DBName, nil
+	},
+	statements: []fakeSQLStatement{
+		{
+			re: fakeSQLRegexp(`SELECT User FROM mysql\.user WHERE user=(\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				return s.server.userExists(fakeSQLUnquote(args[0])), nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`CREATE DATABASE IF NOT EXISTS (\S+)(?: CHARACTER SET (\S+))?(?: COLLATE (\S+))?`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if _, ok := s.server.databases[name]; ok {
+					return nil, nil
+				}
+				if err := s.server.createDatabase(name, false); err != nil {
+					return nil, err
+				}
+				s.server.setMysqlCharset(name, "utf8mb4", "utf8mb4_0900_ai_ci")
+				s.server.setMysqlCharset(name, args[1], args[2])
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`ALTER DATABASE (\S+)(?: CHARACTER SET (\S+))?(?: COLLATE (\S+))?`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if _, ok := s.server.databases[name]; !ok {
+					return nil, fmt.Errorf("database %s does not exist", name)
+				}
+				if s.server.locks[name] {
+					return nil, fmt.Errorf("the statement waits for the metadata lock of %s forever", name)
+				}
+				s.server.setMysqlCharset(name, args[1], args[2])
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT CONCAT\(DEFAULT_CHARACTER_SET_NAME, ' ', DEFAULT_COLLATION_NAME\) FROM information_schema\.SCHEMATA WHERE SCHEMA_NAME = (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if _, ok := s.server.databases[name]; !ok {
+					return nil, nil
+				}
+				settings := s.server.settings[name]
+				return []string{settings["character_set"] + " " + settings["collation"]}, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`DROP DATABASE IF EXISTS (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				return nil, s.server.dropDatabase(fakeSQLUnquote(args[0]), true, false)
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT Host FROM mysql\.user WHERE User = (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if user, ok := s.server.users[fakeSQLUnquote(args[0])]; ok {
+					return slices.Sorted(maps.Keys(user.accounts)), nil
+				}
+				return nil, nil
+			},
+		},
+		{
+			// Accounts of a user share the password, options are kept per host
+			re: fakeSQLRegexp(`CREATE USER ('[^']+')@('[^']+') IDENTIFIED (WITH \S+ )?BY ('(?:[^'\\]|''|\\.)*') (REQUIRE .+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name, host := fakeSQLUnquote(args[0]), fakeSQLUnquote(args[1])
+				if _, ok := s.server.users[name]; !ok {
+					if err := s.server.createUser(name, fakeSQLUnquote(args[3]), false); err != nil {
+						return nil, err
+					}
+					s.server.users[name].accounts = map[string]string{}
+				}
+				user := s.server.users[name]
+				if _, ok := user.accounts[host]; ok {
+					return nil, fmt.Errorf("account %s@%s already exists", name, host)
+				}
+				user.accounts[host] = args[2] + args[4]
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`ALTER USER ('[^']+')@('[^']+') (?:IDENTIFIED (WITH \S+ )?BY ('(?:[^'\\]|''|\\.)*') )?(REQUIRE .+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name, host := fakeSQLUnquote(args[0]), fakeSQLUnquote(args[1])
+				user, ok := s.server.users[name]
+				if _, exists := user.accounts[host]; !ok || !exists {
+					return nil, fmt.Errorf("account %s@%s does not exist", name, host)
+				}
+				if args[3] != "" {
+					if err := s.server.alterUser(name, fakeSQLUnquote(args[3])); err != nil {
+						return nil, err
+					}
+				}
+				user.accounts[host] = args[2] + args[4]
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT VERSION\(\)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if s.server.version == "" {
+					return []string{"8.0.36"}, nil
+				}
+				return []string{s.server.version}, nil
+			},
+		},
+		{
+			// MySQL roles are accounts, they're kept as name@host, and MariaDB roles are names
+			re: fakeSQLRegexp("(GRANT|REVOKE) ('[^']+'@'[^']+'|`[^`]+`) (?:TO|FROM) ('[^']+')@('[^']+')"),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				role := fakeSQLUnquote(args[1])
+				if roleName, roleHost, ok := strings.Cut(args[1], "@"); ok {
+					role = fakeSQLUnquote(roleName) + "@" + fakeSQLUnquote(roleHost)
+				}
+				name, host := fakeSQLUnquote(args[2]), fakeSQLUnquote(args[3])
+				user, ok := s.server.users[name]
+				if _, exists := user.accounts[host]; !ok || !exists {
+					return nil, fmt.Errorf("account %s@%s does not exist", name, host)
+				}
+				if user.roles == nil {
+					user.roles = map[string][]string{}
+				}
+				if args[0] == "REVOKE" {
+					user.roles[host] = slices.DeleteFunc(user.roles[host], func(r string) bool { return r == role })
+				} else if !slices.Contains(user.roles[host], role) {
+					user.roles[host] = append(user.roles[host], role)
+				}
+				return nil, nil
+			},
+		},
+		{
+			// Default roles aren't applied by the fake
+			re:  fakeSQLRegexp(`SET DEFAULT ROLE .+ (TO|FOR) '[^']+'@'[^']+'`),
+			run: noop,
+		},
+		{
+			re: fakeSQLRegexp(`SELECT Role FROM mysql\.roles_mapping WHERE User = (\S+) AND Host = ('[^']+')`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if user, ok := s.server.users[fakeSQLUnquote(args[0])]; ok {
+					return slices.Clone(user.roles[fakeSQLUnquote(args[1])]), nil
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT JSON_ARRAY\(FROM_USER, FROM_HOST\) FROM mysql\.role_edges WHERE TO_USER = (\S+) AND TO_HOST = ('[^']+')`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				user, ok := s.server.users[fakeSQLUnquote(args[0])]
+				if !ok {
+					return nil, nil
+				}
+				edges := []string{}
+				for _, role := range user.roles[fakeSQLUnquote(args[1])] {
+					name, host, _ := strings.Cut(role, "@")
+					edge, err := json.Marshal([]string{name, host})
+					if err != nil {
+						return nil, err
+					}
+					edges = append(edges, string(edge))
+				}
+				return edges, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`GRANT (.+) ON (\S+)\.\* TO ('[^']+')@('[^']+')`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name, database := fakeSQLUnquote(args[2]), fakeSQLUnquote(args[1])
+				if err := s.server.grant(name, database, args[0]); err != nil {
+					return nil, err
+				}
+				user := s.server.users[name]
+				if user.privileges == nil {
+					user.privileges = map[string][]string{}
+				}
+				account := database + "@" + fakeSQLUnquote(args[3])
+				for _, privilege := range strings.Split(args[0], ", ") {
+					if !slices.Contains(user.privileges[account], privilege) {
+						user.privileges[account] = append(user.privileges[account], privilege)
+					}
+				}
+				return nil, nil
+			},
+		},
+		{
+			// The access type of the user is derived from privileges that are left on the database
+			re: fakeSQLRegexp(`REVOKE (.+) ON (\S+)\.\* FROM ('[^']+')@('[^']+')`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name, database := fakeSQLUnquote(args[2]), fakeSQLUnquote(args[1])
+				user, ok := s.server.users[name]
+				if !ok {
+					return nil, fmt.Errorf("user %s does not exist", name)
+				}
+				revoked := strings.Split(args[0], ", ")
+				account := database + "@" + fakeSQLUnquote(args[3])
+				user.privileges[account] = slices.DeleteFunc(user.privileges[account], func(privilege string) bool {
+					return slices.Contains(revoked, privilege) || slices.Contains(revoked, "ALL PRIVILEGES")
+				})
+				if len(user.privileges[account]) == 0 {
+					delete(user.privileges, account)
+				}
+				left := []string{}
+				for account, privileges := range user.privileges {
+					if strings.HasPrefix(account, database+"@") {
+						left = append(left, privileges...)
+					}
+				}
+				delete(user.grants, database)
+				if len(left) > 0 {
+					user.grants[database] = fakeSQLAccessType(strings.Join(left, ", "))
+				}
+				return nil, nil
+			},
+		},
+		{
+			// Privileges of accounts on databases are listed after the global USAGE
+			re: fakeSQLRegexp(`SHOW GRANTS FOR ('[^']+')@('[^']+')`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name, host := fakeSQLUnquote(args[0]), fakeSQLUnquote(args[1])
+				user, ok := s.server.users[name]
+				if _, exists := user.accounts[host]; !ok || !exists {
+					return nil, fmt.Errorf("there is no such grant defined for user '%s' on host '%s'", name, host)
+				}
+				account := mysqlQuoteIdentifier(name) + "@" + mysqlQuoteIdentifier(host)
+				grants := []string{"GRANT USAGE ON *.* TO " + account}
+				for _, database := range slices.Sorted(maps.Keys(user.privileges)) {
+					if database, ok := strings.CutSuffix(database, "@"+host); ok {
+						grants = append(grants, fmt.Sprintf("GRANT %s ON %s.* TO %s", strings.Join(user.privileges[database+"@"+host], ", "), mysqlQuoteIdentifier(database), account))
+					}
+				}
+				return grants, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`DROP USER ('[^']+')@('[^']+')`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name, host := fakeSQLUnquote(args[0]), fakeSQLUnquote(args[1])
+				user, ok := s.server.users[name]
+				if _, exists := user.accounts[host]; !ok || !exists {
+					return nil, fmt.Errorf("account %s@%s does not exist", name, host)
+				}
+				delete(user.accounts, host)
+				delete(user.roles, host)
+				maps.DeleteFunc(user.privileges, func(account string, _ []string) bool {
+					return strings.HasSuffix(account, "@"+host)
+				})
+				// The user is gone with its last account
+				if len(user.accounts) > 0 {
+					return nil, nil
+				}
+				return nil, s.server.dropUser(name, false)
+			},
+		},
+		{
+			re:     fakeSQLRegexp(`USE (\S+)`),
+			public: true,
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				database := fakeSQLUnquote(args[0])
+				if _, err := s.checkQuery(database, "SELECT"); err != nil {
+					return nil, err
+				}
+				s.database = database
+				return nil, nil
+			},
+		},
+	},
+}
+
+// fakeClickhouseOnCluster matches the optional ON CLUSTER clause, it's checked by onCluster
+const fakeClickhouseOnCluster = `(?: ON CLUSTER '[^']+')?`
+
+var fakeClickhouseClusterRegexp = regexp.MustCompile(` ON CLUSTER ('[^']+')`)
+
+// fakeClickhouseTable returns the unquoted database and table of a row policy
+func fakeClickhouseTable(name string) string {
+	database, table, _ := strings.Cut(name, ".")
+	return fakeSQLUnquote(database) + "." + fakeSQLUnquote(table)
+}
+
+var fakeClickhouseDialect = fakeSQLDialect{
+	parseDSN: func(dsn string) (string, string, string, error) {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", "", "", err
+		}
+		password, _ := u.User.Password()
+		return u.User.Username(), password, strings.TrimPrefix(u.Path, "/"), nil
+	},
+	systemDatabases: []string{"default"},
+	statements: []fakeSQLStatement{
+		{
+			re: fakeSQLRegexp(`SELECT name FROM system\.databases WHERE name = (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				return s.server.databaseExists(fakeSQLUnquote(args[0])), nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT name FROM system\.users WHERE name = (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				return s.server.userExists(fakeSQLUnquote(args[0])), nil
+			},
+		},
+		{
+			// The engine of the database is kept in its settings
+			re: fakeSQLRegexp(`CREATE DATABASE IF NOT EXISTS (\S+)` + fakeClickhouseOnCluster + `(?: ENGINE = (.+))?`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if err := s.onCluster("DATABASE "+name, true); err != nil {
+					return nil, err
+				}
+				if _, ok := s.server.databases[name]; ok {
+					return nil, nil
+				}
+				if err := s.server.createDatabase(name, false); err != nil {
+					return nil, err
+				}
+				s.server.settings[name] = map[string]string{"engine": "Atomic"}
+				if args[1] != "" {
+					s.server.settings[name]["engine"] = args[1]
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`DROP DATABASE IF EXISTS (\S+)` + fakeClickhouseOnCluster),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if err := s.onCluster("DATABASE "+name, false); err != nil {
+					return nil, err
+				}
+				delete(s.server.clusters, "DATABASE "+name)
+				return nil, s.server.dropDatabase(name, true, false)
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT arrayStringConcat\(groupArray\(host\), ', '\) FROM \(SELECT hostName\(\) AS host FROM clusterAllReplicas\('[^']+', system\.one\)\) ` +
+				`WHERE host NOT IN \(SELECT hostName\(\) FROM clusterAllReplicas\('[^']+', system\.databases\) WHERE name = ('[^']+')\)`),
+			public: true,
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				missing := []string{}
+				for _, host := range slices.Sorted(maps.Keys(s.server.clusterHosts)) {
+					if !slices.Contains(s.server.clusterHosts[host], fakeSQLUnquote(args[0])) {
+						missing = append(missing, host)
+					}
+				}
+				return []string{strings.Join(missing, ", ")}, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`CREATE SETTINGS PROFILE IF NOT EXISTS ('[^']+')` + fakeClickhouseOnCluster),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if err := s.onCluster("SETTINGS PROFILE "+fakeSQLUnquote(args[0]), true); err != nil {
+					return nil, err
+				}
+				if _, ok := s.server.profiles[fakeSQLUnquote(args[0])]; !ok {
+					s.server.profiles[fakeSQLUnquote(args[0])] = ""
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`ALTER SETTINGS PROFILE ('[^']+')` + fakeClickhouseOnCluster + ` SETTINGS (.+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if _, ok := s.server.profiles[fakeSQLUnquote(args[0])]; !ok {
+					return nil, fmt.Errorf("settings profile %s does not exist", args[0])
+				}
+				if err := s.onCluster("SETTINGS PROFILE "+fakeSQLUnquote(args[0]), false); err != nil {
+					return nil, err
+				}
+				s.server.profiles[fakeSQLUnquote(args[0])] = args[1]
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`DROP SETTINGS PROFILE IF EXISTS ('[^']+')` + fakeClickhouseOnCluster),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				entity := "SETTINGS PROFILE " + fakeSQLUnquote(args[0])
+				if err := s.onCluster(entity, false); err != nil {
+					return nil, err
+				}
+				delete(s.server.profiles, fakeSQLUnquote(args[0]))
+				delete(s.server.clusters, entity)
+				// Dropped profiles are removed from users that they're assigned to
+				for _, user := range s.server.users {
+					profiles := slices.DeleteFunc(strings.Split(user.settings["profiles"], ", "), func(profile string) bool {
+						return profile == "" || profile == "PROFILE "+args[0]
+					})
+					if len(profiles) == 0 {
+						delete(user.settings, "profiles")
+					} else {
+						user.settings["profiles"] = strings.Join(profiles, ", ")
+					}
+				}
+				return nil, nil
+			},
+		},
+		{
+			// Profiles of users are kept in their settings
+			re: fakeSQLRegexp(`ALTER USER ('[^']+')` + fakeClickhouseOnCluster + ` SETTINGS (.+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				user, ok := s.server.users[fakeSQLUnquote(args[0])]
+				if !ok {
+					return nil, fmt.Errorf("user %s does not exist", args[0])
+				}
+				if err := s.onCluster("USER "+fakeSQLUnquote(args[0]), false); err != nil {
+					return nil, err
+				}
+				user.settings["profiles"] = args[1]
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`CREATE QUOTA IF NOT EXISTS ('[^']+')` + fakeClickhouseOnCluster),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if err := s.onCluster("QUOTA "+fakeSQLUnquote(args[0]), true); err != nil {
+					return nil, err
+				}
+				if _, ok := s.server.quotas[fakeSQLUnquote(args[0])]; !ok {
+					s.server.quotas[fakeSQLUnquote(args[0])] = map[string]string{}
+				}
+				return nil, nil
+			},
+		},
+		{
+			// The quota of a user is kept in its settings
+			re: fakeSQLRegexp(`ALTER QUOTA ('[^']+')` + fakeClickhouseOnCluster + ` FOR INTERVAL (.+) TO ('[^']+')`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				quota, ok := s.server.quotas[fakeSQLUnquote(args[0])]
+				if !ok {
+					return nil, fmt.Errorf("quota %s does not exist", args[0])
+				}
+				user, ok := s.server.users[fakeSQLUnquote(args[2])]
+				if !ok {
+					return nil, fmt.Errorf("user %s does not exist", args[2])
+				}
+				if err := s.onCluster("QUOTA "+fakeSQLUnquote(args[0]), false); err != nil {
+					return nil, err
+				}
+				user.settings["quota"] = fakeSQLUnquote(args[0])
+				for _, interval := range strings.Split(args[1], ", FOR INTERVAL ") {
+					duration, limits, ok := strings.Cut(interval, " second ")
+					if !ok {
+						return nil, fmt.Errorf("unsupported quota interval: %s", interval)
+					}
+					quota[duration] = limits
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`DROP QUOTA IF EXISTS ('[^']+')` + fakeClickhouseOnCluster),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if err := s.onCluster("QUOTA "+name, false); err != nil {
+					return nil, err
+				}
+				delete(s.server.quotas, name)
+				delete(s.server.clusters, "QUOTA "+name)
+				for _, user := range s.server.users {
+					if user.settings["quota"] == name {
+						delete(user.settings, "quota")
+					}
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT arrayStringConcat\(groupArray\(toString\(duration\)\), ','\) FROM system\.quota_limits WHERE quota_name = ('[^']+')`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				durations := slices.Sorted(maps.Keys(s.server.quotas[fakeSQLUnquote(args[0])]))
+				return []string{strings.Join(durations, ",")}, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`CREATE ROW POLICY OR REPLACE ('[^']+')` + fakeClickhouseOnCluster + ` ON (\S+) FOR SELECT USING \((.+)\) TO ('[^']+')`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if err := s.onCluster("ROW POLICY "+name+" ON "+fakeClickhouseTable(args[1]), true); err != nil {
+					return nil, err
+				}
+				if _, ok := s.server.rowPolicies[name]; !ok {
+					s.server.rowPolicies[name] = map[string]string{}
+				}
+				s.server.rowPolicies[name][fakeClickhouseTable(args[1])] = args[2]
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`DROP ROW POLICY IF EXISTS ('[^']+') ON (\S+)` + fakeClickhouseOnCluster),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				entity := "ROW POLICY " + fakeSQLUnquote(args[0]) + " ON " + fakeClickhouseTable(args[1])
+				if err := s.onCluster(entity, false); err != nil {
+					return nil, err
+				}
+				delete(s.server.rowPolicies[fakeSQLUnquote(args[0])], fakeClickhouseTable(args[1]))
+				delete(s.server.clusters, entity)
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT arrayStringConcat\(groupArray\(if\(table = '', '\*', table\)\), ','\) FROM system\.row_policies WHERE short_name = ('[^']+') AND database = ('[^']+')`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				tables := []string{}
+				for _, table := range slices.Sorted(maps.Keys(s.server.rowPolicies[fakeSQLUnquote(args[0])])) {
+					if database, table, _ := strings.Cut(table, "."); database == fakeSQLUnquote(args[1]) {
+						tables = append(tables, table)
+					}
+				}
+				return []string{strings.Join(tables, ",")}, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`CREATE USER IF NOT EXISTS (\S+)` + fakeClickhouseOnCluster + ` IDENTIFIED BY (.+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if err := s.onCluster("USER "+fakeSQLUnquote(args[0]), true); err != nil {
+					return nil, err
+				}
+				return nil, s.server.createUser(fakeSQLUnquote(args[0]), fakeSQLUnquote(args[1]), true)
+			},
+		},
+		{
+			re: fakeSQLRegexp(`ALTER USER (\S+)` + fakeClickhouseOnCluster + ` IDENTIFIED BY (.+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if err := s.onCluster("USER "+fakeSQLUnquote(args[0]), false); err != nil {
+					return nil, err
+				}
+				return nil, s.server.alterUser(fakeSQLUnquote(args[0]), fakeSQLUnquote(args[1]))
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT arrayStringConcat\(groupArray\(toString\(access_type\)\), ','\) FROM system\.grants WHERE user_name = ('[^']+') AND database = ('[^']+') AND table IS NULL AND is_partial_revoke = 0`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if user, ok := s.server.users[fakeSQLUnquote(args[0])]; ok {
+					return []string{strings.Join(user.privileges[fakeSQLUnquote(args[1])], ",")}, nil
+				}
+				return []string{""}, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`REVOKE` + fakeClickhouseOnCluster + ` (.+) ON (\S+)\.\* FROM (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name, database := fakeSQLUnquote(args[2]), fakeSQLUnquote(args[1])
+				user, ok := s.server.users[name]
+				if !ok {
+					return nil, fmt.Errorf("user %s does not exist", name)
+				}
+				if err := s.onCluster("USER "+name, false); err != nil {
+					return nil, err
+				}
+				revoked := strings.Split(args[0], ", ")
+				privileges := []string{}
+				for _, privilege := range user.privileges[database] {
+					if !slices.Contains(revoked, privilege) && !slices.Contains(revoked, "ALL") {
+						privileges = append(privileges, privilege)
+					}
+				}
+				return nil, s.server.setPrivileges(name, database, privileges)
+			},
+		},
+		{
+			re: fakeSQLRegexp(`GRANT` + fakeClickhouseOnCluster + ` (.+) ON (\S+)\.\* TO (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name, database := fakeSQLUnquote(args[2]), fakeSQLUnquote(args[1])
+				user, ok := s.server.users[name]
+				if !ok {
+					return nil, fmt.Errorf("user %s does not exist", name)
+				}
+				if err := s.onCluster("USER "+name, false); err != nil {
+					return nil, err
+				}
+				privileges := slices.Clone(user.privileges[database])
+				for _, privilege := range strings.Split(args[0], ", ") {
+					if !slices.Contains(privileges, privilege) {
+						privileges = append(privileges, privilege)
+					}
+				}
+				return nil, s.server.setPrivileges(name, database, privileges)
+			},
+		},
+		{
+			re: fakeSQLRegexp(`DROP USER IF EXISTS (\S+)` + fakeClickhouseOnCluster),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				entity := "USER " + fakeSQLUnquote(args[0])
+				if err := s.onCluster(entity, false); err != nil {
+					return nil, err
+				}
+				delete(s.server.clusters, entity)
+				return nil, s.server.dropUser(fakeSQLUnquote(args[0]), true)
+			},
+		},
+	},
+}
+
+var fakePostgresDialect = fakeSQLDialect{
+	parseDSN: func(dsn string) (string, string, string, error) {
+		values, err := fakePostgresParseDSN(dsn)
+		if err != nil {
+			return "", "", "", err
+		}
+		return values["user"], values["password"], values["dbname"], nil
+	},
+	systemDatabases: []string{"postgres"},
+	statements: []fakeSQLStatement{
+		{
+			re: fakeSQLRegexp(`SELECT 1 FROM pg_database WHERE datname = (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				return s.server.databaseExists(fakeSQLUnquote(args[0])), nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT 1 FROM pg_user WHERE usename = (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				return s.server.userExists(fakeSQLUnquote(args[0])), nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`CREATE DATABASE (\S+)( .+)?`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if s.inTx {
+					return nil, errors.New("CREATE DATABASE cannot run inside a transaction block")
+				}
+				options, err := s.server.databaseOptions(args[1])
+				if err != nil {
+					return nil, err
+				}
+				name := fakeSQLUnquote(args[0])
+				if err := s.server.createDatabase(name, false); err != nil {
+					return nil, err
+				}
+				s.server.options[name] = options
+				return nil, nil
+			},
+		},
+		{
+			re:  fakeSQLRegexp(`REVOKE CONNECT ON DATABASE \S+ FROM PUBLIC, .+`),
+			run: noop,
+		},
+		{
+			// Privileges on the database are stored with it, so they're removed too
+			re: fakeSQLRegexp(`DROP DATABASE (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if s.inTx {
+					return nil, errors.New("DROP DATABASE cannot run inside a transaction block")
+				}
+				name := fakeSQLUnquote(args[0])
+				for _, session := range s.server.sessions {
+					if session.database == name {
+						return nil, &pq.Error{Code: "55006", Message: fmt.Sprintf("database %s is being accessed by other users", name)}
+					}
+				}
+				return nil, s.server.dropDatabase(name, false, true)
+			},
+		},
+		{
+			re: fakeSQLRegexp(`CREATE USER (\S+) WITH ENCRYPTED PASSWORD (.+) NOSUPERUSER`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				return nil, s.server.createUser(fakeSQLUnquote(args[0]), fakeSQLUnquote(args[1]), false)
+			},
+		},
+		{
+			re: fakeSQLRegexp(`ALTER ROLE (\S+) WITH ENCRYPTED PASSWORD (.+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				return nil, s.server.alterUser(fakeSQLUnquote(args[0]), fakeSQLUnquote(args[1]))
+			},
+		},
+		{
+			re: fakeSQLRegexp(`GRANT ALL PRIVILEGES ON DATABASE (\S+) TO (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				database := fakeSQLUnquote(args[0])
+				if err := s.server.grant(fakeSQLUnquote(args[1]), database, "ALL"); err != nil {
+					return nil, err
+				}
+				return nil, s.server.grantObject(fakeSQLUnquote(args[1]), fakeSQLObject{database: database, kind: "DATABASE"}, "ALL")
+			},
+		},
+		{
+			// Only privileges on tables are checked, when users are running queries
+			re: fakeSQLRegexp(`GRANT (.+) ON ALL (TABLES|SEQUENCES|FUNCTIONS) IN SCHEMA (\S+) TO (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if err := s.lock(args[2]); err != nil {
+					return nil, err
+				}
+				name := fakeSQLUnquote(args[3])
+				if strings.EqualFold(args[1], "TABLES") {
+					if err := s.server.grant(name, s.database, args[0]); err != nil {
+						return nil, err
+					}
+				}
+				object := fakeSQLObject{database: s.database, kind: strings.ToUpper(args[1]), schema: fakeSQLUnquote(args[2])}
+				return nil, s.server.grantObject(name, object, args[0])
+			},
+		},
+		{
+			re: fakeSQLRegexp(`GRANT ([A-Z, ]+) ON SCHEMA (\S+) TO (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if err := s.lock(args[1]); err != nil {
+					return nil, err
+				}
+				object := fakeSQLObject{database: s.database, kind: "SCHEMA", schema: fakeSQLUnquote(args[1])}
+				return nil, s.server.grantObject(fakeSQLUnquote(args[2]), object, args[0])
+			},
+		},
+		{
+			re: fakeSQLRegexp(`GRANT ([A-Z, ]+) ON DATABASE (\S+) TO (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				object := fakeSQLObject{database: fakeSQLUnquote(args[1]), kind: "DATABASE"}
+				return nil, s.server.grantObject(fakeSQLUnquote(args[2]), object, args[0])
+			},
+		},
+		{
+			// The main user changes its own default privileges, when users are removed
+			re: fakeSQLRegexp(`ALTER DEFAULT PRIVILEGES FOR ROLE (\S+) IN SCHEMA (\S+) ` +
+				`(?:GRANT (.+) ON (TABLES|SEQUENCES|FUNCTIONS) TO|REVOKE (.+) ON (TABLES|SEQUENCES|FUNCTIONS) FROM) (\S+)`),
+			public: true,
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				owner := fakeSQLUnquote(args[0])
+				if _, ok := s.server.users[owner]; !ok {
+					return nil, fmt.Errorf("role %s does not exist", owner)
+				}
+				if !s.server.users[s.user].admin && s.user != owner {
+					return nil, fmt.Errorf("permission denied to change default privileges of %s", owner)
+				}
+				object := fakeSQLObject{database: s.database, owner: owner, kind: strings.ToUpper(args[3] + args[5]), schema: fakeSQLUnquote(args[1])}
+				if args[2] == "" {
+					return nil, s.server.revokeObject(fakeSQLUnquote(args[6]), object, args[4])
+				}
+				return nil, s.server.grantObject(fakeSQLUnquote(args[6]), object, args[2])
+			},
+		},
+		{
+			// The access type of the user is revoked with all privileges, otherwise
+			// it's derived from privileges that are left on tables
+			re: fakeSQLRegexp(`REVOKE ([A-Z, ]+) ON (SCHEMA|ALL (?:TABLES|SEQUENCES|FUNCTIONS) IN SCHEMA) (\S+) FROM (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if err := s.lock(args[2]); err != nil {
+					return nil, err
+				}
+				name := fakeSQLUnquote(args[3])
+				kind := strings.TrimSuffix(strings.TrimPrefix(strings.ToUpper(args[1]), "ALL "), " IN SCHEMA")
+				if err := s.server.revokeObject(name, fakeSQLObject{database: s.database, kind: kind, schema: fakeSQLUnquote(args[2])}, args[0]); err != nil {
+					return nil, err
+				}
+				if strings.EqualFold(args[0], "ALL") {
+					return nil, s.server.revoke(name, s.database)
+				}
+				if accessType := s.server.users[name].grantedTables(s.database); accessType != "" {
+					s.server.users[name].grants[s.database] = accessType
+					return nil, nil
+				}
+				return nil, s.server.revoke(name, s.database)
+			},
+		},
+		{
+			re: fakeSQLRegexp(`REVOKE ([A-Z, ]+) ON DATABASE (\S+) FROM (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				object := fakeSQLObject{database: fakeSQLUnquote(args[1]), kind: "DATABASE"}
+				return nil, s.server.revokeObject(fakeSQLUnquote(args[2]), object, args[0])
+			},
+		},
+		{
+			// Privileges that are granted to the role directly by acl of objects
+			re: fakeSQLRegexp(`SELECT DISTINCT a\.privilege_type FROM (pg_database|pg_namespace|pg_class|pg_proc|pg_default_acl) .+? WHERE (.+) ` +
+				`AND a\.grantee = \(SELECT oid FROM pg_roles WHERE rolname = (\S+)\)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				user, ok := s.server.users[fakeSQLUnquote(args[2])]
+				if !ok {
+					return nil, nil
+				}
+				object, err := fakePostgresACLObject(s.database, args[0], args[1])
+				if err != nil {
+					return nil, err
+				}
+				return user.grantedObject(object), nil
+			},
+		},
+		{
+			// Databases where the user owns objects, or where it has any dependencies at all
+			re: fakeSQLRegexp(`SELECT DISTINCT d\.datname FROM pg_shdepend s .+ WHERE r\.rolname = (\S+) AND (s\.deptype = 'o'|d\.datallowconn)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				user, ok := s.server.users[fakeSQLUnquote(args[0])]
+				if !ok {
+					return nil, nil
+				}
+				databases := []string{}
+				for database := range user.owns {
+					databases = append(databases, database)
+				}
+				if !strings.Contains(args[1], "deptype") {
+					for database := range user.grants {
+						if !slices.Contains(databases, database) {
+							databases = append(databases, database)
+						}
+					}
+					for object := range user.objects {
+						if !slices.Contains(databases, object.database) {
+							databases = append(databases, object.database)
+						}
+					}
+				}
+				slices.Sort(databases)
+				return databases, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`REASSIGN OWNED BY (\S+) TO (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				from, ok := s.server.users[fakeSQLUnquote(args[0])]
+				if !ok {
+					return nil, fmt.Errorf("role %s does not exist", args[0])
+				}
+				to, ok := s.server.users[fakeSQLUnquote(args[1])]
+				if !ok {
+					return nil, fmt.Errorf("role %s does not exist", args[1])
+				}
+				if from.owns[s.database] {
+					delete(from.owns, s.database)
+					to.owns[s.database] = true
+				}
+				return nil, nil
+			},
+		},
+		{
+			// Objects of the user are dropped, its privileges and default privileges
+			// on its objects are revoked
+			re: fakeSQLRegexp(`DROP OWNED BY (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if err := s.server.revoke(name, s.database); err != nil {
+					return nil, err
+				}
+				delete(s.server.users[name].owns, s.database)
+				for _, user := range s.server.users {
+					maps.DeleteFunc(user.objects, func(object fakeSQLObject, _ []string) bool {
+						return object.database == s.database && (user == s.server.users[name] || object.owner == name)
+					})
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT pg_terminate_backend\(pid\) FROM pg_stat_activity WHERE (usename|datname) = (\S+) AND pid <> pg_backend_pid\(\)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				value := fakeSQLUnquote(args[1])
+				for _, session := range slices.Clone(s.server.sessions) {
+					if session == s || (args[0] == "usename" && session.user != value) || (args[0] == "datname" && session.database != value) {
+						continue
+					}
+					session.terminated = true
+					s.server.disconnect(session)
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`DROP USER (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if user, ok := s.server.users[name]; ok && (len(user.grants) > 0 || len(user.owns) > 0 || len(user.objects) > 0) {
+					return nil, &pq.Error{Code: "2BP01", Message: fmt.Sprintf("role %s cannot be dropped because some objects depend on it", name)}
+				}
+				return nil, s.server.dropUser(name, false)
+			},
+		},
+		{
+			// Roles of extra privileges and the user role of the admin
+			re: fakeSQLRegexp(`(GRANT|REVOKE) (\S+) (?:TO|FROM) (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				member, ok := s.server.users[fakeSQLUnquote(args[2])]
+				if !ok {
+					return nil, fmt.Errorf("role %s does not exist", args[2])
+				}
+				role := fakeSQLUnquote(args[1])
+				if strings.EqualFold(args[0], "REVOKE") {
+					member.memberOf = slices.DeleteFunc(member.memberOf, func(r string) bool { return r == role })
+				} else if !slices.Contains(member.memberOf, role) {
+					member.memberOf = append(member.memberOf, role)
+				}
+				return nil, nil
+			},
+		},
+		{
+			re:  fakeSQLRegexp(`SET LOCAL ROLE \S+`),
+			run: noop,
+		},
+		{
+			// Like on the server, it has no effect outside of transactions
+			re:     fakeSQLRegexp(`SET LOCAL lock_timeout = (\d+)`),
+			public: true,
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if s.inTx {
+					s.lockTimeout = args[0]
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`ALTER DATABASE (\S+) SET (\S+) = (.+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				database := fakeSQLUnquote(args[0])
+				if _, ok := s.server.settings[database]; !ok {
+					s.server.settings[database] = map[string]string{}
+				}
+				s.server.settings[database][args[1]] = args[2]
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`ALTER DATABASE (\S+) RESET (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				delete(s.server.settings[fakeSQLUnquote(args[0])], args[1])
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT split_part\(unnest\(setconfig\), '=', 1\) FROM pg_db_role_setting .+ WHERE datname = (\S+)\)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				return slices.Sorted(maps.Keys(s.server.settings[fakeSQLUnquote(args[0])])), nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`ALTER DATABASE (\S+) (?:WITH (CONNECTION LIMIT) (-?\d+)|(OWNER) TO (\S+))`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if _, ok := s.server.databases[name]; !ok {
+					return nil, fmt.Errorf("database %s does not exist", name)
+				}
+				options, err := s.server.databaseOptions(args[1] + " " + args[2] + args[3] + " " + args[4])
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := s.server.options[name]; !ok {
+					s.server.options[name] = map[string]string{}
+				}
+				maps.Copy(s.server.options[name], options)
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`ALTER ROLE (\S+) WITH CONNECTION LIMIT (-?\d+) VALID UNTIL ('[^']+')`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				user, ok := s.server.users[fakeSQLUnquote(args[0])]
+				if !ok {
+					return nil, fmt.Errorf("role %s does not exist", args[0])
+				}
+				user.connectionLimit, user.validUntil = args[1], fakeSQLUnquote(args[2])
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`ALTER ROLE (\S+) SET (\S+) = (.+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				user, ok := s.server.users[fakeSQLUnquote(args[0])]
+				if !ok {
+					return nil, fmt.Errorf("role %s does not exist", args[0])
+				}
+				user.settings[args[1]] = args[2]
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`ALTER ROLE (\S+) RESET (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if user, ok := s.server.users[fakeSQLUnquote(args[0])]; ok {
+					delete(user.settings, args[1])
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT split_part\(unnest\(setconfig\), '=', 1\) FROM pg_db_role_setting .+ WHERE rolname = (\S+)\)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if user, ok := s.server.users[fakeSQLUnquote(args[0])]; ok {
+					return slices.Sorted(maps.Keys(user.settings)), nil
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`CREATE SCHEMA IF NOT EXISTS (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if _, ok := s.server.schemas[s.database][name]; !ok {
+					s.server.schemas[s.database][name] = ""
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`CREATE SCHEMA (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if _, ok := s.server.schemas[s.database][name]; ok {
+					return nil, fmt.Errorf("schema %s already exists", name)
+				}
+				s.server.schemas[s.database][name] = ""
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT nspname FROM pg_namespace WHERE nspname = (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if _, ok := s.server.schemas[s.database][name]; ok {
+					return []string{name}, nil
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`COMMENT ON SCHEMA (\S+) IS (.+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				name := fakeSQLUnquote(args[0])
+				if _, ok := s.server.schemas[s.database][name]; !ok {
+					return nil, fmt.Errorf("schema %s does not exist", name)
+				}
+				s.server.schemas[s.database][name] = fakeSQLUnquote(args[1])
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`DROP SCHEMA IF EXISTS (\S+)(?: CASCADE)?`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if err := s.lock(args[0]); err != nil {
+					return nil, err
+				}
+				name := fakeSQLUnquote(args[0])
+				delete(s.server.schemas[s.database], name)
+				// Privileges on the schema and its objects are dropped with it
+				for _, user := range s.server.users {
+					maps.DeleteFunc(user.objects, func(object fakeSQLObject, _ []string) bool {
+						return object.database == s.database && object.schema == name
+					})
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`SELECT nspname FROM pg_namespace WHERE obj_description\(oid, 'pg_namespace'\) = ('[^']*') ORDER BY nspname`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				schemas := []string{}
+				for name, comment := range s.server.schemas[s.database] {
+					if comment == fakeSQLUnquote(args[0]) {
+						schemas = append(schemas, name)
+					}
+				}
+				slices.Sort(schemas)
+				return schemas, nil
+			},
+		},
+		{
+			// Owners of schemas aren't tracked by the fake, so they're returned too
+			re: fakeSQLRegexp(`SELECT DISTINCT r\.rolname FROM pg_namespace n .+ WHERE n\.nspname = (\S+) AND .+`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				grantees := []string{}
+				schema := fakeSQLObject{database: s.database, kind: "SCHEMA", schema: fakeSQLUnquote(args[0])}
+				for name, user := range s.server.users {
+					if _, ok := user.objects[schema]; ok {
+						grantees = append(grantees, name)
+					}
+				}
+				slices.Sort(grantees)
+				return grantees, nil
+			},
+		},
+		{
+			// Extensions are installed with the version 1.0 into the public schema by default
+			re: fakeSQLRegexp(`CREATE EXTENSION IF NOT EXISTS (\S+)(?: WITH SCHEMA (\S+))?(?: VERSION (\S+))?`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				if _, ok := s.server.extensions[s.database]; !ok {
+					s.server.extensions[s.database] = map[string]*fakeSQLExtension{}
+				}
+				name := fakeSQLUnquote(args[0])
+				if _, ok := s.server.extensions[s.database][name]; ok {
+					return nil, nil
+				}
+				ext := &fakeSQLExtension{version: "1.0", schema: "public"}
+				if args[1] != "" {
+					ext.schema = fakeSQLUnquote(args[1])
+				}
+				if args[2] != "" {
+					ext.version = fakeSQLUnquote(args[2])
+				}
+				s.server.extensions[s.database][name] = ext
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`ALTER EXTENSION (\S+) (UPDATE TO|SET SCHEMA) (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				ext, ok := s.server.extensions[s.database][fakeSQLUnquote(args[0])]
+				if !ok {
+					return nil, fmt.Errorf("extension %s does not exist", args[0])
+				}
+				if args[1] == "UPDATE TO" {
+					ext.version = fakeSQLUnquote(args[2])
+				} else {
+					ext.schema = fakeSQLUnquote(args[2])
+				}
+				return nil, nil
+			},
+		},
+		{
+			re: fakeSQLRegexp(`DROP EXTENSION IF EXISTS (\S+)`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				delete(s.server.extensions[s.database], fakeSQLUnquote(args[0]))
+				return nil, nil
+			},
+		},
+		{
+			re:     fakeSQLRegexp(`SELECT (extversion|n\.nspname) FROM pg_extension .*WHERE e?\.?extname = (\S+)`),
+			public: true,
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				ext, ok := s.server.extensions[s.database][fakeSQLUnquote(args[1])]
+				if !ok {
+					return nil, nil
+				}
+				if args[0] == "extversion" {
+					return []string{ext.version}, nil
+				}
+				return []string{ext.schema}, nil
+			},
+		},
+		{
+			// Session defaults of users are kept, but they aren't applied by the fake
+			re: fakeSQLRegexp(`ALTER ROLE (\S+) IN DATABASE (\S+) (?:SET (\S+) = (.+)|RESET (\S+))`),
+			run: func(s *fakeSQLSession, args []string) ([]string, error) {
+				user, ok := s.server.users[fakeSQLUnquote(args[0])]
+				if !ok {
+					return nil, fmt.Errorf("role %s does not exist", args[0])
+				}
+				database := fakeSQLUnquote(args[1])
+				if args[4] != "" {
+					delete(user.databaseSettings[database], args[4])
+					return nil, nil
+				}
+				if user.databaseSettings == nil {
+					user.databaseSettings = map[string]map[string]string{}
+				}
+				if _, ok := user.databaseSettings[database]; !ok {
+					user.databaseSettings[database] = map[string]string{}
+				}
+				user.databaseSettings[database][args[2]] = args[3]
+				return nil, nil
+			},
+		},
+	},
+}
+
+var fakePostgresDatabaseOptionRegexp = regexp.MustCompile(`(?i)(TEMPLATE|ENCODING|LC_COLLATE|LC_CTYPE|LOCALE_PROVIDER|ICU_LOCALE|CONNECTION LIMIT|OWNER) ('[^']*'|"[^"]*"|\S+)`)
+
+// databaseOptions parses options of CREATE DATABASE, the owner must exist, and databases
+// with their own encoding or locale can't be copied from template1
+func (s *fakeSQLServer) databaseOptions(clause string) (map[string]string, error) {
+	options := map[string]string{}
+	for _, match := range fakePostgresDatabaseOptionRegexp.FindAllStringSubmatch(clause, -1) {
+		options[strings.ToUpper(match[1])] = fakeSQLUnquote(match[2])
+	}
+	if rest := strings.TrimSpace(fakePostgresDatabaseOptionRegexp.ReplaceAllString(clause, "")); rest != "" {
+		return nil, fmt.Errorf("the fake server doesn't support database options: %s", rest)
+	}
+	if owner, ok := options["OWNER"]; ok && s.users[owner] == nil {
+		return nil, fmt.Errorf("role %s does not exist", owner)
+	}
+	if options["TEMPLATE"] == "" {
+		for _, option := range []string{"ENCODING", "LC_COLLATE", "LC_CTYPE", "LOCALE_PROVIDER", "ICU_LOCALE"} {
+			if _, ok := options[option]; ok {
+				return nil, fmt.Errorf("%s is incompatible with the template database (use template0 as template)", option)
+			}
+		}
+	}
+	return options, nil
+}
+
+// fakePostgresParseDSN parses a key/value connection string, values are quoted by postgresQuoteConnValue
+func fakePostgresParseDSN(dsn string) (map[string]string, error) {
+	values := map[string]string{}
+	for dsn = strings.TrimSpace(dsn); dsn != ""; dsn = strings.TrimSpace(dsn) {
+		key, rest, ok := strings.Cut(dsn, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid connection string: %s", dsn)
+		}
+		var value strings.Builder
+		if strings.HasPrefix(rest, "'") {
+			i := 1
+			for ; i < len(rest) && rest[i] != '\''; i++ {
+				if rest[i] == '\\' {
+					i++
+				}
+				value.WriteByte(rest[i])
+			}
+			if i == len(rest) {
+				return nil, fmt.Errorf("unterminated value of %s", key)
+			}
+			rest = rest[i+1:]
+		} else {
+			v, r, _ := strings.Cut(rest, " ")
+			value.WriteString(v)
+			rest = r
+		}
+		values[key] = value.String()
+		dsn = rest
+	}
+	return values, nil
+}
+
+// database/sql driver of the fake server
+
+type fakeSQLConnector struct {
+	server *fakeSQLServer
+	dsn    string
+}
+
+func (c fakeSQLConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	session, err := c.server.connect(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeSQLConn{session: session}, nil
+}
+
+func (c fakeSQLConnector) Driver() driver.Driver {
+	return fakeSQLDriver{server: c.server}
+}
+
+type fakeSQLDriver struct {
+	server *fakeSQLServer
+}
+
+func (d fakeSQLDriver) Open(dsn string) (driver.Conn, error) {
+	return fakeSQLConnector{server: d.server, dsn: dsn}.Connect(context.Background())
+}
+
+type fakeSQLConn struct {
+	session *fakeSQLSession
+}
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("the fake server doesn't support prepared statements")
+}
+
+func (c *fakeSQLConn) Close() error {
+	c.session.server.mu.Lock()
+	defer c.session.server.mu.Unlock()
+	c.session.server.disconnect(c.session)
+	return nil
+}
+
+func (c *fakeSQLConn) Begin() (driver.Tx, error) {
+	c.session.server.mu.Lock()
+	defer c.session.server.mu.Unlock()
+	if c.session.terminated {
+		return nil, driver.ErrBadConn
+	}
+	c.session.inTx = true
+	return fakeSQLTx{session: c.session}, nil
+}
+
+func (c *fakeSQLConn) Ping(ctx context.Context) error {
+	return c.ResetSession(ctx)
+}
+
+// ResetSession is called before the connection is reused, so pools don't keep terminated sessions
+func (c *fakeSQLConn) ResetSession(ctx context.Context) error {
+	c.session.server.mu.Lock()
+	defer c.session.server.mu.Unlock()
+	if c.session.terminated {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *fakeSQLConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if _, err := c.session.server.run(c.session, query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeSQLConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	values, err := c.session.server.run(c.session, query)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeSQLRows{values: values}, nil
+}
+
+// fakeSQLTx doesn't isolate anything, statements are applied right away,
+// only settings of the transaction are reset by the end of it
+type fakeSQLTx struct {
+	session *fakeSQLSession
+}
+
+func (tx fakeSQLTx) Commit() error {
+	tx.end()
+	return nil
+}
+
+func (tx fakeSQLTx) Rollback() error {
+	tx.end()
+	return nil
+}
+
+func (tx fakeSQLTx) end() {
+	tx.session.server.mu.Lock()
+	defer tx.session.server.mu.Unlock()
+	tx.session.inTx = false
+	tx.session.lockTimeout = ""
+}
+
+type fakeSQLRows struct {
+	values []string
+}
+
+func (r *fakeSQLRows) Columns() []string {
+	return []string{"result"}
+}
+
+func (r *fakeSQLRows) Close() error {
+	return nil
+}
+
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.values[0]
+	r.values = r.values[1:]
+	return nil
+}
@@ -37,6 +37,40 @@ func DeleteDatabase(ctx context.Context, db Database, admin *DatabaseUser) error
 	return nil
 }
 
+// ReconcileExtensions drops extensions that were managed before and are removed from the spec,
+// and returns versions of extensions that are managed now. Engines that don't manage
+// extensions are not returning anything
+func ReconcileExtensions(ctx context.Context, db Database, managed []string, admin *DatabaseUser) (map[string]string, error) {
+	manager, ok := db.(ExtensionManager)
+	if !ok {
+		return nil, nil
+	}
+	if err := manager.pruneExtensions(ctx, admin, managed); err != nil {
+		return nil, err
+	}
+	return manager.ExtensionVersions(ctx, admin)
+}
+
+// PruneSettings resets parameters of the database that were managed before and are removed
+// from the spec, even if there are no parameters in the spec anymore
+func PruneSettings(ctx context.Context, db Database, managed []string, admin *DatabaseUser) error {
+	manager, ok := db.(SettingsManager)
+	if !ok {
+		return nil
+	}
+	return manager.pruneSettings(ctx, admin, managed)
+}
+
+// PruneSchemas removes grants on schemas that are removed from the spec and drops them, if the
+// engine is configured to. It returns schemas that are kept in the database and differ from the spec
+func PruneSchemas(ctx context.Context, db Database, admin *DatabaseUser) ([]string, error) {
+	manager, ok := db.(SchemaManager)
+	if !ok {
+		return nil, nil
+	}
+	return manager.pruneSchemas(ctx, admin)
+}
+
 // CreateOrUpdateUser executes queries to create or update user
 func CreateOrUpdateUser(ctx context.Context, db Database, dbuser *DatabaseUser, admin *DatabaseUser) error {
 	err := db.createOrUpdateUser(ctx, admin, dbuser)
@@ -72,18 +106,20 @@ func DeleteUser(ctx context.Context, db Database, dbuser *DatabaseUser, admin *D
 	return nil
 }
 
-// New returns database interface according to engine type
+// New returns database interface according to engine type,
+// it's not connected to any instance and should only be used
+// to call functions that don't require a connection
 func New(engine string) Database {
-	switch engine {
-	case "postgres":
-		return &Postgres{}
-	case "mysql":
-		return &Mysql{}
-	case "clickhouse":
-		return &ClickHouse{}
-	case "dummy":
-		return &Dummy{}
+	driversMu.RLock()
+	e, ok := drivers[engine]
+	driversMu.RUnlock()
+	if !ok {
+		return nil
 	}
 
-	return nil
+	db, err := e.New(context.Background(), EngineConfig{})
+	if err != nil {
+		return nil
+	}
+	return db
 }
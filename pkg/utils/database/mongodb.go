@@ -0,0 +1,463 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// MongoDB is a database interface, abstracted object
+// represents a database on MongoDB instance
+// can be used to execute commands against MongoDB database
+type MongoDB struct {
+	Backend      string
+	Host         string
+	Port         uint16
+	Database     string
+	Collections  []string `json:"collections"`
+	Sharding     bool     `json:"sharding"`
+	SSLEnabled   bool
+	SkipCAVerify bool
+}
+
+func init() {
+	Register(Engine{
+		Engine:          engines.MongoDB,
+		New:             newMongoDB,
+		BackupContainer: mongodbBackupContainer,
+	})
+}
+
+func newMongoDB(ctx context.Context, cfg EngineConfig) (Database, error) {
+	m := MongoDB{}
+	if err := cfg.decodeSpec(&m); err != nil {
+		return nil, err
+	}
+	m.Backend = cfg.Backend
+	m.Host = cfg.Host
+	m.Port = cfg.Port
+	m.Database = cfg.Database
+	m.SSLEnabled = cfg.SSLEnabled
+	m.SkipCAVerify = cfg.SkipCAVerify
+	return m, nil
+}
+
+const (
+	// Built-in MongoDB roles that are assigned according to the access type
+	mongoRoleMainUser  = "dbOwner"
+	mongoRoleReadWrite = "readWrite"
+	mongoRoleReadOnly  = "read"
+	// https://www.mongodb.com/docs/manual/reference/error-codes/
+	mongoErrNamespaceExists = 48
+	mongoErrUserNotFound    = 11
+)
+
+// Internal helpers, these functions are not part for the `Database` interface
+
+// uri builds a connection string for the server, users are authenticated
+// against the authSource database, that is "admin" for the admin user
+// and the managed database for users created by the operator
+func (m MongoDB) uri(user, password, authSource string) string {
+	params := url.Values{}
+	params.Set("authSource", authSource)
+	if m.SSLEnabled {
+		params.Set("tls", "true")
+		if m.SkipCAVerify {
+			params.Set("tlsInsecure", "true")
+		}
+	}
+	u := url.URL{
+		Scheme:   "mongodb",
+		User:     url.UserPassword(user, password),
+		Host:     fmt.Sprintf("%s:%d", m.Host, m.Port),
+		Path:     "/",
+		RawQuery: params.Encode(),
+	}
+	return u.String()
+}
+
+func (m MongoDB) getClient(ctx context.Context, user, password, authSource string) (*mongo.Client, error) {
+	opts := options.Client().ApplyURI(m.uri(user, password, authSource))
+	timeouts := currentTimeouts()
+	if timeouts.Connect > 0 {
+		opts.SetConnectTimeout(timeouts.Connect)
+		opts.SetServerSelectionTimeout(timeouts.Connect)
+	}
+	// The driver applies the timeout to every operation of the client
+	if timeouts.Statement > 0 {
+		opts.SetTimeout(timeouts.Statement)
+	}
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, timeoutError(fmt.Errorf("mongo.Connect: %w", err))
+	}
+	return client, nil
+}
+
+func (m MongoDB) runCommand(ctx context.Context, database string, command bson.D, admin *DatabaseUser) (bson.M, error) {
+	log := log.FromContext(ctx)
+	client, err := m.getClient(ctx, admin.Username, admin.Password, "admin")
+	if err != nil {
+		log.Error(err, "failed to open a db connection")
+		return nil, err
+	}
+	defer client.Disconnect(ctx) //nolint:errcheck
+
+	result := bson.M{}
+	if err := client.Database(database).RunCommand(ctx, command).Decode(&result); err != nil {
+		return nil, timeoutError(err)
+	}
+	return result, nil
+}
+
+func (m MongoDB) isUserExist(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) bool {
+	log := log.FromContext(ctx)
+	check := bson.D{{Key: "usersInfo", Value: bson.D{{Key: "user", Value: user.Username}, {Key: "db", Value: m.Database}}}}
+	result, err := m.runCommand(ctx, m.Database, check, admin)
+	if err != nil {
+		log.V(2).Info("failed executing command", "error", err)
+		return false
+	}
+	users, ok := result["users"].(bson.A)
+	return ok && len(users) > 0
+}
+
+func (m MongoDB) listCollections(ctx context.Context, user *DatabaseUser) ([]string, error) {
+	client, err := m.getClient(ctx, user.Username, user.Password, m.Database)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Disconnect(ctx) //nolint:errcheck
+
+	collections, err := client.Database(m.Database).ListCollectionNames(ctx, bson.D{}, options.ListCollections().SetNameOnly(true))
+	return collections, timeoutError(err)
+}
+
+func (m MongoDB) createCollections(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	for _, c := range m.Collections {
+		create := bson.D{{Key: "create", Value: c}}
+		if _, err := m.runCommand(ctx, m.Database, create, admin); err != nil {
+			var cmdErr mongo.CommandError
+			if errors.As(err, &cmdErr) && cmdErr.Code == mongoErrNamespaceExists {
+				continue
+			}
+			log.Error(err, "failed to create collection", "collection", c)
+			return err
+		}
+	}
+	return nil
+}
+
+func (m MongoDB) enableSharding(ctx context.Context, admin *DatabaseUser) error {
+	enable := bson.D{{Key: "enableSharding", Value: m.Database}}
+	if _, err := m.runCommand(ctx, "admin", enable, admin); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m MongoDB) userRoles(user *DatabaseUser) (bson.A, error) {
+	var role string
+	switch user.AccessType {
+	case ACCESS_TYPE_MAINUSER:
+		role = mongoRoleMainUser
+	case ACCESS_TYPE_READWRITE:
+		role = mongoRoleReadWrite
+	case ACCESS_TYPE_READONLY:
+		role = mongoRoleReadOnly
+	default:
+		return nil, fmt.Errorf("unknown access type: %s", user.AccessType)
+	}
+
+	roles := bson.A{bson.D{{Key: "role", Value: role}, {Key: "db", Value: m.Database}}}
+	for _, extra := range user.ExtraPrivileges {
+		roles = append(roles, bson.D{{Key: "role", Value: extra}, {Key: "db", Value: m.Database}})
+	}
+	return roles, nil
+}
+
+// Functions that implement the `Database` interface
+
+// CheckStatus checks status of MongoDB database
+// if the connection to database works and collections exist
+func (m MongoDB) CheckStatus(ctx context.Context, user *DatabaseUser) error {
+	client, err := m.getClient(ctx, user.Username, user.Password, m.Database)
+	if err != nil {
+		return fmt.Errorf("db conn test failed - couldn't get db conn: %w", err)
+	}
+	defer client.Disconnect(ctx) //nolint:errcheck
+
+	if err := client.Database(m.Database).RunCommand(ctx, bson.D{{Key: "ping", Value: 1}}).Err(); err != nil {
+		return timeoutError(fmt.Errorf("db conn test failed - failed to execute query: %w", err))
+	}
+
+	if len(m.Collections) > 0 {
+		collections, err := m.listCollections(ctx, user)
+		if err != nil {
+			return err
+		}
+		for _, c := range m.Collections {
+			if !slices.Contains(collections, c) {
+				return fmt.Errorf("couldn't find collection %s in database %s", c, m.Database)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetCredentials returns credentials of the MongoDB database
+func (m MongoDB) GetCredentials(ctx context.Context, user *DatabaseUser) Credentials {
+	return Credentials{
+		Name:     m.Database,
+		Username: user.Username,
+		Password: user.Password,
+	}
+}
+
+// ParseAdminCredentials parse admin username and password of MongoDB database from secret data
+// If "user" key is not defined, take "root" as admin user by default
+func (m MongoDB) ParseAdminCredentials(ctx context.Context, data map[string][]byte) (*DatabaseUser, error) {
+	admin := &DatabaseUser{}
+
+	_, ok := data["user"]
+	if ok {
+		admin.Username = string(data["user"])
+	} else {
+		// default admin username is "root"
+		admin.Username = "root"
+	}
+
+	// if "password" key is defined in data, take value as password
+	_, ok = data["password"]
+	if ok {
+		admin.Password = string(data["password"])
+		return admin, nil
+	}
+
+	// take value of "mongodb-root-password" key as password if "password" key is not defined in data
+	// it's compatible with secret created by the bitnami mongodb chart
+	_, ok = data["mongodb-root-password"]
+	if ok {
+		admin.Password = string(data["mongodb-root-password"])
+		return admin, nil
+	}
+
+	return admin, errors.New("can not find mongodb admin credentials")
+}
+
+func (m MongoDB) GetDatabaseAddress(ctx context.Context) DatabaseAddress {
+	return DatabaseAddress{
+		Host: m.Host,
+		Port: m.Port,
+	}
+}
+
+// QueryAsUser runs a database command, that is expected to be
+// passed as a JSON document, for example: {"count": "collection"},
+// and returns the first field of the reply that is not "ok"
+func (m MongoDB) QueryAsUser(ctx context.Context, query string, user *DatabaseUser) (string, error) {
+	log := log.FromContext(ctx)
+	var command bson.D
+	if err := bson.UnmarshalExtJSON([]byte(query), false, &command); err != nil {
+		log.Error(err, "failed to parse a command", "query", query)
+		return "", err
+	}
+
+	client, err := m.getClient(ctx, user.Username, user.Password, m.Database)
+	if err != nil {
+		log.Error(err, "failed to open a db connection")
+		return "", err
+	}
+	defer client.Disconnect(ctx) //nolint:errcheck
+
+	var result bson.D
+	if err := client.Database(m.Database).RunCommand(ctx, command).Decode(&result); err != nil {
+		log.Error(err, "failed executing query", "query", query)
+		return "", timeoutError(err)
+	}
+
+	for _, elem := range result {
+		if elem.Key == "ok" {
+			continue
+		}
+		if value, ok := elem.Value.(string); ok {
+			return value, nil
+		}
+		return fmt.Sprint(elem.Value), nil
+	}
+	return "", errors.New("command returned no result")
+}
+
+func (m MongoDB) execAsUser(ctx context.Context, query string, user *DatabaseUser) error {
+	var command bson.D
+	if err := bson.UnmarshalExtJSON([]byte(query), false, &command); err != nil {
+		return err
+	}
+
+	client, err := m.getClient(ctx, user.Username, user.Password, m.Database)
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(ctx) //nolint:errcheck
+
+	return client.Database(m.Database).RunCommand(ctx, command).Err()
+}
+
+// createDatabase creates collections that are defined in the spec,
+// MongoDB creates databases implicitly with the first collection,
+// so the database will not be listed until it has at least one
+func (m MongoDB) createDatabase(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if err := m.createCollections(ctx, admin); err != nil {
+		log.Error(err, "failed creating MongoDB collections")
+		return err
+	}
+
+	if m.Sharding {
+		if err := m.enableSharding(ctx, admin); err != nil {
+			log.Error(err, "failed enabling sharding on MongoDB database")
+			return fmt.Errorf("can not enable sharding - %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (m MongoDB) deleteDatabase(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	drop := bson.D{{Key: "dropDatabase", Value: 1}}
+	if _, err := m.runCommand(ctx, m.Database, drop, admin); err != nil {
+		log.Error(err, "failed dropping MongoDB database")
+		return err
+	}
+	return nil
+}
+
+func (m MongoDB) createOrUpdateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if !m.isUserExist(ctx, admin, user) {
+		if err := m.createUser(ctx, admin, user); err != nil {
+			log.Error(err, "failed creating MongoDB user")
+			return err
+		}
+	} else {
+		if err := m.updateUser(ctx, admin, user); err != nil {
+			log.Error(err, "failed updating MongoDB user")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createUser creates a user in the managed database, roles are set
+// with the same command, so setUserPermission is not required here
+func (m MongoDB) createUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if m.isUserExist(ctx, admin, user) {
+		err := fmt.Errorf("user already exists: %s", user.Username)
+		return err
+	}
+
+	roles, err := m.userRoles(user)
+	if err != nil {
+		return err
+	}
+
+	create := bson.D{
+		{Key: "createUser", Value: user.Username},
+		{Key: "pwd", Value: user.Password},
+		{Key: "roles", Value: roles},
+	}
+	if _, err := m.runCommand(ctx, m.Database, create, admin); err != nil {
+		log.Error(err, "failed creating MongoDB user")
+		return err
+	}
+
+	return nil
+}
+
+func (m MongoDB) updateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if !m.isUserExist(ctx, admin, user) {
+		err := fmt.Errorf("user doesn't exist yet: %s", user.Username)
+		return err
+	}
+
+	update := bson.D{
+		{Key: "updateUser", Value: user.Username},
+		{Key: "pwd", Value: user.Password},
+	}
+	if _, err := m.runCommand(ctx, m.Database, update, admin); err != nil {
+		log.Error(err, "failed updating MongoDB user")
+		return err
+	}
+
+	if err := m.setUserPermission(ctx, admin, user); err != nil {
+		return err
+	}
+	return nil
+}
+
+// setUserPermission replaces roles of the user with the ones that
+// match the access type and extra privileges, so removed extra
+// privileges are revoked too
+func (m MongoDB) setUserPermission(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	roles, err := m.userRoles(user)
+	if err != nil {
+		return err
+	}
+
+	update := bson.D{
+		{Key: "updateUser", Value: user.Username},
+		{Key: "roles", Value: roles},
+	}
+	if _, err := m.runCommand(ctx, m.Database, update, admin); err != nil {
+		log.Error(err, "failed granting roles to MongoDB user")
+		return err
+	}
+
+	return nil
+}
+
+func (m MongoDB) deleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	drop := bson.D{{Key: "dropUser", Value: user.Username}}
+	if _, err := m.runCommand(ctx, m.Database, drop, admin); err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == mongoErrUserNotFound {
+			return nil
+		}
+		log.Error(err, "failed deleting MongoDB user")
+		return err
+	}
+
+	return nil
+}
@@ -19,16 +19,22 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GoogleCloudPlatform/cloudsql-proxy/proxy/dialers/mysql"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
-	// do not delete
+	"github.com/db-operator/db-operator/pkg/utils/engines"
 	"github.com/db-operator/db-operator/pkg/utils/kci"
-	_ "github.com/go-sql-driver/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
 )
 
 // Mysql is a database interface, abstraced object
@@ -41,6 +47,89 @@ type Mysql struct {
 	Database     string
 	SSLEnabled   bool
 	SkipCAVerify bool
+	// Name of the DbInstance, admin connections are pooled per instance
+	Instance string
+	// Options of the main user
+	MainUser mysqlUserSpec `json:"-"`
+	// Default character set and collation of the database,
+	// server defaults are used when they're empty
+	Charset   string `json:"-"`
+	Collation string `json:"-"`
+}
+
+// mysqlDatabaseSpec is the mysql specific part of a database spec
+type mysqlDatabaseSpec struct {
+	Charset   string `json:"charset"`
+	Collation string `json:"collation"`
+}
+
+// mysqlUserSpec is the mysql specific part of a user spec, it's coming
+// from the Database for main users, and from DbUsers for other ones
+type mysqlUserSpec struct {
+	// Hosts that accounts of the user are created for, % by default
+	Hosts              []string `json:"hosts"`
+	AuthPlugin         string   `json:"authPlugin"`
+	RequireTLS         string   `json:"requireTLS"`
+	MaxUserConnections *int     `json:"maxUserConnections"`
+	MaxQueriesPerHour  *int     `json:"maxQueriesPerHour"`
+}
+
+// Authentication plugins that can be set for mysql users
+var mysqlAuthPlugins = []string{"caching_sha2_password", "mysql_native_password"}
+
+// Character sets and collations are not quoted, so only
+// characters that can be a part of their names are allowed
+var mysqlCharsetRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Host patterns of accounts are quoted, but only characters
+// that can be a part of host names, IPs and netmasks are allowed
+var mysqlHostRegexp = regexp.MustCompile(`^[A-Za-z0-9%_.:/-]+$`)
+
+// Built-in access types of mysql users
+var mysqlAccessProfiles = map[string]AccessProfile{
+	ACCESS_TYPE_READONLY: {
+		Name:     ACCESS_TYPE_READONLY,
+		Database: []string{"SELECT"},
+	},
+	ACCESS_TYPE_READWRITE: {
+		Name:     ACCESS_TYPE_READWRITE,
+		Database: []string{"SELECT", "UPDATE", "INSERT", "DELETE"},
+	},
+}
+
+func init() {
+	Register(Engine{
+		Engine:          engines.Mysql,
+		New:             newMysql,
+		BackupContainer: mysqlBackupContainer,
+	})
+}
+
+func newMysql(ctx context.Context, cfg EngineConfig) (Database, error) {
+	m := Mysql{
+		Instance:     cfg.Instance,
+		Backend:      cfg.Backend,
+		Host:         cfg.Host,
+		Port:         cfg.Port,
+		Database:     cfg.Database,
+		SSLEnabled:   cfg.SSLEnabled,
+		SkipCAVerify: cfg.SkipCAVerify,
+	}
+	if err := cfg.decodeSpec(&m.MainUser); err != nil {
+		return nil, err
+	}
+	spec := mysqlDatabaseSpec{}
+	if err := cfg.decodeSpec(&spec); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{spec.Charset, spec.Collation} {
+		if name != "" && !mysqlCharsetRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid character set or collation: %s", name)
+		}
+	}
+	m.Charset = spec.Charset
+	m.Collation = spec.Collation
+	return m, nil
 }
 
 const mysqlDefaultSSLMode = "preferred"
@@ -63,6 +152,19 @@ func (m Mysql) sslMode() string {
 	return mysqlDefaultSSLMode
 }
 
+// dataSource returns a connection string, the driver escapes credentials,
+// so they can contain any character
+func (m Mysql) dataSource(user, password string) string {
+	cfg := mysqldriver.NewConfig()
+	cfg.User = user
+	cfg.Passwd = password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(m.Host, strconv.Itoa(int(m.Port)))
+	cfg.TLSConfig = m.sslMode()
+	cfg.Timeout = currentTimeouts().Connect
+	return cfg.FormatDSN()
+}
+
 func (m Mysql) getDbConn(ctx context.Context, user, password string) (*sql.DB, error) {
 	log := log.FromContext(ctx)
 	var db *sql.DB
@@ -77,8 +179,7 @@ func (m Mysql) getDbConn(ctx context.Context, user, password string) (*sql.DB, e
 			return db, err
 		}
 	default:
-		dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%d)/?tls=%s", user, password, m.Host, m.Port, m.sslMode())
-		db, err = sql.Open("mysql", dataSourceName)
+		db, err = sqlOpen("mysql", m.dataSource(user, password))
 		if err != nil {
 			log.Error(err, "failed to validate db connection")
 			return db, err
@@ -89,19 +190,33 @@ func (m Mysql) getDbConn(ctx context.Context, user, password string) (*sql.DB, e
 	return db, nil
 }
 
+// getPooledConn returns a connection pool that is shared between reconciliations,
+// it must not be closed by the caller
+func (m Mysql) getPooledConn(ctx context.Context, user, password string) (*sql.DB, error) {
+	key := connKey{Instance: m.poolInstance(), User: user}
+	return Connections.get(key, m.Backend+" "+m.dataSource(user, password), func() (*sql.DB, error) {
+		return m.getDbConn(ctx, user, password)
+	})
+}
+
+func (m Mysql) poolInstance() string {
+	return poolInstance(m.Instance, m.Host, m.Port)
+}
+
 func (m Mysql) executeQuery(ctx context.Context, query string, admin *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	db, err := m.getDbConn(ctx, admin.Username, admin.Password)
+	db, err := m.getPooledConn(ctx, admin.Username, admin.Password)
 	if err != nil {
 		log.Error(err, "failed to get db connection")
 		return err
 	}
-	defer db.Close()
 
-	rows, err := db.Query(query)
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		log.Error(err, "failed to get db connection")
-		return err
+		return timeoutError(err)
 	}
 	rows.Close()
 
@@ -117,10 +232,12 @@ func (m Mysql) execAsUser(ctx context.Context, query string, user *DatabaseUser)
 	}
 	defer db.Close()
 
-	rows, err := db.Query(query)
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		log.Error(err, "failed to get db connection")
-		return err
+		return timeoutError(err)
 	}
 	rows.Close()
 
@@ -129,15 +246,16 @@ func (m Mysql) execAsUser(ctx context.Context, query string, user *DatabaseUser)
 
 func (m Mysql) isRowExist(ctx context.Context, query string, admin *DatabaseUser) bool {
 	log := log.FromContext(ctx)
-	db, err := m.getDbConn(ctx, admin.Username, admin.Password)
+	db, err := m.getPooledConn(ctx, admin.Username, admin.Password)
 	if err != nil {
 		log.Error(err, "failed to get db connection")
 		return false
 	}
-	defer db.Close()
 
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
 	var result string
-	err = db.QueryRow(query).Scan(&result)
+	err = db.QueryRowContext(ctx, query).Scan(&result)
 	if err != nil {
 		log.Error(err, "failed to get db connection")
 		return false
@@ -147,10 +265,299 @@ func (m Mysql) isRowExist(ctx context.Context, query string, admin *DatabaseUser
 }
 
 func (m Mysql) isUserExist(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) bool {
-	check := fmt.Sprintf("SELECT User FROM mysql.user WHERE user='%s';", user.Username)
+	check := fmt.Sprintf("SELECT User FROM mysql.user WHERE user=%s;", mysqlQuoteLiteral(user.Username))
 	return m.isRowExist(ctx, check, admin)
 }
 
+// queryList runs the query as admin and returns values of the first column of all rows
+func (m Mysql) queryList(ctx context.Context, query string, admin *DatabaseUser) ([]string, error) {
+	db, err := m.getPooledConn(ctx, admin.Username, admin.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, timeoutError(err)
+	}
+	defer rows.Close()
+	result := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, timeoutError(rows.Err())
+}
+
+// userHosts returns hosts of all accounts of the user
+func (m Mysql) userHosts(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) ([]string, error) {
+	query := fmt.Sprintf("SELECT Host FROM mysql.user WHERE User = %s;", mysqlQuoteLiteral(user.Username))
+	return m.queryList(ctx, query, admin)
+}
+
+// mysqlDialect describes how roles are handled by the server, MySQL 8 roles are accounts
+// with hosts, while MariaDB roles are plain names, and MySQL 5.7 doesn't have roles at all
+type mysqlDialect struct {
+	Version string
+	MariaDB bool
+	Roles   bool
+}
+
+// dialect detects the dialect of the server from its version
+func (m Mysql) dialect(ctx context.Context, admin *DatabaseUser) (mysqlDialect, error) {
+	versions, err := m.queryList(ctx, "SELECT VERSION();", admin)
+	if err != nil {
+		return mysqlDialect{}, err
+	}
+	if len(versions) == 0 {
+		return mysqlDialect{}, errors.New("can't get the version of the server")
+	}
+	return parseMysqlVersion(versions[0]), nil
+}
+
+// parseMysqlVersion parses versions like 8.0.36 or 10.11.6-MariaDB-1:10.11.6+maria~ubu2204
+func parseMysqlVersion(version string) mysqlDialect {
+	dialect := mysqlDialect{Version: version, MariaDB: strings.Contains(strings.ToLower(version), "mariadb")}
+	major, _, _ := strings.Cut(version, ".")
+	majorVersion, err := strconv.Atoi(major)
+	// MariaDB has roles since 10.0.5, that is older than any supported version
+	dialect.Roles = dialect.MariaDB || (err == nil && majorVersion >= 8)
+	return dialect
+}
+
+// quoteRole quotes a role name, MySQL roles are granted as accounts of any host
+func (d mysqlDialect) quoteRole(role string) string {
+	if d.MariaDB {
+		return mysqlQuoteIdentifier(role)
+	}
+	return mysqlQuoteAccount(role)
+}
+
+// mysqlRole is a role that is granted to an account, MySQL roles are accounts
+// and can have any host, while MariaDB roles don't have hosts
+type mysqlRole struct {
+	Name string
+	Host string
+}
+
+// quote quotes the role the way it was granted
+func (r mysqlRole) quote(dialect mysqlDialect) string {
+	if dialect.MariaDB {
+		return mysqlQuoteIdentifier(r.Name)
+	}
+	return mysqlQuoteAccountHost(r.Name, r.Host)
+}
+
+// listed checks if the role is one of the roles from the spec, that are granted from any host on MySQL
+func (r mysqlRole) listed(dialect mysqlDialect, roles []string) bool {
+	return slices.Contains(roles, r.Name) && (dialect.MariaDB || r.Host == "%")
+}
+
+// grantedRoles returns roles that are granted to the account, whatever hosts they have
+func (m Mysql) grantedRoles(ctx context.Context, admin *DatabaseUser, dialect mysqlDialect, username, host string) ([]mysqlRole, error) {
+	if dialect.MariaDB {
+		query := fmt.Sprintf("SELECT Role FROM mysql.roles_mapping WHERE User = %s AND Host = %s;", mysqlQuoteLiteral(username), mysqlQuoteLiteral(host))
+		names, err := m.queryList(ctx, query, admin)
+		if err != nil {
+			return nil, err
+		}
+		roles := []mysqlRole{}
+		for _, name := range names {
+			roles = append(roles, mysqlRole{Name: name})
+		}
+		return roles, nil
+	}
+
+	// Names and hosts are returned as JSON, so they can contain any characters
+	query := fmt.Sprintf("SELECT JSON_ARRAY(FROM_USER, FROM_HOST) FROM mysql.role_edges WHERE TO_USER = %s AND TO_HOST = %s;",
+		mysqlQuoteLiteral(username), mysqlQuoteLiteral(host))
+	edges, err := m.queryList(ctx, query, admin)
+	if err != nil {
+		return nil, err
+	}
+	roles := []mysqlRole{}
+	for _, edge := range edges {
+		account := []string{}
+		if err := json.Unmarshal([]byte(edge), &account); err != nil || len(account) != 2 {
+			return nil, fmt.Errorf("unexpected role of the account %s: %s", username, edge)
+		}
+		roles = append(roles, mysqlRole{Name: account[0], Host: account[1]})
+	}
+	return roles, nil
+}
+
+// syncRoles grants roles to the account and makes them default, so they're active in all sessions.
+// Roles that are not listed anymore are revoked, including roles of other hosts on MySQL
+func (m Mysql) syncRoles(ctx context.Context, admin *DatabaseUser, dialect mysqlDialect, username, host string, roles []string) error {
+	log := log.FromContext(ctx)
+	account := mysqlQuoteAccountHost(username, host)
+	granted, err := m.grantedRoles(ctx, admin, dialect, username, host)
+	if err != nil {
+		return err
+	}
+
+	quoted := []string{}
+	for _, role := range roles {
+		quoted = append(quoted, dialect.quoteRole(role))
+		if slices.ContainsFunc(granted, func(r mysqlRole) bool { return r.listed(dialect, []string{role}) }) {
+			continue
+		}
+		grant := fmt.Sprintf("GRANT %s TO %s;", dialect.quoteRole(role), account)
+		if err := m.executeQuery(ctx, grant, admin); err != nil {
+			log.Error(err, "failed to grant a role", "username", username, "host", host, "role", role)
+			return err
+		}
+	}
+	if err := m.revokeRoles(ctx, admin, dialect, username, host, granted, roles); err != nil {
+		return err
+	}
+
+	defaultRoles := "NONE"
+	setDefault := "SET DEFAULT ROLE %s TO %s;"
+	if dialect.MariaDB {
+		// MariaDB can only have one default role, so users with more roles are rejected before
+		setDefault = "SET DEFAULT ROLE %s FOR %s;"
+	}
+	if len(quoted) > 0 {
+		defaultRoles = strings.Join(quoted, ", ")
+	}
+	return m.executeQuery(ctx, fmt.Sprintf(setDefault, defaultRoles, account), admin)
+}
+
+// revokeRoles revokes roles that are granted, but are not kept
+func (m Mysql) revokeRoles(ctx context.Context, admin *DatabaseUser, dialect mysqlDialect, username, host string, granted []mysqlRole, keep []string) error {
+	log := log.FromContext(ctx)
+	for _, role := range granted {
+		if role.listed(dialect, keep) {
+			continue
+		}
+		revoke := fmt.Sprintf("REVOKE %s FROM %s;", role.quote(dialect), mysqlQuoteAccountHost(username, host))
+		if err := m.executeQuery(ctx, revoke, admin); err != nil {
+			log.Error(err, "failed to revoke a role", "username", username, "host", host, "role", role.Name)
+			return err
+		}
+	}
+	return nil
+}
+
+// userSpec returns options of the user, main users are configured by the Database
+func (m Mysql) userSpec(user *DatabaseUser) (mysqlUserSpec, error) {
+	spec := mysqlUserSpec{}
+	if user.AccessType == ACCESS_TYPE_MAINUSER {
+		spec = m.MainUser
+	} else if err := user.decodeSpec(&spec); err != nil {
+		return spec, err
+	}
+	if len(spec.AuthPlugin) > 0 && !slices.Contains(mysqlAuthPlugins, spec.AuthPlugin) {
+		return spec, fmt.Errorf("unsupported authentication plugin: %s", spec.AuthPlugin)
+	}
+	switch strings.ToUpper(spec.RequireTLS) {
+	case "", "NONE", "SSL", "X509":
+	default:
+		return spec, fmt.Errorf("unsupported TLS requirement: %s", spec.RequireTLS)
+	}
+	return spec, nil
+}
+
+// accountHosts returns hosts of accounts that must exist for the spec,
+// IPv4 CIDRs are turned into netmasks that are supported by all versions
+func (s mysqlUserSpec) accountHosts() ([]string, error) {
+	if len(s.Hosts) == 0 {
+		return []string{"%"}, nil
+	}
+	hosts := []string{}
+	for _, host := range s.Hosts {
+		if !mysqlHostRegexp.MatchString(host) {
+			return nil, fmt.Errorf("invalid host: %s", host)
+		}
+		if _, network, err := net.ParseCIDR(host); err == nil && network.IP.To4() != nil {
+			host = fmt.Sprintf("%s/%s", network.IP.String(), net.IP(network.Mask).String())
+		}
+		if !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, nil
+}
+
+// accountOptions returns the TLS requirement and resource limits of accounts,
+// they're always set, so options that are removed from the spec are reset
+func (s mysqlUserSpec) accountOptions() string {
+	require := "NONE"
+	if len(s.RequireTLS) > 0 {
+		require = strings.ToUpper(s.RequireTLS)
+	}
+	maxUserConnections, maxQueriesPerHour := 0, 0
+	if s.MaxUserConnections != nil {
+		maxUserConnections = *s.MaxUserConnections
+	}
+	if s.MaxQueriesPerHour != nil {
+		maxQueriesPerHour = *s.MaxQueriesPerHour
+	}
+	return fmt.Sprintf("REQUIRE %s WITH MAX_USER_CONNECTIONS %d MAX_QUERIES_PER_HOUR %d", require, maxUserConnections, maxQueriesPerHour)
+}
+
+// identifiedBy returns the authentication part of account statements
+func (s mysqlUserSpec) identifiedBy(password string) string {
+	if len(s.AuthPlugin) > 0 {
+		return fmt.Sprintf("IDENTIFIED WITH %s BY %s", s.AuthPlugin, mysqlQuoteLiteral(password))
+	}
+	return fmt.Sprintf("IDENTIFIED BY %s", mysqlQuoteLiteral(password))
+}
+
+// syncAccounts makes sure that the user has an account for every host from the spec, and
+// removes accounts of other hosts. Passwords of existing accounts are only set, when
+// updatePassword is true, so a failed creation can be retried without changing them
+func (m Mysql) syncAccounts(ctx context.Context, admin *DatabaseUser, user *DatabaseUser, updatePassword bool) error {
+	log := log.FromContext(ctx)
+	spec, err := m.userSpec(user)
+	if err != nil {
+		return err
+	}
+	hosts, err := spec.accountHosts()
+	if err != nil {
+		return err
+	}
+	existing, err := m.userHosts(ctx, admin, user)
+	if err != nil {
+		return err
+	}
+
+	for _, host := range hosts {
+		account := mysqlQuoteAccountHost(user.Username, host)
+		var query string
+		switch {
+		case !slices.Contains(existing, host):
+			query = fmt.Sprintf("CREATE USER %s %s %s;", account, spec.identifiedBy(user.Password), spec.accountOptions())
+		case updatePassword:
+			query = fmt.Sprintf("ALTER USER %s %s %s;", account, spec.identifiedBy(user.Password), spec.accountOptions())
+		default:
+			query = fmt.Sprintf("ALTER USER %s %s;", account, spec.accountOptions())
+		}
+		if err := m.executeQuery(ctx, query, admin); err != nil {
+			log.Error(err, "failed to configure an account", "username", user.Username, "host", host)
+			return err
+		}
+	}
+
+	for _, host := range existing {
+		if slices.Contains(hosts, host) {
+			continue
+		}
+		log.Info("removing an account of a host that is not in the spec anymore", "username", user.Username, "host", host)
+		drop := fmt.Sprintf("DROP USER %s;", mysqlQuoteAccountHost(user.Username, host))
+		if err := m.executeQuery(ctx, drop, admin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Functions that implement the `Database` interface
 
 // CheckStatus checks status of mysql database
@@ -162,14 +569,16 @@ func (m Mysql) CheckStatus(ctx context.Context, user *DatabaseUser) error {
 	}
 	defer db.Close()
 
-	if err := db.Ping(); err != nil {
+	queryCtx, cancel := statementContext(ctx)
+	defer cancel()
+	if err := db.PingContext(queryCtx); err != nil {
 		db.Close()
-		return fmt.Errorf("db conn test failed - could not establish a connection: %v", err)
+		return timeoutError(fmt.Errorf("db conn test failed - could not establish a connection: %w", err))
 	}
 
-	check := fmt.Sprintf("USE %s", m.Database)
-	if _, err := db.Exec(check); err != nil {
-		return err
+	check := fmt.Sprintf("USE %s", mysqlQuoteIdentifier(m.Database))
+	if _, err := db.ExecContext(queryCtx, check); err != nil {
+		return timeoutError(err)
 	}
 
 	return nil
@@ -231,28 +640,66 @@ func (m Mysql) QueryAsUser(ctx context.Context, query string, user *DatabaseUser
 	}
 	defer db.Close()
 
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
 	var result string
-	if err := db.QueryRow(query).Scan(&result); err != nil {
+	if err := db.QueryRowContext(ctx, query).Scan(&result); err != nil {
 		log.Error(err, "an error occured while executing a query")
-		return "", err
+		return "", timeoutError(err)
 	}
 	return result, nil
 }
 
 func (m Mysql) createDatabase(ctx context.Context, admin *DatabaseUser) error {
-	create := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`;", m.Database)
+	create := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s%s;", mysqlQuoteIdentifier(m.Database), m.charsetOptions())
 
 	err := m.executeQuery(ctx, create, admin)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return m.alterCharset(ctx, admin)
+}
+
+// charsetOptions returns the CHARACTER SET and COLLATE options of the database with a leading space
+func (m Mysql) charsetOptions() string {
+	options := ""
+	if m.Charset != "" {
+		options += " CHARACTER SET " + m.Charset
+	}
+	if m.Collation != "" {
+		options += " COLLATE " + m.Collation
+	}
+	return options
+}
+
+// alterCharset changes the character set and the collation of an existing database when they
+// don't match the spec, only defaults of new tables are changed, existing tables are not converted
+func (m Mysql) alterCharset(ctx context.Context, admin *DatabaseUser) error {
+	if m.Charset == "" && m.Collation == "" {
+		return nil
+	}
+	query := fmt.Sprintf("SELECT CONCAT(DEFAULT_CHARACTER_SET_NAME, ' ', DEFAULT_COLLATION_NAME) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = %s;",
+		mysqlQuoteLiteral(m.Database))
+	current, err := m.queryList(ctx, query, admin)
+	if err != nil {
+		return err
+	}
+	if len(current) == 0 {
+		return fmt.Errorf("database %s does not exist", m.Database)
+	}
+	charset, collation, _ := strings.Cut(current[0], " ")
+	if (m.Charset == "" || strings.EqualFold(m.Charset, charset)) &&
+		(m.Collation == "" || strings.EqualFold(m.Collation, collation)) {
+		return nil
+	}
+	alter := fmt.Sprintf("ALTER DATABASE %s%s;", mysqlQuoteIdentifier(m.Database), m.charsetOptions())
+	return m.executeQuery(ctx, alter, admin)
 }
 
 func (m Mysql) deleteDatabase(ctx context.Context, admin *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	create := fmt.Sprintf("DROP DATABASE IF EXISTS `%s`;", m.Database)
+	create := fmt.Sprintf("DROP DATABASE IF EXISTS %s;", mysqlQuoteIdentifier(m.Database))
 
 	err := kci.Retry(3, 5*time.Second, func() error {
 		err := m.executeQuery(ctx, create, admin)
@@ -282,23 +729,12 @@ func (m Mysql) createOrUpdateUser(ctx context.Context, admin *DatabaseUser, user
 		}
 	}
 
-	if err := m.setUserPermission(ctx, admin, user); err != nil {
-		return err
-	}
-
 	return nil
 }
 
 func (m Mysql) createUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
-	create := fmt.Sprintf("CREATE USER `%s` IDENTIFIED BY '%s';", user.Username, user.Password)
-
-	if !m.isUserExist(ctx, admin, user) {
-		err := m.executeQuery(ctx, create, admin)
-		if err != nil {
-			return err
-		}
-	} else {
-		err := fmt.Errorf("user already exists: %s", user.Username)
+	// Existing accounts are kept, so a failed creation can be retried
+	if err := m.syncAccounts(ctx, admin, user, false); err != nil {
 		return err
 	}
 
@@ -310,16 +746,13 @@ func (m Mysql) createUser(ctx context.Context, admin *DatabaseUser, user *Databa
 }
 
 func (m Mysql) updateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
-	update := fmt.Sprintf("ALTER USER `%s` IDENTIFIED BY '%s';", user.Username, user.Password)
-
 	if !m.isUserExist(ctx, admin, user) {
 		err := fmt.Errorf("user doesn't exist yet: %s", user.Username)
 		return err
-	} else {
-		err := m.executeQuery(ctx, update, admin)
-		if err != nil {
-			return err
-		}
+	}
+
+	if err := m.syncAccounts(ctx, admin, user, true); err != nil {
+		return err
 	}
 
 	if err := m.setUserPermission(ctx, admin, user); err != nil {
@@ -330,45 +763,129 @@ func (m Mysql) updateUser(ctx context.Context, admin *DatabaseUser, user *Databa
 }
 
 func (m Mysql) setUserPermission(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	spec, err := m.userSpec(user)
+	if err != nil {
+		return err
+	}
+	hosts, err := spec.accountHosts()
+	if err != nil {
+		return err
+	}
+
+	var privileges string
 	switch user.AccessType {
 	case ACCESS_TYPE_MAINUSER:
-		grant := fmt.Sprintf("GRANT ALL PRIVILEGES ON `%s`.* TO '%s'@'%%';", m.Database, user.Username)
-		err := m.executeQuery(ctx, grant, admin)
+		privileges = "ALL PRIVILEGES"
+	default:
+		profile, err := user.accessProfile(mysqlAccessProfiles)
 		if err != nil {
 			return err
 		}
-	case ACCESS_TYPE_READONLY:
-		grant := fmt.Sprintf("GRANT SELECT ON `%s`.* TO '%s'@'%%';", m.Database, user.Username)
-		err := m.executeQuery(ctx, grant, admin)
-		if err != nil {
-			return err
+		// Privileges on the database are inherited by all its objects,
+		// so everything is granted on the database level
+		privileges = joinPrivileges(profile.Database, profile.Tables, profile.Functions)
+		if len(privileges) == 0 {
+			return fmt.Errorf("access profile %s doesn't have any privileges for mysql", profile.Name)
 		}
-	case ACCESS_TYPE_READWRITE:
-		grant := fmt.Sprintf("GRANT SELECT, UPDATE, INSERT, DELETE ON `%s`.* TO '%s'@'%%';", m.Database, user.Username)
-		err := m.executeQuery(ctx, grant, admin)
+	}
+	dialect, err := m.dialect(ctx, admin)
+	if err != nil {
+		return err
+	}
+	if !dialect.Roles && len(user.ExtraPrivileges) > 0 {
+		return fmt.Errorf("extra privileges can't be granted, because roles are not supported by the server version %s", dialect.Version)
+	}
+	// Roles are only active without SET ROLE, when they're default
+	if dialect.MariaDB && len(user.ExtraPrivileges) > 1 {
+		return fmt.Errorf("only one extra privilege can be granted, because MariaDB supports one default role per account, got %d", len(user.ExtraPrivileges))
+	}
+
+	wanted := strings.Split(privileges, ", ")
+	for _, host := range hosts {
+		account := mysqlQuoteAccountHost(user.Username, host)
+		// Only privileges that are not in the access profile anymore are revoked,
+		// so users don't lose access to the database while they're reconciled
+		granted, err := m.grantedPrivileges(ctx, admin, user.Username, host)
 		if err != nil {
 			return err
 		}
-	default:
-		err := fmt.Errorf("unknown access type: %s", user.AccessType)
-		return err
-	}
-	for _, role := range user.ExtraPrivileges {
-		grantRole := fmt.Sprintf("GRANT \"%s\" to \"%s\"", role, user.Username)
-		if err := m.executeQuery(ctx, grantRole, admin); err != nil {
+		revoke := []string{}
+		if !slices.Contains(wanted, "ALL PRIVILEGES") && !slices.Contains(wanted, "ALL") {
+			for _, privilege := range granted {
+				if !slices.Contains(wanted, privilege) {
+					revoke = append(revoke, privilege)
+				}
+			}
+		}
+		if len(revoke) > 0 {
+			query := fmt.Sprintf("REVOKE %s ON %s.* FROM %s;", strings.Join(revoke, ", "), mysqlQuoteIdentifier(m.Database), account)
+			if err := m.executeQuery(ctx, query, admin); err != nil {
+				return err
+			}
+		}
+		grant := fmt.Sprintf("GRANT %s ON %s.* TO %s;", privileges, mysqlQuoteIdentifier(m.Database), account)
+		if err := m.executeQuery(ctx, grant, admin); err != nil {
 			return err
 		}
+		if dialect.Roles {
+			if err := m.syncRoles(ctx, admin, dialect, user.Username, host, user.ExtraPrivileges); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
+// grantedPrivileges returns privileges of the account on the database, that are listed by SHOW GRANTS
+func (m Mysql) grantedPrivileges(ctx context.Context, admin *DatabaseUser, username, host string) ([]string, error) {
+	grants, err := m.queryList(ctx, fmt.Sprintf("SHOW GRANTS FOR %s;", mysqlQuoteAccountHost(username, host)), admin)
+	if err != nil {
+		return nil, err
+	}
+	on := " ON " + mysqlQuoteIdentifier(m.Database) + ".* TO "
+	privileges := []string{}
+	for _, grant := range grants {
+		granted, _, ok := strings.Cut(grant, on)
+		if !ok || !strings.HasPrefix(granted, "GRANT ") {
+			continue
+		}
+		for _, privilege := range strings.Split(strings.TrimPrefix(granted, "GRANT "), ", ") {
+			if !slices.Contains(privileges, privilege) {
+				privileges = append(privileges, privilege)
+			}
+		}
+	}
+	return privileges, nil
+}
+
+// deleteUser removes all accounts of the user, whatever hosts they have
 func (m Mysql) deleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
-	delete := fmt.Sprintf("DROP USER `%s`;", user.Username)
+	if !m.isUserExist(ctx, admin, user) {
+		return nil
+	}
+	hosts, err := m.userHosts(ctx, admin, user)
+	if err != nil {
+		return err
+	}
+	dialect, err := m.dialect(ctx, admin)
+	if err != nil {
+		return err
+	}
 
-	if m.isUserExist(ctx, admin, user) {
-		err := m.executeQuery(ctx, delete, admin)
-		if err != nil {
+	Connections.invalidateUser(m.poolInstance(), user.Username)
+	for _, host := range hosts {
+		if dialect.Roles {
+			granted, err := m.grantedRoles(ctx, admin, dialect, user.Username, host)
+			if err != nil {
+				return err
+			}
+			if err := m.revokeRoles(ctx, admin, dialect, user.Username, host, granted, nil); err != nil {
+				return err
+			}
+		}
+		delete := fmt.Sprintf("DROP USER %s;", mysqlQuoteAccountHost(user.Username, host))
+		if err := m.executeQuery(ctx, delete, admin); err != nil {
 			return err
 		}
 	}
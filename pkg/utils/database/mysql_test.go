@@ -23,10 +23,11 @@ import (
 
 	"github.com/db-operator/db-operator/pkg/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func testMysql() (*Mysql, *DatabaseUser) {
-	return &Mysql{"local", test.GetMysqlHost(), test.GetMysqlPort(), "testdb", false, false}, &DatabaseUser{Username: "testuser", Password: "testpwd", AccessType: ACCESS_TYPE_MAINUSER}
+	return &Mysql{Backend: "local", Host: test.GetMysqlHost(), Port: test.GetMysqlPort(), Database: "testdb"}, &DatabaseUser{Username: "testuser", Password: "testpwd", AccessType: ACCESS_TYPE_MAINUSER}
 }
 
 func getMysqlAdmin() *DatabaseUser {
@@ -174,9 +175,9 @@ func TestMysqlReadOnlyUserLifecycle(t *testing.T) {
 	err := m.createUser(context.TODO(), admin, readonlyUser)
 	assert.NoErrorf(t, err, "Unexpected error %v", err)
 
-	// Test that it can't be created again
+	// Test that it can be created again
 	err = m.createUser(context.TODO(), admin, readonlyUser)
-	assert.Error(t, err, "Was expecting an error")
+	assert.NoErrorf(t, err, "Unexpected error %v", err)
 
 	// Test that it can be updated
 	err = m.updateUser(context.TODO(), admin, readonlyUser)
@@ -249,9 +250,9 @@ func TestMysqlReadWriteUserLifecycle(t *testing.T) {
 	err := m.createUser(context.TODO(), admin, readwriteUser)
 	assert.NoErrorf(t, err, "Unexpected error %v", err)
 
-	// Test that it can't be created again
+	// Test that it can be created again
 	err = m.createUser(context.TODO(), admin, readwriteUser)
-	assert.Error(t, err, "Was expecting an error")
+	assert.NoErrorf(t, err, "Unexpected error %v", err)
 
 	// Test that it can be updated
 	err = m.updateUser(context.TODO(), admin, readwriteUser)
@@ -369,3 +370,176 @@ func TestMysqlParseAdminCredentials(t *testing.T) {
 	assert.Equal(t, "root", cred.Username, "expect same values")
 	assert.Equal(t, string(validData2["mysql-root-password"]), cred.Password, "expect same values")
 }
+
+func TestMysqlUserOptions(t *testing.T) {
+	ctx := context.TODO()
+	admin := &DatabaseUser{Username: "root", Password: "rootpwd"}
+	server := newFakeSQLServer(fakeMysqlDialect, admin)
+	useFakeSQLServer(t, server)
+
+	e, err := GetEngine("mysql")
+	require.NoError(t, err)
+	mainUser := &DatabaseUser{Username: "options_main", Password: "mainpwd", AccessType: ACCESS_TYPE_MAINUSER}
+	cfg := EngineConfig{
+		Instance: "options-mysql", Host: "mysql", Port: 3306, Database: "options", MainUser: mainUser,
+		Spec: []byte(`{"hosts": ["10.0.0.0/8", "app.example.com"], "authPlugin": "caching_sha2_password", "requireTLS": "ssl", "maxUserConnections": 20}`),
+	}
+	db, err := e.New(ctx, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		Connections.Invalidate(poolInstance(cfg.Instance, cfg.Host, cfg.Port))
+	})
+
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	require.NoError(t, CreateOrUpdateUser(ctx, db, mainUser, admin))
+	user := server.users["options_main"]
+	options := "WITH caching_sha2_password REQUIRE SSL WITH MAX_USER_CONNECTIONS 20 MAX_QUERIES_PER_HOUR 0"
+	assert.Equal(t, map[string]string{"10.0.0.0/255.0.0.0": options, "app.example.com": options}, user.accounts)
+	assert.Equal(t, "mainpwd", user.password)
+	assert.Equal(t, []string{"ALL PRIVILEGES"}, user.privileges["options@10.0.0.0/255.0.0.0"])
+	assert.Equal(t, []string{"ALL PRIVILEGES"}, user.privileges["options@app.example.com"])
+
+	// DbUsers are configured by their own spec, and not by the one of the database
+	reader := &DatabaseUser{
+		Username:   "options_reader",
+		Password:   "readerpwd",
+		AccessType: ACCESS_TYPE_READONLY,
+		Spec:       []byte(`{"hosts": ["%", "192.168.1.0/24"], "requireTLS": "x509", "maxQueriesPerHour": 1000}`),
+	}
+	require.NoError(t, CreateUser(ctx, db, reader, admin))
+	options = "REQUIRE X509 WITH MAX_USER_CONNECTIONS 0 MAX_QUERIES_PER_HOUR 1000"
+	assert.Equal(t, map[string]string{"%": options, "192.168.1.0/255.255.255.0": options}, server.users["options_reader"].accounts)
+	assert.Equal(t, map[string][]string{
+		"options@%":                         {"SELECT"},
+		"options@192.168.1.0/255.255.255.0": {"SELECT"},
+	}, server.users["options_reader"].privileges)
+
+	// Accounts of hosts that are removed from the spec are dropped, options are reset
+	reader.Spec = []byte(`{"hosts": ["%"]}`)
+	reader.Password = "readerpwd-new"
+	require.NoError(t, UpdateUser(ctx, db, reader, admin))
+	assert.Equal(t, map[string]string{"%": "REQUIRE NONE WITH MAX_USER_CONNECTIONS 0 MAX_QUERIES_PER_HOUR 0"}, server.users["options_reader"].accounts)
+	assert.Equal(t, map[string][]string{"options@%": {"SELECT"}}, server.users["options_reader"].privileges)
+	assert.Equal(t, "readerpwd-new", server.users["options_reader"].password)
+
+	// All accounts are removed with the user
+	require.NoError(t, DeleteUser(ctx, db, mainUser, admin))
+	assert.NotContains(t, server.users, "options_main")
+
+	reader.Spec = []byte(`{"hosts": ["%'; DROP DATABASE options; --"]}`)
+	assert.Error(t, UpdateUser(ctx, db, reader, admin))
+	reader.Spec = []byte(`{"authPlugin": "auth_socket"}`)
+	assert.Error(t, UpdateUser(ctx, db, reader, admin))
+}
+
+func TestMysqlRoles(t *testing.T) {
+	ctx := context.TODO()
+	for _, tc := range []struct {
+		version string
+		roles   []string
+		// Roles after one of them is removed from the spec
+		kept []string
+		// A role that was granted by an admin, it's revoked as well
+		manual string
+	}{
+		{
+			version: "8.0.36",
+			roles:   []string{"reporting", "auditing"},
+			kept:    []string{"reporting@%"},
+			manual:  "reporting@localhost",
+		},
+		{
+			version: "10.11.6-MariaDB-1:10.11.6+maria~ubu2204",
+			roles:   []string{"reporting"},
+			kept:    []string{"reporting"},
+			manual:  "auditing",
+		},
+	} {
+		t.Run(tc.version, func(t *testing.T) {
+			db, server, admin := testAccessProfileEngine(t, "mysql", fakeMysqlDialect, 3306)
+			server.version = tc.version
+			user := &DatabaseUser{
+				Username:        "roles_user",
+				Password:        "rolespwd",
+				AccessType:      ACCESS_TYPE_READONLY,
+				ExtraPrivileges: []string{"reporting", "auditing"},
+			}
+			if len(tc.roles) == 1 {
+				// MariaDB supports one default role per account, so users with more roles are rejected
+				assert.ErrorContains(t, CreateUser(ctx, db, user, admin), "one default role")
+				require.NoError(t, DeleteUser(ctx, db, user, admin))
+				user.ExtraPrivileges = tc.roles
+				require.NoError(t, CreateUser(ctx, db, user, admin))
+				assert.Equal(t, []string{"reporting"}, server.users["roles_user"].roles["%"])
+			} else {
+				require.NoError(t, CreateUser(ctx, db, user, admin))
+				assert.Equal(t, []string{"reporting@%", "auditing@%"}, server.users["roles_user"].roles["%"])
+			}
+
+			// Roles that are removed from the spec are revoked, and so are roles of other hosts
+			server.users["roles_user"].roles["%"] = append(server.users["roles_user"].roles["%"], tc.manual)
+			user.ExtraPrivileges = []string{"reporting"}
+			require.NoError(t, UpdateUser(ctx, db, user, admin))
+			assert.Equal(t, tc.kept, server.users["roles_user"].roles["%"])
+
+			require.NoError(t, DeleteUser(ctx, db, user, admin))
+			assert.NotContains(t, server.users, "roles_user")
+		})
+	}
+
+	t.Run("5.7.44", func(t *testing.T) {
+		db, server, admin := testAccessProfileEngine(t, "mysql", fakeMysqlDialect, 3306)
+		server.version = "5.7.44-log"
+		user := &DatabaseUser{Username: "roles_user", Password: "rolespwd", AccessType: ACCESS_TYPE_READONLY}
+		require.NoError(t, CreateUser(ctx, db, user, admin), "users without roles are supported by servers without roles")
+		user.ExtraPrivileges = []string{"reporting"}
+		assert.ErrorContains(t, UpdateUser(ctx, db, user, admin), "roles are not supported")
+	})
+}
+
+func TestMysqlCharset(t *testing.T) {
+	ctx := context.TODO()
+	admin := &DatabaseUser{Username: "root", Password: "rootpwd"}
+	server := newFakeSQLServer(fakeMysqlDialect, admin)
+	useFakeSQLServer(t, server)
+
+	e, err := GetEngine("mysql")
+	require.NoError(t, err)
+	cfg := EngineConfig{
+		Instance: "charset-mysql", Host: "mysql", Port: 3306, Database: "charset",
+		Spec: []byte(`{"charset": "latin1", "collation": "latin1_swedish_ci"}`),
+	}
+	db, err := e.New(ctx, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		Connections.Invalidate(poolInstance(cfg.Instance, cfg.Host, cfg.Port))
+	})
+
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	assert.Equal(t, map[string]string{"character_set": "latin1", "collation": "latin1_swedish_ci"}, server.settings["charset"])
+
+	// Nothing is altered when the database is already in sync, so it doesn't wait for
+	// the metadata lock, that is held by a transaction
+	server.locks["charset"] = true
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	delete(server.locks, "charset")
+
+	// A changed spec is applied to the existing database
+	cfg.Spec = []byte(`{"collation": "utf8mb4_unicode_ci"}`)
+	db, err = e.New(ctx, cfg)
+	require.NoError(t, err)
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	assert.Equal(t, map[string]string{"character_set": "utf8mb4", "collation": "utf8mb4_unicode_ci"}, server.settings["charset"])
+
+	// Databases without the spec keep server defaults
+	cfg.Database = "defaults"
+	cfg.Spec = nil
+	db, err = e.New(ctx, cfg)
+	require.NoError(t, err)
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	assert.Equal(t, map[string]string{"character_set": "utf8mb4", "collation": "utf8mb4_0900_ai_ci"}, server.settings["defaults"])
+
+	cfg.Spec = []byte(`{"charset": "utf8mb4; DROP DATABASE charset"}`)
+	_, err = e.New(ctx, cfg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,644 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/db-operator/db-operator/pkg/consts"
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+	"github.com/db-operator/db-operator/pkg/utils/kci"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	// Don't delete below package. Used for driver "oracle"
+	_ "github.com/sijms/go-ora/v2"
+)
+
+// Oracle is a database interface, abstracted object
+// represents a database on Oracle instance
+// can be used to execute queries to Oracle database.
+//
+// By default a database is a schema user in the PDB behind the Service,
+// if PluggableDatabase is set, the Service must point to the CDB root,
+// and every database gets its own PDB with the schema user inside.
+type Oracle struct {
+	Backend  string
+	Host     string
+	Port     uint16
+	Service  string
+	Database string
+	// Create a PDB for each database instead of a schema user
+	PluggableDatabase bool
+	Tablespaces       []string `json:"tablespaces"`
+	Profiles          []string `json:"profiles"`
+	SSLEnabled        bool
+	SkipCAVerify      bool
+}
+
+func init() {
+	Register(Engine{
+		Engine: engines.Oracle,
+		New:    newOracle,
+	})
+}
+
+func newOracle(ctx context.Context, cfg EngineConfig) (Database, error) {
+	o := Oracle{}
+	if err := cfg.decodeSpec(&o); err != nil {
+		return nil, err
+	}
+	o.Backend = cfg.Backend
+	o.Host = cfg.Host
+	o.Port = cfg.Port
+	o.Service = cfg.Annotations[consts.ORACLE_SERVICE_NAME]
+	o.Database = cfg.Database
+	o.PluggableDatabase = cfg.annotationBool(ctx, consts.ORACLE_PLUGGABLE_DATABASE)
+	o.SSLEnabled = cfg.SSLEnabled
+	o.SkipCAVerify = cfg.SkipCAVerify
+	return o, nil
+}
+
+const (
+	// Suffixes of roles that are created for every database
+	// to grant access to objects of the schema
+	oracleReadOnlyRoleSuffix  = "_RO"
+	oracleReadWriteRoleSuffix = "_RW"
+)
+
+// Privileges that the schema owner needs to manage its own objects
+var oracleOwnerPrivileges = []string{
+	"CREATE SESSION",
+	"CREATE TABLE",
+	"CREATE VIEW",
+	"CREATE SEQUENCE",
+	"CREATE PROCEDURE",
+	"CREATE TRIGGER",
+	"CREATE TYPE",
+	"CREATE SYNONYM",
+}
+
+// Internal helpers, these functions are not part for the `Database` interface
+
+// dsn builds a go-ora connection string, SYS can only log in as SYSDBA.
+// The go-ora TIMEOUT option is applied to every read, not only to connecting,
+// so it's not set and connections are limited by the statement context instead
+func (o Oracle) dsn(service, user, password string) string {
+	params := url.Values{}
+	if o.SSLEnabled {
+		params.Set("SSL", "enable")
+		if o.SkipCAVerify {
+			params.Set("SSL VERIFY", "false")
+		}
+	}
+	if strings.EqualFold(user, "sys") {
+		params.Set("DBA PRIVILEGE", "SYSDBA")
+	}
+	dataSourceName := url.URL{
+		Scheme:   "oracle",
+		User:     url.UserPassword(user, password),
+		Host:     fmt.Sprintf("%s:%d", o.Host, o.Port),
+		Path:     "/" + service,
+		RawQuery: params.Encode(),
+	}
+	return dataSourceName.String()
+}
+
+// dbService returns the service that should be used to connect to the database
+func (o Oracle) dbService() string {
+	if o.PluggableDatabase && len(o.Database) > 0 {
+		return o.Database
+	}
+	return o.Service
+}
+
+func (o Oracle) readOnlyRole() string {
+	return o.Database + oracleReadOnlyRoleSuffix
+}
+
+func (o Oracle) readWriteRole() string {
+	return o.Database + oracleReadWriteRoleSuffix
+}
+
+func (o Oracle) getDbConn(service, user, password string) (*sql.DB, error) {
+	if len(service) == 0 {
+		return nil, errors.New("oracle service name is not set")
+	}
+	db, err := sqlOpen("oracle", o.dsn(service, user, password))
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open: %v", err)
+	}
+
+	return db, err
+}
+
+func (o Oracle) executeExec(ctx context.Context, service, query string, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	db, err := o.getDbConn(service, admin.Username, admin.Password)
+	if err != nil {
+		log.Error(err, "failed to open a db connection")
+		return err
+	}
+
+	defer db.Close()
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	_, err = db.ExecContext(ctx, query)
+
+	return timeoutError(err)
+}
+
+func (o Oracle) execAsUser(ctx context.Context, query string, user *DatabaseUser) error {
+	return o.executeExec(ctx, o.dbService(), query, user)
+}
+
+func (o Oracle) isRowExist(ctx context.Context, service string, admin *DatabaseUser, query string, args ...any) bool {
+	log := log.FromContext(ctx)
+	db, err := o.getDbConn(service, admin.Username, admin.Password)
+	if err != nil {
+		log.Error(err, "failed to open a db connection")
+		return false
+	}
+	defer db.Close()
+
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	var name string
+	err = db.QueryRowContext(ctx, query, args...).Scan(&name)
+	if err != nil {
+		log.V(2).Info("failed executing query", "error", err)
+		return false
+	}
+	return true
+}
+
+// isDbExist checks whether the PDB or the schema user exist
+func (o Oracle) isDbExist(ctx context.Context, admin *DatabaseUser) bool {
+	if o.PluggableDatabase {
+		return o.isRowExist(ctx, o.Service, admin, "SELECT pdb_name FROM dba_pdbs WHERE pdb_name = :1", o.Database)
+	}
+	return o.isRowExist(ctx, o.Service, admin, "SELECT username FROM dba_users WHERE username = :1", o.Database)
+}
+
+func (o Oracle) isUserExist(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) bool {
+	return o.isRowExist(ctx, o.dbService(), admin, "SELECT username FROM dba_users WHERE username = :1", user.Username)
+}
+
+func (o Oracle) isRoleGranted(ctx context.Context, admin *DatabaseUser, role, user string) bool {
+	return o.isRowExist(ctx, o.dbService(), admin, "SELECT granted_role FROM dba_role_privs WHERE granted_role = :1 AND grantee = :2", role, user)
+}
+
+func (o Oracle) createPluggableDatabase(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	// The PDB admin is required by the syntax, but it's not used by the operator,
+	// datafiles are placed according to OMF or PDB_FILE_NAME_CONVERT
+	create := fmt.Sprintf("CREATE PLUGGABLE DATABASE %s ADMIN USER %s IDENTIFIED BY %s",
+		oracleQuoteIdentifier(o.Database), oracleQuoteIdentifier(o.Database+"_ADMIN"), oracleQuoteIdentifier(kci.GeneratePass()))
+	open := fmt.Sprintf("ALTER PLUGGABLE DATABASE %s OPEN", oracleQuoteIdentifier(o.Database))
+	saveState := fmt.Sprintf("ALTER PLUGGABLE DATABASE %s SAVE STATE", oracleQuoteIdentifier(o.Database))
+
+	for _, query := range []string{create, open, saveState} {
+		if err := o.executeExec(ctx, o.Service, query, admin); err != nil {
+			log.Error(err, "failed creating pluggable database", "database", o.Database)
+			return err
+		}
+	}
+	return nil
+}
+
+func (o Oracle) createTablespaces(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	for _, tablespace := range o.Tablespaces {
+		if o.isRowExist(ctx, o.dbService(), admin, "SELECT tablespace_name FROM dba_tablespaces WHERE tablespace_name = :1", tablespace) {
+			continue
+		}
+		// Datafiles are managed by Oracle (OMF), so db_create_file_dest must be set
+		create := fmt.Sprintf("CREATE TABLESPACE %s", oracleQuoteIdentifier(tablespace))
+		if err := o.executeExec(ctx, o.dbService(), create, admin); err != nil {
+			log.Error(err, "failed creating tablespace", "tablespace", tablespace)
+			return err
+		}
+	}
+	return nil
+}
+
+func (o Oracle) createSchema(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	// The password is set when the main user is created
+	create := fmt.Sprintf("CREATE USER %s NO AUTHENTICATION", oracleQuoteIdentifier(o.Database))
+	if !o.isRowExist(ctx, o.dbService(), admin, "SELECT username FROM dba_users WHERE username = :1", o.Database) {
+		if err := o.executeExec(ctx, o.dbService(), create, admin); err != nil {
+			log.Error(err, "failed creating schema user", "schema", o.Database)
+			return err
+		}
+	}
+
+	queries := []string{}
+	if len(o.Tablespaces) > 0 {
+		queries = append(queries, fmt.Sprintf("ALTER USER %s DEFAULT TABLESPACE %s", oracleQuoteIdentifier(o.Database), oracleQuoteIdentifier(o.Tablespaces[0])))
+	}
+	for _, tablespace := range o.Tablespaces {
+		queries = append(queries, fmt.Sprintf("ALTER USER %s QUOTA UNLIMITED ON %s", oracleQuoteIdentifier(o.Database), oracleQuoteIdentifier(tablespace)))
+	}
+	for _, role := range []string{o.readOnlyRole(), o.readWriteRole()} {
+		if !o.isRowExist(ctx, o.dbService(), admin, "SELECT role FROM dba_roles WHERE role = :1", role) {
+			queries = append(queries, fmt.Sprintf("CREATE ROLE %s", oracleQuoteIdentifier(role)))
+		}
+	}
+
+	for _, query := range queries {
+		if err := o.executeExec(ctx, o.dbService(), query, admin); err != nil {
+			log.Error(err, "failed configuring schema", "query", query)
+			return err
+		}
+	}
+	return nil
+}
+
+// grantSchemaObjects grants privileges on existing tables and views of the schema to a role,
+// Oracle doesn't have default privileges, so it's repeated on every user reconciliation
+func (o Oracle) grantSchemaObjects(ctx context.Context, admin *DatabaseUser, role, tablePrivileges string) error {
+	// Quoted names are passed to EXECUTE IMMEDIATE as literals, object names are quoted by Oracle
+	grant := fmt.Sprintf(`BEGIN
+  FOR obj IN (SELECT object_name, object_type FROM dba_objects WHERE owner = %s AND object_type IN ('TABLE', 'VIEW')) LOOP
+    IF obj.object_type = 'TABLE' THEN
+      EXECUTE IMMEDIATE 'GRANT %s ON ' || %s || '.' || DBMS_ASSERT.ENQUOTE_NAME(obj.object_name, FALSE) || ' TO ' || %s;
+    ELSE
+      EXECUTE IMMEDIATE 'GRANT SELECT ON ' || %s || '.' || DBMS_ASSERT.ENQUOTE_NAME(obj.object_name, FALSE) || ' TO ' || %s;
+    END IF;
+  END LOOP;
+END;`,
+		oracleQuoteLiteral(o.Database),
+		tablePrivileges,
+		oracleQuoteLiteral(oracleQuoteIdentifier(o.Database)),
+		oracleQuoteLiteral(oracleQuoteIdentifier(role)),
+		oracleQuoteLiteral(oracleQuoteIdentifier(o.Database)),
+		oracleQuoteLiteral(oracleQuoteIdentifier(role)),
+	)
+
+	return o.executeExec(ctx, o.dbService(), grant, admin)
+}
+
+// accessRole returns the role that should be granted to the user
+// and the one that should be revoked from the user
+func (o Oracle) accessRole(user *DatabaseUser) (grant string, revoke string, err error) {
+	switch user.AccessType {
+	case ACCESS_TYPE_READWRITE:
+		return o.readWriteRole(), o.readOnlyRole(), nil
+	case ACCESS_TYPE_READONLY:
+		return o.readOnlyRole(), o.readWriteRole(), nil
+	default:
+		return "", "", fmt.Errorf("unknown access type: %s", user.AccessType)
+	}
+}
+
+// validateNames checks names that are quoted as identifiers, Oracle can't escape
+// double quotes in them, so they must be rejected before any statement is built
+func (o Oracle) validateNames(user *DatabaseUser) error {
+	names := append([]string{o.Database}, o.Tablespaces...)
+	names = append(names, o.Profiles...)
+	if user != nil {
+		names = append(names, user.Username)
+		if err := oracleValidateIdentifier(user.Password); err != nil {
+			return fmt.Errorf("invalid password of the user %s: %w", user.Username, err)
+		}
+		for _, privilege := range user.ExtraPrivileges {
+			if err := oracleValidatePrivilege(privilege); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range names {
+		if err := oracleValidateIdentifier(name); err != nil {
+			return fmt.Errorf("invalid name %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Functions that implement the `Database` interface
+
+// CheckStatus checks status of Oracle database
+// if the connection to database works
+func (o Oracle) CheckStatus(ctx context.Context, user *DatabaseUser) error {
+	db, err := o.getDbConn(o.dbService(), user.Username, user.Password)
+	if err != nil {
+		return fmt.Errorf("db conn test failed - couldn't get db conn: %s", err)
+	}
+	defer db.Close()
+	queryCtx, cancel := statementContext(ctx)
+	defer cancel()
+	res, err := db.QueryContext(queryCtx, "SELECT 1 FROM DUAL")
+	if err != nil {
+		return timeoutError(fmt.Errorf("db conn test failed - failed to execute query: %w", err))
+	}
+	res.Close()
+
+	return nil
+}
+
+// GetCredentials returns credentials of the Oracle database
+func (o Oracle) GetCredentials(ctx context.Context, user *DatabaseUser) Credentials {
+	return Credentials{
+		Name:     o.Database,
+		Username: user.Username,
+		Password: user.Password,
+	}
+}
+
+// ParseAdminCredentials parse admin username and password of Oracle database from secret data
+// If "user" key is not defined, take "SYSTEM" as admin user by default
+func (o Oracle) ParseAdminCredentials(ctx context.Context, data map[string][]byte) (*DatabaseUser, error) {
+	admin := &DatabaseUser{}
+
+	_, ok := data["user"]
+	if ok {
+		admin.Username = string(data["user"])
+	} else {
+		// default admin username is "SYSTEM"
+		admin.Username = "SYSTEM"
+	}
+
+	// if "password" key is defined in data, take value as password
+	_, ok = data["password"]
+	if ok {
+		admin.Password = string(data["password"])
+		return admin, nil
+	}
+
+	// take value of "ORACLE_PWD" or "ORACLE_PASSWORD" key as password if "password" key is not defined in data
+	// they're used by the official Oracle container images and the gvenzl/oracle-free images
+	for _, key := range []string{"ORACLE_PWD", "ORACLE_PASSWORD"} {
+		if pass, ok := data[key]; ok {
+			admin.Password = string(pass)
+			return admin, nil
+		}
+	}
+
+	return admin, errors.New("can not find oracle admin credentials")
+}
+
+func (o Oracle) GetDatabaseAddress(ctx context.Context) DatabaseAddress {
+	return DatabaseAddress{
+		Host: o.Host,
+		Port: o.Port,
+	}
+}
+
+func (o Oracle) QueryAsUser(ctx context.Context, query string, user *DatabaseUser) (string, error) {
+	log := log.FromContext(ctx)
+	db, err := o.getDbConn(o.dbService(), user.Username, user.Password)
+	if err != nil {
+		log.Error(err, "failed to open a db connection")
+		return "", err
+	}
+	defer db.Close()
+
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	var result string
+	if err := db.QueryRowContext(ctx, query).Scan(&result); err != nil {
+		log.Error(err, "failed executing query", "query", query)
+		return "", timeoutError(err)
+	}
+	return result, nil
+}
+
+func (o Oracle) createDatabase(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if err := o.validateNames(nil); err != nil {
+		return err
+	}
+	if o.PluggableDatabase && !o.isDbExist(ctx, admin) {
+		if err := o.createPluggableDatabase(ctx, admin); err != nil {
+			return err
+		}
+	}
+
+	if err := o.createTablespaces(ctx, admin); err != nil {
+		log.Error(err, "failed creating tablespaces")
+		return err
+	}
+
+	if err := o.createSchema(ctx, admin); err != nil {
+		log.Error(err, "failed creating oracle schema")
+		return err
+	}
+
+	return nil
+}
+
+func (o Oracle) deleteDatabase(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if err := o.validateNames(nil); err != nil {
+		return err
+	}
+	if !o.isDbExist(ctx, admin) {
+		return nil
+	}
+
+	queries := []string{}
+	if o.PluggableDatabase {
+		queries = append(queries,
+			fmt.Sprintf("ALTER PLUGGABLE DATABASE %s CLOSE IMMEDIATE", oracleQuoteIdentifier(o.Database)),
+			fmt.Sprintf("DROP PLUGGABLE DATABASE %s INCLUDING DATAFILES", oracleQuoteIdentifier(o.Database)),
+		)
+	} else {
+		// Sessions of the schema owner are blocking the removal
+		queries = append(queries,
+			fmt.Sprintf(`BEGIN
+  FOR s IN (SELECT sid, serial# AS serial FROM v$session WHERE username = %s) LOOP
+    EXECUTE IMMEDIATE 'ALTER SYSTEM KILL SESSION ''' || s.sid || ',' || s.serial || ''' IMMEDIATE';
+  END LOOP;
+END;`, oracleQuoteLiteral(o.Database)),
+			fmt.Sprintf("DROP USER %s CASCADE", oracleQuoteIdentifier(o.Database)),
+		)
+		for _, role := range []string{o.readOnlyRole(), o.readWriteRole()} {
+			if o.isRowExist(ctx, o.Service, admin, "SELECT role FROM dba_roles WHERE role = :1", role) {
+				queries = append(queries, fmt.Sprintf("DROP ROLE %s", oracleQuoteIdentifier(role)))
+			}
+		}
+	}
+
+	for _, query := range queries {
+		err := kci.Retry(3, 5*time.Second, func() error {
+			err := o.executeExec(ctx, o.Service, query, admin)
+			if err != nil {
+				// This error will result in a retry
+				log.V(2).Info("failed with error, retrying", "error", err)
+				return err
+			}
+
+			return nil
+		})
+		if err != nil {
+			log.Error(err, "failed deleting oracle database", "query", query)
+			return err
+		}
+	}
+	return nil
+}
+
+func (o Oracle) createOrUpdateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if !o.isUserExist(ctx, admin, user) {
+		if err := o.createUser(ctx, admin, user); err != nil {
+			log.Error(err, "failed creating oracle user")
+			return err
+		}
+	} else {
+		if err := o.updateUser(ctx, admin, user); err != nil {
+			log.Error(err, "failed updating oracle user")
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o Oracle) createUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if err := o.validateNames(user); err != nil {
+		return err
+	}
+	create := fmt.Sprintf("CREATE USER %s IDENTIFIED BY %s", oracleQuoteIdentifier(user.Username), oracleQuoteIdentifier(user.Password))
+
+	if !o.isUserExist(ctx, admin, user) {
+		if err := o.executeExec(ctx, o.dbService(), create, admin); err != nil {
+			log.Error(err, "failed creating oracle user")
+			return err
+		}
+	} else {
+		err := fmt.Errorf("user already exists: %s", user.Username)
+		return err
+	}
+
+	if err := o.setUserPermission(ctx, admin, user); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updateUser sets the password, the main user is the schema user,
+// that is created by createDatabase without authentication
+func (o Oracle) updateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if err := o.validateNames(user); err != nil {
+		return err
+	}
+	update := fmt.Sprintf("ALTER USER %s IDENTIFIED BY %s", oracleQuoteIdentifier(user.Username), oracleQuoteIdentifier(user.Password))
+
+	if !o.isUserExist(ctx, admin, user) {
+		err := fmt.Errorf("user doesn't exist yet: %s", user.Username)
+		return err
+	}
+
+	if err := o.executeExec(ctx, o.dbService(), update, admin); err != nil {
+		log.Error(err, "failed updating oracle user")
+		return err
+	}
+
+	if err := o.setUserPermission(ctx, admin, user); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o Oracle) setUserPermission(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if err := o.validateNames(user); err != nil {
+		return err
+	}
+	queries := []string{}
+
+	if user.AccessType == ACCESS_TYPE_MAINUSER {
+		if user.Username == o.Database {
+			queries = append(queries, fmt.Sprintf("GRANT %s TO %s", strings.Join(oracleOwnerPrivileges, ", "), oracleQuoteIdentifier(user.Username)))
+		} else {
+			// Only the schema user can own objects of the schema
+			queries = append(queries,
+				fmt.Sprintf("GRANT CREATE SESSION TO %s", oracleQuoteIdentifier(user.Username)),
+				fmt.Sprintf("GRANT %s TO %s", oracleQuoteIdentifier(o.readWriteRole()), oracleQuoteIdentifier(user.Username)),
+			)
+		}
+	} else {
+		grant, revoke, err := o.accessRole(user)
+		if err != nil {
+			return err
+		}
+		if err := o.grantSchemaObjects(ctx, admin, o.readOnlyRole(), "SELECT"); err != nil {
+			log.Error(err, "failed granting schema objects", "role", o.readOnlyRole())
+			return err
+		}
+		if err := o.grantSchemaObjects(ctx, admin, o.readWriteRole(), "SELECT, INSERT, UPDATE, DELETE"); err != nil {
+			log.Error(err, "failed granting schema objects", "role", o.readWriteRole())
+			return err
+		}
+		if o.isRoleGranted(ctx, admin, revoke, user.Username) {
+			queries = append(queries, fmt.Sprintf("REVOKE %s FROM %s", oracleQuoteIdentifier(revoke), oracleQuoteIdentifier(user.Username)))
+		}
+		queries = append(queries,
+			fmt.Sprintf("GRANT CREATE SESSION TO %s", oracleQuoteIdentifier(user.Username)),
+			fmt.Sprintf("GRANT %s TO %s", oracleQuoteIdentifier(grant), oracleQuoteIdentifier(user.Username)),
+		)
+	}
+
+	// Extra privileges are system privileges or roles that are allowed on the instance
+	for _, privilege := range user.ExtraPrivileges {
+		queries = append(queries, fmt.Sprintf("GRANT %s TO %s", privilege, oracleQuoteIdentifier(user.Username)))
+	}
+
+	// Only one profile can be assigned to a user
+	if len(o.Profiles) > 0 {
+		queries = append(queries, fmt.Sprintf("ALTER USER %s PROFILE %s", oracleQuoteIdentifier(user.Username), oracleQuoteIdentifier(o.Profiles[0])))
+	}
+
+	for _, query := range queries {
+		if err := o.executeExec(ctx, o.dbService(), query, admin); err != nil {
+			log.Error(err, "failed setting user permissions", "query", query)
+			return err
+		}
+	}
+	return nil
+}
+
+func (o Oracle) deleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if err := o.validateNames(user); err != nil {
+		return err
+	}
+	drop := fmt.Sprintf("DROP USER %s CASCADE", oracleQuoteIdentifier(user.Username))
+
+	// Users of a pluggable database are removed together with it
+	if !o.isDbExist(ctx, admin) && o.PluggableDatabase {
+		return nil
+	}
+
+	if o.isUserExist(ctx, admin, user) {
+		if err := o.executeExec(ctx, o.dbService(), drop, admin); err != nil {
+			log.Error(err, "failed deleting oracle user")
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,496 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/db-operator/db-operator/pkg/utils/database/pluginpb"
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Plugin is a database interface implementation that is driven
+// by an out-of-process engine over gRPC, the protocol is defined
+// in pluginpb/plugin.proto
+type Plugin struct {
+	EngineConfig
+}
+
+// PluginTLS configures the connection to a plugin. The CA of the server is read
+// from ca.crt of the secret, a client certificate for mTLS from tls.crt and tls.key.
+// Admin credentials are sent with every request, so plain text is only used,
+// when it's allowed explicitly by Insecure
+type PluginTLS struct {
+	Insecure        bool
+	SecretNamespace string
+	SecretName      string
+	// Overrides the name that is verified in the server certificate
+	ServerName string
+}
+
+// pluginSecrets reads secrets that are referenced by PluginTLS,
+// it's set by the operator on startup
+var pluginSecrets client.Reader
+
+// ConfigurePluginSecrets sets the reader of secrets with certificates of plugin connections
+func ConfigurePluginSecrets(reader client.Reader) {
+	pluginSecrets = reader
+}
+
+func init() {
+	Register(Engine{
+		Engine: engines.Plugin,
+		New:    newPlugin,
+	})
+}
+
+func newPlugin(ctx context.Context, cfg EngineConfig) (Database, error) {
+	return Plugin{EngineConfig: cfg}, nil
+}
+
+// Internal helpers, these functions are not part for the `Database` interface
+
+// transportCredentials returns credentials of the connection to the plugin
+func (p Plugin) transportCredentials(ctx context.Context) (credentials.TransportCredentials, error) {
+	if p.PluginTLS == nil {
+		return nil, errors.New("plugin tls is not set")
+	}
+	if p.PluginTLS.Insecure {
+		return insecure.NewCredentials(), nil
+	}
+	if pluginSecrets == nil {
+		return nil, errors.New("plugin secrets can't be read")
+	}
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: p.PluginTLS.SecretNamespace, Name: p.PluginTLS.SecretName}
+	if err := pluginSecrets.Get(ctx, key, secret); err != nil {
+		return nil, fmt.Errorf("can't get the plugin tls secret: %v", err)
+	}
+
+	config := &tls.Config{ServerName: p.PluginTLS.ServerName, MinVersion: tls.VersionTLS12}
+	if ca, ok := secret.Data["ca.crt"]; ok {
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.New("invalid ca.crt in the plugin tls secret")
+		}
+	}
+	if _, ok := secret.Data[corev1.TLSCertKey]; ok {
+		cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate in the plugin tls secret: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(config), nil
+}
+
+func (p Plugin) getClient(ctx context.Context) (pluginpb.EngineClient, *grpc.ClientConn, error) {
+	if len(p.PluginEndpoint) == 0 {
+		return nil, nil, errors.New("plugin endpoint is not set")
+	}
+	creds, err := p.transportCredentials(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := grpc.NewClient(p.PluginEndpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't connect to the plugin: %v", err)
+	}
+	return pluginpb.NewEngineClient(conn), conn, nil
+}
+
+// call opens a connection to the plugin and runs f with a client,
+// gRPC statuses are turned back into plain errors
+func (p Plugin) call(ctx context.Context, f func(client pluginpb.EngineClient) error) error {
+	log := log.FromContext(ctx)
+	client, conn, err := p.getClient(ctx)
+	if err != nil {
+		log.Error(err, "failed to open a plugin connection")
+		return err
+	}
+	defer conn.Close()
+
+	if err := f(client); err != nil {
+		st := status.Convert(err)
+		if st.Code() == codes.DeadlineExceeded {
+			return fmt.Errorf("%w: %s", ErrTimeout, strings.TrimPrefix(st.Message(), ErrTimeout.Error()+": "))
+		}
+		return errors.New(st.Message())
+	}
+	return nil
+}
+
+func (p Plugin) database() *pluginpb.Database {
+	return pluginDatabaseToProto(p.EngineConfig)
+}
+
+func pluginDatabaseToProto(cfg EngineConfig) *pluginpb.Database {
+	return &pluginpb.Database{
+		Backend:      cfg.Backend,
+		Host:         cfg.Host,
+		Port:         uint32(cfg.Port),
+		Name:         cfg.Database,
+		SslEnabled:   cfg.SSLEnabled,
+		SkipCaVerify: cfg.SkipCAVerify,
+		Monitoring:   cfg.Monitoring,
+		MainUser:     pluginUserToProto(cfg.MainUser),
+		Annotations:  cfg.Annotations,
+		Spec:         cfg.Spec,
+	}
+}
+
+func pluginDatabaseFromProto(db *pluginpb.Database) EngineConfig {
+	return EngineConfig{
+		Backend:      db.GetBackend(),
+		Host:         db.GetHost(),
+		Port:         uint16(db.GetPort()),
+		Database:     db.GetName(),
+		SSLEnabled:   db.GetSslEnabled(),
+		SkipCAVerify: db.GetSkipCaVerify(),
+		Monitoring:   db.GetMonitoring(),
+		MainUser:     pluginUserFromProto(db.GetMainUser()),
+		Annotations:  db.GetAnnotations(),
+		Spec:         db.GetSpec(),
+	}
+}
+
+func pluginUserToProto(user *DatabaseUser) *pluginpb.User {
+	if user == nil {
+		return nil
+	}
+	return &pluginpb.User{
+		Username:             user.Username,
+		Password:             user.Password,
+		AccessType:           user.AccessType,
+		ExtraPrivileges:      user.ExtraPrivileges,
+		GrantToAdmin:         user.GrantToAdmin,
+		GrantToAdminOnDelete: user.GrantToAdminOnDelete,
+		AccessProfile:        pluginAccessProfileToProto(user.AccessProfile),
+		DeletionPolicy:       user.DeletionPolicy,
+		Spec:                 user.Spec,
+	}
+}
+
+func pluginUserFromProto(user *pluginpb.User) *DatabaseUser {
+	if user == nil {
+		return nil
+	}
+	return &DatabaseUser{
+		Username:             user.GetUsername(),
+		Password:             user.GetPassword(),
+		AccessType:           user.GetAccessType(),
+		ExtraPrivileges:      user.GetExtraPrivileges(),
+		GrantToAdmin:         user.GetGrantToAdmin(),
+		GrantToAdminOnDelete: user.GetGrantToAdminOnDelete(),
+		AccessProfile:        pluginAccessProfileFromProto(user.GetAccessProfile()),
+		DeletionPolicy:       user.GetDeletionPolicy(),
+		Spec:                 user.GetSpec(),
+	}
+}
+
+func pluginAccessProfileToProto(profile *AccessProfile) *pluginpb.AccessProfile {
+	if profile == nil {
+		return nil
+	}
+	return &pluginpb.AccessProfile{
+		Name:      profile.Name,
+		Database:  profile.Database,
+		Schemas:   profile.Schemas,
+		Tables:    profile.Tables,
+		Sequences: profile.Sequences,
+		Functions: profile.Functions,
+		DefaultPrivileges: &pluginpb.DefaultPrivileges{
+			Tables:    profile.DefaultPrivileges.Tables,
+			Sequences: profile.DefaultPrivileges.Sequences,
+			Functions: profile.DefaultPrivileges.Functions,
+		},
+	}
+}
+
+func pluginAccessProfileFromProto(profile *pluginpb.AccessProfile) *AccessProfile {
+	if profile == nil {
+		return nil
+	}
+	return &AccessProfile{
+		Name:      profile.GetName(),
+		Database:  profile.GetDatabase(),
+		Schemas:   profile.GetSchemas(),
+		Tables:    profile.GetTables(),
+		Sequences: profile.GetSequences(),
+		Functions: profile.GetFunctions(),
+		DefaultPrivileges: DefaultPrivileges{
+			Tables:    profile.GetDefaultPrivileges().GetTables(),
+			Sequences: profile.GetDefaultPrivileges().GetSequences(),
+			Functions: profile.GetDefaultPrivileges().GetFunctions(),
+		},
+	}
+}
+
+// Functions that implement the `Database` interface
+
+// CheckStatus checks status of the database through the plugin
+func (p Plugin) CheckStatus(ctx context.Context, user *DatabaseUser) error {
+	return p.call(ctx, func(client pluginpb.EngineClient) error {
+		_, err := client.CheckStatus(ctx, &pluginpb.UserRequest{Database: p.database(), User: pluginUserToProto(user)})
+		return err
+	})
+}
+
+// GetCredentials returns credentials of the database
+func (p Plugin) GetCredentials(ctx context.Context, user *DatabaseUser) Credentials {
+	return Credentials{
+		Name:     p.Database,
+		Username: user.Username,
+		Password: user.Password,
+	}
+}
+
+// ParseAdminCredentials parse admin username and password of the plugin engine from secret data,
+// it's not sent to the plugin, because it's also used before an instance is known
+func (p Plugin) ParseAdminCredentials(ctx context.Context, data map[string][]byte) (*DatabaseUser, error) {
+	admin := &DatabaseUser{}
+
+	_, ok := data["user"]
+	if ok {
+		admin.Username = string(data["user"])
+	} else {
+		return nil, errors.New("can't find user in the admin secret")
+	}
+
+	_, ok = data["password"]
+	if ok {
+		admin.Password = string(data["password"])
+		return admin, nil
+	}
+
+	return nil, errors.New("can't find password in the admin secret")
+}
+
+// GetDatabaseAddress returns the address of the database
+func (p Plugin) GetDatabaseAddress(ctx context.Context) DatabaseAddress {
+	return DatabaseAddress{
+		Host: p.Host,
+		Port: p.Port,
+	}
+}
+
+// QueryAsUser executes a query through the plugin and returns its result
+func (p Plugin) QueryAsUser(ctx context.Context, query string, user *DatabaseUser) (string, error) {
+	var result string
+	err := p.call(ctx, func(client pluginpb.EngineClient) error {
+		res, err := client.QueryAsUser(ctx, &pluginpb.QueryRequest{Database: p.database(), User: pluginUserToProto(user), Query: query})
+		result = res.GetResult()
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+func (p Plugin) execAsUser(ctx context.Context, query string, user *DatabaseUser) error {
+	return p.call(ctx, func(client pluginpb.EngineClient) error {
+		_, err := client.ExecAsUser(ctx, &pluginpb.QueryRequest{Database: p.database(), User: pluginUserToProto(user), Query: query})
+		return err
+	})
+}
+
+func (p Plugin) createDatabase(ctx context.Context, admin *DatabaseUser) error {
+	return p.call(ctx, func(client pluginpb.EngineClient) error {
+		_, err := client.CreateDatabase(ctx, &pluginpb.AdminRequest{Database: p.database(), Admin: pluginUserToProto(admin)})
+		return err
+	})
+}
+
+func (p Plugin) deleteDatabase(ctx context.Context, admin *DatabaseUser) error {
+	return p.call(ctx, func(client pluginpb.EngineClient) error {
+		_, err := client.DeleteDatabase(ctx, &pluginpb.AdminRequest{Database: p.database(), Admin: pluginUserToProto(admin)})
+		return err
+	})
+}
+
+func (p Plugin) adminUserRequest(admin *DatabaseUser, user *DatabaseUser) *pluginpb.AdminUserRequest {
+	return &pluginpb.AdminUserRequest{
+		Database: p.database(),
+		Admin:    pluginUserToProto(admin),
+		User:     pluginUserToProto(user),
+	}
+}
+
+func (p Plugin) createOrUpdateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return p.call(ctx, func(client pluginpb.EngineClient) error {
+		_, err := client.CreateOrUpdateUser(ctx, p.adminUserRequest(admin, user))
+		return err
+	})
+}
+
+func (p Plugin) createUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return p.call(ctx, func(client pluginpb.EngineClient) error {
+		_, err := client.CreateUser(ctx, p.adminUserRequest(admin, user))
+		return err
+	})
+}
+
+func (p Plugin) updateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return p.call(ctx, func(client pluginpb.EngineClient) error {
+		_, err := client.UpdateUser(ctx, p.adminUserRequest(admin, user))
+		return err
+	})
+}
+
+func (p Plugin) deleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return p.call(ctx, func(client pluginpb.EngineClient) error {
+		_, err := client.DeleteUser(ctx, p.adminUserRequest(admin, user))
+		return err
+	})
+}
+
+func (p Plugin) setUserPermission(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return p.call(ctx, func(client pluginpb.EngineClient) error {
+		_, err := client.SetUserPermission(ctx, p.adminUserRequest(admin, user))
+		return err
+	})
+}
+
+// PluginServer serves databases over the plugin protocol,
+// it can be used to build out-of-process engines
+type PluginServer struct {
+	pluginpb.UnimplementedEngineServer
+	// New builds a Database for every request
+	New func(ctx context.Context, cfg EngineConfig) (Database, error)
+}
+
+// NewPluginServer returns a PluginServer that is registered in the grpc server
+func NewPluginServer(srv *grpc.Server, newDatabase func(ctx context.Context, cfg EngineConfig) (Database, error)) *PluginServer {
+	s := &PluginServer{New: newDatabase}
+	pluginpb.RegisterEngineServer(srv, s)
+	return s
+}
+
+// pluginStatusError keeps the timeout class of an error, when it's sent to the operator
+func pluginStatusError(err error) error {
+	if IsTimeout(err) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return err
+}
+
+func (s *PluginServer) database(ctx context.Context, db *pluginpb.Database) (Database, error) {
+	if db == nil {
+		return nil, errors.New("database is not set in the request")
+	}
+	return s.New(ctx, pluginDatabaseFromProto(db))
+}
+
+func (s *PluginServer) CheckStatus(ctx context.Context, req *pluginpb.UserRequest) (*emptypb.Empty, error) {
+	db, err := s.database(ctx, req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, pluginStatusError(db.CheckStatus(ctx, pluginUserFromProto(req.GetUser())))
+}
+
+func (s *PluginServer) QueryAsUser(ctx context.Context, req *pluginpb.QueryRequest) (*pluginpb.QueryResponse, error) {
+	db, err := s.database(ctx, req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	result, err := db.QueryAsUser(ctx, req.GetQuery(), pluginUserFromProto(req.GetUser()))
+	if err != nil {
+		return nil, pluginStatusError(err)
+	}
+	return &pluginpb.QueryResponse{Result: result}, nil
+}
+
+func (s *PluginServer) ExecAsUser(ctx context.Context, req *pluginpb.QueryRequest) (*emptypb.Empty, error) {
+	db, err := s.database(ctx, req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, pluginStatusError(db.execAsUser(ctx, req.GetQuery(), pluginUserFromProto(req.GetUser())))
+}
+
+func (s *PluginServer) CreateDatabase(ctx context.Context, req *pluginpb.AdminRequest) (*emptypb.Empty, error) {
+	db, err := s.database(ctx, req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, pluginStatusError(db.createDatabase(ctx, pluginUserFromProto(req.GetAdmin())))
+}
+
+func (s *PluginServer) DeleteDatabase(ctx context.Context, req *pluginpb.AdminRequest) (*emptypb.Empty, error) {
+	db, err := s.database(ctx, req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, pluginStatusError(db.deleteDatabase(ctx, pluginUserFromProto(req.GetAdmin())))
+}
+
+func (s *PluginServer) CreateOrUpdateUser(ctx context.Context, req *pluginpb.AdminUserRequest) (*emptypb.Empty, error) {
+	db, err := s.database(ctx, req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, pluginStatusError(db.createOrUpdateUser(ctx, pluginUserFromProto(req.GetAdmin()), pluginUserFromProto(req.GetUser())))
+}
+
+func (s *PluginServer) CreateUser(ctx context.Context, req *pluginpb.AdminUserRequest) (*emptypb.Empty, error) {
+	db, err := s.database(ctx, req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, pluginStatusError(db.createUser(ctx, pluginUserFromProto(req.GetAdmin()), pluginUserFromProto(req.GetUser())))
+}
+
+func (s *PluginServer) UpdateUser(ctx context.Context, req *pluginpb.AdminUserRequest) (*emptypb.Empty, error) {
+	db, err := s.database(ctx, req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, pluginStatusError(db.updateUser(ctx, pluginUserFromProto(req.GetAdmin()), pluginUserFromProto(req.GetUser())))
+}
+
+func (s *PluginServer) DeleteUser(ctx context.Context, req *pluginpb.AdminUserRequest) (*emptypb.Empty, error) {
+	db, err := s.database(ctx, req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, pluginStatusError(db.deleteUser(ctx, pluginUserFromProto(req.GetAdmin()), pluginUserFromProto(req.GetUser())))
+}
+
+func (s *PluginServer) SetUserPermission(ctx context.Context, req *pluginpb.AdminUserRequest) (*emptypb.Empty, error) {
+	db, err := s.database(ctx, req.GetDatabase())
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, pluginStatusError(db.setUserPermission(ctx, pluginUserFromProto(req.GetAdmin()), pluginUserFromProto(req.GetUser())))
+}
@@ -0,0 +1,296 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// testPluginServer serves the dummy over the plugin protocol and returns
+// a plugin database that is connected to it, requests are recorded
+func testPluginServer(t *testing.T, dummy Dummy) (Plugin, *[]EngineConfig) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	requests := []EngineConfig{}
+	srv := grpc.NewServer()
+	NewPluginServer(srv, func(ctx context.Context, cfg EngineConfig) (Database, error) {
+		requests = append(requests, cfg)
+		return dummy, nil
+	})
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	db, err := newPlugin(context.TODO(), EngineConfig{
+		Host:           "127.0.0.1",
+		Port:           4242,
+		Database:       "testdb",
+		SSLEnabled:     true,
+		Annotations:    map[string]string{"key": "value"},
+		Spec:           []byte(`{"key":"value"}`),
+		PluginEndpoint: lis.Addr().String(),
+		PluginTLS:      &PluginTLS{Insecure: true},
+	})
+	assert.NoError(t, err)
+	return db.(Plugin), &requests
+}
+
+// testConformance runs every function of the `Database` interface against
+// the expected and the actual implementations and compares the results
+func testConformance(t *testing.T, expected Database, actual Database) {
+	ctx := context.TODO()
+	admin := &DatabaseUser{Username: "admin", Password: "adminpwd"}
+	user := NewDummyUser(ACCESS_TYPE_READWRITE)
+	user.ExtraPrivileges = []string{"extra"}
+
+	calls := map[string]func(db Database) (string, error){
+		"CheckStatus": func(db Database) (string, error) {
+			return "", db.CheckStatus(ctx, user)
+		},
+		"QueryAsUser": func(db Database) (string, error) {
+			return db.QueryAsUser(ctx, "SELECT 1", user)
+		},
+		"execAsUser": func(db Database) (string, error) {
+			return "", db.execAsUser(ctx, "SELECT 1", user)
+		},
+		"CreateDatabase": func(db Database) (string, error) {
+			return "", CreateDatabase(ctx, db, admin)
+		},
+		"DeleteDatabase": func(db Database) (string, error) {
+			return "", DeleteDatabase(ctx, db, admin)
+		},
+		"CreateOrUpdateUser": func(db Database) (string, error) {
+			return "", CreateOrUpdateUser(ctx, db, user, admin)
+		},
+		"CreateUser": func(db Database) (string, error) {
+			return "", CreateUser(ctx, db, user, admin)
+		},
+		"updateUser": func(db Database) (string, error) {
+			return "", db.updateUser(ctx, admin, user)
+		},
+		"DeleteUser": func(db Database) (string, error) {
+			return "", DeleteUser(ctx, db, user, admin)
+		},
+		"setUserPermission": func(db Database) (string, error) {
+			return "", db.setUserPermission(ctx, admin, user)
+		},
+	}
+
+	for name, call := range calls {
+		expectedRes, expectedErr := call(expected)
+		actualRes, actualErr := call(actual)
+		assert.Equal(t, expectedRes, actualRes, name)
+		if expectedErr == nil {
+			assert.NoError(t, actualErr, name)
+		} else {
+			assert.EqualError(t, actualErr, expectedErr.Error(), name)
+		}
+	}
+}
+
+func TestPluginConformance(t *testing.T) {
+	dummy := Dummy{}
+	p, requests := testPluginServer(t, dummy)
+
+	testConformance(t, dummy, p)
+
+	// Every request is sent with the database, so the plugin doesn't need a state
+	assert.Len(t, *requests, 10)
+	for _, req := range *requests {
+		assert.Equal(t, "127.0.0.1", req.Host)
+		assert.Equal(t, uint16(4242), req.Port)
+		assert.Equal(t, "testdb", req.Database)
+		assert.True(t, req.SSLEnabled)
+		assert.Equal(t, map[string]string{"key": "value"}, req.Annotations)
+		assert.Equal(t, []byte(`{"key":"value"}`), req.Spec)
+	}
+}
+
+func TestPluginConformanceErrors(t *testing.T) {
+	dummy := Dummy{Error: errors.New("dummy error")}
+	p, _ := testPluginServer(t, dummy)
+
+	testConformance(t, dummy, p)
+}
+
+func TestPluginNoEndpoint(t *testing.T) {
+	p := New("plugin")
+	assert.NotNil(t, p)
+
+	err := p.CheckStatus(context.TODO(), NewDummyUser(ACCESS_TYPE_MAINUSER))
+	assert.EqualError(t, err, "plugin endpoint is not set")
+}
+
+func TestPluginNoTLS(t *testing.T) {
+	p := Plugin{EngineConfig: EngineConfig{PluginEndpoint: "127.0.0.1:4242"}}
+
+	err := p.CheckStatus(context.TODO(), NewDummyUser(ACCESS_TYPE_MAINUSER))
+	assert.EqualError(t, err, "plugin tls is not set")
+}
+
+// testCertificate issues a certificate for 127.0.0.1 that is signed by the parent,
+// or a self-signed ca, when the parent is not set. It returns the certificate and
+// the key in the PEM format
+func testCertificate(t *testing.T, name string, parent *tls.Certificate) (tls.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, any(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	assert.NoError(t, err)
+	cert.Leaf, err = x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert, certPEM, keyPEM
+}
+
+func TestPluginMutualTLS(t *testing.T) {
+	ca, caPEM, _ := testCertificate(t, "ca", nil)
+	serverCert, _, _ := testCertificate(t, "server", &ca)
+	_, clientPEM, clientKeyPEM := testCertificate(t, "client", &ca)
+
+	caPool := x509.NewCertPool()
+	caPool.AddCert(ca.Leaf)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    caPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})))
+	NewPluginServer(srv, func(ctx context.Context, cfg EngineConfig) (Database, error) {
+		return Dummy{}, nil
+	})
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	secret := func(name string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "plugins", Name: name}, Data: data}
+	}
+	reader := fake.NewClientBuilder().WithObjects(
+		secret("mtls", map[string][]byte{
+			"ca.crt":                caPEM,
+			corev1.TLSCertKey:       clientPEM,
+			corev1.TLSPrivateKeyKey: clientKeyPEM,
+		}),
+		secret("ca-only", map[string][]byte{"ca.crt": caPEM}),
+	).Build()
+	previous := pluginSecrets
+	ConfigurePluginSecrets(reader)
+	t.Cleanup(func() { ConfigurePluginSecrets(previous) })
+
+	status := func(pluginTLS *PluginTLS) error {
+		p := Plugin{EngineConfig: EngineConfig{PluginEndpoint: lis.Addr().String(), PluginTLS: pluginTLS}}
+		return p.CheckStatus(context.TODO(), NewDummyUser(ACCESS_TYPE_MAINUSER))
+	}
+	assert.NoError(t, status(&PluginTLS{SecretNamespace: "plugins", SecretName: "mtls"}))
+	// The server requires a client certificate
+	assert.Error(t, status(&PluginTLS{SecretNamespace: "plugins", SecretName: "ca-only"}))
+	// The server is not serving plain text
+	assert.Error(t, status(&PluginTLS{Insecure: true}))
+	// The certificate is verified against the server name
+	assert.Error(t, status(&PluginTLS{SecretNamespace: "plugins", SecretName: "mtls", ServerName: "other.example.com"}))
+	assert.ErrorContains(t, status(&PluginTLS{SecretNamespace: "plugins", SecretName: "missing"}), "can't get the plugin tls secret")
+}
+
+func TestPluginUserProto(t *testing.T) {
+	user := &DatabaseUser{
+		Username:        "user",
+		Password:        "password",
+		AccessType:      "app-readwrite",
+		ExtraPrivileges: []string{"role"},
+		AccessProfile: &AccessProfile{
+			Name:     "app-readwrite",
+			Database: []string{"CONNECT"},
+			Schemas:  []string{"USAGE"},
+			Tables:   []string{"SELECT", "INSERT"},
+			DefaultPrivileges: DefaultPrivileges{
+				Tables: []string{"SELECT"},
+			},
+		},
+		DeletionPolicy: "reassign",
+		Spec:           []byte(`{"connectionLimit":5}`),
+	}
+	assert.Equal(t, user, pluginUserFromProto(pluginUserToProto(user)))
+	assert.Nil(t, pluginUserFromProto(pluginUserToProto(nil)))
+
+	user.AccessProfile = nil
+	assert.Equal(t, user, pluginUserFromProto(pluginUserToProto(user)))
+}
+
+func TestPluginParseAdminCredentials(t *testing.T) {
+	p := New("plugin")
+
+	_, err := p.ParseAdminCredentials(context.TODO(), map[string][]byte{"user": []byte("admin")})
+	assert.Error(t, err)
+
+	admin, err := p.ParseAdminCredentials(context.TODO(), map[string][]byte{"user": []byte("admin"), "password": []byte("pwd")})
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", admin.Username)
+	assert.Equal(t, "pwd", admin.Password)
+}
+
+func TestPluginConformanceTimeout(t *testing.T) {
+	dummy := Dummy{Error: timeoutError(context.DeadlineExceeded)}
+	p, _ := testPluginServer(t, dummy)
+
+	testConformance(t, dummy, p)
+	assert.True(t, IsTimeout(p.CheckStatus(context.TODO(), NewDummyUser(ACCESS_TYPE_MAINUSER))))
+}
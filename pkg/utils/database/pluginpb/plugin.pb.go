@@ -0,0 +1,859 @@
+// Copyright 2024 Datacosmos
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: plugin.proto
+
+package pluginpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Database is sent with every request, so plugins can stay stateless
+type Database struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Backend      string                 `protobuf:"bytes,1,opt,name=backend,proto3" json:"backend,omitempty"`
+	Host         string                 `protobuf:"bytes,2,opt,name=host,proto3" json:"host,omitempty"`
+	Port         uint32                 `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	Name         string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	SslEnabled   bool                   `protobuf:"varint,5,opt,name=ssl_enabled,json=sslEnabled,proto3" json:"ssl_enabled,omitempty"`
+	SkipCaVerify bool                   `protobuf:"varint,6,opt,name=skip_ca_verify,json=skipCaVerify,proto3" json:"skip_ca_verify,omitempty"`
+	Monitoring   bool                   `protobuf:"varint,7,opt,name=monitoring,proto3" json:"monitoring,omitempty"`
+	MainUser     *User                  `protobuf:"bytes,8,opt,name=main_user,json=mainUser,proto3" json:"main_user,omitempty"`
+	// Annotations of the DbInstance
+	Annotations map[string]string `protobuf:"bytes,9,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Engine specific part of the Database spec as JSON
+	Spec          []byte `protobuf:"bytes,10,opt,name=spec,proto3" json:"spec,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Database) Reset() {
+	*x = Database{}
+	mi := &file_plugin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Database) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Database) ProtoMessage() {}
+
+func (x *Database) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Database.ProtoReflect.Descriptor instead.
+func (*Database) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Database) GetBackend() string {
+	if x != nil {
+		return x.Backend
+	}
+	return ""
+}
+
+func (x *Database) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *Database) GetPort() uint32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *Database) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Database) GetSslEnabled() bool {
+	if x != nil {
+		return x.SslEnabled
+	}
+	return false
+}
+
+func (x *Database) GetSkipCaVerify() bool {
+	if x != nil {
+		return x.SkipCaVerify
+	}
+	return false
+}
+
+func (x *Database) GetMonitoring() bool {
+	if x != nil {
+		return x.Monitoring
+	}
+	return false
+}
+
+func (x *Database) GetMainUser() *User {
+	if x != nil {
+		return x.MainUser
+	}
+	return nil
+}
+
+func (x *Database) GetAnnotations() map[string]string {
+	if x != nil {
+		return x.Annotations
+	}
+	return nil
+}
+
+func (x *Database) GetSpec() []byte {
+	if x != nil {
+		return x.Spec
+	}
+	return nil
+}
+
+type User struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Username string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Password string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	// readOnly, readWrite or main
+	AccessType           string   `protobuf:"bytes,3,opt,name=access_type,json=accessType,proto3" json:"access_type,omitempty"`
+	ExtraPrivileges      []string `protobuf:"bytes,4,rep,name=extra_privileges,json=extraPrivileges,proto3" json:"extra_privileges,omitempty"`
+	GrantToAdmin         bool     `protobuf:"varint,5,opt,name=grant_to_admin,json=grantToAdmin,proto3" json:"grant_to_admin,omitempty"`
+	GrantToAdminOnDelete bool     `protobuf:"varint,6,opt,name=grant_to_admin_on_delete,json=grantToAdminOnDelete,proto3" json:"grant_to_admin_on_delete,omitempty"`
+	// Set when the access type references a DbAccessProfile
+	AccessProfile *AccessProfile `protobuf:"bytes,7,opt,name=access_profile,json=accessProfile,proto3" json:"access_profile,omitempty"`
+	// Overrides the deletion policy of the database for the user
+	DeletionPolicy string `protobuf:"bytes,8,opt,name=deletion_policy,json=deletionPolicy,proto3" json:"deletion_policy,omitempty"`
+	// Engine specific part of the DbUser spec as JSON
+	Spec          []byte `protobuf:"bytes,9,opt,name=spec,proto3" json:"spec,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *User) Reset() {
+	*x = User{}
+	mi := &file_plugin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *User) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*User) ProtoMessage() {}
+
+func (x *User) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use User.ProtoReflect.Descriptor instead.
+func (*User) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *User) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *User) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *User) GetAccessType() string {
+	if x != nil {
+		return x.AccessType
+	}
+	return ""
+}
+
+func (x *User) GetExtraPrivileges() []string {
+	if x != nil {
+		return x.ExtraPrivileges
+	}
+	return nil
+}
+
+func (x *User) GetGrantToAdmin() bool {
+	if x != nil {
+		return x.GrantToAdmin
+	}
+	return false
+}
+
+func (x *User) GetGrantToAdminOnDelete() bool {
+	if x != nil {
+		return x.GrantToAdminOnDelete
+	}
+	return false
+}
+
+func (x *User) GetAccessProfile() *AccessProfile {
+	if x != nil {
+		return x.AccessProfile
+	}
+	return nil
+}
+
+func (x *User) GetDeletionPolicy() string {
+	if x != nil {
+		return x.DeletionPolicy
+	}
+	return ""
+}
+
+func (x *User) GetSpec() []byte {
+	if x != nil {
+		return x.Spec
+	}
+	return nil
+}
+
+// AccessProfile lists privileges that are granted per kind of objects
+type AccessProfile struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Name      string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Database  []string               `protobuf:"bytes,2,rep,name=database,proto3" json:"database,omitempty"`
+	Schemas   []string               `protobuf:"bytes,3,rep,name=schemas,proto3" json:"schemas,omitempty"`
+	Tables    []string               `protobuf:"bytes,4,rep,name=tables,proto3" json:"tables,omitempty"`
+	Sequences []string               `protobuf:"bytes,5,rep,name=sequences,proto3" json:"sequences,omitempty"`
+	Functions []string               `protobuf:"bytes,6,rep,name=functions,proto3" json:"functions,omitempty"`
+	// Privileges on objects that are created by the main user later
+	DefaultPrivileges *DefaultPrivileges `protobuf:"bytes,7,opt,name=default_privileges,json=defaultPrivileges,proto3" json:"default_privileges,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *AccessProfile) Reset() {
+	*x = AccessProfile{}
+	mi := &file_plugin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AccessProfile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccessProfile) ProtoMessage() {}
+
+func (x *AccessProfile) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccessProfile.ProtoReflect.Descriptor instead.
+func (*AccessProfile) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AccessProfile) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *AccessProfile) GetDatabase() []string {
+	if x != nil {
+		return x.Database
+	}
+	return nil
+}
+
+func (x *AccessProfile) GetSchemas() []string {
+	if x != nil {
+		return x.Schemas
+	}
+	return nil
+}
+
+func (x *AccessProfile) GetTables() []string {
+	if x != nil {
+		return x.Tables
+	}
+	return nil
+}
+
+func (x *AccessProfile) GetSequences() []string {
+	if x != nil {
+		return x.Sequences
+	}
+	return nil
+}
+
+func (x *AccessProfile) GetFunctions() []string {
+	if x != nil {
+		return x.Functions
+	}
+	return nil
+}
+
+func (x *AccessProfile) GetDefaultPrivileges() *DefaultPrivileges {
+	if x != nil {
+		return x.DefaultPrivileges
+	}
+	return nil
+}
+
+type DefaultPrivileges struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tables        []string               `protobuf:"bytes,1,rep,name=tables,proto3" json:"tables,omitempty"`
+	Sequences     []string               `protobuf:"bytes,2,rep,name=sequences,proto3" json:"sequences,omitempty"`
+	Functions     []string               `protobuf:"bytes,3,rep,name=functions,proto3" json:"functions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DefaultPrivileges) Reset() {
+	*x = DefaultPrivileges{}
+	mi := &file_plugin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DefaultPrivileges) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DefaultPrivileges) ProtoMessage() {}
+
+func (x *DefaultPrivileges) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DefaultPrivileges.ProtoReflect.Descriptor instead.
+func (*DefaultPrivileges) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DefaultPrivileges) GetTables() []string {
+	if x != nil {
+		return x.Tables
+	}
+	return nil
+}
+
+func (x *DefaultPrivileges) GetSequences() []string {
+	if x != nil {
+		return x.Sequences
+	}
+	return nil
+}
+
+func (x *DefaultPrivileges) GetFunctions() []string {
+	if x != nil {
+		return x.Functions
+	}
+	return nil
+}
+
+type UserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Database      *Database              `protobuf:"bytes,1,opt,name=database,proto3" json:"database,omitempty"`
+	User          *User                  `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserRequest) Reset() {
+	*x = UserRequest{}
+	mi := &file_plugin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserRequest) ProtoMessage() {}
+
+func (x *UserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserRequest.ProtoReflect.Descriptor instead.
+func (*UserRequest) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UserRequest) GetDatabase() *Database {
+	if x != nil {
+		return x.Database
+	}
+	return nil
+}
+
+func (x *UserRequest) GetUser() *User {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+type AdminRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Database      *Database              `protobuf:"bytes,1,opt,name=database,proto3" json:"database,omitempty"`
+	Admin         *User                  `protobuf:"bytes,2,opt,name=admin,proto3" json:"admin,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AdminRequest) Reset() {
+	*x = AdminRequest{}
+	mi := &file_plugin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AdminRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdminRequest) ProtoMessage() {}
+
+func (x *AdminRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdminRequest.ProtoReflect.Descriptor instead.
+func (*AdminRequest) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AdminRequest) GetDatabase() *Database {
+	if x != nil {
+		return x.Database
+	}
+	return nil
+}
+
+func (x *AdminRequest) GetAdmin() *User {
+	if x != nil {
+		return x.Admin
+	}
+	return nil
+}
+
+type AdminUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Database      *Database              `protobuf:"bytes,1,opt,name=database,proto3" json:"database,omitempty"`
+	Admin         *User                  `protobuf:"bytes,2,opt,name=admin,proto3" json:"admin,omitempty"`
+	User          *User                  `protobuf:"bytes,3,opt,name=user,proto3" json:"user,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AdminUserRequest) Reset() {
+	*x = AdminUserRequest{}
+	mi := &file_plugin_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AdminUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdminUserRequest) ProtoMessage() {}
+
+func (x *AdminUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdminUserRequest.ProtoReflect.Descriptor instead.
+func (*AdminUserRequest) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AdminUserRequest) GetDatabase() *Database {
+	if x != nil {
+		return x.Database
+	}
+	return nil
+}
+
+func (x *AdminUserRequest) GetAdmin() *User {
+	if x != nil {
+		return x.Admin
+	}
+	return nil
+}
+
+func (x *AdminUserRequest) GetUser() *User {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+type QueryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Database      *Database              `protobuf:"bytes,1,opt,name=database,proto3" json:"database,omitempty"`
+	User          *User                  `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	Query         string                 `protobuf:"bytes,3,opt,name=query,proto3" json:"query,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_plugin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *QueryRequest) GetDatabase() *Database {
+	if x != nil {
+		return x.Database
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetUser() *User {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+type QueryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_plugin_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *QueryResponse) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+var File_plugin_proto protoreflect.FileDescriptor
+
+const file_plugin_proto_rawDesc = "" +
+	"\n" +
+	"\fplugin.proto\x12\x14dboperator.plugin.v1\x1a\x1bgoogle/protobuf/empty.proto\"\xa7\x03\n" +
+	"\bDatabase\x12\x18\n" +
+	"\abackend\x18\x01 \x01(\tR\abackend\x12\x12\n" +
+	"\x04host\x18\x02 \x01(\tR\x04host\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\rR\x04port\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\x12\x1f\n" +
+	"\vssl_enabled\x18\x05 \x01(\bR\n" +
+	"sslEnabled\x12$\n" +
+	"\x0eskip_ca_verify\x18\x06 \x01(\bR\fskipCaVerify\x12\x1e\n" +
+	"\n" +
+	"monitoring\x18\a \x01(\bR\n" +
+	"monitoring\x127\n" +
+	"\tmain_user\x18\b \x01(\v2\x1a.dboperator.plugin.v1.UserR\bmainUser\x12Q\n" +
+	"\vannotations\x18\t \x03(\v2/.dboperator.plugin.v1.Database.AnnotationsEntryR\vannotations\x12\x12\n" +
+	"\x04spec\x18\n" +
+	" \x01(\fR\x04spec\x1a>\n" +
+	"\x10AnnotationsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xf1\x02\n" +
+	"\x04User\x12\x1a\n" +
+	"\busername\x18\x01 \x01(\tR\busername\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\x12\x1f\n" +
+	"\vaccess_type\x18\x03 \x01(\tR\n" +
+	"accessType\x12)\n" +
+	"\x10extra_privileges\x18\x04 \x03(\tR\x0fextraPrivileges\x12$\n" +
+	"\x0egrant_to_admin\x18\x05 \x01(\bR\fgrantToAdmin\x126\n" +
+	"\x18grant_to_admin_on_delete\x18\x06 \x01(\bR\x14grantToAdminOnDelete\x12J\n" +
+	"\x0eaccess_profile\x18\a \x01(\v2#.dboperator.plugin.v1.AccessProfileR\raccessProfile\x12'\n" +
+	"\x0fdeletion_policy\x18\b \x01(\tR\x0edeletionPolicy\x12\x12\n" +
+	"\x04spec\x18\t \x01(\fR\x04spec\"\x85\x02\n" +
+	"\rAccessProfile\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1a\n" +
+	"\bdatabase\x18\x02 \x03(\tR\bdatabase\x12\x18\n" +
+	"\aschemas\x18\x03 \x03(\tR\aschemas\x12\x16\n" +
+	"\x06tables\x18\x04 \x03(\tR\x06tables\x12\x1c\n" +
+	"\tsequences\x18\x05 \x03(\tR\tsequences\x12\x1c\n" +
+	"\tfunctions\x18\x06 \x03(\tR\tfunctions\x12V\n" +
+	"\x12default_privileges\x18\a \x01(\v2'.dboperator.plugin.v1.DefaultPrivilegesR\x11defaultPrivileges\"g\n" +
+	"\x11DefaultPrivileges\x12\x16\n" +
+	"\x06tables\x18\x01 \x03(\tR\x06tables\x12\x1c\n" +
+	"\tsequences\x18\x02 \x03(\tR\tsequences\x12\x1c\n" +
+	"\tfunctions\x18\x03 \x03(\tR\tfunctions\"y\n" +
+	"\vUserRequest\x12:\n" +
+	"\bdatabase\x18\x01 \x01(\v2\x1e.dboperator.plugin.v1.DatabaseR\bdatabase\x12.\n" +
+	"\x04user\x18\x02 \x01(\v2\x1a.dboperator.plugin.v1.UserR\x04user\"|\n" +
+	"\fAdminRequest\x12:\n" +
+	"\bdatabase\x18\x01 \x01(\v2\x1e.dboperator.plugin.v1.DatabaseR\bdatabase\x120\n" +
+	"\x05admin\x18\x02 \x01(\v2\x1a.dboperator.plugin.v1.UserR\x05admin\"\xb0\x01\n" +
+	"\x10AdminUserRequest\x12:\n" +
+	"\bdatabase\x18\x01 \x01(\v2\x1e.dboperator.plugin.v1.DatabaseR\bdatabase\x120\n" +
+	"\x05admin\x18\x02 \x01(\v2\x1a.dboperator.plugin.v1.UserR\x05admin\x12.\n" +
+	"\x04user\x18\x03 \x01(\v2\x1a.dboperator.plugin.v1.UserR\x04user\"\x90\x01\n" +
+	"\fQueryRequest\x12:\n" +
+	"\bdatabase\x18\x01 \x01(\v2\x1e.dboperator.plugin.v1.DatabaseR\bdatabase\x12.\n" +
+	"\x04user\x18\x02 \x01(\v2\x1a.dboperator.plugin.v1.UserR\x04user\x12\x14\n" +
+	"\x05query\x18\x03 \x01(\tR\x05query\"'\n" +
+	"\rQueryResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\tR\x06result2\xa5\x06\n" +
+	"\x06Engine\x12H\n" +
+	"\vCheckStatus\x12!.dboperator.plugin.v1.UserRequest\x1a\x16.google.protobuf.Empty\x12V\n" +
+	"\vQueryAsUser\x12\".dboperator.plugin.v1.QueryRequest\x1a#.dboperator.plugin.v1.QueryResponse\x12H\n" +
+	"\n" +
+	"ExecAsUser\x12\".dboperator.plugin.v1.QueryRequest\x1a\x16.google.protobuf.Empty\x12L\n" +
+	"\x0eCreateDatabase\x12\".dboperator.plugin.v1.AdminRequest\x1a\x16.google.protobuf.Empty\x12L\n" +
+	"\x0eDeleteDatabase\x12\".dboperator.plugin.v1.AdminRequest\x1a\x16.google.protobuf.Empty\x12T\n" +
+	"\x12CreateOrUpdateUser\x12&.dboperator.plugin.v1.AdminUserRequest\x1a\x16.google.protobuf.Empty\x12L\n" +
+	"\n" +
+	"CreateUser\x12&.dboperator.plugin.v1.AdminUserRequest\x1a\x16.google.protobuf.Empty\x12L\n" +
+	"\n" +
+	"UpdateUser\x12&.dboperator.plugin.v1.AdminUserRequest\x1a\x16.google.protobuf.Empty\x12L\n" +
+	"\n" +
+	"DeleteUser\x12&.dboperator.plugin.v1.AdminUserRequest\x1a\x16.google.protobuf.Empty\x12S\n" +
+	"\x11SetUserPermission\x12&.dboperator.plugin.v1.AdminUserRequest\x1a\x16.google.protobuf.EmptyB@Z>github.com/db-operator/db-operator/pkg/utils/database/pluginpbb\x06proto3"
+
+var (
+	file_plugin_proto_rawDescOnce sync.Once
+	file_plugin_proto_rawDescData []byte
+)
+
+func file_plugin_proto_rawDescGZIP() []byte {
+	file_plugin_proto_rawDescOnce.Do(func() {
+		file_plugin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_plugin_proto_rawDesc), len(file_plugin_proto_rawDesc)))
+	})
+	return file_plugin_proto_rawDescData
+}
+
+var file_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_plugin_proto_goTypes = []any{
+	(*Database)(nil),          // 0: dboperator.plugin.v1.Database
+	(*User)(nil),              // 1: dboperator.plugin.v1.User
+	(*AccessProfile)(nil),     // 2: dboperator.plugin.v1.AccessProfile
+	(*DefaultPrivileges)(nil), // 3: dboperator.plugin.v1.DefaultPrivileges
+	(*UserRequest)(nil),       // 4: dboperator.plugin.v1.UserRequest
+	(*AdminRequest)(nil),      // 5: dboperator.plugin.v1.AdminRequest
+	(*AdminUserRequest)(nil),  // 6: dboperator.plugin.v1.AdminUserRequest
+	(*QueryRequest)(nil),      // 7: dboperator.plugin.v1.QueryRequest
+	(*QueryResponse)(nil),     // 8: dboperator.plugin.v1.QueryResponse
+	nil,                       // 9: dboperator.plugin.v1.Database.AnnotationsEntry
+	(*emptypb.Empty)(nil),     // 10: google.protobuf.Empty
+}
+var file_plugin_proto_depIdxs = []int32{
+	1,  // 0: dboperator.plugin.v1.Database.main_user:type_name -> dboperator.plugin.v1.User
+	9,  // 1: dboperator.plugin.v1.Database.annotations:type_name -> dboperator.plugin.v1.Database.AnnotationsEntry
+	2,  // 2: dboperator.plugin.v1.User.access_profile:type_name -> dboperator.plugin.v1.AccessProfile
+	3,  // 3: dboperator.plugin.v1.AccessProfile.default_privileges:type_name -> dboperator.plugin.v1.DefaultPrivileges
+	0,  // 4: dboperator.plugin.v1.UserRequest.database:type_name -> dboperator.plugin.v1.Database
+	1,  // 5: dboperator.plugin.v1.UserRequest.user:type_name -> dboperator.plugin.v1.User
+	0,  // 6: dboperator.plugin.v1.AdminRequest.database:type_name -> dboperator.plugin.v1.Database
+	1,  // 7: dboperator.plugin.v1.AdminRequest.admin:type_name -> dboperator.plugin.v1.User
+	0,  // 8: dboperator.plugin.v1.AdminUserRequest.database:type_name -> dboperator.plugin.v1.Database
+	1,  // 9: dboperator.plugin.v1.AdminUserRequest.admin:type_name -> dboperator.plugin.v1.User
+	1,  // 10: dboperator.plugin.v1.AdminUserRequest.user:type_name -> dboperator.plugin.v1.User
+	0,  // 11: dboperator.plugin.v1.QueryRequest.database:type_name -> dboperator.plugin.v1.Database
+	1,  // 12: dboperator.plugin.v1.QueryRequest.user:type_name -> dboperator.plugin.v1.User
+	4,  // 13: dboperator.plugin.v1.Engine.CheckStatus:input_type -> dboperator.plugin.v1.UserRequest
+	7,  // 14: dboperator.plugin.v1.Engine.QueryAsUser:input_type -> dboperator.plugin.v1.QueryRequest
+	7,  // 15: dboperator.plugin.v1.Engine.ExecAsUser:input_type -> dboperator.plugin.v1.QueryRequest
+	5,  // 16: dboperator.plugin.v1.Engine.CreateDatabase:input_type -> dboperator.plugin.v1.AdminRequest
+	5,  // 17: dboperator.plugin.v1.Engine.DeleteDatabase:input_type -> dboperator.plugin.v1.AdminRequest
+	6,  // 18: dboperator.plugin.v1.Engine.CreateOrUpdateUser:input_type -> dboperator.plugin.v1.AdminUserRequest
+	6,  // 19: dboperator.plugin.v1.Engine.CreateUser:input_type -> dboperator.plugin.v1.AdminUserRequest
+	6,  // 20: dboperator.plugin.v1.Engine.UpdateUser:input_type -> dboperator.plugin.v1.AdminUserRequest
+	6,  // 21: dboperator.plugin.v1.Engine.DeleteUser:input_type -> dboperator.plugin.v1.AdminUserRequest
+	6,  // 22: dboperator.plugin.v1.Engine.SetUserPermission:input_type -> dboperator.plugin.v1.AdminUserRequest
+	10, // 23: dboperator.plugin.v1.Engine.CheckStatus:output_type -> google.protobuf.Empty
+	8,  // 24: dboperator.plugin.v1.Engine.QueryAsUser:output_type -> dboperator.plugin.v1.QueryResponse
+	10, // 25: dboperator.plugin.v1.Engine.ExecAsUser:output_type -> google.protobuf.Empty
+	10, // 26: dboperator.plugin.v1.Engine.CreateDatabase:output_type -> google.protobuf.Empty
+	10, // 27: dboperator.plugin.v1.Engine.DeleteDatabase:output_type -> google.protobuf.Empty
+	10, // 28: dboperator.plugin.v1.Engine.CreateOrUpdateUser:output_type -> google.protobuf.Empty
+	10, // 29: dboperator.plugin.v1.Engine.CreateUser:output_type -> google.protobuf.Empty
+	10, // 30: dboperator.plugin.v1.Engine.UpdateUser:output_type -> google.protobuf.Empty
+	10, // 31: dboperator.plugin.v1.Engine.DeleteUser:output_type -> google.protobuf.Empty
+	10, // 32: dboperator.plugin.v1.Engine.SetUserPermission:output_type -> google.protobuf.Empty
+	23, // [23:33] is the sub-list for method output_type
+	13, // [13:23] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_plugin_proto_init() }
+func file_plugin_proto_init() {
+	if File_plugin_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugin_proto_rawDesc), len(file_plugin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_plugin_proto_goTypes,
+		DependencyIndexes: file_plugin_proto_depIdxs,
+		MessageInfos:      file_plugin_proto_msgTypes,
+	}.Build()
+	File_plugin_proto = out.File
+	file_plugin_proto_goTypes = nil
+	file_plugin_proto_depIdxs = nil
+}
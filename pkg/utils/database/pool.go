@@ -0,0 +1,290 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolConfig bounds connection pools that are cached by a ConnectionManager
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+	// Pools are opened per database and user, so the number of pools of an instance
+	// is bounded, least recently used idle pools are closed when there are more
+	MaxPoolsPerInstance int
+}
+
+// DefaultPoolConfig is used when the operator config doesn't set limits
+var DefaultPoolConfig = PoolConfig{
+	MaxOpenConns:        4,
+	MaxIdleConns:        2,
+	ConnMaxIdleTime:     5 * time.Minute,
+	ConnMaxLifetime:     30 * time.Minute,
+	MaxPoolsPerInstance: 16,
+}
+
+// sqlOpen opens connections of sql engines, tests are replacing it
+// to run engines against an embedded stand-in of a database server
+var sqlOpen = sql.Open
+
+// Connections are shared by all engines, so reconciliations of databases
+// on the same instance are reusing admin connections
+var Connections = NewConnectionManager(DefaultPoolConfig)
+
+// connKey identifies a pool, a new pool is opened for every combination
+// of an instance, a database and a user
+type connKey struct {
+	Instance string
+	Database string
+	User     string
+}
+
+type connPool struct {
+	db *sql.DB
+	// A connection string that was used to open the pool, if it's changed,
+	// e.g. the admin password or the instance address, the pool is reopened
+	dsn string
+	// A value of the manager clock, when the pool was used the last time
+	used uint64
+}
+
+// ConnectionManager caches *sql.DB pools, it implements prometheus.Collector
+// to export the pool usage
+type ConnectionManager struct {
+	mu     sync.Mutex
+	config PoolConfig
+	pools  map[connKey]*connPool
+	// Incremented on every use of a pool to find the least recently used ones
+	clock uint64
+}
+
+// NewConnectionManager creates an empty ConnectionManager
+func NewConnectionManager(config PoolConfig) *ConnectionManager {
+	return &ConnectionManager{
+		config: config,
+		pools:  map[connKey]*connPool{},
+	}
+}
+
+// Configure changes limits of existing and new pools
+func (m *ConnectionManager) Configure(config PoolConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = config
+	instances := map[string]bool{}
+	for key, pool := range m.pools {
+		m.applyConfig(pool.db)
+		instances[key.Instance] = true
+	}
+	for instance := range instances {
+		m.evict(instance)
+	}
+}
+
+// Invalidate closes all pools of an instance, it should be called
+// when the instance address or the admin credentials are changed
+func (m *ConnectionManager) Invalidate(instance string) {
+	m.invalidate(func(key connKey) bool {
+		return key.Instance == instance
+	})
+}
+
+// Close closes all pools
+func (m *ConnectionManager) Close() {
+	m.invalidate(func(key connKey) bool {
+		return true
+	})
+}
+
+// invalidateDatabase closes pools that are connected to a database,
+// open connections are blocking the database removal on some engines
+func (m *ConnectionManager) invalidateDatabase(instance, database string) {
+	m.invalidate(func(key connKey) bool {
+		return key.Instance == instance && key.Database == database
+	})
+}
+
+// invalidateUser closes pools of a user that is going to be removed
+func (m *ConnectionManager) invalidateUser(instance, user string) {
+	m.invalidate(func(key connKey) bool {
+		return key.Instance == instance && key.User == user
+	})
+}
+
+func (m *ConnectionManager) invalidate(match func(key connKey) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, pool := range m.pools {
+		if match(key) {
+			pool.db.Close()
+			delete(m.pools, key)
+		}
+	}
+}
+
+// get returns a cached pool or opens a new one, open is called without
+// holding the lock, because some drivers are connecting right away
+func (m *ConnectionManager) get(key connKey, dsn string, open func() (*sql.DB, error)) (*sql.DB, error) {
+	m.mu.Lock()
+	if pool, ok := m.pools[key]; ok && pool.dsn == dsn {
+		m.clock++
+		pool.used = m.clock
+		m.mu.Unlock()
+		return pool.db, nil
+	}
+	m.mu.Unlock()
+
+	db, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pool, ok := m.pools[key]; ok {
+		if pool.dsn == dsn {
+			// Another reconciliation has opened the same pool in the meantime
+			db.Close()
+			m.clock++
+			pool.used = m.clock
+			return pool.db, nil
+		}
+		pool.db.Close()
+	}
+	m.applyConfig(db)
+	m.clock++
+	m.pools[key] = &connPool{db: db, dsn: dsn, used: m.clock}
+	m.evict(key.Instance)
+	return db, nil
+}
+
+// evict closes least recently used pools of the instance, until it has no more pools
+// than allowed. Pools with connections in use are kept, so the limit can be exceeded
+// for a while, when all of them are busy. The lock must be held by the caller
+func (m *ConnectionManager) evict(instance string) {
+	if m.config.MaxPoolsPerInstance <= 0 {
+		return
+	}
+	keys := []connKey{}
+	for key := range m.pools {
+		if key.Instance == instance {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b connKey) int {
+		return cmp.Compare(m.pools[a].used, m.pools[b].used)
+	})
+	open := len(keys)
+	for _, key := range keys {
+		if open <= m.config.MaxPoolsPerInstance {
+			return
+		}
+		pool := m.pools[key]
+		if pool.db.Stats().InUse > 0 {
+			continue
+		}
+		pool.db.Close()
+		delete(m.pools, key)
+		open--
+	}
+}
+
+func (m *ConnectionManager) applyConfig(db *sql.DB) {
+	db.SetMaxOpenConns(m.config.MaxOpenConns)
+	db.SetMaxIdleConns(m.config.MaxIdleConns)
+	db.SetConnMaxIdleTime(m.config.ConnMaxIdleTime)
+	db.SetConnMaxLifetime(m.config.ConnMaxLifetime)
+}
+
+// poolInstance returns a name that is used to group pools of an instance,
+// it's the DbInstance name, if it's known, or the instance address
+func poolInstance(instance, host string, port uint16) string {
+	if len(instance) > 0 {
+		return instance
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+var (
+	poolLabels             = []string{"instance", "database", "user"}
+	poolOpenConnectionDesc = prometheus.NewDesc(
+		"db_operator_connection_pool_open_connections",
+		"Number of established connections, both in use and idle",
+		poolLabels, nil,
+	)
+	poolInUseConnectionDesc = prometheus.NewDesc(
+		"db_operator_connection_pool_in_use_connections",
+		"Number of connections that are currently in use",
+		poolLabels, nil,
+	)
+	poolIdleConnectionDesc = prometheus.NewDesc(
+		"db_operator_connection_pool_idle_connections",
+		"Number of idle connections",
+		poolLabels, nil,
+	)
+	poolMaxOpenConnectionDesc = prometheus.NewDesc(
+		"db_operator_connection_pool_max_open_connections",
+		"Maximum number of open connections",
+		poolLabels, nil,
+	)
+	poolWaitCountDesc = prometheus.NewDesc(
+		"db_operator_connection_pool_wait_count_total",
+		"Total number of connections that had to be waited for",
+		poolLabels, nil,
+	)
+	poolWaitDurationDesc = prometheus.NewDesc(
+		"db_operator_connection_pool_wait_seconds_total",
+		"Total time spent waiting for connections",
+		poolLabels, nil,
+	)
+)
+
+// Describe implements prometheus.Collector
+func (m *ConnectionManager) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolOpenConnectionDesc
+	ch <- poolInUseConnectionDesc
+	ch <- poolIdleConnectionDesc
+	ch <- poolMaxOpenConnectionDesc
+	ch <- poolWaitCountDesc
+	ch <- poolWaitDurationDesc
+}
+
+// Collect implements prometheus.Collector
+func (m *ConnectionManager) Collect(ch chan<- prometheus.Metric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, pool := range m.pools {
+		stats := pool.db.Stats()
+		labels := []string{key.Instance, key.Database, key.User}
+		ch <- prometheus.MustNewConstMetric(poolOpenConnectionDesc, prometheus.GaugeValue, float64(stats.OpenConnections), labels...)
+		ch <- prometheus.MustNewConstMetric(poolInUseConnectionDesc, prometheus.GaugeValue, float64(stats.InUse), labels...)
+		ch <- prometheus.MustNewConstMetric(poolIdleConnectionDesc, prometheus.GaugeValue, float64(stats.Idle), labels...)
+		ch <- prometheus.MustNewConstMetric(poolMaxOpenConnectionDesc, prometheus.GaugeValue, float64(stats.MaxOpenConnections), labels...)
+		ch <- prometheus.MustNewConstMetric(poolWaitCountDesc, prometheus.CounterValue, float64(stats.WaitCount), labels...)
+		ch <- prometheus.MustNewConstMetric(poolWaitDurationDesc, prometheus.CounterValue, stats.WaitDuration.Seconds(), labels...)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// testOpen doesn't connect anywhere, postgres pools are only connecting on the first query
+func testOpen(dsn string) func() (*sql.DB, error) {
+	return func() (*sql.DB, error) {
+		return sql.Open("postgres", dsn)
+	}
+}
+
+func assertClosed(t *testing.T, db *sql.DB) {
+	t.Helper()
+	assert.EqualError(t, db.Ping(), "sql: database is closed")
+}
+
+func TestPoolReuse(t *testing.T) {
+	m := NewConnectionManager(DefaultPoolConfig)
+	defer m.Close()
+	key := connKey{Instance: "test", Database: "postgres", User: "admin"}
+
+	first, err := m.get(key, "host=a", testOpen("host=a"))
+	assert.NoError(t, err)
+	second, err := m.get(key, "host=a", testOpen("host=a"))
+	assert.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, DefaultPoolConfig.MaxOpenConns, first.Stats().MaxOpenConnections)
+
+	// A changed password or address reopens the pool
+	third, err := m.get(key, "host=b", testOpen("host=b"))
+	assert.NoError(t, err)
+	assert.NotSame(t, first, third)
+	assertClosed(t, first)
+}
+
+func TestPoolInvalidate(t *testing.T) {
+	m := NewConnectionManager(DefaultPoolConfig)
+	defer m.Close()
+	open := func(key connKey) *sql.DB {
+		db, err := m.get(key, "host=a", testOpen("host=a"))
+		assert.NoError(t, err)
+		return db
+	}
+
+	adminDb := open(connKey{Instance: "test", Database: "db", User: "admin"})
+	open(connKey{Instance: "test", Database: "postgres", User: "admin"})
+	userDb := open(connKey{Instance: "test", Database: "db", User: "user"})
+	other := open(connKey{Instance: "other", Database: "db", User: "admin"})
+
+	m.invalidateDatabase("test", "db")
+	assertClosed(t, adminDb)
+	assertClosed(t, userDb)
+	assert.Len(t, m.pools, 2)
+
+	m.invalidateUser("test", "admin")
+	assert.Len(t, m.pools, 1)
+
+	m.Invalidate("other")
+	assertClosed(t, other)
+	assert.Empty(t, m.pools)
+}
+
+func TestPoolConfigure(t *testing.T) {
+	m := NewConnectionManager(DefaultPoolConfig)
+	defer m.Close()
+	db, err := m.get(connKey{Instance: "test"}, "host=a", testOpen("host=a"))
+	assert.NoError(t, err)
+
+	m.Configure(PoolConfig{MaxOpenConns: 10, MaxIdleConns: 1, ConnMaxIdleTime: time.Minute, ConnMaxLifetime: time.Hour})
+	assert.Equal(t, 10, db.Stats().MaxOpenConnections)
+}
+
+func TestPoolEviction(t *testing.T) {
+	config := DefaultPoolConfig
+	config.MaxPoolsPerInstance = 2
+	m := NewConnectionManager(config)
+	defer m.Close()
+	open := func(key connKey) *sql.DB {
+		db, err := m.get(key, "host=a", testOpen("host=a"))
+		assert.NoError(t, err)
+		return db
+	}
+
+	first := open(connKey{Instance: "test", Database: "first", User: "admin"})
+	second := open(connKey{Instance: "test", Database: "second", User: "admin"})
+	other := open(connKey{Instance: "other", Database: "first", User: "admin"})
+	// The first pool is used again, so the second one is the least recently used
+	assert.Same(t, first, open(connKey{Instance: "test", Database: "first", User: "admin"}))
+
+	open(connKey{Instance: "test", Database: "third", User: "admin"})
+	assertClosed(t, second)
+	assert.Same(t, first, m.pools[connKey{Instance: "test", Database: "first", User: "admin"}].db, "the recently used pool must be kept")
+	assert.Same(t, other, m.pools[connKey{Instance: "other", Database: "first", User: "admin"}].db, "pools of other instances are not counted")
+	assert.Len(t, m.pools, 3)
+
+	// A lower limit is applied to existing pools
+	config.MaxPoolsPerInstance = 1
+	m.Configure(config)
+	assertClosed(t, first)
+	assert.Len(t, m.pools, 2)
+}
+
+func TestPoolMetrics(t *testing.T) {
+	m := NewConnectionManager(DefaultPoolConfig)
+	defer m.Close()
+	assert.Equal(t, 0, testutil.CollectAndCount(m))
+
+	_, err := m.get(connKey{Instance: "test", Database: "db", User: "admin"}, "host=a", testOpen("host=a"))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, testutil.CollectAndCount(m))
+	assert.Equal(t, 1, testutil.CollectAndCount(m, "db_operator_connection_pool_max_open_connections"))
+}
+
+func TestPoolInstance(t *testing.T) {
+	assert.Equal(t, "test", poolInstance("test", "localhost", 5432))
+	assert.Equal(t, "localhost:5432", poolInstance("", "localhost", 5432))
+}
@@ -22,10 +22,15 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	// Don't delete below package. Used for driver "cloudsqlpostgres"
 	_ "github.com/GoogleCloudPlatform/cloudsql-proxy/proxy/dialers/postgres"
+	"github.com/db-operator/db-operator/pkg/consts"
+	"github.com/db-operator/db-operator/pkg/utils/engines"
 	"github.com/db-operator/db-operator/pkg/utils/kci"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -37,17 +42,40 @@ import (
 // represents a database on postgres instance
 // can be used to execute query to postgres database
 type Postgres struct {
+	// Name of the DbInstance, admin connections are pooled per instance
+	Instance         string `json:"-"`
 	Backend          string
 	Host             string
 	Port             uint16
 	Database         string
 	Monitoring       bool
-	Extensions       []string
+	Extensions       []string            `json:"extensions"`
+	ExtensionConfigs []postgresExtension `json:"extensionConfigs"`
+	PruneExtensions  bool                `json:"pruneExtensions"`
 	SSLEnabled       bool
 	SkipCAVerify     bool
-	DropPublicSchema bool
-	Schemas          []string
-	Template         string
+	DropPublicSchema bool     `json:"dropPublicSchema"`
+	Schemas          []string `json:"schemas"`
+	Template         string   `json:"template"`
+	// What happens to schemas that are removed from the spec
+	SchemaPrunePolicy string `json:"schemaPrunePolicy"`
+	// Roles that create objects in the database besides the main user,
+	// default privileges of users are set for objects of all of them
+	ObjectOwners []string `json:"objectOwners"`
+	// Users that can create objects are acting as the main user,
+	// so all objects in the database are owned by the main user
+	EnforceMainUserOwnership bool `json:"enforceMainUserOwnership"`
+	// What happens to objects of users that are removed, unless it's set per user
+	DeletionPolicy string `json:"deletionPolicy"`
+	// Options that can only be set when the database is created
+	Encoding  string `json:"encoding"`
+	LcCollate string `json:"lcCollate"`
+	LcCtype   string `json:"lcCtype"`
+	IcuLocale string `json:"icuLocale"`
+	// Options that are applied on every reconciliation
+	ConnectionLimit *int              `json:"connectionLimit"`
+	Owner           string            `json:"owner"`
+	Settings        map[string]string `json:"settings"`
 	// A user that is created with the Database
 	//  it's required to set default privileges
 	//  for additional users
@@ -59,6 +87,76 @@ type Postgres struct {
 	RDSIAMImpersonateWorkaround bool
 }
 
+// postgresExtension is an extension from the spec, versions and
+// schemas are only changed when they're set
+type postgresExtension struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Schema  string `json:"schema"`
+}
+
+// postgresUserSpec is the postgres specific part of the DbUser spec
+type postgresUserSpec struct {
+	ConnectionLimit *int              `json:"connectionLimit"`
+	ValidUntil      *time.Time        `json:"validUntil"`
+	Settings        map[string]string `json:"settings"`
+}
+
+// Built-in access types of postgres users, sequences are required to insert into
+// tables with serial or identity columns, and functions to call stored procedures
+var postgresAccessProfiles = map[string]AccessProfile{
+	ACCESS_TYPE_READONLY: {
+		Name:      ACCESS_TYPE_READONLY,
+		Schemas:   []string{"USAGE"},
+		Tables:    []string{"SELECT"},
+		Sequences: []string{"USAGE", "SELECT"},
+		Functions: []string{"EXECUTE"},
+		DefaultPrivileges: DefaultPrivileges{
+			Tables:    []string{"SELECT"},
+			Sequences: []string{"USAGE", "SELECT"},
+			Functions: []string{"EXECUTE"},
+		},
+	},
+	ACCESS_TYPE_READWRITE: {
+		Name:      ACCESS_TYPE_READWRITE,
+		Schemas:   []string{"USAGE"},
+		Tables:    []string{"SELECT", "INSERT", "DELETE", "UPDATE"},
+		Sequences: []string{"USAGE", "SELECT", "UPDATE"},
+		Functions: []string{"EXECUTE"},
+		DefaultPrivileges: DefaultPrivileges{
+			Tables:    []string{"SELECT", "INSERT", "DELETE", "UPDATE"},
+			Sequences: []string{"USAGE", "SELECT", "UPDATE"},
+			Functions: []string{"EXECUTE"},
+		},
+	},
+}
+
+func init() {
+	Register(Engine{
+		Engine:          engines.Postgres,
+		New:             newPostgres,
+		BackupContainer: postgresBackupContainer,
+	})
+}
+
+func newPostgres(ctx context.Context, cfg EngineConfig) (Database, error) {
+	p := Postgres{}
+	if err := cfg.decodeSpec(&p); err != nil {
+		return nil, err
+	}
+	p.Instance = cfg.Instance
+	p.Backend = cfg.Backend
+	p.Host = cfg.Host
+	p.Port = cfg.Port
+	p.Database = cfg.Database
+	p.Monitoring = cfg.Monitoring
+	p.SSLEnabled = cfg.SSLEnabled
+	p.SkipCAVerify = cfg.SkipCAVerify
+	p.MainUser = cfg.MainUser
+	p.RDSIAMImpersonateWorkaround = cfg.annotationBool(ctx, consts.RDS_IAM_IMPERSONATE_WORKAROUND)
+	return p, nil
+}
+
 const postgresDefaultSSLMode = "disable"
 
 // Internal helpers, these functions are not part for the `Database` interface
@@ -79,8 +177,8 @@ func (p Postgres) sslMode() string {
 	return postgresDefaultSSLMode
 }
 
-func (p Postgres) getDbConn(dbname, user, password string) (*sql.DB, error) {
-	var db *sql.DB
+// dataSource returns a driver name and a connection string
+func (p Postgres) dataSource(dbname, user, password string) (string, string) {
 	var sqldriver string
 
 	switch p.Backend {
@@ -90,8 +188,30 @@ func (p Postgres) getDbConn(dbname, user, password string) (*sql.DB, error) {
 		sqldriver = "postgres"
 	}
 
-	dataSourceName := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=%s", p.Host, p.Port, dbname, user, password, p.sslMode())
-	db, err := sql.Open(sqldriver, dataSourceName)
+	dataSourceName := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=%s",
+		postgresQuoteConnValue(p.Host),
+		p.Port,
+		postgresQuoteConnValue(dbname),
+		postgresQuoteConnValue(user),
+		postgresQuoteConnValue(password),
+		p.sslMode(),
+	)
+
+	// Timeouts are also set on the server side, so statements are cancelled
+	// even if the cancel request from the driver doesn't reach the server
+	timeouts := currentTimeouts()
+	if timeouts.Connect > 0 {
+		dataSourceName += fmt.Sprintf(" connect_timeout=%d", timeoutSeconds(timeouts.Connect))
+	}
+	if timeouts.Statement > 0 {
+		dataSourceName += fmt.Sprintf(" statement_timeout=%d", timeouts.Statement.Milliseconds())
+	}
+	return sqldriver, dataSourceName
+}
+
+func (p Postgres) getDbConn(dbname, user, password string) (*sql.DB, error) {
+	sqldriver, dataSourceName := p.dataSource(dbname, user, password)
+	db, err := sqlOpen(sqldriver, dataSourceName)
 	if err != nil {
 		return nil, fmt.Errorf("sql.Open: %v", err)
 	}
@@ -99,20 +219,83 @@ func (p Postgres) getDbConn(dbname, user, password string) (*sql.DB, error) {
 	return db, err
 }
 
+// getPooledConn returns a connection pool that is shared between reconciliations,
+// it must not be closed by the caller
+func (p Postgres) getPooledConn(dbname, user, password string) (*sql.DB, error) {
+	sqldriver, dataSourceName := p.dataSource(dbname, user, password)
+	key := connKey{Instance: p.poolInstance(), Database: dbname, User: user}
+	return Connections.get(key, sqldriver+" "+dataSourceName, func() (*sql.DB, error) {
+		return p.getDbConn(dbname, user, password)
+	})
+}
+
+func (p Postgres) poolInstance() string {
+	return poolInstance(p.Instance, p.Host, p.Port)
+}
+
+// executeExec executes a DDL query in a transaction, that limits how long it waits for locks
 func (p Postgres) executeExec(ctx context.Context, database, query string, admin *DatabaseUser) error {
+	return p.executeExecInTx(ctx, database, admin, query)
+}
+
+// executeExecOutsideTx executes a query that can't run in a transaction, like CREATE DATABASE,
+// so the lock timeout is not set for it
+func (p Postgres) executeExecOutsideTx(ctx context.Context, database, query string, admin *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	db, err := p.getDbConn(database, admin.Username, admin.Password)
+	db, err := p.getPooledConn(database, admin.Username, admin.Password)
 	if err != nil {
 		log.Error(err, "failed to open a db connection")
 		return err
 	}
 
-	defer db.Close()
-	_, err = db.Exec(query)
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	_, err = db.ExecContext(ctx, query)
 
+	return timeoutError(err)
+}
+
+// setLockTimeout limits how long DDL of the transaction waits for locks, so it fails instead
+// of queueing behind long transactions and blocking other sessions, that are queued behind it
+func setLockTimeout(ctx context.Context, tx *sql.Tx) error {
+	timeout := currentTimeouts().Lock
+	if timeout <= 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL lock_timeout = %d;", timeout.Milliseconds()))
 	return err
 }
 
+// executeExecInTx executes queries in one transaction, so none of them is applied when one fails
+func (p Postgres) executeExecInTx(ctx context.Context, database string, admin *DatabaseUser, queries ...string) error {
+	log := log.FromContext(ctx)
+	db, err := p.getPooledConn(database, admin.Username, admin.Password)
+	if err != nil {
+		log.Error(err, "failed to open a db connection")
+		return err
+	}
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error(err, "failed to begin a transaction")
+		return timeoutError(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err := setLockTimeout(ctx, tx); err != nil {
+		log.Error(err, "failed to set the lock timeout")
+		return timeoutError(err)
+	}
+	for _, query := range queries {
+		if _, err = tx.ExecContext(ctx, query); err != nil {
+			return timeoutError(err)
+		}
+	}
+
+	return timeoutError(tx.Commit())
+}
+
 func (p Postgres) execAsUser(ctx context.Context, query string, user *DatabaseUser) error {
 	log := log.FromContext(ctx)
 	db, err := p.getDbConn(p.Database, user.Username, user.Password)
@@ -122,54 +305,73 @@ func (p Postgres) execAsUser(ctx context.Context, query string, user *DatabaseUs
 	}
 
 	defer db.Close()
-	_, err = db.Exec(query)
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	_, err = db.ExecContext(ctx, query)
 
-	return err
+	return timeoutError(err)
 }
 
+// execSettingRole executes the query as the user, the role is only set
+// for the transaction, so it doesn't leak to other users of the pool
 func (p Postgres) execSettingRole(ctx context.Context, database, query string, user *DatabaseUser, admin *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	setUserRole := fmt.Sprintf("SET ROLE \"%s\"", user.Username)
-	db, err := p.getDbConn(database, admin.Username, admin.Password)
+	setUserRole := fmt.Sprintf("SET LOCAL ROLE %s", postgresQuoteIdentifier(user.Username))
+	db, err := p.getPooledConn(database, admin.Username, admin.Password)
 	if err != nil {
 		log.Error(err, "failed to open a db connection")
 		return err
 	}
-	_, err = db.Exec(setUserRole)
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error(err, "failed to begin a transaction")
+		return timeoutError(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err := setLockTimeout(ctx, tx); err != nil {
+		log.Error(err, "failed to set the lock timeout")
+		return timeoutError(err)
+	}
+	_, err = tx.ExecContext(ctx, setUserRole)
 	if err != nil {
 		log.Error(err, "failed to set role", "query", setUserRole)
-		return err
+		return timeoutError(err)
 	}
 
-	defer db.Close()
-	_, err = db.Exec(query)
+	if _, err = tx.ExecContext(ctx, query); err != nil {
+		return timeoutError(err)
+	}
 
-	return err
+	return timeoutError(tx.Commit())
 }
 
 func (p Postgres) isDbExist(ctx context.Context, admin *DatabaseUser) bool {
-	check := fmt.Sprintf("SELECT 1 FROM pg_database WHERE datname = '%s';", p.Database)
+	check := fmt.Sprintf("SELECT 1 FROM pg_database WHERE datname = %s;", postgresQuoteLiteral(p.Database))
 
 	return p.isRowExist(ctx, "postgres", check, admin.Username, admin.Password)
 }
 
 func (p Postgres) isUserExist(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) bool {
-	check := fmt.Sprintf("SELECT 1 FROM pg_user WHERE usename = '%s';", user.Username)
+	check := fmt.Sprintf("SELECT 1 FROM pg_user WHERE usename = %s;", postgresQuoteLiteral(user.Username))
 
 	return p.isRowExist(ctx, "postgres", check, admin.Username, admin.Password)
 }
 
 func (p Postgres) isRowExist(ctx context.Context, database, query, user, password string) bool {
 	log := log.FromContext(ctx)
-	db, err := p.getDbConn(database, user, password)
+	db, err := p.getPooledConn(database, user, password)
 	if err != nil {
 		log.Error(err, "failed to open a db connection")
 		return false
 	}
-	defer db.Close()
 
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
 	var name string
-	err = db.QueryRow(query).Scan(&name)
+	err = db.QueryRowContext(ctx, query).Scan(&name)
 	if err != nil {
 		log.V(2).Info("failed executing query", "error", err)
 		return false
@@ -177,6 +379,32 @@ func (p Postgres) isRowExist(ctx context.Context, database, query, user, passwor
 	return true
 }
 
+// queryList returns values of the first column of all rows of the query
+func (p Postgres) queryList(ctx context.Context, database, query string, admin *DatabaseUser) ([]string, error) {
+	db, err := p.getPooledConn(database, admin.Username, admin.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, timeoutError(err)
+	}
+	defer rows.Close()
+
+	result := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, timeoutError(rows.Err())
+}
+
 func (p Postgres) dropPublicSchema(ctx context.Context, admin *DatabaseUser) error {
 	log := log.FromContext(ctx)
 	if p.Monitoring {
@@ -191,11 +419,27 @@ func (p Postgres) dropPublicSchema(ctx context.Context, admin *DatabaseUser) err
 	return nil
 }
 
+// postgresManagedSchemaComment marks schemas that are created by the operator,
+// so they can be found when they're removed from the spec
+const postgresManagedSchemaComment = "managed by db-operator"
+
 func (p Postgres) createSchemas(ctx context.Context, ac4tor *DatabaseUser) error {
 	log := log.FromContext(ctx)
 	for _, s := range p.Schemas {
-		createSchema := fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS \"%s\";", s)
-		if err := p.executeExec(ctx, p.Database, createSchema, ac4tor); err != nil {
+		// Schemas that exist already are left as they are, only schemas created
+		// by the operator are marked, so others are never pruned
+		exists := fmt.Sprintf("SELECT nspname FROM pg_namespace WHERE nspname = %s;", postgresQuoteLiteral(s))
+		found, err := p.queryList(ctx, p.Database, exists, ac4tor)
+		if err != nil {
+			log.Error(err, "failed to check if schema exists", "schema", s)
+			return err
+		}
+		if len(found) > 0 {
+			continue
+		}
+		createSchema := fmt.Sprintf("CREATE SCHEMA %s;", postgresQuoteIdentifier(s))
+		markSchema := fmt.Sprintf("COMMENT ON SCHEMA %s IS %s;", postgresQuoteIdentifier(s), postgresQuoteLiteral(postgresManagedSchemaComment))
+		if err := p.executeExecInTx(ctx, p.Database, ac4tor, createSchema, markSchema); err != nil {
 			log.Error(err, "failed to create schema", "schema", s)
 			return err
 		}
@@ -204,6 +448,68 @@ func (p Postgres) createSchemas(ctx context.Context, ac4tor *DatabaseUser) error
 	return nil
 }
 
+// createPublicSchema brings the public schema back, when it's not dropped according to the spec anymore
+func (p Postgres) createPublicSchema(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	create := "CREATE SCHEMA IF NOT EXISTS public;"
+	if err := p.executeExec(ctx, p.Database, create, admin); err != nil {
+		log.Error(err, "failed to create the schema Public")
+		return err
+	}
+	return nil
+}
+
+// pruneSchemas revokes grants on schemas that were created by the operator, but are removed from the spec.
+// Schemas are dropped with the drop policy, otherwise they're returned, because they differ from the spec
+func (p Postgres) pruneSchemas(ctx context.Context, admin *DatabaseUser) ([]string, error) {
+	log := log.FromContext(ctx)
+	if !p.isDbExist(ctx, admin) {
+		return nil, nil
+	}
+	query := fmt.Sprintf("SELECT nspname FROM pg_namespace WHERE obj_description(oid, 'pg_namespace') = %s ORDER BY nspname;",
+		postgresQuoteLiteral(postgresManagedSchemaComment))
+	managed, err := p.queryList(ctx, p.Database, query, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	retained := []string{}
+	for _, schema := range managed {
+		if slices.Contains(p.Schemas, schema) {
+			continue
+		}
+		granteesQuery := fmt.Sprintf("SELECT DISTINCT r.rolname FROM pg_namespace n CROSS JOIN LATERAL aclexplode(n.nspacl) a "+
+			"JOIN pg_roles r ON r.oid = a.grantee WHERE n.nspname = %s AND a.grantee <> n.nspowner ORDER BY r.rolname;", postgresQuoteLiteral(schema))
+		grantees, err := p.queryList(ctx, p.Database, granteesQuery, admin)
+		if err != nil {
+			return nil, err
+		}
+		for _, grantee := range grantees {
+			// The main user keeps access to its objects in schemas that are not dropped
+			if grantee == p.MainUser.Username || grantee == admin.Username {
+				continue
+			}
+			user := &DatabaseUser{Username: grantee}
+			if err := p.revokeSchemaPrivileges(ctx, admin, user, schema, p.objectOwners(ctx, admin, user)); err != nil {
+				return nil, err
+			}
+		}
+
+		if p.SchemaPrunePolicy != SCHEMA_PRUNE_POLICY_DROP {
+			log.Info("schema is removed from the spec, but it's retained in the database", "schema", schema)
+			retained = append(retained, schema)
+			continue
+		}
+		log.Info("dropping schema that is removed from the spec", "schema", schema)
+		drop := fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE;", postgresQuoteIdentifier(schema))
+		if err := p.executeExec(ctx, p.Database, drop, admin); err != nil {
+			log.Error(err, "failed to drop schema", "schema", schema)
+			return nil, err
+		}
+	}
+	return retained, nil
+}
+
 func (p Postgres) checkSchemas(ctx context.Context, user *DatabaseUser) error {
 	if p.DropPublicSchema {
 		query := "SELECT 1 FROM pg_cataLog.pg_namespace WHERE nspname = 'public';"
@@ -212,7 +518,7 @@ func (p Postgres) checkSchemas(ctx context.Context, user *DatabaseUser) error {
 		}
 	}
 	for _, s := range p.Schemas {
-		query := fmt.Sprintf("SELECT 1 FROM pg_cataLog.pg_namespace WHERE nspname = '%s';", s)
+		query := fmt.Sprintf("SELECT 1 FROM pg_cataLog.pg_namespace WHERE nspname = %s;", postgresQuoteLiteral(s))
 		if !p.isRowExist(ctx, p.Database, query, user.Username, user.Password) {
 			return fmt.Errorf("couldn't find schema %s in database %s", s, p.Database)
 		}
@@ -221,20 +527,77 @@ func (p Postgres) checkSchemas(ctx context.Context, user *DatabaseUser) error {
 }
 
 func (p Postgres) addExtensions(ctx context.Context, admin *DatabaseUser) error {
-	for _, ext := range p.Extensions {
-		query := fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS \"%s\";", ext)
-		err := p.executeExec(ctx, p.Database, query, admin)
+	log := log.FromContext(ctx)
+	for _, ext := range p.extensions() {
+		name := postgresQuoteIdentifier(ext.Name)
+		version, err := p.extensionVersion(ctx, ext.Name, admin)
 		if err != nil {
 			return err
 		}
+
+		queries := []string{}
+		if len(version) == 0 {
+			query := fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", name)
+			if len(ext.Schema) > 0 {
+				query += fmt.Sprintf(" WITH SCHEMA %s", postgresQuoteIdentifier(ext.Schema))
+			}
+			if len(ext.Version) > 0 {
+				query += fmt.Sprintf(" VERSION %s", postgresQuoteLiteral(ext.Version))
+			}
+			queries = append(queries, query+";")
+		} else {
+			if len(ext.Version) > 0 && ext.Version != version {
+				log.Info("updating extension", "extension", ext.Name, "from", version, "to", ext.Version)
+				queries = append(queries, fmt.Sprintf("ALTER EXTENSION %s UPDATE TO %s;", name, postgresQuoteLiteral(ext.Version)))
+			}
+			if len(ext.Schema) > 0 {
+				schema, err := p.queryList(ctx, p.Database, fmt.Sprintf("SELECT n.nspname FROM pg_extension e "+
+					"JOIN pg_namespace n ON n.oid = e.extnamespace WHERE e.extname = %s;", postgresQuoteLiteral(ext.Name)), admin)
+				if err != nil {
+					return err
+				}
+				if !slices.Contains(schema, ext.Schema) {
+					queries = append(queries, fmt.Sprintf("ALTER EXTENSION %s SET SCHEMA %s;", name, postgresQuoteIdentifier(ext.Schema)))
+				}
+			}
+		}
+
+		for _, query := range queries {
+			if err := p.executeExec(ctx, p.Database, query, admin); err != nil {
+				log.Error(err, "failed managing extension", "extension", ext.Name, "query", query)
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+// extensions returns all extensions from the spec, names
+// without options are turned into extensions with defaults
+func (p Postgres) extensions() []postgresExtension {
+	extensions := []postgresExtension{}
+	for _, name := range p.Extensions {
+		if !slices.ContainsFunc(p.ExtensionConfigs, func(ext postgresExtension) bool { return ext.Name == name }) {
+			extensions = append(extensions, postgresExtension{Name: name})
+		}
+	}
+	return append(extensions, p.ExtensionConfigs...)
+}
+
+// extensionVersion returns the installed version of the extension, it's empty if it's not installed
+func (p Postgres) extensionVersion(ctx context.Context, name string, user *DatabaseUser) (string, error) {
+	query := fmt.Sprintf("SELECT extversion FROM pg_extension WHERE extname = %s;", postgresQuoteLiteral(name))
+	versions, err := p.queryList(ctx, p.Database, query, user)
+	if err != nil || len(versions) == 0 {
+		return "", err
+	}
+	return versions[0], nil
+}
+
 func (p Postgres) enableMonitoring(ctx context.Context, admin *DatabaseUser) error {
 	monitoringExtension := "pg_stat_statements"
 
-	query := fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS \"%s\";", monitoringExtension)
+	query := fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s;", postgresQuoteIdentifier(monitoringExtension))
 	err := p.executeExec(ctx, p.Database, query, admin)
 	if err != nil {
 		return err
@@ -243,17 +606,575 @@ func (p Postgres) enableMonitoring(ctx context.Context, admin *DatabaseUser) err
 	return nil
 }
 
+// postgresObjectPrivileges are privileges on a kind of schema objects,
+// e.g. TABLES, that can be granted on all objects of a schema at once
+type postgresObjectPrivileges struct {
+	objects    string
+	privileges []string
+	// The catalog of objects of the kind, their acl column and a filter
+	// of the kind, the catalog is joined with schemas as n
+	catalog string
+	acl     string
+	filter  string
+	// The object type of default privileges in pg_default_acl
+	defaultType string
+}
+
+func postgresSchemaObjects(tables, sequences, functions []string) []postgresObjectPrivileges {
+	relations := "pg_class o JOIN pg_namespace n ON n.oid = o.relnamespace"
+	return []postgresObjectPrivileges{
+		{objects: "TABLES", privileges: tables, catalog: relations, acl: "o.relacl", filter: "o.relkind IN ('r', 'v', 'm', 'f', 'p')", defaultType: "r"},
+		{objects: "SEQUENCES", privileges: sequences, catalog: relations, acl: "o.relacl", filter: "o.relkind = 'S'", defaultType: "S"},
+		{objects: "FUNCTIONS", privileges: functions, catalog: "pg_proc o JOIN pg_namespace n ON n.oid = o.pronamespace", acl: "o.proacl", filter: "o.prokind <> 'p'", defaultType: "f"},
+	}
+}
+
+// grantedPrivileges returns privileges that are granted to the user directly on objects of the catalog,
+// that are matching the condition, privileges of PUBLIC and of roles of the user are not included
+func (p Postgres) grantedPrivileges(ctx context.Context, database, catalog, acl, condition string, admin, user *DatabaseUser) ([]string, error) {
+	query := fmt.Sprintf("SELECT DISTINCT a.privilege_type FROM %s, aclexplode(%s) a WHERE %s AND a.grantee = (SELECT oid FROM pg_roles WHERE rolname = %s)",
+		catalog, acl, condition, postgresQuoteLiteral(user.Username))
+	return p.queryList(ctx, database, query, admin)
+}
+
+// postgresRevokedPrivileges returns granted privileges that are not wanted anymore,
+// nothing is revoked, when all privileges are wanted
+func postgresRevokedPrivileges(granted, wanted []string) []string {
+	normalized := []string{}
+	for _, privilege := range wanted {
+		switch privilege = strings.ToUpper(strings.TrimSpace(privilege)); privilege {
+		case "ALL", "ALL PRIVILEGES":
+			return nil
+		case "TEMP":
+			privilege = "TEMPORARY"
+		}
+		normalized = append(normalized, privilege)
+	}
+	revoked := []string{}
+	for _, privilege := range granted {
+		if !slices.Contains(normalized, privilege) {
+			revoked = append(revoked, privilege)
+		}
+	}
+	return revoked
+}
+
+// grantAccessProfile grants privileges of an access profile on the database and on every schema,
+// default privileges are set for objects that will be created by the main user. Privileges that
+// are granted, but are not in the profile anymore are revoked before, like on ClickHouse
+func (p Postgres) grantAccessProfile(ctx context.Context, admin, actingUser, user *DatabaseUser, profile AccessProfile, schemas []string) error {
+	log := log.FromContext(ctx)
+	granted, err := p.grantedPrivileges(ctx, "postgres", "pg_database o", "o.datacl", "o.datname = "+postgresQuoteLiteral(p.Database), admin, user)
+	if err != nil {
+		log.Error(err, "failed getting privileges of postgres user on database", "username", user.Username)
+		return err
+	}
+	queries := []string{}
+	if revoke := postgresRevokedPrivileges(granted, profile.Database); len(revoke) > 0 {
+		queries = append(queries, fmt.Sprintf("REVOKE %s ON DATABASE %s FROM %s;",
+			strings.Join(revoke, ", "),
+			postgresQuoteIdentifier(p.Database),
+			postgresQuoteIdentifier(user.Username),
+		))
+	}
+	if len(profile.Database) > 0 {
+		queries = append(queries, fmt.Sprintf("GRANT %s ON DATABASE %s TO %s;",
+			joinPrivileges(profile.Database),
+			postgresQuoteIdentifier(p.Database),
+			postgresQuoteIdentifier(user.Username),
+		))
+	}
+	for _, query := range queries {
+		if err := p.executeExec(ctx, "postgres", query, admin); err != nil {
+			log.Error(err, "failed updating postgres user", "query", query)
+			return err
+		}
+	}
+
+	// If user is granted to the admin, admin can alter default privileges
+	// on installations that are not providing superusers
+	alterDefaults := func(query string) error {
+		if p.RDSIAMImpersonateWorkaround {
+			return p.execSettingRole(ctx, p.Database, query, actingUser, admin)
+		}
+		return p.executeExec(ctx, p.Database, query, admin)
+	}
+
+	owners := p.objectOwners(ctx, admin, user)
+	for _, s := range schemas {
+		schema := postgresQuoteLiteral(s)
+		granted, err := p.grantedPrivileges(ctx, p.Database, "pg_namespace n", "n.nspacl", "n.nspname = "+schema, admin, user)
+		if err != nil {
+			log.Error(err, "failed getting privileges of postgres user on schema", "username", user.Username, "schema", s)
+			return err
+		}
+		queries := []string{}
+		if revoke := postgresRevokedPrivileges(granted, profile.Schemas); len(revoke) > 0 {
+			queries = append(queries, fmt.Sprintf("REVOKE %s ON SCHEMA %s FROM %s",
+				strings.Join(revoke, ", "),
+				postgresQuoteIdentifier(s),
+				postgresQuoteIdentifier(user.Username),
+			))
+		}
+		if len(profile.Schemas) > 0 {
+			queries = append(queries, fmt.Sprintf("GRANT %s ON SCHEMA %s TO %s",
+				joinPrivileges(profile.Schemas),
+				postgresQuoteIdentifier(s),
+				postgresQuoteIdentifier(user.Username),
+			))
+		}
+		for _, objects := range postgresSchemaObjects(profile.Tables, profile.Sequences, profile.Functions) {
+			granted, err := p.grantedPrivileges(ctx, p.Database, objects.catalog, objects.acl, "n.nspname = "+schema+" AND "+objects.filter, admin, user)
+			if err != nil {
+				log.Error(err, "failed getting privileges of postgres user on schema objects", "username", user.Username, "schema", s, "objects", objects.objects)
+				return err
+			}
+			if revoke := postgresRevokedPrivileges(granted, objects.privileges); len(revoke) > 0 {
+				queries = append(queries, fmt.Sprintf("REVOKE %s ON ALL %s IN SCHEMA %s FROM %s",
+					strings.Join(revoke, ", "),
+					objects.objects,
+					postgresQuoteIdentifier(s),
+					postgresQuoteIdentifier(user.Username),
+				))
+			}
+			// Objects that are created since the last reconciliation are getting privileges too
+			if len(objects.privileges) > 0 {
+				queries = append(queries, fmt.Sprintf("GRANT %s ON ALL %s IN SCHEMA %s TO %s",
+					joinPrivileges(objects.privileges),
+					objects.objects,
+					postgresQuoteIdentifier(s),
+					postgresQuoteIdentifier(user.Username),
+				))
+			}
+		}
+		for _, query := range queries {
+			if err := p.executeExec(ctx, p.Database, query, admin); err != nil {
+				log.Error(err, "failed updating postgres user", "query", query)
+				return err
+			}
+		}
+
+		defaults := profile.DefaultPrivileges
+		for _, owner := range owners {
+			for _, objects := range postgresSchemaObjects(defaults.Tables, defaults.Sequences, defaults.Functions) {
+				condition := fmt.Sprintf("n.nspname = %s AND o.defaclobjtype = '%s' AND o.defaclrole = (SELECT oid FROM pg_roles WHERE rolname = %s)",
+					schema, objects.defaultType, postgresQuoteLiteral(owner))
+				granted, err := p.grantedPrivileges(ctx, p.Database, "pg_default_acl o JOIN pg_namespace n ON n.oid = o.defaclnamespace", "o.defaclacl", condition, admin, user)
+				if err != nil {
+					log.Error(err, "failed getting default privileges of postgres user", "username", user.Username, "schema", s, "owner", owner)
+					return err
+				}
+				queries := []string{}
+				if revoke := postgresRevokedPrivileges(granted, objects.privileges); len(revoke) > 0 {
+					queries = append(queries, fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA %s REVOKE %s ON %s FROM %s;",
+						postgresQuoteIdentifier(owner),
+						postgresQuoteIdentifier(s),
+						strings.Join(revoke, ", "),
+						objects.objects,
+						postgresQuoteIdentifier(user.Username),
+					))
+				}
+				if len(objects.privileges) > 0 {
+					queries = append(queries, fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA %s GRANT %s ON %s TO %s;",
+						postgresQuoteIdentifier(owner),
+						postgresQuoteIdentifier(s),
+						joinPrivileges(objects.privileges),
+						objects.objects,
+						postgresQuoteIdentifier(user.Username),
+					))
+				}
+				for _, query := range queries {
+					if err := alterDefaults(query); err != nil {
+						log.Error(err, "failed updating postgres user", "query", query)
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// objectOwners returns roles, default privileges on objects of which are managed for the user.
+// Owners that don't exist yet are skipped, they're picked up when the user is updated
+func (p Postgres) objectOwners(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) []string {
+	log := log.FromContext(ctx)
+	owners := []string{p.MainUser.Username}
+	for _, owner := range p.ObjectOwners {
+		if owner == user.Username || slices.Contains(owners, owner) {
+			continue
+		}
+		if !p.isUserExist(ctx, admin, &DatabaseUser{Username: owner}) {
+			log.Info("an object owner doesn't exist yet, skipping", "owner", owner, "username", user.Username)
+			continue
+		}
+		owners = append(owners, owner)
+	}
+	return owners
+}
+
+// setRoleOptions applies attributes and parameters of the role from the DbUser spec,
+// they're reset to defaults when they're removed from the spec
+func (p Postgres) setRoleOptions(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	// Main users are not configured by DbUsers
+	if len(user.Spec) == 0 {
+		return nil
+	}
+	spec := postgresUserSpec{}
+	if err := user.decodeSpec(&spec); err != nil {
+		return err
+	}
+	if err := engines.ValidatePostgresSettings(spec.Settings); err != nil {
+		return err
+	}
+
+	role := postgresQuoteIdentifier(user.Username)
+	connectionLimit := -1
+	if spec.ConnectionLimit != nil {
+		connectionLimit = *spec.ConnectionLimit
+	}
+	validUntil := "infinity"
+	if spec.ValidUntil != nil {
+		validUntil = spec.ValidUntil.UTC().Format(time.RFC3339)
+	}
+	queries := []string{
+		fmt.Sprintf("ALTER ROLE %s WITH CONNECTION LIMIT %d VALID UNTIL %s;", role, connectionLimit, postgresQuoteLiteral(validUntil)),
+	}
+
+	current, err := p.queryList(ctx, "postgres", fmt.Sprintf("SELECT split_part(unnest(setconfig), '=', 1) FROM pg_db_role_setting "+
+		"WHERE setdatabase = 0 AND setrole = (SELECT oid FROM pg_roles WHERE rolname = %s);", postgresQuoteLiteral(user.Username)), admin)
+	if err != nil {
+		log.Error(err, "failed getting role settings", "username", user.Username)
+		return err
+	}
+	for _, name := range current {
+		if _, ok := spec.Settings[name]; !ok && engines.IsPostgresSettingName(name) {
+			queries = append(queries, fmt.Sprintf("ALTER ROLE %s RESET %s;", role, name))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(spec.Settings)) {
+		queries = append(queries, fmt.Sprintf("ALTER ROLE %s SET %s = %s;", role, name, postgresSettingValue(name, spec.Settings[name])))
+	}
+
+	for _, query := range queries {
+		if err := p.executeExec(ctx, "postgres", query, admin); err != nil {
+			log.Error(err, "failed setting role options", "username", user.Username, "query", query)
+			return err
+		}
+	}
+	return nil
+}
+
+// createDatabaseQuery returns a query that creates the database with options from the spec
+func (p Postgres) createDatabaseQuery(ctx context.Context, admin *DatabaseUser) string {
+	log := log.FromContext(ctx)
+	create := fmt.Sprintf("CREATE DATABASE %s", postgresQuoteIdentifier(p.Database))
+
+	template := p.Template
+	// template1 can only be copied with its own encoding and locale
+	if len(template) == 0 && (len(p.Encoding) > 0 || len(p.LcCollate) > 0 || len(p.LcCtype) > 0 || len(p.IcuLocale) > 0) {
+		template = "template0"
+	}
+	if len(template) > 0 {
+		log.Info("Creating database from template", "database", p.Database, "template", template)
+		create += fmt.Sprintf(" TEMPLATE %s", postgresQuoteIdentifier(template))
+	}
+	if len(p.Encoding) > 0 {
+		create += fmt.Sprintf(" ENCODING %s", postgresQuoteLiteral(p.Encoding))
+	}
+	if len(p.LcCollate) > 0 {
+		create += fmt.Sprintf(" LC_COLLATE %s", postgresQuoteLiteral(p.LcCollate))
+	}
+	if len(p.LcCtype) > 0 {
+		create += fmt.Sprintf(" LC_CTYPE %s", postgresQuoteLiteral(p.LcCtype))
+	}
+	if len(p.IcuLocale) > 0 {
+		create += fmt.Sprintf(" LOCALE_PROVIDER icu ICU_LOCALE %s", postgresQuoteLiteral(p.IcuLocale))
+	}
+	if p.ConnectionLimit != nil {
+		create += fmt.Sprintf(" CONNECTION LIMIT %d", *p.ConnectionLimit)
+	}
+	// The main user is created after the database, so it becomes the owner later
+	if len(p.Owner) > 0 && p.isUserExist(ctx, admin, &DatabaseUser{Username: p.Owner}) {
+		create += fmt.Sprintf(" OWNER %s", postgresQuoteIdentifier(p.Owner))
+	}
+	return create + ";"
+}
+
+// alterDatabase applies options of the database that can be changed after it's created
+func (p Postgres) alterDatabase(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	queries := []string{}
+	if p.ConnectionLimit != nil {
+		queries = append(queries, fmt.Sprintf("ALTER DATABASE %s WITH CONNECTION LIMIT %d;", postgresQuoteIdentifier(p.Database), *p.ConnectionLimit))
+	}
+
+	// All settings are only managed when they're set, so the ones that are set manually
+	// are not reset by default, parameters that were set by the operator before are reset
+	// by pruneSettings, even when settings are removed from the spec completely
+	if p.Settings != nil {
+		current, err := p.databaseSettings(ctx, admin)
+		if err != nil {
+			log.Error(err, "failed getting database settings", "database", p.Database)
+			return err
+		}
+		for _, name := range current {
+			if _, ok := p.Settings[name]; !ok && engines.IsPostgresSettingName(name) {
+				queries = append(queries, fmt.Sprintf("ALTER DATABASE %s RESET %s;", postgresQuoteIdentifier(p.Database), name))
+			}
+		}
+		if err := engines.ValidatePostgresSettings(p.Settings); err != nil {
+			return err
+		}
+		for _, name := range slices.Sorted(maps.Keys(p.Settings)) {
+			queries = append(queries, fmt.Sprintf("ALTER DATABASE %s SET %s = %s;",
+				postgresQuoteIdentifier(p.Database), name, postgresSettingValue(name, p.Settings[name]),
+			))
+		}
+	}
+
+	for _, query := range queries {
+		if err := p.executeExec(ctx, "postgres", query, admin); err != nil {
+			log.Error(err, "failed altering the database", "query", query)
+			return err
+		}
+	}
+	return p.setDatabaseOwner(ctx, admin)
+}
+
+// pruneSettings resets parameters that were managed before, but are removed from the spec,
+// parameters that are set manually are kept, unless there are settings in the spec
+func (p Postgres) pruneSettings(ctx context.Context, admin *DatabaseUser, managed []string) error {
+	log := log.FromContext(ctx)
+	for _, name := range managed {
+		if _, ok := p.Settings[name]; ok || !engines.IsPostgresSettingName(name) {
+			continue
+		}
+		reset := fmt.Sprintf("ALTER DATABASE %s RESET %s;", postgresQuoteIdentifier(p.Database), name)
+		if err := p.executeExec(ctx, "postgres", reset, admin); err != nil {
+			log.Error(err, "failed resetting a database parameter", "query", reset)
+			return err
+		}
+	}
+	return nil
+}
+
+// setDatabaseOwner changes the owner of the database, if the owner already exists
+func (p Postgres) setDatabaseOwner(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if len(p.Owner) == 0 {
+		return nil
+	}
+	if !p.isUserExist(ctx, admin, &DatabaseUser{Username: p.Owner}) {
+		log.Info("the owner of the database doesn't exist yet, skipping", "owner", p.Owner)
+		return nil
+	}
+	owner := fmt.Sprintf("ALTER DATABASE %s OWNER TO %s;", postgresQuoteIdentifier(p.Database), postgresQuoteIdentifier(p.Owner))
+	if err := p.executeExec(ctx, "postgres", owner, admin); err != nil {
+		log.Error(err, "failed changing the owner of the database", "owner", p.Owner)
+		return err
+	}
+	return nil
+}
+
+// databaseSettings returns names of parameters that are set for the database
+func (p Postgres) databaseSettings(ctx context.Context, admin *DatabaseUser) ([]string, error) {
+	query := fmt.Sprintf("SELECT split_part(unnest(setconfig), '=', 1) FROM pg_db_role_setting "+
+		"WHERE setrole = 0 AND setdatabase = (SELECT oid FROM pg_database WHERE datname = %s);", postgresQuoteLiteral(p.Database))
+	return p.queryList(ctx, "postgres", query, admin)
+}
+
+// Settings that take a list of values, elements of them are quoted separately,
+// otherwise the whole value would be a single element, e.g. a schema named "app, public"
+var postgresListSettings = []string{
+	"search_path",
+	"temp_tablespaces",
+	"local_preload_libraries",
+	"session_preload_libraries",
+}
+
+// postgresSettingValue quotes the value of the setting, values of list settings are split on commas,
+// other values can contain commas, like application_name or DateStyle, so they're kept as they are
+func postgresSettingValue(name, value string) string {
+	if !slices.Contains(postgresListSettings, strings.ToLower(name)) {
+		return postgresQuoteLiteral(value)
+	}
+	elements := []string{}
+	for _, element := range strings.Split(value, ",") {
+		elements = append(elements, postgresQuoteLiteral(strings.TrimSpace(element)))
+	}
+	return strings.Join(elements, ", ")
+}
+
+// deletionPolicy returns the deletion policy of the user, falling back to the one of the database,
+// objects are reassigned by default, because dropping them can't be undone
+func (p Postgres) deletionPolicy(user *DatabaseUser) string {
+	if len(user.DeletionPolicy) > 0 {
+		return user.DeletionPolicy
+	}
+	if len(p.DeletionPolicy) > 0 {
+		return p.DeletionPolicy
+	}
+	return DELETION_POLICY_REASSIGN
+}
+
+// releaseOwnedObjects handles objects that are owned by the user in all databases it has
+// dependencies in, according to the deletion policy. Privileges of the user are always dropped
+func (p Postgres) releaseOwnedObjects(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	policy := p.deletionPolicy(user)
+	username := postgresQuoteLiteral(user.Username)
+
+	if policy == DELETION_POLICY_FAIL {
+		owned := fmt.Sprintf("SELECT DISTINCT d.datname FROM pg_shdepend s "+
+			"JOIN pg_database d ON d.oid = s.dbid JOIN pg_roles r ON r.oid = s.refobjid "+
+			"WHERE r.rolname = %s AND s.deptype = 'o';", username)
+		databases, err := p.queryList(ctx, "postgres", owned, admin)
+		if err != nil {
+			log.Error(err, "failed getting databases with objects of the user", "username", user.Username)
+			return err
+		}
+		if len(databases) > 0 {
+			return fmt.Errorf("%w: %s owns objects in databases: %s", ErrOwnedObjects, user.Username, strings.Join(databases, ", "))
+		}
+	}
+
+	dependent := fmt.Sprintf("SELECT DISTINCT d.datname FROM pg_shdepend s "+
+		"JOIN pg_database d ON d.oid = s.dbid JOIN pg_roles r ON r.oid = s.refobjid "+
+		"WHERE r.rolname = %s AND d.datallowconn;", username)
+	databases, err := p.queryList(ctx, "postgres", dependent, admin)
+	if err != nil {
+		log.Error(err, "failed getting databases with dependencies of the user", "username", user.Username)
+		return err
+	}
+	// Privileges on the database itself are shared dependencies, so it's not always listed
+	if !slices.Contains(databases, p.Database) && p.isDbExist(ctx, admin) {
+		databases = append(databases, p.Database)
+	}
+
+	// Objects are reassigned to the main user, unless it's the main user that is removed
+	newOwner := p.MainUser.Username
+	if user.AccessType == ACCESS_TYPE_MAINUSER || user.Username == newOwner {
+		newOwner = admin.Username
+	}
+	for _, database := range databases {
+		queries := []string{}
+		if policy == DELETION_POLICY_REASSIGN {
+			queries = append(queries, fmt.Sprintf("REASSIGN OWNED BY %s TO %s;", postgresQuoteIdentifier(user.Username), postgresQuoteIdentifier(newOwner)))
+		}
+		queries = append(queries, fmt.Sprintf("DROP OWNED BY %s;", postgresQuoteIdentifier(user.Username)))
+		for _, query := range queries {
+			if err := p.executeExec(ctx, database, query, admin); err != nil {
+				log.Error(err, "failed releasing objects of the user", "username", user.Username, "database", database, "query", query)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// terminateBackends closes sessions that are blocking the removal of a role or a database,
+// it's done on the best effort basis, because the admin isn't always allowed to do that
+func (p Postgres) terminateBackends(ctx context.Context, admin *DatabaseUser, column, value string) {
+	log := log.FromContext(ctx)
+	terminate := fmt.Sprintf("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE %s = %s AND pid <> pg_backend_pid();",
+		column, postgresQuoteLiteral(value),
+	)
+	if err := p.executeExec(ctx, "postgres", terminate, admin); err != nil {
+		log.Error(err, "failed terminating backends", column, value)
+	}
+}
+
+// postgresCanCreate returns true if users of the profile can create objects
+func postgresCanCreate(profile AccessProfile) bool {
+	for _, privilege := range append(slices.Clone(profile.Database), profile.Schemas...) {
+		switch strings.ToUpper(privilege) {
+		case "CREATE", "ALL", "ALL PRIVILEGES":
+			return true
+		}
+	}
+	return false
+}
+
+// enforceMainUserOwnership lets the user act as the main user, and switches its sessions in the
+// database to the main user role, so objects that are created by the user are owned by the main user
+func (p Postgres) enforceMainUserOwnership(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	queries := []string{
+		fmt.Sprintf("GRANT %s TO %s;", postgresQuoteIdentifier(p.MainUser.Username), postgresQuoteIdentifier(user.Username)),
+		fmt.Sprintf("ALTER ROLE %s IN DATABASE %s SET role = %s;",
+			postgresQuoteIdentifier(user.Username),
+			postgresQuoteIdentifier(p.Database),
+			postgresQuoteLiteral(p.MainUser.Username),
+		),
+	}
+	for _, query := range queries {
+		if err := p.executeExec(ctx, "postgres", query, admin); err != nil {
+			log.Error(err, "failed enforcing the main user ownership", "username", user.Username, "query", query)
+			return err
+		}
+	}
+	return nil
+}
+
 func (p Postgres) checkExtensions(ctx context.Context, user *DatabaseUser) error {
-	for _, ext := range p.Extensions {
-		query := fmt.Sprintf("SELECT 1 FROM pg_extension WHERE extname = '%s';", ext)
-		if !p.isRowExist(ctx, p.Database, query, user.Username, user.Password) {
-			return fmt.Errorf("couldn't find extension %s in database %s", ext, p.Database)
+	for _, ext := range p.extensions() {
+		version, err := p.extensionVersion(ctx, ext.Name, user)
+		if err != nil {
+			return err
+		}
+		if len(version) == 0 {
+			return fmt.Errorf("couldn't find extension %s in database %s", ext.Name, p.Database)
+		}
+		if len(ext.Version) > 0 && ext.Version != version {
+			return fmt.Errorf("extension %s in database %s has version %s instead of %s", ext.Name, p.Database, version, ext.Version)
 		}
 	}
 
 	return nil
 }
 
+// pruneExtensions drops extensions that were managed before, but are removed from the spec
+func (p Postgres) pruneExtensions(ctx context.Context, admin *DatabaseUser, managed []string) error {
+	log := log.FromContext(ctx)
+	if !p.PruneExtensions {
+		return nil
+	}
+	current := []string{}
+	for _, ext := range p.extensions() {
+		current = append(current, ext.Name)
+	}
+	for _, name := range managed {
+		// The monitoring extension is added by the operator
+		if slices.Contains(current, name) || (p.Monitoring && name == "pg_stat_statements") {
+			continue
+		}
+		log.Info("dropping extension that is removed from the spec", "extension", name)
+		drop := fmt.Sprintf("DROP EXTENSION IF EXISTS %s;", postgresQuoteIdentifier(name))
+		if err := p.executeExec(ctx, p.Database, drop, admin); err != nil {
+			log.Error(err, "failed dropping extension", "extension", name)
+			return err
+		}
+	}
+	return nil
+}
+
+// ExtensionVersions returns installed versions of extensions from the spec
+func (p Postgres) ExtensionVersions(ctx context.Context, admin *DatabaseUser) (map[string]string, error) {
+	versions := map[string]string{}
+	for _, ext := range p.extensions() {
+		version, err := p.extensionVersion(ctx, ext.Name, admin)
+		if err != nil {
+			return nil, err
+		}
+		if len(version) > 0 {
+			versions[ext.Name] = version
+		}
+	}
+	return versions, nil
+}
+
 // Functions that implement the `Database` interface
 
 // CheckStatus checks status of postgres database
@@ -264,9 +1185,11 @@ func (p Postgres) CheckStatus(ctx context.Context, user *DatabaseUser) error {
 		return fmt.Errorf("db conn test failed - couldn't get db conn: %s", err)
 	}
 	defer db.Close()
-	res, err := db.Query("SELECT 1")
+	queryCtx, cancel := statementContext(ctx)
+	defer cancel()
+	res, err := db.QueryContext(queryCtx, "SELECT 1")
 	if err != nil {
-		return fmt.Errorf("db conn test failed - failed to execute query: %s", err)
+		return timeoutError(fmt.Errorf("db conn test failed - failed to execute query: %w", err))
 	}
 	res.Close()
 
@@ -345,51 +1268,54 @@ func (p Postgres) QueryAsUser(ctx context.Context, query string, user *DatabaseU
 	}
 	defer db.Close()
 
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
 	var result string
-	if err := db.QueryRow(query).Scan(&result); err != nil {
+	if err := db.QueryRowContext(ctx, query).Scan(&result); err != nil {
 		log.Error(err, "failed executing query", "query", query)
-		return "", err
+		return "", timeoutError(err)
 	}
 	return result, nil
 }
 
 func (p Postgres) createDatabase(ctx context.Context, admin *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	var create string
-	if len(p.Template) > 0 {
-		log.Info("Creating database from template", "database", p.Database, "template", p.Template)
-		create = fmt.Sprintf("CREATE DATABASE \"%s\" TEMPLATE \"%s\";", p.Database, p.Template)
-	} else {
-		create = fmt.Sprintf("CREATE DATABASE \"%s\";", p.Database)
-	}
-
 	if !p.isDbExist(ctx, admin) {
-		err := p.executeExec(ctx, "postgres", create, admin)
+		create := p.createDatabaseQuery(ctx, admin)
+		err := p.executeExecOutsideTx(ctx, "postgres", create, admin)
 		if err != nil {
 			log.Error(err, "failed creating postgres database")
 			return err
 		}
 	}
 
+	if err := p.alterDatabase(ctx, admin); err != nil {
+		return fmt.Errorf("can not update database options - %w", err)
+	}
+
 	if p.Monitoring {
 		err := p.enableMonitoring(ctx, admin)
 		if err != nil {
-			return fmt.Errorf("can not enable monitoring - %s", err)
+			return fmt.Errorf("can not enable monitoring - %w", err)
 		}
 	}
 
 	err := p.addExtensions(ctx, admin)
 	if err != nil {
-		return fmt.Errorf("can not add extension - %s", err)
+		return fmt.Errorf("can not add extension - %w", err)
 	}
 
 	if p.DropPublicSchema {
 		if err := p.dropPublicSchema(ctx, admin); err != nil {
-			return fmt.Errorf("can not drop public schema - %s", err)
+			return fmt.Errorf("can not drop public schema - %w", err)
 		}
 		if len(p.Schemas) == 0 {
 			log.Info("the public schema is dropped, but no additional schemas are created, schema creation must be handled on the application side now")
 		}
+	} else {
+		if err := p.createPublicSchema(ctx, admin); err != nil {
+			return fmt.Errorf("can not create public schema - %w", err)
+		}
 	}
 
 	if len(p.Schemas) > 0 {
@@ -404,8 +1330,8 @@ func (p Postgres) createDatabase(ctx context.Context, admin *DatabaseUser) error
 
 func (p Postgres) deleteDatabase(ctx context.Context, admin *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	revoke := fmt.Sprintf("REVOKE CONNECT ON DATABASE \"%s\" FROM PUBLIC, \"%s\";", p.Database, admin.Username)
-	delete := fmt.Sprintf("DROP DATABASE \"%s\";", p.Database)
+	revoke := fmt.Sprintf("REVOKE CONNECT ON DATABASE %s FROM PUBLIC, %s;", postgresQuoteIdentifier(p.Database), postgresQuoteIdentifier(admin.Username))
+	delete := fmt.Sprintf("DROP DATABASE %s;", postgresQuoteIdentifier(p.Database))
 
 	if p.isDbExist(ctx, admin) {
 		err := p.executeExec(ctx, "postgres", revoke, admin)
@@ -414,8 +1340,11 @@ func (p Postgres) deleteDatabase(ctx context.Context, admin *DatabaseUser) error
 			return err
 		}
 
+		// Pooled connections and sessions of users are blocking the removal
+		Connections.invalidateDatabase(p.poolInstance(), p.Database)
+		p.terminateBackends(ctx, admin, "datname", p.Database)
 		err = kci.Retry(3, 5*time.Second, func() error {
-			err := p.executeExec(ctx, "postgres", delete, admin)
+			err := p.executeExecOutsideTx(ctx, "postgres", delete, admin)
 			if err != nil {
 				// This error will result in a retry
 				log.V(2).Info("failed with error, retrying", "error", err)
@@ -454,16 +1383,18 @@ func (p Postgres) createOrUpdateUser(ctx context.Context, admin *DatabaseUser, u
 
 func (p Postgres) createUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	create := fmt.Sprintf("CREATE USER \"%s\" WITH ENCRYPTED PASSWORD '%s' NOSUPERUSER;", user.Username, user.Password)
+	create := fmt.Sprintf("CREATE USER %s WITH ENCRYPTED PASSWORD %s NOSUPERUSER;", postgresQuoteIdentifier(user.Username), postgresQuoteLiteral(user.Password))
 
+	// An existing user is kept, so a failed creation can be retried
 	if !p.isUserExist(ctx, admin, user) {
 		err := p.executeExec(ctx, "postgres", create, admin)
 		if err != nil {
 			log.Error(err, "failed creating postgres user")
 			return err
 		}
-	} else {
-		err := fmt.Errorf("user already exists: %s", user.Username)
+	}
+
+	if err := p.setRoleOptions(ctx, admin, user); err != nil {
 		return err
 	}
 
@@ -476,7 +1407,7 @@ func (p Postgres) createUser(ctx context.Context, admin *DatabaseUser, user *Dat
 
 func (p Postgres) updateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
 	log := log.FromContext(ctx)
-	update := fmt.Sprintf("ALTER ROLE \"%s\" WITH ENCRYPTED PASSWORD '%s';", user.Username, user.Password)
+	update := fmt.Sprintf("ALTER ROLE %s WITH ENCRYPTED PASSWORD %s;", postgresQuoteIdentifier(user.Username), postgresQuoteLiteral(user.Password))
 
 	if !p.isUserExist(ctx, admin, user) {
 		err := fmt.Errorf("user doesn't exist yet: %s", user.Username)
@@ -489,6 +1420,10 @@ func (p Postgres) updateUser(ctx context.Context, admin *DatabaseUser, user *Dat
 		}
 	}
 
+	if err := p.setRoleOptions(ctx, admin, user); err != nil {
+		return err
+	}
+
 	if err := p.setUserPermission(ctx, admin, user); err != nil {
 		return err
 	}
@@ -511,7 +1446,7 @@ func (p Postgres) setUserPermission(ctx context.Context, admin *DatabaseUser, us
 	// Grant user role to the admin user. It's required to make generic instances work with Azure.
 	if user.GrantToAdmin {
 		actingUser = admin
-		assignRoleToAdmin := fmt.Sprintf("GRANT \"%s\" TO \"%s\";", user.Username, admin.Username)
+		assignRoleToAdmin := fmt.Sprintf("GRANT %s TO %s;", postgresQuoteIdentifier(user.Username), postgresQuoteIdentifier(admin.Username))
 		if err := p.executeExec(ctx, p.Database, assignRoleToAdmin, admin); err != nil {
 			log.Error(err, "failed granting user to admin", "username", user.Username, "admin", admin.Username)
 		}
@@ -521,100 +1456,47 @@ func (p Postgres) setUserPermission(ctx context.Context, admin *DatabaseUser, us
 
 	switch user.AccessType {
 	case ACCESS_TYPE_MAINUSER:
-		grant := fmt.Sprintf("GRANT ALL PRIVILEGES ON DATABASE \"%s\" TO \"%s\";", p.Database, user.Username)
+		grant := fmt.Sprintf("GRANT ALL PRIVILEGES ON DATABASE %s TO %s;", postgresQuoteIdentifier(p.Database), postgresQuoteIdentifier(user.Username))
 		err := p.executeExec(ctx, "postgres", grant, admin)
 		if err != nil {
 			log.Error(err, "failed granting all privileges to user", "query", grant)
 			return err
 		}
-		grantCreateToAdmin := fmt.Sprintf("GRANT CREATE ON DATABASE \"%s\" to \"%s\";", p.Database, admin.Username)
+		if p.Owner == user.Username {
+			if err := p.setDatabaseOwner(ctx, admin); err != nil {
+				return err
+			}
+		}
+		grantCreateToAdmin := fmt.Sprintf("GRANT CREATE ON DATABASE %s to %s;", postgresQuoteIdentifier(p.Database), postgresQuoteIdentifier(admin.Username))
 		if err := p.executeExec(ctx, p.Database, grantCreateToAdmin, admin); err != nil {
 			log.Error(err, "failed to grant usage access on database", "username", user.Username, "database", p.Database)
 			return err
 		}
 
 		for _, s := range schemas {
-			grantUserAccess := fmt.Sprintf("GRANT ALL ON SCHEMA \"%s\" TO \"%s\"", s, user.Username)
+			grantUserAccess := fmt.Sprintf("GRANT ALL ON SCHEMA %s TO %s", postgresQuoteIdentifier(s), postgresQuoteIdentifier(user.Username))
 			if err := p.executeExec(ctx, p.Database, grantUserAccess, admin); err != nil {
 				log.Error(err, "failed to grant usage access on schema", "username", user.Username, "schema", s)
 				return err
 			}
 		}
-	case ACCESS_TYPE_READWRITE:
-		for _, s := range schemas {
-			grantUsage := fmt.Sprintf("GRANT USAGE ON SCHEMA \"%s\" TO \"%s\"", s, user.Username)
-			grantTables := fmt.Sprintf("GRANT SELECT, INSERT, DELETE, UPDATE ON ALL TABLES IN SCHEMA \"%s\" TO \"%s\"", s, user.Username)
-			defaultPrivileges := fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE \"%s\" IN SCHEMA \"%s\" GRANT SELECT, INSERT, DELETE, UPDATE ON TABLES TO \"%s\";",
-				p.MainUser.Username,
-				s,
-				user.Username,
-			)
-			err := p.executeExec(ctx, p.Database, grantUsage, admin)
-			if err != nil {
-				log.Error(err, "failed updating postgres user", "query", grantTables)
-				return err
-			}
-			err = p.executeExec(ctx, p.Database, grantTables, admin)
-			if err != nil {
-				log.Error(err, "failed updating postgres user", "query", grantTables)
-				return err
-			}
-			// If user is granted to the admin, admin can alter default privileges
-			// on installations that are not providing superusers
-			if p.RDSIAMImpersonateWorkaround {
-				err = p.execSettingRole(ctx, p.Database, defaultPrivileges, actingUser, admin)
-				if err != nil {
-					log.Error(err, "failed updating postgres user", "query", defaultPrivileges)
-					return err
-				}
-			} else {
-				err = p.executeExec(ctx, p.Database, defaultPrivileges, admin)
-				if err != nil {
-					log.Error(err, "failed updating postgres user", "query", defaultPrivileges)
-					return err
-				}
-			}
+	default:
+		profile, err := user.accessProfile(postgresAccessProfiles)
+		if err != nil {
+			return err
 		}
-	case ACCESS_TYPE_READONLY:
-		for _, s := range schemas {
-			grantUsage := fmt.Sprintf("GRANT USAGE ON SCHEMA \"%s\" TO \"%s\"", s, user.Username)
-			grantTables := fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA \"%s\" TO \"%s\"", s, user.Username)
-			defaultPrivileges := fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE \"%s\" IN SCHEMA \"%s\" GRANT SELECT ON TABLES TO \"%s\";",
-				p.MainUser.Username,
-				s,
-				user.Username,
-			)
-			err := p.executeExec(ctx, p.Database, grantUsage, admin)
-			if err != nil {
-				log.Error(err, "failed updating postgres user", "query", grantUsage)
-				return err
-			}
-			err = p.executeExec(ctx, p.Database, grantTables, admin)
-			if err != nil {
-				log.Error(err, "failed updating postgres user", "query", grantTables)
+		if err := p.grantAccessProfile(ctx, admin, actingUser, user, profile, schemas); err != nil {
+			return err
+		}
+		if p.EnforceMainUserOwnership && postgresCanCreate(profile) {
+			if err := p.enforceMainUserOwnership(ctx, admin, user); err != nil {
 				return err
 			}
-			if p.RDSIAMImpersonateWorkaround {
-				err = p.execSettingRole(ctx, p.Database, defaultPrivileges, actingUser, admin)
-				if err != nil {
-					log.Error(err, "failed updating postgres user", "query", defaultPrivileges)
-					return err
-				}
-			} else {
-				err = p.executeExec(ctx, p.Database, defaultPrivileges, admin)
-				if err != nil {
-					log.Error(err, "failed updating postgres user", "query", defaultPrivileges)
-					return err
-				}
-			}
 		}
-	default:
-		err := fmt.Errorf("unknown access type: %s", user.AccessType)
-		return err
 	}
 
 	for _, role := range user.ExtraPrivileges {
-		grantRole := fmt.Sprintf("GRANT \"%s\" to \"%s\"", role, user.Username)
+		grantRole := fmt.Sprintf("GRANT %s to %s", postgresQuoteIdentifier(role), postgresQuoteIdentifier(user.Username))
 		if err := p.executeExec(ctx, "postgres", grantRole, admin); err != nil {
 			return err
 		}
@@ -623,6 +1505,49 @@ func (p Postgres) setUserPermission(ctx context.Context, admin *DatabaseUser, us
 	return nil
 }
 
+// revokeSchemaPrivileges revokes privileges of the user on the schema, its objects and
+// default privileges on objects of owners
+func (p Postgres) revokeSchemaPrivileges(ctx context.Context, admin, user *DatabaseUser, schema string, owners []string) error {
+	log := log.FromContext(ctx)
+	// Everything that can be granted by an access profile is revoked, even if
+	// the current profile of the user doesn't have it, because it might've been
+	// granted by a previous one, revoking privileges that are not granted is a no-op
+	for _, objects := range postgresSchemaObjects(nil, nil, nil) {
+		for _, owner := range owners {
+			revokeDefaults := fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA %s REVOKE ALL ON %s FROM %s;",
+				postgresQuoteIdentifier(owner),
+				postgresQuoteIdentifier(schema),
+				objects.objects,
+				postgresQuoteIdentifier(user.Username),
+			)
+			// The main user changes its own default privileges, other owners can't log in
+			actingUser := admin
+			if owner == p.MainUser.Username {
+				actingUser = p.MainUser
+			}
+			if err := p.executeExec(ctx, p.Database, revokeDefaults, actingUser); err != nil {
+				log.Error(err, "failed removing default privileges from schema", "username", user.Username, "schema", schema, "owner", owner)
+				return err
+			}
+		}
+		revokeObjects := fmt.Sprintf("REVOKE ALL ON ALL %s IN SCHEMA %s FROM %s;",
+			objects.objects,
+			postgresQuoteIdentifier(schema),
+			postgresQuoteIdentifier(user.Username),
+		)
+		if err := p.executeExec(ctx, p.Database, revokeObjects, admin); err != nil {
+			log.Error(err, "failed revoking privileges from schema objects", "username", user.Username, "schema", schema)
+			return err
+		}
+	}
+	revokeAll := fmt.Sprintf("REVOKE ALL ON SCHEMA %s FROM %s;", postgresQuoteIdentifier(schema), postgresQuoteIdentifier(user.Username))
+	if err := p.executeExec(ctx, p.Database, revokeAll, admin); err != nil {
+		log.Error(err, "failed revoking privileges from schema", "username", user.Username, "schema", schema)
+		return err
+	}
+	return nil
+}
+
 func (p Postgres) deleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
 	log := log.FromContext(ctx)
 	if user.AccessType != ACCESS_TYPE_MAINUSER && p.isUserExist(ctx, admin, user) {
@@ -633,51 +1558,39 @@ func (p Postgres) deleteUser(ctx context.Context, admin *DatabaseUser, user *Dat
 
 		// Remove all extra roles
 		for _, role := range user.ExtraPrivileges {
-			grantRole := fmt.Sprintf("REVOKE \"%s\" from \"%s\"", role, user.Username)
+			grantRole := fmt.Sprintf("REVOKE %s from %s", postgresQuoteIdentifier(role), postgresQuoteIdentifier(user.Username))
 			if err := p.executeExec(ctx, "postgres", grantRole, admin); err != nil {
 				return err
 			}
 		}
 		if !user.GrantToAdmin && user.GrantToAdminOnDelete {
 			log.Info("Granting user to admin", "user", user.Username)
-			assignRoleToAdmin := fmt.Sprintf("GRANT \"%s\" TO \"%s\";", user.Username, admin.Username)
+			assignRoleToAdmin := fmt.Sprintf("GRANT %s TO %s;", postgresQuoteIdentifier(user.Username), postgresQuoteIdentifier(admin.Username))
 			if err := p.executeExec(ctx, p.Database, assignRoleToAdmin, admin); err != nil {
 				log.Error(err, "failed granting user to admin", "username", user.Username, "admin", admin.Username)
 			}
 		}
 
+		owners := p.objectOwners(ctx, admin, user)
 		for _, schema := range schemas {
-			revokeDefaults := fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE \"%s\" IN SCHEMA \"%s\" REVOKE ALL ON TABLES FROM \"%s\";",
-				p.MainUser.Username,
-				schema,
-				user.Username,
-			)
-			if err := p.executeExec(ctx, p.Database, revokeDefaults, p.MainUser); err != nil {
-				log.Error(err, "failed removing default privileges from schema", "username", user.Username, "schema", schema)
-				return err
-			}
-			revokeAll := fmt.Sprintf("REVOKE ALL ON SCHEMA \"%s\" FROM \"%s\";", schema, user.Username)
-			if err := p.executeExec(ctx, p.Database, revokeAll, admin); err != nil {
-				log.Error(err, "failed revoking privileges from schema", "username", user.Username, "schema", schema)
-				return err
-			}
-			dropOwned := fmt.Sprintf("DROP OWNED BY \"%s\";", user.Username)
-			if err := p.executeExec(ctx, p.Database, dropOwned, admin); err != nil {
-				log.Error(err, "failed dropping owned", "username", user.Username)
+			if err := p.revokeSchemaPrivileges(ctx, admin, user, schema, owners); err != nil {
 				return err
 			}
-
 		}
 	}
-	delete := fmt.Sprintf("DROP USER \"%s\";", user.Username)
+	delete := fmt.Sprintf("DROP USER %s;", postgresQuoteIdentifier(user.Username))
 	if p.isUserExist(ctx, admin, user) {
+		if err := p.releaseOwnedObjects(ctx, admin, user); err != nil {
+			return err
+		}
+		Connections.invalidateUser(p.poolInstance(), user.Username)
+		p.terminateBackends(ctx, admin, "usename", user.Username)
 		err := p.executeExec(ctx, "postgres", delete, admin)
 		if err != nil {
-			pqErr := err.(*pq.Error)
-			if pqErr.Code == "2BP01" {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "2BP01" {
 				// 2BP01 dependent_objects_still_exist
-				log.Error(err, "dependent objects still exist")
-				return nil
+				return fmt.Errorf("%w: %v", ErrOwnedObjects, err)
 			}
 			return err
 		}
@@ -18,10 +18,13 @@ package database
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
 	"testing"
 
 	"github.com/db-operator/db-operator/pkg/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func testPostgres() (*Postgres, *DatabaseUser) {
@@ -220,9 +223,9 @@ func TestPostgresReadOnlyUserLifecycleNoAdminGrant(t *testing.T) {
 	err := p.createUser(context.TODO(), admin, readonlyUser)
 	assert.NoErrorf(t, err, "Unexpected error %v", err)
 
-	// Test that it can't be created again
+	// Test that it can be created again
 	err = p.createUser(context.TODO(), admin, readonlyUser)
-	assert.Error(t, err, "Was expecting an error")
+	assert.NoErrorf(t, err, "Unexpected error %v", err)
 
 	// Test that it can be updated
 	err = p.updateUser(context.TODO(), admin, readonlyUser)
@@ -299,9 +302,9 @@ func TestPostgresReadWriteUserLifecycleNoAdminGrant(t *testing.T) {
 	err := p.createUser(context.TODO(), admin, readwriteUser)
 	assert.NoErrorf(t, err, "Unexpected error %v", err)
 
-	// Test that it can't be created again
+	// Test that it can be created again
 	err = p.createUser(context.TODO(), admin, readwriteUser)
-	assert.Error(t, err, "Was expecting an error")
+	assert.NoErrorf(t, err, "Unexpected error %v", err)
 
 	// Test that it can be updated
 	err = p.updateUser(context.TODO(), admin, readwriteUser)
@@ -382,9 +385,9 @@ func TestPostgresReadOnlyUserLifecycleAdminGrant(t *testing.T) {
 	err := p.createUser(context.TODO(), admin, readonlyUser)
 	assert.NoErrorf(t, err, "Unexpected error %v", err)
 
-	// Test that it can't be created again
+	// Test that it can be created again
 	err = p.createUser(context.TODO(), admin, readonlyUser)
-	assert.Error(t, err, "Was expecting an error")
+	assert.NoErrorf(t, err, "Unexpected error %v", err)
 
 	// Test that it can be updated
 	err = p.updateUser(context.TODO(), admin, readonlyUser)
@@ -461,9 +464,9 @@ func TestPostgresReadWriteUserLifecycleAdminGrant(t *testing.T) {
 	err := p.createUser(context.TODO(), admin, readwriteUser)
 	assert.NoErrorf(t, err, "Unexpected error %v", err)
 
-	// Test that it can't be created again
+	// Test that it can be created again
 	err = p.createUser(context.TODO(), admin, readwriteUser)
-	assert.Error(t, err, "Was expecting an error")
+	assert.NoErrorf(t, err, "Unexpected error %v", err)
 
 	// Test that it can be updated
 	err = p.updateUser(context.TODO(), admin, readwriteUser)
@@ -659,3 +662,323 @@ func TestPostgresParseAdminCredentials(t *testing.T) {
 	assert.Equal(t, "postgres", cred.Username, "expect same values")
 	assert.Equal(t, string(validData3["postgresql-postgres-password"]), cred.Password, "expect same values")
 }
+
+func TestPostgresDeletionPolicy(t *testing.T) {
+	ctx := context.TODO()
+	admin := &DatabaseUser{Username: "admin", Password: "adminpwd"}
+	server := newFakeSQLServer(fakePostgresDialect, admin)
+	useFakeSQLServer(t, server)
+
+	e, err := GetEngine("postgres")
+	require.NoError(t, err)
+	mainUser := &DatabaseUser{Username: "policy_main", Password: "mainpwd", AccessType: ACCESS_TYPE_MAINUSER}
+	cfg := EngineConfig{
+		Instance: "policy-postgres", Host: "postgres", Port: 5432, Database: "policy", MainUser: mainUser,
+		Spec: []byte(`{"deletionPolicy": "fail"}`),
+	}
+	db, err := e.New(ctx, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		Connections.Invalidate(poolInstance(cfg.Instance, cfg.Host, cfg.Port))
+	})
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	require.NoError(t, CreateOrUpdateUser(ctx, db, mainUser, admin))
+
+	migrator := &DatabaseUser{Username: "policy_migrator", Password: "migratorpwd", AccessType: ACCESS_TYPE_READWRITE}
+	require.NoError(t, CreateUser(ctx, db, migrator, admin))
+	server.users[migrator.Username].owns["policy"] = true
+
+	// The policy of the database doesn't let users with objects be removed
+	err = DeleteUser(ctx, db, migrator, admin)
+	assert.True(t, errors.Is(err, ErrOwnedObjects), "unexpected error: %v", err)
+	assert.NoError(t, db.CheckStatus(ctx, migrator), "the user must not be removed")
+
+	// Objects are given to the main user, when it's overridden by the user,
+	// and sessions of the user are terminated
+	_, dsn := db.(Postgres).dataSource("policy", migrator.Username, migrator.Password)
+	session := server.openSession(t, dsn)
+	migrator.DeletionPolicy = DELETION_POLICY_REASSIGN
+	require.NoError(t, DeleteUser(ctx, db, migrator, admin))
+	assert.True(t, server.users[mainUser.Username].owns["policy"])
+	assert.NotContains(t, server.users, migrator.Username)
+	_, err = session.ExecContext(ctx, "SELECT 1")
+	assert.ErrorIs(t, err, driver.ErrBadConn, "the session of the user must be terminated")
+
+	// Sessions are closed before the database is dropped, otherwise it can't be dropped
+	_, dsn = db.(Postgres).dataSource("policy", mainUser.Username, mainUser.Password)
+	session = server.openSession(t, dsn)
+	require.NoError(t, DeleteDatabase(ctx, db, admin))
+	assert.NotContains(t, server.databases, "policy")
+	_, err = session.ExecContext(ctx, "SELECT 1")
+	assert.ErrorIs(t, err, driver.ErrBadConn, "the session in the database must be terminated")
+}
+
+func TestPostgresDefaultDeletionPolicy(t *testing.T) {
+	// Objects are never dropped, unless it's requested
+	p := Postgres{}
+	assert.Equal(t, DELETION_POLICY_REASSIGN, p.deletionPolicy(&DatabaseUser{}))
+	p.DeletionPolicy = DELETION_POLICY_DROP
+	assert.Equal(t, DELETION_POLICY_DROP, p.deletionPolicy(&DatabaseUser{}))
+	assert.Equal(t, DELETION_POLICY_FAIL, p.deletionPolicy(&DatabaseUser{DeletionPolicy: DELETION_POLICY_FAIL}))
+}
+
+func TestPostgresDatabaseOptions(t *testing.T) {
+	ctx := context.TODO()
+	admin := &DatabaseUser{Username: "admin", Password: "adminpwd"}
+	server := newFakeSQLServer(fakePostgresDialect, admin)
+	useFakeSQLServer(t, server)
+
+	e, err := GetEngine("postgres")
+	require.NoError(t, err)
+	mainUser := &DatabaseUser{Username: "options_main", Password: "mainpwd", AccessType: ACCESS_TYPE_MAINUSER}
+	cfg := EngineConfig{
+		Instance: "options-postgres", Host: "postgres", Port: 5432, Database: "options", MainUser: mainUser,
+		Spec: []byte(`{
+			"encoding": "UTF8", "lcCollate": "C", "icuLocale": "de-DE", "connectionLimit": 20, "owner": "options_main",
+			"settings": {"statement_timeout": "30s", "search_path": "$user, public, app"}
+		}`),
+	}
+	db, err := e.New(ctx, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		Connections.Invalidate(poolInstance(cfg.Instance, cfg.Host, cfg.Port))
+	})
+
+	// The owner doesn't exist yet, and template1 can't be copied with another locale
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	assert.Equal(t, map[string]string{
+		"TEMPLATE": "template0", "ENCODING": "UTF8", "LC_COLLATE": "C", "LOCALE_PROVIDER": "icu", "ICU_LOCALE": "de-DE", "CONNECTION LIMIT": "20",
+	}, server.options["options"])
+	assert.Equal(t, map[string]string{"search_path": "'$user', 'public', 'app'", "statement_timeout": "'30s'"}, server.settings["options"])
+
+	require.NoError(t, CreateOrUpdateUser(ctx, db, mainUser, admin))
+	assert.Equal(t, "options_main", server.options["options"]["OWNER"])
+
+	// Settings that are removed from the spec are reset on the next reconciliation
+	cfg.Spec = []byte(`{"connectionLimit": -1, "settings": {"timezone": "UTC"}}`)
+	db, err = e.New(ctx, cfg)
+	require.NoError(t, err)
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	assert.Equal(t, "-1", server.options["options"]["CONNECTION LIMIT"])
+	assert.Equal(t, map[string]string{"timezone": "'UTC'"}, server.settings["options"])
+
+	// Without settings in the spec, only the ones that were managed before are reset
+	server.settings["options"]["work_mem"] = "'64MB'"
+	cfg.Spec = []byte(`{}`)
+	db, err = e.New(ctx, cfg)
+	require.NoError(t, err)
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	require.NoError(t, PruneSettings(ctx, db, []string{"timezone"}, admin))
+	assert.Equal(t, map[string]string{"work_mem": "'64MB'"}, server.settings["options"])
+
+	cfg.Spec = []byte(`{"settings": {"statement_timeout = 0; DROP DATABASE options; --": "1"}}`)
+	db, err = e.New(ctx, cfg)
+	require.NoError(t, err)
+	assert.Error(t, CreateDatabase(ctx, db, admin))
+}
+
+func TestPostgresExtensions(t *testing.T) {
+	ctx := context.TODO()
+	admin := &DatabaseUser{Username: "admin", Password: "adminpwd"}
+	server := newFakeSQLServer(fakePostgresDialect, admin)
+	useFakeSQLServer(t, server)
+
+	e, err := GetEngine("postgres")
+	require.NoError(t, err)
+	mainUser := &DatabaseUser{Username: "extensions_main", Password: "mainpwd", AccessType: ACCESS_TYPE_MAINUSER}
+	cfg := EngineConfig{
+		Instance: "extensions-postgres", Host: "postgres", Port: 5432, Database: "extensions", MainUser: mainUser,
+		Spec: []byte(`{
+			"extensions": ["uuid-ossp", "postgis"],
+			"extensionConfigs": [{"name": "postgis", "version": "3.4.0", "schema": "gis"}]
+		}`),
+	}
+	db, err := e.New(ctx, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		Connections.Invalidate(poolInstance(cfg.Instance, cfg.Host, cfg.Port))
+	})
+
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	require.NoError(t, CreateOrUpdateUser(ctx, db, mainUser, admin))
+	assert.Equal(t, map[string]*fakeSQLExtension{
+		"uuid-ossp": {version: "1.0", schema: "public"},
+		"postgis":   {version: "3.4.0", schema: "gis"},
+	}, server.extensions["extensions"])
+	require.NoError(t, db.CheckStatus(ctx, mainUser))
+
+	versions, err := ReconcileExtensions(ctx, db, nil, admin)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"uuid-ossp": "1.0", "postgis": "3.4.0"}, versions)
+
+	// Extensions are updated and moved when the spec is changed, removed ones are kept without pruning
+	cfg.Spec = []byte(`{"extensionConfigs": [{"name": "postgis", "version": "3.5.0", "schema": "public"}]}`)
+	db, err = e.New(ctx, cfg)
+	require.NoError(t, err)
+	assert.Error(t, db.CheckStatus(ctx, mainUser), "the version is not updated yet")
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	assert.Equal(t, &fakeSQLExtension{version: "3.5.0", schema: "public"}, server.extensions["extensions"]["postgis"])
+	versions, err = ReconcileExtensions(ctx, db, []string{"postgis", "uuid-ossp"}, admin)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"postgis": "3.5.0"}, versions)
+	assert.Contains(t, server.extensions["extensions"], "uuid-ossp")
+
+	cfg.Spec = []byte(`{"extensions": ["postgis"], "pruneExtensions": true}`)
+	db, err = e.New(ctx, cfg)
+	require.NoError(t, err)
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	versions, err = ReconcileExtensions(ctx, db, []string{"postgis", "uuid-ossp"}, admin)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"postgis": "3.5.0"}, versions)
+	assert.NotContains(t, server.extensions["extensions"], "uuid-ossp")
+}
+
+func TestPostgresSchemaPrune(t *testing.T) {
+	ctx := context.TODO()
+	admin := &DatabaseUser{Username: "admin", Password: "adminpwd"}
+	server := newFakeSQLServer(fakePostgresDialect, admin)
+	useFakeSQLServer(t, server)
+
+	e, err := GetEngine("postgres")
+	require.NoError(t, err)
+	mainUser := &DatabaseUser{Username: "schemas_main", Password: "mainpwd", AccessType: ACCESS_TYPE_MAINUSER}
+	reader := &DatabaseUser{Username: "schemas_reader", Password: "readerpwd", AccessType: ACCESS_TYPE_READONLY}
+	cfg := EngineConfig{
+		Instance: "schemas-postgres", Host: "postgres", Port: 5432, Database: "schemas", MainUser: mainUser,
+		Spec: []byte(`{"schemas": ["app", "reports"]}`),
+	}
+	newDb := func(spec string) Database {
+		cfg.Spec = []byte(spec)
+		db, err := e.New(ctx, cfg)
+		require.NoError(t, err)
+		return db
+	}
+	t.Cleanup(func() {
+		Connections.Invalidate(poolInstance(cfg.Instance, cfg.Host, cfg.Port))
+	})
+
+	db := newDb(`{"schemas": ["app", "reports"]}`)
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	require.NoError(t, CreateOrUpdateUser(ctx, db, mainUser, admin))
+	require.NoError(t, CreateUser(ctx, db, reader, admin))
+	assert.Equal(t, "managed by db-operator", server.schemas["schemas"]["reports"])
+	retained, err := PruneSchemas(ctx, db, admin)
+	require.NoError(t, err)
+	assert.Empty(t, retained)
+
+	// Schemas are retained by default, but users lose access to them
+	db = newDb(`{"schemas": ["app"]}`)
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	retained, err = PruneSchemas(ctx, db, admin)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reports"}, retained)
+	for object := range server.users["schemas_reader"].objects {
+		assert.NotEqual(t, "reports", object.schema, "privileges on the schema and its objects must be revoked")
+	}
+	assert.Contains(t, server.users["schemas_reader"].objects, fakeSQLObject{database: "schemas", kind: "SCHEMA", schema: "app"})
+	assert.Contains(t, server.users["schemas_main"].objects, fakeSQLObject{database: "schemas", kind: "SCHEMA", schema: "reports"})
+	assert.Contains(t, server.schemas["schemas"], "reports")
+
+	// Grants of users are recomputed for the new set of schemas
+	require.NoError(t, UpdateUser(ctx, db, reader, admin))
+	assert.Equal(t, ACCESS_TYPE_READONLY, server.users["schemas_reader"].grants["schemas"])
+
+	db = newDb(`{"schemas": ["app"], "schemaPrunePolicy": "drop"}`)
+	retained, err = PruneSchemas(ctx, db, admin)
+	require.NoError(t, err)
+	assert.Empty(t, retained)
+	assert.NotContains(t, server.schemas["schemas"], "reports")
+	assert.NotContains(t, server.users["schemas_main"].objects, fakeSQLObject{database: "schemas", kind: "SCHEMA", schema: "reports"})
+	assert.Contains(t, server.schemas["schemas"], "app")
+
+	// The public schema is created again, when it's not supposed to be dropped anymore
+	db = newDb(`{"schemas": ["app"], "dropPublicSchema": true}`)
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	assert.NotContains(t, server.schemas["schemas"], "public")
+	db = newDb(`{"schemas": ["app"]}`)
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	assert.Contains(t, server.schemas["schemas"], "public")
+	public := fakeSQLObject{database: "schemas", kind: "SCHEMA", schema: "public"}
+	assert.NotContains(t, server.users["schemas_reader"].objects, public)
+	require.NoError(t, UpdateUser(ctx, db, reader, admin))
+	assert.Equal(t, []string{"USAGE"}, server.users["schemas_reader"].objects[public])
+}
+
+func TestPostgresSchemaPruneExisting(t *testing.T) {
+	ctx := context.TODO()
+	admin := &DatabaseUser{Username: "admin", Password: "adminpwd"}
+	server := newFakeSQLServer(fakePostgresDialect, admin)
+	useFakeSQLServer(t, server)
+
+	e, err := GetEngine("postgres")
+	require.NoError(t, err)
+	cfg := EngineConfig{Instance: "existing-postgres", Host: "postgres", Port: 5432, Database: "existing"}
+	newDb := func(spec string) Database {
+		cfg.Spec = []byte(spec)
+		db, err := e.New(ctx, cfg)
+		require.NoError(t, err)
+		return db
+	}
+	t.Cleanup(func() {
+		Connections.Invalidate(poolInstance(cfg.Instance, cfg.Host, cfg.Port))
+	})
+
+	require.NoError(t, CreateDatabase(ctx, newDb(`{}`), admin))
+	server.schemas["existing"]["billing"] = "owned by the billing team"
+	server.schemas["existing"]["legacy"] = ""
+
+	// Schemas that existed before are not marked as created by the operator
+	require.NoError(t, CreateDatabase(ctx, newDb(`{"schemas": ["billing", "legacy", "app"]}`), admin))
+	assert.Equal(t, "owned by the billing team", server.schemas["existing"]["billing"])
+	assert.Equal(t, "", server.schemas["existing"]["legacy"])
+	assert.Equal(t, "managed by db-operator", server.schemas["existing"]["app"])
+
+	// So they're not dropped, when they're removed from the spec
+	retained, err := PruneSchemas(ctx, newDb(`{"schemaPrunePolicy": "drop"}`), admin)
+	require.NoError(t, err)
+	assert.Empty(t, retained)
+	assert.Contains(t, server.schemas["existing"], "billing")
+	assert.Contains(t, server.schemas["existing"], "legacy")
+	assert.NotContains(t, server.schemas["existing"], "app")
+}
+
+func TestPostgresSettingValue(t *testing.T) {
+	assert.Equal(t, `'$user', 'public'`, postgresSettingValue("search_path", "$user, public"))
+	assert.Equal(t, `'pg_stat_statements', 'auto_explain'`, postgresSettingValue("session_preload_libraries", "pg_stat_statements,auto_explain"))
+	assert.Equal(t, `'ISO, MDY'`, postgresSettingValue("DateStyle", "ISO, MDY"))
+	assert.Equal(t, `'billing, nightly'`, postgresSettingValue("application_name", "billing, nightly"))
+	assert.Equal(t, `'30s'`, postgresSettingValue("statement_timeout", "30s"))
+}
+
+func TestPostgresRoleOptions(t *testing.T) {
+	ctx := context.TODO()
+	db, server, admin := testAccessProfileEngine(t, "postgres", fakePostgresDialect, 5432)
+	user := &DatabaseUser{
+		Username:   "analytics",
+		Password:   "analyticspwd",
+		AccessType: ACCESS_TYPE_READONLY,
+		Spec: []byte(`{
+			"connectionLimit": 5, "validUntil": "2030-01-02T03:04:05Z",
+			"settings": {"statement_timeout": "5min", "work_mem": "64MB", "search_path": "analytics, public"}
+		}`),
+	}
+
+	require.NoError(t, CreateUser(ctx, db, user, admin))
+	role := server.users["analytics"]
+	assert.Equal(t, "5", role.connectionLimit)
+	assert.Equal(t, "2030-01-02T03:04:05Z", role.validUntil)
+	assert.Equal(t, map[string]string{
+		"search_path": "'analytics', 'public'", "statement_timeout": "'5min'", "work_mem": "'64MB'",
+	}, role.settings)
+
+	// Options that are removed from the spec are reset
+	user.Spec = []byte(`{"settings": {"statement_timeout": "1min"}}`)
+	require.NoError(t, UpdateUser(ctx, db, user, admin))
+	assert.Equal(t, "-1", role.connectionLimit)
+	assert.Equal(t, "infinity", role.validUntil)
+	assert.Equal(t, map[string]string{"statement_timeout": "'1min'"}, server.users["analytics"].settings)
+
+	// Main users are not configured by DbUsers
+	assert.Empty(t, server.users["profile_main"].settings)
+	assert.Empty(t, server.users["profile_main"].connectionLimit)
+}
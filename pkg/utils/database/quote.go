@@ -0,0 +1,133 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Names, passwords and privileges are coming from user-controlled resources,
+// so they must never be put into a statement without being quoted by one of
+// the functions below. Identifiers are names of databases, users, roles, schemas
+// and so on, literals are string values, e.g. passwords.
+
+// postgresQuoteIdentifier quotes a postgres identifier, it's cut at the first
+// zero byte, because postgres can't store it anyway
+func postgresQuoteIdentifier(name string) string {
+	return pq.QuoteIdentifier(name)
+}
+
+// postgresQuoteLiteral quotes a postgres string literal
+func postgresQuoteLiteral(value string) string {
+	return strings.TrimSpace(pq.QuoteLiteral(value))
+}
+
+// postgresQuoteConnValue quotes a value of a key/value connection string
+func postgresQuoteConnValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// mysqlQuoteIdentifier quotes a mysql identifier with backticks
+func mysqlQuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// mysqlQuoteLiteral quotes a mysql string literal, quotes are doubled, so the literal
+// is terminated correctly even if NO_BACKSLASH_ESCAPES is set on the server, but then
+// backslashes are not unescaped by it, and values with backslashes are not preserved
+func mysqlQuoteLiteral(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `''`)
+	return "'" + value + "'"
+}
+
+// mysqlQuoteAccount quotes a mysql account name that can connect from any host
+func mysqlQuoteAccount(user string) string {
+	return mysqlQuoteAccountHost(user, "%")
+}
+
+// mysqlQuoteAccountHost quotes a mysql account name that can connect from the host
+func mysqlQuoteAccountHost(user, host string) string {
+	return mysqlQuoteLiteral(user) + "@" + mysqlQuoteLiteral(host)
+}
+
+// clickhouseQuoteIdentifier quotes a clickhouse identifier with backticks
+func clickhouseQuoteIdentifier(name string) string {
+	name = strings.ReplaceAll(name, `\`, `\\`)
+	name = strings.ReplaceAll(name, "`", "\\`")
+	return "`" + name + "`"
+}
+
+// clickhouseQuoteLiteral quotes a clickhouse string literal
+func clickhouseQuoteLiteral(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// Identifiers that don't have to be quoted in clickhouse
+var clickhousePlainIdentifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// sqlserverQuoteIdentifier quotes an sql server identifier like QUOTENAME does
+func sqlserverQuoteIdentifier(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+// sqlserverQuoteLiteral quotes an sql server unicode string literal
+func sqlserverQuoteLiteral(value string) string {
+	return "N'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// oracleQuoteIdentifier quotes an oracle identifier, oracle doesn't have a way
+// to escape a double quote in an identifier, so names must be checked by
+// oracleValidateIdentifier before they're quoted
+func oracleQuoteIdentifier(name string) string {
+	return `"` + name + `"`
+}
+
+// oracleValidateIdentifier checks if a name can be used as a quoted identifier,
+// passwords are quoted as identifiers too, so they're checked by this function as well
+func oracleValidateIdentifier(name string) error {
+	if strings.ContainsAny(name, "\"\x00") {
+		return errors.New("oracle identifiers can't contain double quotes or zero bytes")
+	}
+	return nil
+}
+
+// oracleQuoteLiteral quotes an oracle string literal
+func oracleQuoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// System privileges can't be quoted, so only letters, digits and spaces are allowed,
+// e.g. "CREATE TABLE" or "SELECT ANY DICTIONARY"
+var oraclePrivilegeRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_$# ]*$`)
+
+// oracleValidatePrivilege checks if a privilege can be granted without quoting
+func oracleValidatePrivilege(privilege string) error {
+	if !oraclePrivilegeRegexp.MatchString(privilege) {
+		return fmt.Errorf("invalid oracle privilege: %s", privilege)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// quoteSeeds are known to break statements that are built without quoting
+var quoteSeeds = []string{
+	"",
+	"user",
+	"user\"; DROP DATABASE test; --",
+	"user'; DROP DATABASE test; --",
+	"user`; DROP DATABASE test; --",
+	"user]; DROP DATABASE test; --",
+	"user\\'; DROP DATABASE test; --",
+	"user\\",
+	"'\"`[]\\",
+	"user\x00",
+	"юзер",
+}
+
+// lexQuoted reads a quoted token the way the database parser does. The token must
+// start with open, the closing quote can be escaped by doubling it or by a backslash,
+// if the dialect allows that. It returns the unescaped value and everything that's
+// left after the token, so if the rest is not empty, it would be parsed as SQL
+func lexQuoted(s string, open, close byte, doubled, backslash bool) (value string, rest string, ok bool) {
+	if len(s) == 0 || s[0] != open {
+		return "", s, false
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case backslash && c == '\\':
+			if i+1 == len(s) {
+				return "", "", false
+			}
+			i++
+			b.WriteByte(s[i])
+		case c == close:
+			if doubled && i+1 < len(s) && s[i+1] == close {
+				i++
+				b.WriteByte(close)
+				continue
+			}
+			return b.String(), s[i+1:], true
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", false
+}
+
+// assertQuoted checks that the quoted value is a single token with the original value
+func assertQuoted(t *testing.T, expected, quoted, value, rest string, ok bool) {
+	t.Helper()
+	assert.True(t, ok, "unterminated token %q", quoted)
+	assert.Empty(t, rest, "token %q is terminated too early", quoted)
+	assert.Equal(t, expected, value, "token %q", quoted)
+}
+
+func FuzzPostgresQuote(f *testing.F) {
+	for _, seed := range quoteSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		// Postgres can't store zero bytes in names
+		expected, _, _ := strings.Cut(s, "\x00")
+		quoted := postgresQuoteIdentifier(s)
+		value, rest, ok := lexQuoted(quoted, '"', '"', true, false)
+		assertQuoted(t, expected, quoted, value, rest, ok)
+
+		// Backslashes are only escapes in E'' literals
+		quoted = postgresQuoteLiteral(s)
+		if strings.HasPrefix(quoted, "E") {
+			value, rest, ok = lexQuoted(quoted[1:], '\'', '\'', true, true)
+		} else {
+			value, rest, ok = lexQuoted(quoted, '\'', '\'', true, false)
+		}
+		assertQuoted(t, s, quoted, value, rest, ok)
+
+		quoted = postgresQuoteConnValue(s)
+		value, rest, ok = lexQuoted(quoted, '\'', '\'', false, true)
+		assertQuoted(t, s, quoted, value, rest, ok)
+	})
+}
+
+func FuzzMysqlQuote(f *testing.F) {
+	for _, seed := range quoteSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		quoted := mysqlQuoteIdentifier(s)
+		value, rest, ok := lexQuoted(quoted, '`', '`', true, false)
+		assertQuoted(t, s, quoted, value, rest, ok)
+
+		quoted = mysqlQuoteLiteral(s)
+		value, rest, ok = lexQuoted(quoted, '\'', '\'', true, true)
+		assertQuoted(t, s, quoted, value, rest, ok)
+
+		// With NO_BACKSLASH_ESCAPES the literal is still terminated correctly,
+		// but the value is only preserved when there are no backslashes
+		value, rest, ok = lexQuoted(quoted, '\'', '\'', true, false)
+		assert.True(t, ok)
+		assert.Empty(t, rest)
+		if !strings.Contains(s, `\`) {
+			assert.Equal(t, s, value)
+		}
+
+		quoted = mysqlQuoteAccount(s)
+		value, rest, ok = lexQuoted(quoted, '\'', '\'', true, true)
+		assert.True(t, ok)
+		assert.Equal(t, s, value)
+		assert.Equal(t, "@'%'", rest)
+	})
+}
+
+func FuzzClickhouseQuote(f *testing.F) {
+	for _, seed := range quoteSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		quoted := clickhouseQuoteIdentifier(s)
+		value, rest, ok := lexQuoted(quoted, '`', '`', true, true)
+		assertQuoted(t, s, quoted, value, rest, ok)
+
+		quoted = clickhouseQuoteLiteral(s)
+		value, rest, ok = lexQuoted(quoted, '\'', '\'', true, true)
+		assertQuoted(t, s, quoted, value, rest, ok)
+
+		quoted = clickhouseSettingName(s)
+		if quoted != s {
+			value, rest, ok = lexQuoted(quoted, '`', '`', true, true)
+			assertQuoted(t, s, quoted, value, rest, ok)
+		} else {
+			assert.Regexp(t, clickhousePlainIdentifierRegexp, s)
+		}
+	})
+}
+
+func FuzzSQLServerQuote(f *testing.F) {
+	for _, seed := range quoteSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		quoted := sqlserverQuoteIdentifier(s)
+		value, rest, ok := lexQuoted(quoted, '[', ']', true, false)
+		assertQuoted(t, s, quoted, value, rest, ok)
+
+		quoted = sqlserverQuoteLiteral(s)
+		assert.True(t, strings.HasPrefix(quoted, "N"))
+		value, rest, ok = lexQuoted(quoted[1:], '\'', '\'', true, false)
+		assertQuoted(t, s, quoted, value, rest, ok)
+	})
+}
+
+func FuzzOracleQuote(f *testing.F) {
+	for _, seed := range quoteSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if err := oracleValidateIdentifier(s); err == nil {
+			quoted := oracleQuoteIdentifier(s)
+			value, rest, ok := lexQuoted(quoted, '"', '"', false, false)
+			assertQuoted(t, s, quoted, value, rest, ok)
+		} else {
+			assert.True(t, strings.ContainsAny(s, "\"\x00"))
+		}
+
+		quoted := oracleQuoteLiteral(s)
+		value, rest, ok := lexQuoted(quoted, '\'', '\'', true, false)
+		assertQuoted(t, s, quoted, value, rest, ok)
+
+		if err := oracleValidatePrivilege(s); err == nil {
+			assert.NotContains(t, s, "\"")
+			assert.NotContains(t, s, "'")
+			assert.NotContains(t, s, ";")
+		}
+	})
+}
+
+func TestQuoteExamples(t *testing.T) {
+	assert.Equal(t, `"user""name"`, postgresQuoteIdentifier(`user"name`))
+	assert.Equal(t, `'user''name'`, postgresQuoteLiteral(`user'name`))
+	assert.Equal(t, `E'user\\''name'`, postgresQuoteLiteral(`user\'name`))
+	assert.Equal(t, "`user``name`", mysqlQuoteIdentifier("user`name"))
+	assert.Equal(t, `'user\\''name'`, mysqlQuoteLiteral(`user\'name`))
+	assert.Equal(t, "`user\\`name`", clickhouseQuoteIdentifier("user`name"))
+	assert.Equal(t, `'user\\\'name'`, clickhouseQuoteLiteral(`user\'name`))
+	assert.Equal(t, "max_threads", clickhouseSettingName("max_threads"))
+	assert.Equal(t, "[user]]name]", sqlserverQuoteIdentifier("user]name"))
+	assert.Equal(t, "N'user''name'", sqlserverQuoteLiteral("user'name"))
+	assert.Equal(t, `"USER"`, oracleQuoteIdentifier("USER"))
+	assert.Equal(t, "'user''name'", oracleQuoteLiteral("user'name"))
+}
+
+func TestOracleValidateNames(t *testing.T) {
+	o := Oracle{Database: "TESTDB", Tablespaces: []string{"DATA"}}
+	user := &DatabaseUser{Username: "USER", Password: "pwd", ExtraPrivileges: []string{"CREATE TABLE"}}
+	assert.NoError(t, o.validateNames(user))
+	assert.NoError(t, o.validateNames(nil))
+
+	user.Password = `pwd" ACCOUNT UNLOCK`
+	err := o.validateNames(user)
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "ACCOUNT UNLOCK")
+
+	user.Password = "pwd"
+	user.ExtraPrivileges = []string{"DBA TO PUBLIC; --"}
+	assert.Error(t, o.validateNames(user))
+
+	o.Tablespaces = []string{`DATA" QUOTA UNLIMITED`}
+	assert.Error(t, o.validateNames(nil))
+}
@@ -0,0 +1,490 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+)
+
+// RecordedOperation is a call of the `Database` interface that was made on a Recorder
+type RecordedOperation struct {
+	// Name of the function, e.g. "createDatabase" or "CheckStatus"
+	Method   string
+	Database string
+	// The user that was passed to the function, or the admin,
+	// if the function doesn't have a user argument
+	User  string
+	Query string
+	// An error that was returned
+	Err error
+}
+
+// RecordedGrant is an access of a user to a database
+type RecordedGrant struct {
+	AccessType string
+	// A custom access profile, it's nil for built-in access types
+	Profile         *AccessProfile
+	ExtraPrivileges []string
+}
+
+// RecordedUser is a user of the in-memory model
+type RecordedUser struct {
+	Password string
+	// Grants per database
+	Grants map[string]RecordedGrant
+}
+
+// recorderState is an in-memory model of a database server
+type recorderState struct {
+	mu         sync.Mutex
+	databases  map[string]bool
+	users      map[string]*RecordedUser
+	operations []RecordedOperation
+	// Injected errors per function, they're returned by the next calls
+	failures map[string][]error
+}
+
+func newRecorderState() *recorderState {
+	return &recorderState{
+		databases: map[string]bool{},
+		users:     map[string]*RecordedUser{},
+		failures:  map[string][]error{},
+	}
+}
+
+var (
+	recorderStatesMu sync.Mutex
+	// States of Recorders that are created by the "recorder" engine per DbInstance,
+	// so a test can inspect what controllers have done with databases of its instance,
+	// and tests that use different instances can run in parallel
+	recorderStates = map[string]*recorderState{}
+)
+
+// recorderStateOf returns the state of the instance, it's created on the first call
+func recorderStateOf(instance string) *recorderState {
+	recorderStatesMu.Lock()
+	defer recorderStatesMu.Unlock()
+	state, ok := recorderStates[instance]
+	if !ok {
+		state = newRecorderState()
+		recorderStates[instance] = state
+	}
+	return state
+}
+
+// Recorder is a database interface implementation for testing, it keeps an in-memory
+// model of databases, users and grants and records every operation in order.
+// It doesn't connect anywhere, so controllers can be tested without a database server
+type Recorder struct {
+	Host     string
+	Port     uint16
+	Database string
+	state    *recorderState
+}
+
+func init() {
+	Register(Engine{
+		Engine: engines.Recorder,
+		New:    newRecorder,
+	})
+}
+
+func newRecorder(ctx context.Context, cfg EngineConfig) (Database, error) {
+	return Recorder{
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		Database: cfg.Database,
+		state:    recorderStateOf(cfg.Instance),
+	}, nil
+}
+
+// RecorderOf returns a Recorder that shares the state with databases of the DbInstance,
+// that are created by the "recorder" engine
+func RecorderOf(instance string) Recorder {
+	return Recorder{
+		Host:  DB_DUMMY_HOSTNAME,
+		Port:  DB_DUMMY_PORT,
+		state: recorderStateOf(instance),
+	}
+}
+
+// ReleaseRecorder removes the state of the DbInstance, so the next Recorder
+// of an instance with the same name starts without databases and users
+func ReleaseRecorder(instance string) {
+	recorderStatesMu.Lock()
+	defer recorderStatesMu.Unlock()
+	delete(recorderStates, instance)
+}
+
+// NewRecorder returns a Recorder that doesn't share the state with the "recorder" engine
+func NewRecorder(database string) Recorder {
+	return Recorder{
+		Host:     DB_DUMMY_HOSTNAME,
+		Port:     DB_DUMMY_PORT,
+		Database: database,
+		state:    newRecorderState(),
+	}
+}
+
+// Functions that are used by tests to set up and inspect the recorder
+
+// ForDatabase returns a Recorder of another database that shares the state
+func (r Recorder) ForDatabase(database string) Recorder {
+	r.Database = database
+	return r
+}
+
+// FailNext makes the next call of the function return the error,
+// errors are returned in the order they're injected
+func (r Recorder) FailNext(method string, err error) {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	r.state.failures[method] = append(r.state.failures[method], err)
+}
+
+// Reset removes all databases, users, operations and injected errors
+func (r Recorder) Reset() {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	r.state.databases = map[string]bool{}
+	r.state.users = map[string]*RecordedUser{}
+	r.state.operations = nil
+	r.state.failures = map[string][]error{}
+}
+
+// Operations returns all recorded operations in the order they were called
+func (r Recorder) Operations() []RecordedOperation {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	return slices.Clone(r.state.operations)
+}
+
+// Methods returns names of the recorded functions in the order they were called
+func (r Recorder) Methods() []string {
+	methods := []string{}
+	for _, op := range r.Operations() {
+		methods = append(methods, op.Method)
+	}
+	return methods
+}
+
+// Databases returns sorted names of existing databases
+func (r Recorder) Databases() []string {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	databases := []string{}
+	for name := range r.state.databases {
+		databases = append(databases, name)
+	}
+	sort.Strings(databases)
+	return databases
+}
+
+// User returns a copy of an existing user
+func (r Recorder) User(name string) (RecordedUser, bool) {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	user, ok := r.state.users[name]
+	if !ok {
+		return RecordedUser{}, false
+	}
+	grants := map[string]RecordedGrant{}
+	for db, grant := range user.Grants {
+		grants[db] = RecordedGrant{AccessType: grant.AccessType, Profile: grant.Profile, ExtraPrivileges: slices.Clone(grant.ExtraPrivileges)}
+	}
+	return RecordedUser{Password: user.Password, Grants: grants}, true
+}
+
+// Internal helpers, these functions are not part for the `Database` interface
+
+// record adds an operation and returns an injected error, if there is one,
+// the state must be locked by the caller
+func (r Recorder) record(method, user, query string) error {
+	var err error
+	if failures := r.state.failures[method]; len(failures) > 0 {
+		err = failures[0]
+		r.state.failures[method] = failures[1:]
+	}
+	r.state.operations = append(r.state.operations, RecordedOperation{
+		Method:   method,
+		Database: r.Database,
+		User:     user,
+		Query:    query,
+		Err:      err,
+	})
+	return err
+}
+
+// fail sets the error of the last recorded operation, the state must be locked by the caller
+func (r Recorder) fail(err error) error {
+	r.state.operations[len(r.state.operations)-1].Err = err
+	return err
+}
+
+// checkUser returns an error if the user can't connect to the database,
+// the state must be locked by the caller
+func (r Recorder) checkUser(user *DatabaseUser) error {
+	if !r.state.databases[r.Database] {
+		return fmt.Errorf("database doesn't exist: %s", r.Database)
+	}
+	recorded, ok := r.state.users[user.Username]
+	if !ok || recorded.Password != user.Password {
+		return fmt.Errorf("authentication failed for user: %s", user.Username)
+	}
+	if _, ok := recorded.Grants[r.Database]; !ok {
+		return fmt.Errorf("user %s doesn't have access to the database %s", user.Username, r.Database)
+	}
+	return nil
+}
+
+// checkQuery returns an error if the user can't run the query on the database,
+// the state must be locked by the caller
+func (r Recorder) checkQuery(user *DatabaseUser, query string) error {
+	if err := r.checkUser(user); err != nil {
+		return err
+	}
+	grant := r.state.users[user.Username].Grants[r.Database]
+	if grant.Profile != nil {
+		// Custom profiles are checked by privileges on tables,
+		// that are named after the first keyword of queries
+		keyword, _, _ := strings.Cut(strings.ToUpper(strings.TrimSpace(query)), " ")
+		if !slices.Contains(strings.Split(joinPrivileges(grant.Profile.Database, grant.Profile.Tables), ", "), keyword) {
+			return fmt.Errorf("permission denied for user %s to run: %s", user.Username, query)
+		}
+		return nil
+	}
+	if !slices.Contains(allowedAccessTypes(query), grant.AccessType) {
+		return fmt.Errorf("permission denied for user %s to run: %s", user.Username, query)
+	}
+	return nil
+}
+
+// allowedAccessTypes returns access types that can run the query, only the first
+// keyword is checked, that is enough for queries that are used by tests
+func allowedAccessTypes(query string) []string {
+	keyword, _, _ := strings.Cut(strings.ToUpper(strings.TrimSpace(query)), " ")
+	switch keyword {
+	case "SELECT", "SHOW":
+		return []string{ACCESS_TYPE_MAINUSER, ACCESS_TYPE_READWRITE, ACCESS_TYPE_READONLY}
+	case "INSERT", "UPDATE", "DELETE":
+		return []string{ACCESS_TYPE_MAINUSER, ACCESS_TYPE_READWRITE}
+	default:
+		return []string{ACCESS_TYPE_MAINUSER}
+	}
+}
+
+// setPermission grants the user access to the database, the state must be locked by the caller
+func (r Recorder) setPermission(user *DatabaseUser) error {
+	if err := r.record("setUserPermission", user.Username, ""); err != nil {
+		return err
+	}
+	if !r.state.databases[r.Database] {
+		return r.fail(fmt.Errorf("database doesn't exist: %s", r.Database))
+	}
+	recorded, ok := r.state.users[user.Username]
+	if !ok {
+		return r.fail(fmt.Errorf("user doesn't exist yet: %s", user.Username))
+	}
+	var profile *AccessProfile
+	switch user.AccessType {
+	case ACCESS_TYPE_MAINUSER, ACCESS_TYPE_READWRITE, ACCESS_TYPE_READONLY:
+	default:
+		if user.AccessProfile == nil {
+			return r.fail(fmt.Errorf("unknown access type: %s", user.AccessType))
+		}
+		if err := user.AccessProfile.Validate(); err != nil {
+			return r.fail(err)
+		}
+		copied := *user.AccessProfile
+		profile = &copied
+	}
+	recorded.Grants[r.Database] = RecordedGrant{
+		AccessType:      user.AccessType,
+		Profile:         profile,
+		ExtraPrivileges: slices.Clone(user.ExtraPrivileges),
+	}
+	return nil
+}
+
+// create adds the user and grants the access, the state must be locked by the caller
+func (r Recorder) create(user *DatabaseUser) error {
+	if err := r.record("createUser", user.Username, ""); err != nil {
+		return err
+	}
+	if _, ok := r.state.users[user.Username]; !ok {
+		r.state.users[user.Username] = &RecordedUser{
+			Password: user.Password,
+			Grants:   map[string]RecordedGrant{},
+		}
+	}
+	return r.setPermission(user)
+}
+
+// update changes the password and grants the access, the state must be locked by the caller
+func (r Recorder) update(user *DatabaseUser) error {
+	if err := r.record("updateUser", user.Username, ""); err != nil {
+		return err
+	}
+	recorded, ok := r.state.users[user.Username]
+	if !ok {
+		return r.fail(fmt.Errorf("user doesn't exist yet: %s", user.Username))
+	}
+	recorded.Password = user.Password
+	return r.setPermission(user)
+}
+
+// Functions that implement the `Database` interface
+
+// CheckStatus checks if the user can connect to the database
+func (r Recorder) CheckStatus(ctx context.Context, user *DatabaseUser) error {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	if err := r.record("CheckStatus", user.Username, ""); err != nil {
+		return err
+	}
+	if err := r.checkUser(user); err != nil {
+		return r.fail(err)
+	}
+	return nil
+}
+
+// GetCredentials returns credentials of the database
+func (r Recorder) GetCredentials(ctx context.Context, user *DatabaseUser) Credentials {
+	return Credentials{
+		Name:     r.Database,
+		Username: user.Username,
+		Password: user.Password,
+	}
+}
+
+// ParseAdminCredentials reads the "user" and "password" keys of the admin secret
+func (r Recorder) ParseAdminCredentials(ctx context.Context, data map[string][]byte) (*DatabaseUser, error) {
+	password, ok := data["password"]
+	if !ok {
+		return nil, errors.New("can not find recorder admin credentials")
+	}
+	admin := &DatabaseUser{Username: "admin", Password: string(password)}
+	if user, ok := data["user"]; ok {
+		admin.Username = string(user)
+	}
+	return admin, nil
+}
+
+// GetDatabaseAddress returns the address of the instance
+func (r Recorder) GetDatabaseAddress(ctx context.Context) DatabaseAddress {
+	return DatabaseAddress{
+		Host: r.Host,
+		Port: r.Port,
+	}
+}
+
+// QueryAsUser returns the query, so templates can be tested, the query
+// is only allowed if the access type of the user permits it
+func (r Recorder) QueryAsUser(ctx context.Context, query string, user *DatabaseUser) (string, error) {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	if err := r.record("QueryAsUser", user.Username, query); err != nil {
+		return "", err
+	}
+	if err := r.checkQuery(user, query); err != nil {
+		return "", r.fail(err)
+	}
+	return query, nil
+}
+
+func (r Recorder) execAsUser(ctx context.Context, query string, user *DatabaseUser) error {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	if err := r.record("execAsUser", user.Username, query); err != nil {
+		return err
+	}
+	if err := r.checkQuery(user, query); err != nil {
+		return r.fail(err)
+	}
+	return nil
+}
+
+func (r Recorder) createDatabase(ctx context.Context, admin *DatabaseUser) error {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	if err := r.record("createDatabase", admin.Username, ""); err != nil {
+		return err
+	}
+	r.state.databases[r.Database] = true
+	return nil
+}
+
+func (r Recorder) deleteDatabase(ctx context.Context, admin *DatabaseUser) error {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	if err := r.record("deleteDatabase", admin.Username, ""); err != nil {
+		return err
+	}
+	delete(r.state.databases, r.Database)
+	for _, user := range r.state.users {
+		delete(user.Grants, r.Database)
+	}
+	return nil
+}
+
+func (r Recorder) createOrUpdateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	if err := r.record("createOrUpdateUser", user.Username, ""); err != nil {
+		return err
+	}
+	if _, ok := r.state.users[user.Username]; ok {
+		return r.update(user)
+	}
+	return r.create(user)
+}
+
+func (r Recorder) createUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	return r.create(user)
+}
+
+func (r Recorder) updateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	return r.update(user)
+}
+
+func (r Recorder) setUserPermission(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	return r.setPermission(user)
+}
+
+func (r Recorder) deleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	if err := r.record("deleteUser", user.Username, ""); err != nil {
+		return err
+	}
+	delete(r.state.users, user.Username)
+	return nil
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorderLifecycle(t *testing.T) {
+	ctx := context.TODO()
+	r := NewRecorder("testdb")
+	admin := &DatabaseUser{Username: "admin", Password: "adminpwd"}
+	user := &DatabaseUser{Username: "testuser", Password: "testpwd", AccessType: ACCESS_TYPE_MAINUSER}
+
+	assert.Error(t, r.CheckStatus(ctx, user))
+	assert.NoError(t, CreateDatabase(ctx, r, admin))
+	assert.NoError(t, CreateOrUpdateUser(ctx, r, user, admin))
+	assert.NoError(t, r.CheckStatus(ctx, user))
+	assert.Equal(t, []string{"testdb"}, r.Databases())
+
+	recorded, ok := r.User("testuser")
+	assert.True(t, ok)
+	assert.Equal(t, "testpwd", recorded.Password)
+	assert.Equal(t, map[string]RecordedGrant{"testdb": {AccessType: ACCESS_TYPE_MAINUSER}}, recorded.Grants)
+
+	// The password is changed on update, so the old one doesn't work anymore
+	user.Password = "newpwd"
+	assert.NoError(t, CreateOrUpdateUser(ctx, r, user, admin))
+	assert.Error(t, r.CheckStatus(ctx, &DatabaseUser{Username: "testuser", Password: "testpwd"}))
+	assert.NoError(t, r.CheckStatus(ctx, user))
+
+	result, err := r.QueryAsUser(ctx, "SELECT 1", user)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 1", result)
+
+	assert.NoError(t, DeleteDatabase(ctx, r, admin))
+	assert.NoError(t, DeleteUser(ctx, r, user, admin))
+	assert.Empty(t, r.Databases())
+	_, ok = r.User("testuser")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{
+		"CheckStatus",
+		"createDatabase",
+		"createOrUpdateUser", "createUser", "setUserPermission",
+		"CheckStatus",
+		"createOrUpdateUser", "updateUser", "setUserPermission",
+		"CheckStatus", "CheckStatus",
+		"QueryAsUser",
+		"deleteDatabase",
+		"deleteUser",
+	}, r.Methods())
+
+	ops := r.Operations()
+	assert.Error(t, ops[0].Err)
+	assert.Equal(t, RecordedOperation{Method: "createDatabase", Database: "testdb", User: "admin"}, ops[1])
+	assert.Equal(t, RecordedOperation{Method: "QueryAsUser", Database: "testdb", User: "testuser", Query: "SELECT 1"}, ops[11])
+}
+
+func TestRecorderGrants(t *testing.T) {
+	ctx := context.TODO()
+	r := NewRecorder("testdb")
+	other := r.ForDatabase("otherdb")
+	admin := &DatabaseUser{Username: "admin", Password: "adminpwd"}
+	user := &DatabaseUser{Username: "testuser", Password: "testpwd", AccessType: ACCESS_TYPE_READONLY, ExtraPrivileges: []string{"extra"}}
+
+	// Permissions can't be granted on a database that doesn't exist
+	assert.Error(t, CreateUser(ctx, r, user, admin))
+
+	assert.NoError(t, CreateDatabase(ctx, r, admin))
+	assert.NoError(t, CreateDatabase(ctx, other, admin))
+	assert.NoError(t, CreateUser(ctx, r, user, admin))
+	assert.NoError(t, CreateUser(ctx, other, user, admin))
+	assert.Equal(t, []string{"otherdb", "testdb"}, r.Databases())
+
+	recorded, _ := r.User("testuser")
+	assert.Equal(t, map[string]RecordedGrant{
+		"testdb":  {AccessType: ACCESS_TYPE_READONLY, ExtraPrivileges: []string{"extra"}},
+		"otherdb": {AccessType: ACCESS_TYPE_READONLY, ExtraPrivileges: []string{"extra"}},
+	}, recorded.Grants)
+
+	// Grants on a removed database are removed as well
+	assert.NoError(t, DeleteDatabase(ctx, other, admin))
+	assert.Error(t, other.CheckStatus(ctx, user))
+	assert.NoError(t, r.CheckStatus(ctx, user))
+	recorded, _ = r.User("testuser")
+	assert.Len(t, recorded.Grants, 1)
+
+	user.AccessType = "unknown"
+	assert.Error(t, r.setUserPermission(ctx, admin, user))
+}
+
+func TestRecorderFailNext(t *testing.T) {
+	ctx := context.TODO()
+	r := NewRecorder("testdb")
+	admin := &DatabaseUser{Username: "admin", Password: "adminpwd"}
+	user := NewDummyUser(ACCESS_TYPE_READWRITE)
+	injected := errors.New("injected error")
+
+	r.FailNext("setUserPermission", injected)
+	r.FailNext("createDatabase", injected)
+	assert.ErrorIs(t, CreateDatabase(ctx, r, admin), injected)
+	assert.NoError(t, CreateDatabase(ctx, r, admin))
+	assert.ErrorIs(t, CreateOrUpdateUser(ctx, r, user, admin), injected)
+	assert.NoError(t, CreateOrUpdateUser(ctx, r, user, admin))
+
+	failed := []string{}
+	for _, op := range r.Operations() {
+		if op.Err != nil {
+			failed = append(failed, op.Method)
+		}
+	}
+	assert.Equal(t, []string{"createDatabase", "setUserPermission"}, failed)
+
+	r.Reset()
+	assert.Empty(t, r.Operations())
+	assert.Empty(t, r.Databases())
+}
+
+func TestRecorderEngine(t *testing.T) {
+	defer engines.AllowHidden(false)
+	defer ReleaseRecorder("first")
+	defer ReleaseRecorder("second")
+
+	_, err := GetEngine("recorder")
+	assert.Error(t, err)
+	engines.AllowHidden(true)
+	engine, err := GetEngine("recorder")
+	assert.NoError(t, err)
+	assert.NotContains(t, engines.Names(), "recorder")
+
+	// Recorders of the engine share the state per instance
+	db, err := engine.New(context.TODO(), EngineConfig{Instance: "first", Host: "127.0.0.1", Port: 5432, Database: "testdb"})
+	assert.NoError(t, err)
+	assert.NoError(t, CreateDatabase(context.TODO(), db, &DatabaseUser{Username: "admin"}))
+	assert.Equal(t, []string{"testdb"}, RecorderOf("first").Databases())
+	assert.Empty(t, RecorderOf("second").Databases())
+	assert.Equal(t, DatabaseAddress{Host: "127.0.0.1", Port: 5432}, db.GetDatabaseAddress(context.TODO()))
+
+	// A released state is not used anymore
+	ReleaseRecorder("first")
+	assert.Empty(t, RecorderOf("first").Databases())
+
+	admin, err := db.ParseAdminCredentials(context.TODO(), map[string][]byte{"password": []byte("adminpwd")})
+	assert.NoError(t, err)
+	assert.Equal(t, &DatabaseUser{Username: "admin", Password: "adminpwd"}, admin)
+	_, err = db.ParseAdminCredentials(context.TODO(), map[string][]byte{})
+	assert.Error(t, err)
+}
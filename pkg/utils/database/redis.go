@@ -0,0 +1,513 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Redis is a database interface, abstracted object
+// represents a key prefix on Redis or Valkey instance,
+// users are restricted to keys of the prefix by ACLs
+type Redis struct {
+	Backend string
+	Host    string
+	Port    uint16
+	// Keys of the database are prefixed by its name and a colon
+	Database string
+	// A logical database that users are connected to
+	DB           int `json:"db"`
+	SSLEnabled   bool
+	SkipCAVerify bool
+}
+
+// Key prefixes are used in glob patterns of ACLs and SCAN,
+// so characters that have a meaning in patterns are not allowed
+var redisPrefixRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Extra privileges are ACL rules of commands and categories, e.g. +@pubsub or -flushdb
+var redisRuleRegexp = regexp.MustCompile(`^[+-]@?[a-z][a-z|-]*$`)
+
+// Keys are deleted in batches of this size, when the database is removed
+const redisScanCount = 1000
+
+func init() {
+	Register(Engine{
+		Engine: engines.Redis,
+		New:    newRedis,
+	})
+}
+
+func newRedis(ctx context.Context, cfg EngineConfig) (Database, error) {
+	r := Redis{}
+	if err := cfg.decodeSpec(&r); err != nil {
+		return nil, err
+	}
+	if len(cfg.Database) > 0 && !redisPrefixRegexp.MatchString(cfg.Database) {
+		return nil, fmt.Errorf("invalid key prefix: %s", cfg.Database)
+	}
+	if r.DB < 0 {
+		return nil, fmt.Errorf("invalid logical database: %d", r.DB)
+	}
+	r.Backend = cfg.Backend
+	r.Host = cfg.Host
+	r.Port = cfg.Port
+	r.Database = cfg.Database
+	r.SSLEnabled = cfg.SSLEnabled
+	r.SkipCAVerify = cfg.SkipCAVerify
+	return r, nil
+}
+
+// redisDial opens connections to servers, tests are replacing it
+// to run the engine against an embedded stand-in of a server
+var redisDial = func(ctx context.Context, address string, tlsConfig *tls.Config) (net.Conn, error) {
+	if tlsConfig != nil {
+		dialer := &tls.Dialer{Config: tlsConfig}
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+	dialer := &net.Dialer{}
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+// redisError is an error reply of the server
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// redisConn is a connection that sends commands in the RESP2 format,
+// replies are strings, integers, nil, arrays of them or redisError
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
+
+// do sends a command and reads its reply, error replies are returned as errors
+func (c *redisConn) do(ctx context.Context, args ...string) (any, error) {
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := c.conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, command.String()); err != nil {
+		return nil, timeoutError(err)
+	}
+	reply, err := c.readReply()
+	if err != nil {
+		return nil, timeoutError(err)
+	}
+	if replyErr, ok := reply.(redisError); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+func (c *redisConn) readReply() (any, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return nil, errors.New("empty reply of the server")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return redisError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		items := make([]any, 0, size)
+		for i := 0; i < size; i++ {
+			item, err := c.readReply()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply of the server: %s", line)
+	}
+}
+
+// Internal helpers, these functions are not part for the `Database` interface
+
+// connect authenticates the user and selects the logical database
+func (r Redis) connect(ctx context.Context, user, password string) (*redisConn, error) {
+	var tlsConfig *tls.Config
+	if r.SSLEnabled {
+		tlsConfig = &tls.Config{ServerName: r.Host, InsecureSkipVerify: r.SkipCAVerify} //nolint:gosec
+	}
+	dialCtx := ctx
+	if timeout := currentTimeouts().Connect; timeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	conn, err := redisDial(dialCtx, net.JoinHostPort(r.Host, strconv.Itoa(int(r.Port))), tlsConfig)
+	if err != nil {
+		return nil, timeoutError(fmt.Errorf("redis dial: %w", err))
+	}
+
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if _, err := c.do(ctx, "AUTH", user, password); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if r.DB != 0 {
+		if _, err := c.do(ctx, "SELECT", strconv.Itoa(r.DB)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// command runs one command as the user
+func (r Redis) command(ctx context.Context, user *DatabaseUser, args ...string) (any, error) {
+	c, err := r.connect(ctx, user.Username, user.Password)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.do(ctx, args...)
+}
+
+// keyPattern returns the pattern of keys and channels of the database
+func (r Redis) keyPattern() (string, error) {
+	if !redisPrefixRegexp.MatchString(r.Database) {
+		return "", fmt.Errorf("invalid key prefix: %s", r.Database)
+	}
+	return r.Database + ":*", nil
+}
+
+// aclRules returns rules of ACL SETUSER that replace all permissions of the user,
+// dangerous commands are never allowed, because they're not restricted by key patterns,
+// so they're removed after extra privileges, that would allow them otherwise
+func (r Redis) aclRules(user *DatabaseUser) ([]string, error) {
+	pattern, err := r.keyPattern()
+	if err != nil {
+		return nil, err
+	}
+	rules := []string{"reset", "on", ">" + user.Password, "~" + pattern, "&" + pattern}
+	switch user.AccessType {
+	case ACCESS_TYPE_MAINUSER:
+		rules = append(rules, "+@all")
+	case ACCESS_TYPE_READWRITE:
+		rules = append(rules, "+@connection", "+@read", "+@write")
+	case ACCESS_TYPE_READONLY:
+		rules = append(rules, "+@connection", "+@read")
+	default:
+		return nil, fmt.Errorf("unknown access type: %s", user.AccessType)
+	}
+
+	for _, rule := range user.ExtraPrivileges {
+		if !redisRuleRegexp.MatchString(rule) {
+			return nil, fmt.Errorf("invalid ACL rule: %s", rule)
+		}
+		rules = append(rules, rule)
+	}
+	return append(rules, "-@dangerous"), nil
+}
+
+// checkStandalone returns an error on servers in the cluster mode, because
+// ACL users are not shared by nodes, and keys of the prefix are spread across them
+func (r Redis) checkStandalone(ctx context.Context, c *redisConn) error {
+	reply, err := c.do(ctx, "INFO", "cluster")
+	if err != nil {
+		return err
+	}
+	if strings.Contains(redisReplyString(reply), "cluster_enabled:1") {
+		return errors.New("redis cluster mode is not supported")
+	}
+	return nil
+}
+
+// setUser creates the user or replaces its password and permissions
+func (r Redis) setUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	rules, err := r.aclRules(user)
+	if err != nil {
+		return err
+	}
+	c, err := r.connect(ctx, admin.Username, admin.Password)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err := r.checkStandalone(ctx, c); err != nil {
+		return err
+	}
+	if _, err := c.do(ctx, append([]string{"ACL", "SETUSER", user.Username}, rules...)...); err != nil {
+		return err
+	}
+	return r.saveACL(ctx, admin)
+}
+
+// saveACL writes users to the ACL file, servers without
+// the file keep users in memory or in the config file
+func (r Redis) saveACL(ctx context.Context, admin *DatabaseUser) error {
+	_, err := r.command(ctx, admin, "ACL", "SAVE")
+	var replyErr redisError
+	if errors.As(err, &replyErr) && strings.Contains(string(replyErr), "ACL file") {
+		return nil
+	}
+	return err
+}
+
+func (r Redis) isUserExist(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) bool {
+	log := log.FromContext(ctx)
+	reply, err := r.command(ctx, admin, "ACL", "GETUSER", user.Username)
+	if err != nil {
+		log.V(2).Info("failed executing command", "error", err)
+		return false
+	}
+	return reply != nil
+}
+
+// redisReplyString formats a reply, elements of arrays are separated by new lines
+func redisReplyString(reply any) string {
+	switch value := reply.(type) {
+	case nil:
+		return ""
+	case []any:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, redisReplyString(item))
+		}
+		return strings.Join(items, "\n")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Functions that implement the `Database` interface
+
+// CheckStatus checks status of Redis database
+// if the user can connect and run commands
+func (r Redis) CheckStatus(ctx context.Context, user *DatabaseUser) error {
+	c, err := r.connect(ctx, user.Username, user.Password)
+	if err != nil {
+		return fmt.Errorf("db conn test failed - couldn't get db conn: %w", err)
+	}
+	defer c.Close()
+	if _, err := c.do(ctx, "PING"); err != nil {
+		return fmt.Errorf("db conn test failed - failed to execute query: %w", err)
+	}
+	return nil
+}
+
+// GetCredentials returns credentials of the Redis database
+func (r Redis) GetCredentials(ctx context.Context, user *DatabaseUser) Credentials {
+	return Credentials{
+		Name:     r.Database,
+		Username: user.Username,
+		Password: user.Password,
+	}
+}
+
+// ParseAdminCredentials parse admin username and password of Redis database from secret data
+// If "user" key is not defined, take "default" as admin user by default
+func (r Redis) ParseAdminCredentials(ctx context.Context, data map[string][]byte) (*DatabaseUser, error) {
+	admin := &DatabaseUser{}
+
+	if user, ok := data["user"]; ok {
+		admin.Username = string(user)
+	} else {
+		admin.Username = "default"
+	}
+
+	if password, ok := data["password"]; ok {
+		admin.Password = string(password)
+	} else if password, ok := data["redis-password"]; ok {
+		// the key that is used by the bitnami redis and valkey charts
+		admin.Password = string(password)
+	} else {
+		return nil, errors.New("no admin password found")
+	}
+
+	return admin, nil
+}
+
+func (r Redis) GetDatabaseAddress(ctx context.Context) DatabaseAddress {
+	return DatabaseAddress{
+		Host: r.Host,
+		Port: r.Port,
+	}
+}
+
+// QueryAsUser runs a command, that is expected to be passed with
+// arguments separated by spaces, for example: GET app:config
+func (r Redis) QueryAsUser(ctx context.Context, query string, user *DatabaseUser) (string, error) {
+	log := log.FromContext(ctx)
+	args := strings.Fields(query)
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+	reply, err := r.command(ctx, user, args...)
+	if err != nil {
+		log.Error(err, "failed executing query", "query", query)
+		return "", err
+	}
+	return redisReplyString(reply), nil
+}
+
+func (r Redis) execAsUser(ctx context.Context, query string, user *DatabaseUser) error {
+	_, err := r.QueryAsUser(ctx, query, user)
+	return err
+}
+
+// createDatabase only checks that the logical database can be selected
+// on a standalone server, because keys of the prefix don't have to be created
+func (r Redis) createDatabase(ctx context.Context, admin *DatabaseUser) error {
+	if _, err := r.keyPattern(); err != nil {
+		return err
+	}
+	c, err := r.connect(ctx, admin.Username, admin.Password)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return r.checkStandalone(ctx, c)
+}
+
+// deleteDatabase removes all keys of the prefix from the logical database
+func (r Redis) deleteDatabase(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	pattern, err := r.keyPattern()
+	if err != nil {
+		return err
+	}
+	c, err := r.connect(ctx, admin.Username, admin.Password)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	// SCAN only returns keys of one node of a cluster
+	if err := r.checkStandalone(ctx, c); err != nil {
+		return err
+	}
+
+	cursor := "0"
+	for {
+		reply, err := c.do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", strconv.Itoa(redisScanCount))
+		if err != nil {
+			log.Error(err, "failed scanning keys of Redis database")
+			return err
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return fmt.Errorf("unexpected reply of SCAN: %v", reply)
+		}
+		keys, _ := page[1].([]any)
+		if len(keys) > 0 {
+			args := []string{"UNLINK"}
+			for _, key := range keys {
+				args = append(args, redisReplyString(key))
+			}
+			if _, err := c.do(ctx, args...); err != nil {
+				log.Error(err, "failed deleting keys of Redis database")
+				return err
+			}
+		}
+		cursor = redisReplyString(page[0])
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+func (r Redis) createOrUpdateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if err := r.setUser(ctx, admin, user); err != nil {
+		log.Error(err, "failed setting Redis user")
+		return err
+	}
+	return nil
+}
+
+// createUser creates a user with ACL SETUSER, permissions are set
+// with the same command, so setUserPermission is not required here
+func (r Redis) createUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	if r.isUserExist(ctx, admin, user) {
+		return fmt.Errorf("user already exists: %s", user.Username)
+	}
+	return r.setUser(ctx, admin, user)
+}
+
+func (r Redis) updateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	if !r.isUserExist(ctx, admin, user) {
+		return fmt.Errorf("user doesn't exist yet: %s", user.Username)
+	}
+	return r.setUser(ctx, admin, user)
+}
+
+// setUserPermission replaces all rules of the user, because ACL SETUSER
+// starts with reset, so the password is set again together with them
+func (r Redis) setUserPermission(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	return r.setUser(ctx, admin, user)
+}
+
+func (r Redis) deleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if _, err := r.command(ctx, admin, "ACL", "DELUSER", user.Username); err != nil {
+		log.Error(err, "failed deleting Redis user")
+		return err
+	}
+	return r.saveACL(ctx, admin)
+}
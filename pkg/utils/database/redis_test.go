@@ -0,0 +1,448 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedisServer is a stand-in of a server that understands commands of the engine,
+// ACLs are checked by key patterns and by the categories of the few known commands
+type fakeRedisServer struct {
+	mu sync.Mutex
+	// Keys per logical database
+	keys  map[int]map[string]string
+	users map[string]*fakeRedisUser
+	// Executed commands in order, arguments are separated by spaces
+	commands []string
+	// The server reports the cluster mode
+	cluster bool
+}
+
+type fakeRedisUser struct {
+	password string
+	rules    []string
+}
+
+// Categories of commands that can be run by users
+var fakeRedisCategories = map[string]string{
+	"PING":   "connection",
+	"SELECT": "connection",
+	"GET":    "read",
+	"SET":    "write",
+}
+
+func newFakeRedisServer(admin *DatabaseUser) *fakeRedisServer {
+	return &fakeRedisServer{
+		keys: map[int]map[string]string{},
+		users: map[string]*fakeRedisUser{
+			admin.Username: {password: admin.Password, rules: []string{"on", "~*", "&*", "+@all"}},
+		},
+	}
+}
+
+// useFakeRedisServer makes the redis engine connect to the server until the test is finished
+func useFakeRedisServer(t *testing.T, server *fakeRedisServer) {
+	dial := redisDial
+	redisDial = func(ctx context.Context, address string, tlsConfig *tls.Config) (net.Conn, error) {
+		client, conn := net.Pipe()
+		go server.serve(conn)
+		return client, nil
+	}
+	t.Cleanup(func() {
+		redisDial = dial
+	})
+}
+
+// Commands returns executed commands in order
+func (s *fakeRedisServer) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands)
+}
+
+func (s *fakeRedisServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	session := &fakeRedisSession{server: s}
+	for {
+		args, err := fakeRedisReadCommand(reader)
+		if err != nil {
+			return
+		}
+		reply := session.run(args)
+		if _, err := io.WriteString(conn, fakeRedisReply(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func fakeRedisReadCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := []string{}
+	for i := 0; i < count; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args = append(args, string(data[:size]))
+	}
+	return args, nil
+}
+
+func fakeRedisReply(reply any) string {
+	switch value := reply.(type) {
+	case nil:
+		return "$-1\r\n"
+	case redisError:
+		return "-" + string(value) + "\r\n"
+	case int:
+		return fmt.Sprintf(":%d\r\n", value)
+	case string:
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case []any:
+		result := fmt.Sprintf("*%d\r\n", len(value))
+		for _, item := range value {
+			result += fakeRedisReply(item)
+		}
+		return result
+	default:
+		panic(fmt.Sprintf("unsupported reply: %v", reply))
+	}
+}
+
+type fakeRedisSession struct {
+	server *fakeRedisServer
+	user   string
+	db     int
+}
+
+func (session *fakeRedisSession) run(args []string) any {
+	s := session.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, strings.Join(args, " "))
+
+	name := strings.ToUpper(args[0])
+	if name == "AUTH" {
+		user, ok := s.users[args[1]]
+		if !ok || user.password != args[2] || !slices.Contains(user.rules, "on") {
+			return redisError("WRONGPASS invalid username-password pair or user is disabled.")
+		}
+		session.user = args[1]
+		return "OK"
+	}
+	user, ok := s.users[session.user]
+	if !ok {
+		return redisError("NOAUTH Authentication required.")
+	}
+	if err := user.allowed(name, args[1:]); err != nil {
+		return err
+	}
+
+	switch name {
+	case "PING":
+		return "PONG"
+	case "SELECT":
+		db, err := strconv.Atoi(args[1])
+		if err != nil || db < 0 || db > 15 {
+			return redisError("ERR DB index is out of range")
+		}
+		session.db = db
+		return "OK"
+	case "GET":
+		if value, ok := s.keys[session.db][args[1]]; ok {
+			return value
+		}
+		return nil
+	case "SET":
+		if _, ok := s.keys[session.db]; !ok {
+			s.keys[session.db] = map[string]string{}
+		}
+		s.keys[session.db][args[1]] = args[2]
+		return "OK"
+	case "UNLINK":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.keys[session.db][key]; ok {
+				delete(s.keys[session.db], key)
+				deleted++
+			}
+		}
+		return deleted
+	case "SCAN":
+		// All keys are returned in one page of the cursor 0
+		keys := []any{}
+		for _, key := range slices.Sorted(maps.Keys(s.keys[session.db])) {
+			if ok, _ := path.Match(args[3], key); ok {
+				keys = append(keys, key)
+			}
+		}
+		return []any{"0", keys}
+	case "INFO":
+		if s.cluster {
+			return "# Cluster\r\ncluster_enabled:1\r\n"
+		}
+		return "# Cluster\r\ncluster_enabled:0\r\n"
+	case "ACL":
+		return session.acl(args[1:])
+	}
+	return redisError("ERR unknown command '" + args[0] + "'")
+}
+
+func (session *fakeRedisSession) acl(args []string) any {
+	s := session.server
+	switch strings.ToUpper(args[0]) {
+	case "SETUSER":
+		user, ok := s.users[args[1]]
+		if !ok {
+			user = &fakeRedisUser{}
+			s.users[args[1]] = user
+		}
+		for _, rule := range args[2:] {
+			switch {
+			case rule == "reset":
+				user.password = ""
+				user.rules = nil
+			case strings.HasPrefix(rule, ">"):
+				user.password = rule[1:]
+			default:
+				user.rules = append(user.rules, rule)
+			}
+		}
+		return "OK"
+	case "GETUSER":
+		if user, ok := s.users[args[1]]; ok {
+			return []any{"flags", []any{}, "rules", strings.Join(user.rules, " ")}
+		}
+		return nil
+	case "DELUSER":
+		if _, ok := s.users[args[1]]; ok {
+			delete(s.users, args[1])
+			return 1
+		}
+		return 0
+	case "SAVE":
+		return redisError("ERR This Redis instance is not configured to use an ACL file. You may want to specify users via the ACL SETUSER command and then issue a CONFIG REWRITE (assuming you have a Redis configuration file set) in order to store users in the Redis configuration.")
+	}
+	return redisError("ERR unknown subcommand")
+}
+
+// allowed checks the category of the command and patterns of its keys, the last
+// matching rule wins, like on the server, unknown commands are only run by admins
+func (user *fakeRedisUser) allowed(name string, args []string) any {
+	category, ok := fakeRedisCategories[name]
+	if !ok {
+		category = "admin"
+	}
+	allowed := false
+	for _, rule := range user.rules {
+		switch rule {
+		case "+@all", "+@" + category:
+			allowed = true
+		case "-@all", "-@" + category:
+			allowed = false
+		}
+	}
+	if !allowed {
+		return redisError("NOPERM User has no permissions to run the '" + strings.ToLower(name) + "' command")
+	}
+	if category == "read" || category == "write" {
+		for _, rule := range user.rules {
+			if ok, _ := path.Match(strings.TrimPrefix(rule, "~"), args[0]); ok && strings.HasPrefix(rule, "~") {
+				return nil
+			}
+		}
+		return redisError("NOPERM No permissions to access a key")
+	}
+	return nil
+}
+
+func testRedis() (*Redis, *DatabaseUser) {
+	return &Redis{
+		Host:     "127.0.0.1",
+		Port:     6379,
+		Database: "team-a",
+	}, &DatabaseUser{
+		Username:   "testuser",
+		Password:   "testpwd",
+		AccessType: ACCESS_TYPE_MAINUSER,
+	}
+}
+
+func TestRedisACLRules(t *testing.T) {
+	r, dbu := testRedis()
+
+	rules, err := r.aclRules(dbu)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"reset", "on", ">testpwd", "~team-a:*", "&team-a:*", "+@all", "-@dangerous"}, rules)
+
+	dbu.AccessType = ACCESS_TYPE_READWRITE
+	dbu.ExtraPrivileges = []string{"+@pubsub", "-flushdb"}
+	rules, err = r.aclRules(dbu)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"reset", "on", ">testpwd", "~team-a:*", "&team-a:*", "+@connection", "+@read", "+@write", "+@pubsub", "-flushdb", "-@dangerous"}, rules)
+
+	dbu.AccessType = ACCESS_TYPE_READONLY
+	dbu.ExtraPrivileges = nil
+	rules, err = r.aclRules(dbu)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"reset", "on", ">testpwd", "~team-a:*", "&team-a:*", "+@connection", "+@read", "-@dangerous"}, rules)
+
+	dbu.ExtraPrivileges = []string{"+flushall", "+@dangerous"}
+	rules, err = r.aclRules(dbu)
+	assert.NoError(t, err)
+	assert.Equal(t, "-@dangerous", rules[len(rules)-1], "extra privileges must not allow dangerous commands")
+
+	dbu.ExtraPrivileges = []string{"~*"}
+	_, err = r.aclRules(dbu)
+	assert.Error(t, err)
+
+	dbu.ExtraPrivileges = nil
+	r.Database = "team-*"
+	_, err = r.aclRules(dbu)
+	assert.Error(t, err)
+}
+
+func TestRedisGetCredentials(t *testing.T) {
+	r, dbu := testRedis()
+
+	cred := r.GetCredentials(context.TODO(), dbu)
+	assert.Equal(t, cred.Username, dbu.Username)
+	assert.Equal(t, cred.Name, r.Database)
+	assert.Equal(t, cred.Password, dbu.Password)
+}
+
+func TestRedisParseAdminCredentials(t *testing.T) {
+	r, _ := testRedis()
+
+	_, err := r.ParseAdminCredentials(context.TODO(), map[string][]byte{"user": []byte("admin")})
+	assert.Error(t, err)
+
+	admin, err := r.ParseAdminCredentials(context.TODO(), map[string][]byte{"redis-password": []byte("secret")})
+	assert.NoError(t, err)
+	assert.Equal(t, &DatabaseUser{Username: "default", Password: "secret"}, admin)
+
+	admin, err = r.ParseAdminCredentials(context.TODO(), map[string][]byte{"user": []byte("admin"), "password": []byte("adminpwd")})
+	assert.NoError(t, err)
+	assert.Equal(t, &DatabaseUser{Username: "admin", Password: "adminpwd"}, admin)
+}
+
+func TestRedisUserLifecycle(t *testing.T) {
+	ctx := context.TODO()
+	admin := &DatabaseUser{Username: "default", Password: "defaultpwd"}
+	server := newFakeRedisServer(admin)
+	useFakeRedisServer(t, server)
+
+	e, err := GetEngine("redis")
+	require.NoError(t, err)
+	db, err := e.New(ctx, EngineConfig{Host: "redis", Port: 6379, Database: "team-a", Spec: []byte(`{"db": 2}`)})
+	require.NoError(t, err)
+
+	mainUser := &DatabaseUser{Username: "team-a-main", Password: "mainpwd", AccessType: ACCESS_TYPE_MAINUSER}
+	reader := &DatabaseUser{Username: "team-a-reader", Password: "readerpwd", AccessType: ACCESS_TYPE_READONLY}
+	writer := &DatabaseUser{Username: "team-a-writer", Password: "writerpwd", AccessType: ACCESS_TYPE_READWRITE}
+
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	require.NoError(t, CreateOrUpdateUser(ctx, db, mainUser, admin))
+	require.NoError(t, CreateUser(ctx, db, reader, admin))
+	require.NoError(t, CreateUser(ctx, db, writer, admin))
+	assert.Error(t, CreateUser(ctx, db, writer, admin), "the user exists already")
+	assert.Contains(t, server.Commands(), "ACL SETUSER team-a-reader reset on >readerpwd ~team-a:* &team-a:* +@connection +@read -@dangerous")
+	for _, user := range []*DatabaseUser{mainUser, reader, writer} {
+		assert.NoError(t, db.CheckStatus(ctx, user), user.Username)
+	}
+
+	// Users are connected to the logical database, and can only use keys of the prefix
+	require.NoError(t, db.execAsUser(ctx, "SET team-a:config on", writer))
+	assert.Equal(t, "on", server.keys[2]["team-a:config"])
+	value, err := db.QueryAsUser(ctx, "GET team-a:config", reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "on", value)
+	assert.Error(t, db.execAsUser(ctx, "SET team-a:config off", reader), "the readonly user must not write")
+	assert.Error(t, db.execAsUser(ctx, "SET team-b:config on", writer), "keys of other prefixes must not be accessible")
+	_, err = db.QueryAsUser(ctx, "GET team-b:config", mainUser)
+	assert.Error(t, err)
+
+	// The password is replaced together with the rules
+	old := *writer
+	writer.Password = "writerpwd-new"
+	require.NoError(t, UpdateUser(ctx, db, writer, admin))
+	assert.Error(t, db.CheckStatus(ctx, &old))
+	assert.NoError(t, db.CheckStatus(ctx, writer))
+
+	require.NoError(t, DeleteUser(ctx, db, reader, admin))
+	assert.NotContains(t, server.users, "team-a-reader")
+
+	// Only keys of the prefix are removed with the database
+	server.keys[2]["team-b:config"] = "on"
+	require.NoError(t, DeleteDatabase(ctx, db, admin))
+	assert.Equal(t, map[string]string{"team-b:config": "on"}, server.keys[2])
+}
+
+func TestRedisClusterMode(t *testing.T) {
+	ctx := context.TODO()
+	admin := &DatabaseUser{Username: "default", Password: "defaultpwd"}
+	server := newFakeRedisServer(admin)
+	server.cluster = true
+	useFakeRedisServer(t, server)
+
+	e, err := GetEngine("redis")
+	require.NoError(t, err)
+	db, err := e.New(ctx, EngineConfig{Host: "redis", Port: 6379, Database: "team-a"})
+	require.NoError(t, err)
+
+	mainUser := &DatabaseUser{Username: "team-a-main", Password: "mainpwd", AccessType: ACCESS_TYPE_MAINUSER}
+	assert.ErrorContains(t, CreateDatabase(ctx, db, admin), "cluster mode")
+	assert.ErrorContains(t, CreateOrUpdateUser(ctx, db, mainUser, admin), "cluster mode")
+	assert.NotContains(t, server.users, "team-a-main")
+
+	// Keys are not removed from one node only
+	server.keys[0] = map[string]string{"team-a:config": "on"}
+	assert.ErrorContains(t, DeleteDatabase(ctx, db, admin), "cluster mode")
+	assert.Equal(t, "on", server.keys[0]["team-a:config"])
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+	"github.com/db-operator/db-operator/pkg/utils/kci"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Engine{}
+)
+
+// EngineConfig is passed to an engine constructor to build a Database
+type EngineConfig struct {
+	// Name of the DbInstance, admin connections are pooled per instance
+	Instance     string
+	Backend      string
+	Host         string
+	Port         uint16
+	Database     string
+	SSLEnabled   bool
+	SkipCAVerify bool
+	Monitoring   bool
+	// A user that is created with the Database
+	MainUser *DatabaseUser
+	// Annotations of the DbInstance
+	Annotations map[string]string
+	// Engine specific part of the Database spec as JSON,
+	// e.g. the content of spec.postgres for the postgres engine
+	Spec []byte
+	// Address of the gRPC server, it's only used by the plugin engine
+	PluginEndpoint string
+	// Security of the connection to the gRPC server, it's only used by the plugin engine
+	PluginTLS *PluginTLS
+}
+
+// BackupConfig is passed to an engine to build a backup container
+type BackupConfig struct {
+	Image string
+	Host  string
+	Port  string
+	// A secret with the database credentials,
+	// it's mounted to the CredentialsPath
+	SecretName      string
+	CredentialsPath string
+	Bucket          string
+	SSLEnabled      bool
+	Monitoring      bool
+	PromPushGateway string
+	Resources       corev1.ResourceRequirements
+	VolumeMounts    []corev1.VolumeMount
+}
+
+// Engine is a database engine together with its driver,
+// the description of the engine is shared with API types
+type Engine struct {
+	engines.Engine
+	// New builds a Database
+	New func(ctx context.Context, cfg EngineConfig) (Database, error)
+	// BackupContainer builds a container that makes a database dump,
+	// it's nil if the engine doesn't support backups
+	BackupContainer func(cfg BackupConfig) (corev1.Container, error)
+}
+
+// Register makes the driver of an engine available by its name, it panics if the engine
+// is invalid or is already registered. Engines that are not built-in are registered in
+// the engines package too, so they can be used by DbInstances
+func Register(engine Engine) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if len(engine.Name) == 0 {
+		panic("database: engine name is empty")
+	}
+	if engine.New == nil {
+		panic("database: engine constructor is nil for " + engine.Name)
+	}
+	if _, ok := drivers[engine.Name]; ok {
+		panic("database: Register called twice for engine " + engine.Name)
+	}
+	if _, ok := engines.Lookup(engine.Name); !ok {
+		engines.Register(engine.Engine)
+	}
+	drivers[engine.Name] = engine
+}
+
+// GetEngine returns a registered engine that can be used by DbInstances
+func GetEngine(name string) (Engine, error) {
+	if _, err := engines.Get(name); err != nil {
+		return Engine{}, err
+	}
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	engine, ok := drivers[name]
+	if !ok {
+		return Engine{}, fmt.Errorf("engine %s doesn't have a driver", name)
+	}
+	return engine, nil
+}
+
+// ParseSecretData reads credentials from the data of a secret
+func (e Engine) ParseSecretData(data map[string][]byte) (Credentials, error) {
+	cred := Credentials{}
+	if name, ok := data[e.SecretKeys.Database]; ok {
+		cred.Name = string(name)
+	} else {
+		return cred, fmt.Errorf("%s key does not exist in secret data", e.SecretKeys.Database)
+	}
+
+	if user, ok := data[e.SecretKeys.User]; ok {
+		cred.Username = string(user)
+	} else {
+		return cred, fmt.Errorf("%s key does not exist in secret data", e.SecretKeys.User)
+	}
+
+	if pass, ok := data[e.SecretKeys.Password]; ok {
+		cred.Password = string(pass)
+	} else {
+		return cred, fmt.Errorf("%s key does not exist in secret data", e.SecretKeys.Password)
+	}
+
+	return cred, nil
+}
+
+// SecretData builds the data of a secret, names are adjusted to the engine limits
+func (e Engine) SecretData(dbName, dbUser, password string) map[string][]byte {
+	if e.DatabaseNameLengthLimit > 0 {
+		dbName = kci.StringSanitize(dbName, e.DatabaseNameLengthLimit)
+	}
+	if e.UserNameLengthLimit > 0 {
+		dbUser = kci.StringSanitize(dbUser, e.UserNameLengthLimit)
+	}
+	if e.UpperCaseIdentifiers {
+		dbName = strings.ToUpper(dbName)
+		dbUser = strings.ToUpper(dbUser)
+	}
+	return map[string][]byte{
+		e.SecretKeys.Database: []byte(dbName),
+		e.SecretKeys.User:     []byte(dbUser),
+		e.SecretKeys.Password: []byte(password),
+	}
+}
+
+// decodeSpec reads the engine specific part of the Database spec into db
+func (cfg EngineConfig) decodeSpec(db any) error {
+	if len(cfg.Spec) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(cfg.Spec, db); err != nil {
+		return fmt.Errorf("can't parse the engine spec: %v", err)
+	}
+	return nil
+}
+
+// annotationBool returns a value of a boolean DbInstance annotation, false if it's not set
+func (cfg EngineConfig) annotationBool(ctx context.Context, annotation string) bool {
+	log := log.FromContext(ctx)
+	val, ok := cfg.Annotations[annotation]
+	if !ok {
+		return false
+	}
+	boolVal, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Info(
+			"can't parse a value of an annotation into a bool, ignoring",
+			"annotation",
+			annotation,
+			"value",
+			val,
+			"error",
+			err,
+		)
+		return false
+	}
+	return boolVal
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/db-operator/db-operator/pkg/consts"
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRegistryEngines(t *testing.T) {
+	assert.Equal(t, []string{"clickhouse", "mongodb", "mysql", "oracle", "plugin", "postgres", "redis", "sqlserver"}, engines.Names())
+
+	_, err := GetEngine("dummy")
+	assert.Error(t, err)
+	assert.NotNil(t, New("dummy"))
+
+	_, err = GetEngine("unknown")
+	assert.Error(t, err)
+	assert.Nil(t, New("unknown"))
+
+	assert.Panics(t, func() { Register(Engine{Engine: engines.Postgres, New: newPostgres}) })
+	assert.Panics(t, func() { Register(Engine{Engine: engines.Engine{Name: "nameless"}}) })
+}
+
+func TestRegistryParseSecretData(t *testing.T) {
+	engine, err := GetEngine("mysql")
+	assert.NoError(t, err)
+
+	_, err = engine.ParseSecretData(map[string][]byte{"DB": []byte("db"), "USER": []byte("user")})
+	assert.EqualError(t, err, "PASSWORD key does not exist in secret data")
+
+	cred, err := engine.ParseSecretData(map[string][]byte{"DB": []byte("db"), "USER": []byte("user"), "PASSWORD": []byte("pwd")})
+	assert.NoError(t, err)
+	assert.Equal(t, Credentials{Name: "db", Username: "user", Password: "pwd"}, cred)
+}
+
+func TestRegistrySecretData(t *testing.T) {
+	engine, err := GetEngine("postgres")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		consts.POSTGRES_DB:       []byte("test-db"),
+		consts.POSTGRES_USER:     []byte("test-user"),
+		consts.POSTGRES_PASSWORD: []byte("pwd"),
+	}, engine.SecretData("test-db", "test-user", "pwd"))
+
+	engine, err = GetEngine("oracle")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		consts.ORACLE_DB:       []byte("TEST_DB"),
+		consts.ORACLE_USER:     []byte("TEST_USER"),
+		consts.ORACLE_PASSWORD: []byte("pwd"),
+	}, engine.SecretData("test-db", "test-user", "pwd"))
+}
+
+func TestRegistrySSLMode(t *testing.T) {
+	engine, err := GetEngine("postgres")
+	assert.NoError(t, err)
+
+	mode, err := engine.SSLMode(consts.SSL_VERIFY_CA)
+	assert.NoError(t, err)
+	assert.Equal(t, "verify-ca", mode)
+
+	_, err = engine.SSLMode("unknown")
+	assert.Error(t, err)
+}
+
+func TestRegistryNewFromSpec(t *testing.T) {
+	engine, err := GetEngine("postgres")
+	assert.NoError(t, err)
+
+	db, err := engine.New(context.TODO(), EngineConfig{
+		Host:        "127.0.0.1",
+		Port:        5432,
+		Database:    "testdb",
+		Annotations: map[string]string{consts.RDS_IAM_IMPERSONATE_WORKAROUND: "true"},
+		Spec:        []byte(`{"extensions":["uuid-ossp"],"schemas":["first"],"template":"template0"}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Postgres{
+		Host:                        "127.0.0.1",
+		Port:                        5432,
+		Database:                    "testdb",
+		Extensions:                  []string{"uuid-ossp"},
+		Schemas:                     []string{"first"},
+		Template:                    "template0",
+		RDSIAMImpersonateWorkaround: true,
+	}, db)
+
+	_, err = engine.New(context.TODO(), EngineConfig{Spec: []byte(`{"extensions":"uuid-ossp"}`)})
+	assert.Error(t, err)
+
+	engine, err = GetEngine("clickhouse")
+	assert.NoError(t, err)
+
+	db, err = engine.New(context.TODO(), EngineConfig{
+		Database: "testdb",
+		Spec:     []byte(`{"clusterName":"cluster","replicationFactor":2}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, ClickHouse{Database: "testdb", ClusterName: "cluster", ReplicationFactor: 2}, db)
+}
+
+func TestRegistryBackupContainer(t *testing.T) {
+	engine, err := GetEngine("postgres")
+	assert.NoError(t, err)
+
+	container, err := engine.BackupContainer(BackupConfig{
+		Image:           "postgresbackupimage:latest",
+		Host:            "db-host",
+		Port:            "5432",
+		SecretName:      "db-creds",
+		CredentialsPath: "/srv/k8s/db-cred/",
+		Bucket:          "bucket",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres-dump", container.Name)
+	assert.Equal(t, "postgresbackupimage:latest", container.Image)
+	assert.Contains(t, container.Env, corev1.EnvVar{Name: "DB_PASSWORD_FILE", Value: "/srv/k8s/db-cred/POSTGRES_PASSWORD"})
+	assert.NotContains(t, container.Env, corev1.EnvVar{Name: "PROMETHEUS_PUSH_GATEWAY"})
+
+	engine, err = GetEngine("sqlserver")
+	assert.NoError(t, err)
+	assert.Nil(t, engine.BackupContainer)
+}
+
+type testExternal struct {
+	ExternalDatabase
+	created string
+}
+
+func (e *testExternal) CreateDatabase(ctx context.Context, admin *DatabaseUser) error {
+	e.created = admin.Username
+	return nil
+}
+
+func TestRegistryFromExternal(t *testing.T) {
+	ext := &testExternal{}
+	db := FromExternal(ext)
+
+	err := CreateDatabase(context.TODO(), db, &DatabaseUser{Username: "admin"})
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", ext.created)
+}
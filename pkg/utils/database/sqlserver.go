@@ -0,0 +1,550 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+	"github.com/db-operator/db-operator/pkg/utils/kci"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	// Don't delete below package. Used for driver "sqlserver"
+	_ "github.com/microsoft/go-mssqldb"
+)
+
+// SQLServer is a database interface, abstracted object
+// represents a database on SQL Server instance
+// can be used to execute queries to SQL Server database
+type SQLServer struct {
+	Backend      string
+	Host         string
+	Port         uint16
+	Database     string
+	Schemas      []string `json:"schemas"`
+	Roles        []string `json:"roles"`
+	SSLEnabled   bool
+	SkipCAVerify bool
+}
+
+func init() {
+	Register(Engine{
+		Engine: engines.SQLServer,
+		New:    newSQLServer,
+	})
+}
+
+func newSQLServer(ctx context.Context, cfg EngineConfig) (Database, error) {
+	s := SQLServer{}
+	if err := cfg.decodeSpec(&s); err != nil {
+		return nil, err
+	}
+	s.Backend = cfg.Backend
+	s.Host = cfg.Host
+	s.Port = cfg.Port
+	s.Database = cfg.Database
+	s.SSLEnabled = cfg.SSLEnabled
+	s.SkipCAVerify = cfg.SkipCAVerify
+	return s, nil
+}
+
+const (
+	// Fixed database roles that are assigned according to the access type
+	sqlserverRoleOwner  = "db_owner"
+	sqlserverRoleReader = "db_datareader"
+	sqlserverRoleWriter = "db_datawriter"
+)
+
+// Internal helpers, these functions are not part for the `Database` interface
+
+// dsn builds a go-mssqldb connection string in the URL format
+func (s SQLServer) dsn(dbname, user, password string) string {
+	params := url.Values{}
+	params.Set("database", dbname)
+	if s.SSLEnabled {
+		params.Set("encrypt", "true")
+		if s.SkipCAVerify {
+			params.Set("TrustServerCertificate", "true")
+		}
+	} else {
+		params.Set("encrypt", "disable")
+	}
+	if timeout := currentTimeouts().Connect; timeout > 0 {
+		params.Set("dial timeout", strconv.Itoa(timeoutSeconds(timeout)))
+	}
+	dataSourceName := url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(user, password),
+		Host:     fmt.Sprintf("%s:%d", s.Host, s.Port),
+		RawQuery: params.Encode(),
+	}
+	return dataSourceName.String()
+}
+
+func (s SQLServer) getDbConn(dbname, user, password string) (*sql.DB, error) {
+	db, err := sqlOpen("sqlserver", s.dsn(dbname, user, password))
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open: %v", err)
+	}
+
+	return db, err
+}
+
+func (s SQLServer) executeExec(ctx context.Context, database, query string, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	db, err := s.getDbConn(database, admin.Username, admin.Password)
+	if err != nil {
+		log.Error(err, "failed to open a db connection")
+		return err
+	}
+
+	defer db.Close()
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	_, err = db.ExecContext(ctx, query)
+
+	return timeoutError(err)
+}
+
+func (s SQLServer) execAsUser(ctx context.Context, query string, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	db, err := s.getDbConn(s.Database, user.Username, user.Password)
+	if err != nil {
+		log.Error(err, "failed to open a db connection")
+		return err
+	}
+
+	defer db.Close()
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	_, err = db.ExecContext(ctx, query)
+
+	return timeoutError(err)
+}
+
+func (s SQLServer) isRowExist(ctx context.Context, database, query, user, password string) bool {
+	log := log.FromContext(ctx)
+	db, err := s.getDbConn(database, user, password)
+	if err != nil {
+		log.Error(err, "failed to open a db connection")
+		return false
+	}
+	defer db.Close()
+
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	var name string
+	err = db.QueryRowContext(ctx, query).Scan(&name)
+	if err != nil {
+		log.V(2).Info("failed executing query", "error", err)
+		return false
+	}
+	return true
+}
+
+func (s SQLServer) isDbExist(ctx context.Context, admin *DatabaseUser) bool {
+	check := fmt.Sprintf("SELECT name FROM sys.databases WHERE name = %s;", sqlserverQuoteLiteral(s.Database))
+
+	return s.isRowExist(ctx, "master", check, admin.Username, admin.Password)
+}
+
+func (s SQLServer) isUserExist(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) bool {
+	check := fmt.Sprintf("SELECT name FROM sys.server_principals WHERE name = %s AND type = 'S';", sqlserverQuoteLiteral(user.Username))
+
+	return s.isRowExist(ctx, "master", check, admin.Username, admin.Password)
+}
+
+func (s SQLServer) createSchemas(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	for _, schema := range s.Schemas {
+		// CREATE SCHEMA must be the only statement in a batch, hence EXEC
+		createSchema := fmt.Sprintf("IF SCHEMA_ID(%s) IS NULL EXEC(%s);", sqlserverQuoteLiteral(schema), sqlserverQuoteLiteral("CREATE SCHEMA "+sqlserverQuoteIdentifier(schema)))
+		if err := s.executeExec(ctx, s.Database, createSchema, admin); err != nil {
+			log.Error(err, "failed to create schema", "schema", schema)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s SQLServer) checkSchemas(ctx context.Context, user *DatabaseUser) error {
+	for _, schema := range s.Schemas {
+		query := fmt.Sprintf("SELECT name FROM sys.schemas WHERE name = %s;", sqlserverQuoteLiteral(schema))
+		if !s.isRowExist(ctx, s.Database, query, user.Username, user.Password) {
+			return fmt.Errorf("couldn't find schema %s in database %s", schema, s.Database)
+		}
+	}
+	return nil
+}
+
+// accessRoles returns fixed database roles that should be granted to the user
+func (s SQLServer) accessRoles(user *DatabaseUser) ([]string, error) {
+	switch user.AccessType {
+	case ACCESS_TYPE_MAINUSER:
+		return []string{sqlserverRoleOwner}, nil
+	case ACCESS_TYPE_READWRITE:
+		return []string{sqlserverRoleReader, sqlserverRoleWriter}, nil
+	case ACCESS_TYPE_READONLY:
+		return []string{sqlserverRoleReader}, nil
+	default:
+		return nil, fmt.Errorf("unknown access type: %s", user.AccessType)
+	}
+}
+
+// grantedRoles returns database roles that the user is a member of
+func (s SQLServer) grantedRoles(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) ([]string, error) {
+	db, err := s.getDbConn(s.Database, admin.Username, admin.Password)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	query := fmt.Sprintf("SELECT r.name FROM sys.database_role_members m JOIN sys.database_principals r ON r.principal_id = m.role_principal_id JOIN sys.database_principals u ON u.principal_id = m.member_principal_id WHERE u.name = %s;", sqlserverQuoteLiteral(user.Username))
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, timeoutError(err)
+	}
+	defer rows.Close()
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, timeoutError(rows.Err())
+}
+
+// sqlserverRoleChanges returns roles that are not granted yet, and granted roles
+// that are not wanted anymore, role names are case insensitive like on the server
+func sqlserverRoleChanges(granted, wanted []string) (grant []string, revoke []string) {
+	contains := func(roles []string, role string) bool {
+		return slices.ContainsFunc(roles, func(r string) bool { return strings.EqualFold(r, role) })
+	}
+	for _, role := range wanted {
+		if !contains(granted, role) && !contains(grant, role) {
+			grant = append(grant, role)
+		}
+	}
+	for _, role := range granted {
+		if !contains(wanted, role) {
+			revoke = append(revoke, role)
+		}
+	}
+	return grant, revoke
+}
+
+// Functions that implement the `Database` interface
+
+// CheckStatus checks status of SQL Server database
+// if the connection to database works
+func (s SQLServer) CheckStatus(ctx context.Context, user *DatabaseUser) error {
+	db, err := s.getDbConn(s.Database, user.Username, user.Password)
+	if err != nil {
+		return fmt.Errorf("db conn test failed - couldn't get db conn: %s", err)
+	}
+	defer db.Close()
+	queryCtx, cancel := statementContext(ctx)
+	defer cancel()
+	res, err := db.QueryContext(queryCtx, "SELECT 1")
+	if err != nil {
+		return timeoutError(fmt.Errorf("db conn test failed - failed to execute query: %w", err))
+	}
+	res.Close()
+
+	if err := s.checkSchemas(ctx, user); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetCredentials returns credentials of the SQL Server database
+func (s SQLServer) GetCredentials(ctx context.Context, user *DatabaseUser) Credentials {
+	return Credentials{
+		Name:     s.Database,
+		Username: user.Username,
+		Password: user.Password,
+	}
+}
+
+// ParseAdminCredentials parse admin username and password of SQL Server database from secret data
+// If "user" key is not defined, take "sa" as admin user by default
+func (s SQLServer) ParseAdminCredentials(ctx context.Context, data map[string][]byte) (*DatabaseUser, error) {
+	admin := &DatabaseUser{}
+
+	_, ok := data["user"]
+	if ok {
+		admin.Username = string(data["user"])
+	} else {
+		// default admin username is "sa"
+		admin.Username = "sa"
+	}
+
+	// if "password" key is defined in data, take value as password
+	_, ok = data["password"]
+	if ok {
+		admin.Password = string(data["password"])
+		return admin, nil
+	}
+
+	// take value of "MSSQL_SA_PASSWORD" key as password if "password" key is not defined in data
+	// it's the variable that is used by the official SQL Server container image
+	_, ok = data["MSSQL_SA_PASSWORD"]
+	if ok {
+		admin.Password = string(data["MSSQL_SA_PASSWORD"])
+		return admin, nil
+	}
+
+	return admin, errors.New("can not find sqlserver admin credentials")
+}
+
+func (s SQLServer) GetDatabaseAddress(ctx context.Context) DatabaseAddress {
+	return DatabaseAddress{
+		Host: s.Host,
+		Port: s.Port,
+	}
+}
+
+func (s SQLServer) QueryAsUser(ctx context.Context, query string, user *DatabaseUser) (string, error) {
+	log := log.FromContext(ctx)
+	db, err := s.getDbConn(s.Database, user.Username, user.Password)
+	if err != nil {
+		log.Error(err, "failed to open a db connection")
+		return "", err
+	}
+	defer db.Close()
+
+	ctx, cancel := statementContext(ctx)
+	defer cancel()
+	var result string
+	if err := db.QueryRowContext(ctx, query).Scan(&result); err != nil {
+		log.Error(err, "failed executing query", "query", query)
+		return "", timeoutError(err)
+	}
+	return result, nil
+}
+
+func (s SQLServer) createDatabase(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	create := fmt.Sprintf("CREATE DATABASE %s;", sqlserverQuoteIdentifier(s.Database))
+
+	if !s.isDbExist(ctx, admin) {
+		err := s.executeExec(ctx, "master", create, admin)
+		if err != nil {
+			log.Error(err, "failed creating sqlserver database")
+			return err
+		}
+	}
+
+	if len(s.Schemas) > 0 {
+		if err := s.createSchemas(ctx, admin); err != nil {
+			log.Error(err, "failed creating additional schemas")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropDatabaseQuery returns a batch that drops the database. Open sessions are blocking
+// the removal, so they are rolled back by the single user mode, that is set in the same
+// batch, so no other session can take the only connection before the database is dropped.
+// The database is made available again, when it can't be dropped
+func (s SQLServer) dropDatabaseQuery() string {
+	name := sqlserverQuoteIdentifier(s.Database)
+	return fmt.Sprintf("ALTER DATABASE %s SET SINGLE_USER WITH ROLLBACK IMMEDIATE; "+
+		"BEGIN TRY DROP DATABASE %s; END TRY "+
+		"BEGIN CATCH ALTER DATABASE %s SET MULTI_USER; THROW; END CATCH;", name, name, name)
+}
+
+func (s SQLServer) deleteDatabase(ctx context.Context, admin *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	drop := s.dropDatabaseQuery()
+
+	if s.isDbExist(ctx, admin) {
+		err := kci.Retry(3, 5*time.Second, func() error {
+			err := s.executeExec(ctx, "master", drop, admin)
+			if err != nil {
+				// This error will result in a retry
+				log.V(2).Info("failed with error, retrying", "error", err)
+				return err
+			}
+
+			return nil
+		})
+		if err != nil {
+			log.V(2).Info("failed with error, retrying", "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s SQLServer) createOrUpdateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	if !s.isUserExist(ctx, admin, user) {
+		if err := s.createUser(ctx, admin, user); err != nil {
+			log.Error(err, "failed creating sqlserver user")
+			return err
+		}
+	} else {
+		if err := s.updateUser(ctx, admin, user); err != nil {
+			log.Error(err, "failed updating sqlserver user")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createUser creates a server login and maps it to a database user
+func (s SQLServer) createUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	createLogin := fmt.Sprintf("CREATE LOGIN %s WITH PASSWORD = %s;", sqlserverQuoteIdentifier(user.Username), sqlserverQuoteLiteral(user.Password))
+	createUser := fmt.Sprintf("IF DATABASE_PRINCIPAL_ID(%s) IS NULL CREATE USER %s FOR LOGIN %s;", sqlserverQuoteLiteral(user.Username), sqlserverQuoteIdentifier(user.Username), sqlserverQuoteIdentifier(user.Username))
+
+	if !s.isUserExist(ctx, admin, user) {
+		if err := s.executeExec(ctx, "master", createLogin, admin); err != nil {
+			log.Error(err, "failed creating sqlserver login")
+			return err
+		}
+	} else {
+		err := fmt.Errorf("user already exists: %s", user.Username)
+		return err
+	}
+
+	if err := s.executeExec(ctx, s.Database, createUser, admin); err != nil {
+		log.Error(err, "failed mapping sqlserver login to a database user")
+		return err
+	}
+
+	if err := s.setUserPermission(ctx, admin, user); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s SQLServer) updateUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	update := fmt.Sprintf("ALTER LOGIN %s WITH PASSWORD = %s;", sqlserverQuoteIdentifier(user.Username), sqlserverQuoteLiteral(user.Password))
+	// The login might have been created for another database
+	createUser := fmt.Sprintf("IF DATABASE_PRINCIPAL_ID(%s) IS NULL CREATE USER %s FOR LOGIN %s;", sqlserverQuoteLiteral(user.Username), sqlserverQuoteIdentifier(user.Username), sqlserverQuoteIdentifier(user.Username))
+
+	if !s.isUserExist(ctx, admin, user) {
+		err := fmt.Errorf("user doesn't exist yet: %s", user.Username)
+		return err
+	} else {
+		if err := s.executeExec(ctx, "master", update, admin); err != nil {
+			log.Error(err, "failed updating sqlserver login")
+			return err
+		}
+	}
+
+	if err := s.executeExec(ctx, s.Database, createUser, admin); err != nil {
+		log.Error(err, "failed mapping sqlserver login to a database user")
+		return err
+	}
+
+	if err := s.setUserPermission(ctx, admin, user); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s SQLServer) setUserPermission(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	wanted, err := s.accessRoles(user)
+	if err != nil {
+		return err
+	}
+
+	// Roles from the Database spec are only assigned to the main user,
+	// DbUsers are getting additional roles from extra privileges
+	if user.AccessType == ACCESS_TYPE_MAINUSER {
+		wanted = append(wanted, s.Roles...)
+		if len(s.Schemas) > 0 {
+			defaultSchema := fmt.Sprintf("ALTER USER %s WITH DEFAULT_SCHEMA = %s;", sqlserverQuoteIdentifier(user.Username), sqlserverQuoteIdentifier(s.Schemas[0]))
+			if err := s.executeExec(ctx, s.Database, defaultSchema, admin); err != nil {
+				log.Error(err, "failed setting default schema", "query", defaultSchema)
+				return err
+			}
+		}
+	}
+	wanted = append(wanted, user.ExtraPrivileges...)
+
+	// Roles that are not listed anymore are revoked, so changing the access type
+	// or removing a role from the spec doesn't leave old privileges behind
+	granted, err := s.grantedRoles(ctx, admin, user)
+	if err != nil {
+		log.Error(err, "failed getting roles of user")
+		return err
+	}
+	grant, revoke := sqlserverRoleChanges(granted, wanted)
+
+	for _, role := range revoke {
+		dropMember := fmt.Sprintf("ALTER ROLE %s DROP MEMBER %s;", sqlserverQuoteIdentifier(role), sqlserverQuoteIdentifier(user.Username))
+		if err := s.executeExec(ctx, s.Database, dropMember, admin); err != nil {
+			log.Error(err, "failed revoking role from user", "query", dropMember)
+			return err
+		}
+	}
+
+	for _, role := range grant {
+		addMember := fmt.Sprintf("ALTER ROLE %s ADD MEMBER %s;", sqlserverQuoteIdentifier(role), sqlserverQuoteIdentifier(user.Username))
+		if err := s.executeExec(ctx, s.Database, addMember, admin); err != nil {
+			log.Error(err, "failed granting role to user", "query", addMember)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s SQLServer) deleteUser(ctx context.Context, admin *DatabaseUser, user *DatabaseUser) error {
+	log := log.FromContext(ctx)
+	dropUser := fmt.Sprintf("IF DATABASE_PRINCIPAL_ID(%s) IS NOT NULL DROP USER %s;", sqlserverQuoteLiteral(user.Username), sqlserverQuoteIdentifier(user.Username))
+	dropLogin := fmt.Sprintf("DROP LOGIN %s;", sqlserverQuoteIdentifier(user.Username))
+
+	if s.isDbExist(ctx, admin) {
+		if err := s.executeExec(ctx, s.Database, dropUser, admin); err != nil {
+			log.Error(err, "failed dropping sqlserver database user")
+			return err
+		}
+	}
+
+	if s.isUserExist(ctx, admin, user) {
+		if err := s.executeExec(ctx, "master", dropLogin, admin); err != nil {
+			log.Error(err, "failed dropping sqlserver login")
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/sijms/go-ora/v2/network"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TimeoutConfig limits how long engines are waiting for a database server
+type TimeoutConfig struct {
+	// Connect limits establishing a new connection
+	Connect time.Duration
+	// Statement limits a single statement, including the time
+	// that is spent waiting for a free pooled connection
+	Statement time.Duration
+	// Lock limits how long a postgres statement waits for a lock, e.g. DDL
+	// fails instead of queueing behind long transactions and blocking other sessions
+	Lock time.Duration
+}
+
+// DefaultTimeoutConfig is used when the operator config doesn't set timeouts
+var DefaultTimeoutConfig = TimeoutConfig{
+	Connect:   10 * time.Second,
+	Statement: 2 * time.Minute,
+	Lock:      10 * time.Second,
+}
+
+var (
+	timeoutsMu sync.RWMutex
+	timeouts   = DefaultTimeoutConfig
+)
+
+// ConfigureTimeouts changes timeouts of all engines, connection strings
+// are changed as well, so pooled connections are reopened
+func ConfigureTimeouts(config TimeoutConfig) {
+	timeoutsMu.Lock()
+	defer timeoutsMu.Unlock()
+	timeouts = config
+}
+
+func currentTimeouts() TimeoutConfig {
+	timeoutsMu.RLock()
+	defer timeoutsMu.RUnlock()
+	return timeouts
+}
+
+// ErrTimeout is wrapped by errors of operations that have exceeded one of
+// the configured timeouts, they're expected to succeed after a retry
+var ErrTimeout = errors.New("database operation timed out")
+
+// IsTimeout returns true if an operation has failed because of a timeout
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrTimeout)
+}
+
+// statementContext returns a context that is cancelled when the statement timeout is exceeded
+func statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := currentTimeouts().Statement; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// timeoutSeconds converts a timeout to whole seconds for drivers that don't accept
+// durations, it's rounded up, so a positive timeout is never turned into "no timeout"
+func timeoutSeconds(timeout time.Duration) int {
+	return int((timeout + time.Second - 1) / time.Second)
+}
+
+// timeoutError wraps an error with ErrTimeout if it's caused by a timeout
+func timeoutError(err error) error {
+	if err == nil || IsTimeout(err) || !isTimeoutCause(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrTimeout, err)
+}
+
+// isTimeoutCause checks errors of the context, the network and all the drivers
+// that are used by engines, because they're reporting timeouts differently
+func isTimeoutCause(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// query_canceled is returned when statement_timeout is exceeded
+		return pqErr.Code == "57014" || pqErr.Code == "55P03"
+	}
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		// ER_LOCK_WAIT_TIMEOUT, ER_QUERY_TIMEOUT and the mariadb ER_STATEMENT_TIMEOUT
+		case 1205, 3024, 1969:
+			return true
+		}
+		return false
+	}
+	var clickhouseErr *clickhouse.Exception
+	if errors.As(err, &clickhouseErr) {
+		// TIMEOUT_EXCEEDED and SOCKET_TIMEOUT
+		return clickhouseErr.Code == 159 || clickhouseErr.Code == 209
+	}
+	var mssqlErr mssql.Error
+	if errors.As(err, &mssqlErr) {
+		// Lock request time out period exceeded
+		return mssqlErr.Number == 1222
+	}
+	var oracleErr *network.OracleError
+	if errors.As(err, &oracleErr) {
+		switch oracleErr.ErrCode {
+		// Cancelled operation, resource busy and timeouts waiting for locks
+		case 1013, 54, 4021, 30006:
+			return true
+		}
+		return false
+	}
+	if mongo.IsTimeout(err) {
+		return true
+	}
+	return status.Code(err) == codes.DeadlineExceeded
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/sijms/go-ora/v2/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTimeoutError(t *testing.T) {
+	timeouts := []error{
+		context.DeadlineExceeded,
+		fmt.Errorf("can not add extension - %w", context.DeadlineExceeded),
+		&pq.Error{Code: "57014"},
+		&pq.Error{Code: "55P03"},
+		&mysqldriver.MySQLError{Number: 1205},
+		&clickhouse.Exception{Code: 159},
+		mssql.Error{Number: 1222},
+		&network.OracleError{ErrCode: 1013},
+		status.Error(codes.DeadlineExceeded, "deadline exceeded"),
+	}
+	for _, err := range timeouts {
+		wrapped := timeoutError(err)
+		assert.True(t, IsTimeout(wrapped), err.Error())
+		assert.ErrorContains(t, wrapped, err.Error())
+		// Errors are not wrapped twice
+		assert.Equal(t, wrapped, timeoutError(wrapped))
+	}
+
+	others := []error{
+		errors.New("some error"),
+		&pq.Error{Code: "42P04"},
+		&mysqldriver.MySQLError{Number: 1007},
+		&clickhouse.Exception{Code: 82},
+		mssql.Error{Number: 1801},
+		&network.OracleError{ErrCode: 1920},
+		status.Error(codes.Unknown, "deadline exceeded"),
+	}
+	for _, err := range others {
+		assert.False(t, IsTimeout(timeoutError(err)), err.Error())
+	}
+	assert.NoError(t, timeoutError(nil))
+}
+
+func TestStatementContext(t *testing.T) {
+	defer ConfigureTimeouts(DefaultTimeoutConfig)
+
+	ConfigureTimeouts(TimeoutConfig{Statement: time.Minute})
+	ctx, cancel := statementContext(context.TODO())
+	deadline, ok := ctx.Deadline()
+	cancel()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	ConfigureTimeouts(TimeoutConfig{})
+	ctx, cancel = statementContext(context.TODO())
+	_, ok = ctx.Deadline()
+	cancel()
+	assert.False(t, ok)
+}
+
+func TestTimeoutSeconds(t *testing.T) {
+	assert.Equal(t, 0, timeoutSeconds(0))
+	assert.Equal(t, 1, timeoutSeconds(time.Millisecond))
+	assert.Equal(t, 10, timeoutSeconds(10*time.Second))
+	assert.Equal(t, 11, timeoutSeconds(10*time.Second+time.Millisecond))
+}
+
+func TestPostgresDataSourceTimeouts(t *testing.T) {
+	defer ConfigureTimeouts(DefaultTimeoutConfig)
+	p := Postgres{Host: "127.0.0.1", Port: 5432}
+
+	ConfigureTimeouts(TimeoutConfig{Connect: 5 * time.Second, Statement: time.Minute, Lock: 3 * time.Second})
+	_, dsn := p.dataSource("testdb", "testuser", "testpwd")
+	assert.Contains(t, dsn, " connect_timeout=5 statement_timeout=60000")
+	// The lock timeout is only set for DDL, and not for the whole connection
+	assert.NotContains(t, dsn, "lock_timeout")
+
+	ConfigureTimeouts(TimeoutConfig{})
+	_, dsn = p.dataSource("testdb", "testuser", "testpwd")
+	assert.NotContains(t, dsn, "timeout")
+}
+
+func TestPostgresLockTimeout(t *testing.T) {
+	defer ConfigureTimeouts(DefaultTimeoutConfig)
+	ctx := context.TODO()
+	admin := &DatabaseUser{Username: "admin", Password: "adminpwd"}
+	server := newFakeSQLServer(fakePostgresDialect, admin)
+	useFakeSQLServer(t, server)
+
+	e, err := GetEngine("postgres")
+	require.NoError(t, err)
+	mainUser := &DatabaseUser{Username: "locks_main", Password: "mainpwd", AccessType: ACCESS_TYPE_MAINUSER}
+	cfg := EngineConfig{Instance: "lock-postgres", Host: "postgres", Port: 5432, Database: "locks", MainUser: mainUser}
+	db, err := e.New(ctx, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		Connections.Invalidate(poolInstance(cfg.Instance, cfg.Host, cfg.Port))
+	})
+	user := &DatabaseUser{Username: "locks_user", Password: "userpwd", AccessType: ACCESS_TYPE_READWRITE}
+
+	ConfigureTimeouts(TimeoutConfig{Lock: 3 * time.Second})
+	require.NoError(t, CreateDatabase(ctx, db, admin))
+	require.NoError(t, CreateOrUpdateUser(ctx, db, mainUser, admin))
+	require.NoError(t, CreateUser(ctx, db, user, admin))
+	require.NoError(t, db.CheckStatus(ctx, user))
+	_, err = db.QueryAsUser(ctx, "SELECT 1", user)
+	require.NoError(t, err)
+
+	// DDL waits for locks for a limited time
+	server.locks["locks.public"] = true
+	err = UpdateUser(ctx, db, user, admin)
+	assert.Error(t, err)
+	assert.True(t, IsTimeout(err), "DDL must be canceled by the lock timeout")
+	assert.NoError(t, db.CheckStatus(ctx, user), "status checks don't wait for locks of DDL")
+
+	// The timeout is not set, when it's disabled
+	ConfigureTimeouts(TimeoutConfig{})
+	assert.ErrorContains(t, UpdateUser(ctx, db, user, admin), "waits for the lock of locks.public forever")
+	delete(server.locks, "locks.public")
+	assert.NoError(t, UpdateUser(ctx, db, user, admin))
+}
@@ -16,14 +16,47 @@
 
 package database
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 
+	"github.com/db-operator/db-operator/pkg/utils/engines"
+)
+
+const (
+	ACCESS_TYPE_READONLY  = engines.ACCESS_TYPE_READONLY
+	ACCESS_TYPE_READWRITE = engines.ACCESS_TYPE_READWRITE
+	ACCESS_TYPE_MAINUSER  = engines.ACCESS_TYPE_MAINUSER
+)
+
+// Deletion policies define what happens to objects that are owned by a user, when it's removed
 const (
-	ACCESS_TYPE_READONLY  = "readOnly"
-	ACCESS_TYPE_READWRITE = "readWrite"
-	ACCESS_TYPE_MAINUSER  = "main"
+	// Objects are reassigned to the main user
+	DELETION_POLICY_REASSIGN = "reassign"
+	// Objects are dropped together with the user
+	DELETION_POLICY_DROP = "drop"
+	// The user isn't removed while it owns objects
+	DELETION_POLICY_FAIL = "fail"
 )
 
+// Schema prune policies define what happens to schemas that are removed from the spec
+const (
+	// Schemas are kept in the database, grants on them are revoked
+	SCHEMA_PRUNE_POLICY_RETAIN = "retain"
+	// Schemas are dropped with all their objects
+	SCHEMA_PRUNE_POLICY_DROP = "drop"
+)
+
+// CLICKHOUSE_ENGINE_REPLICATED is the database engine of clickhouse that replicates
+// the database to all hosts of the cluster, other engines are used as defaults of tables
+const CLICKHOUSE_ENGINE_REPLICATED = engines.CLICKHOUSE_ENGINE_REPLICATED
+
+// ErrOwnedObjects is wrapped by errors of users that can't be removed,
+// because objects that depend on them still exist
+var ErrOwnedObjects = errors.New("user owns objects")
+
 // Credentials contains credentials to connect database
 type Credentials struct {
 	Name             string
@@ -33,9 +66,12 @@ type Credentials struct {
 }
 
 type DatabaseUser struct {
-	Username        string `yaml:"user"`
-	Password        string `yaml:"password"`
-	AccessType      string
+	Username   string `yaml:"user"`
+	Password   string `yaml:"password"`
+	AccessType string
+	// AccessProfile is set when the access type references a custom access
+	// profile, built-in access types are handled by engines themselves
+	AccessProfile   *AccessProfile
 	ExtraPrivileges []string
 	GrantToAdmin    bool
 	// A workaround mostly for AWS RDS. Since we can't
@@ -44,6 +80,43 @@ type DatabaseUser struct {
 	// user is revoked from rds_iam, hence it should
 	// happen while it's being deleted
 	GrantToAdminOnDelete bool
+	// DeletionPolicy overrides the deletion policy of the database for the user
+	DeletionPolicy string
+	// Spec is the engine specific part of the DbUser spec (e.g. spec.postgres) as JSON,
+	// it's not set for main users
+	Spec []byte
+}
+
+// decodeSpec reads the engine specific part of the DbUser spec into spec
+func (user *DatabaseUser) decodeSpec(spec any) error {
+	if len(user.Spec) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(user.Spec, spec); err != nil {
+		return fmt.Errorf("can't parse the user spec: %v", err)
+	}
+	return nil
+}
+
+// ExtensionManager is implemented by engines that are managing extensions of databases
+type ExtensionManager interface {
+	// ExtensionVersions returns installed versions of extensions from the spec
+	ExtensionVersions(ctx context.Context, admin *DatabaseUser) (map[string]string, error)
+	// pruneExtensions drops extensions that were managed before, but are removed from the spec
+	pruneExtensions(ctx context.Context, admin *DatabaseUser, managed []string) error
+}
+
+// SettingsManager is implemented by engines that are setting parameters of databases
+type SettingsManager interface {
+	// pruneSettings resets parameters that were managed before, but are removed from the spec
+	pruneSettings(ctx context.Context, admin *DatabaseUser, managed []string) error
+}
+
+// SchemaManager is implemented by engines that are removing schemas of databases
+type SchemaManager interface {
+	// pruneSchemas handles schemas that were created by the operator, but are removed from the spec,
+	// it returns schemas that are left in the database
+	pruneSchemas(ctx context.Context, admin *DatabaseUser) ([]string, error)
 }
 
 // DatabaseAddress contains host and port of a database instance
@@ -28,39 +28,37 @@ import (
 
 // Generic represents database instance which can be connected by address and port
 type Generic struct {
-	Host         string
-	Port         uint16
-	Engine       string
-	User         string
-	Password     string
-	PublicIP     string
-	SSLEnabled   bool
-	SkipCAVerify bool
+	Host   string
+	Port   uint16
+	Engine string
+	// Annotations of the DbInstance, some engines are configured with them
+	Annotations map[string]string
+	// Only used by the plugin engine
+	PluginEndpoint string
+	PluginTLS      *kcidb.PluginTLS
+	User           string
+	Password       string
+	PublicIP       string
+	SSLEnabled     bool
+	SkipCAVerify   bool
 }
 
 func makeInterface(in *Generic) (kcidb.Database, error) {
-	switch in.Engine {
-	case "postgres":
-		db := kcidb.Postgres{
-			Host:         in.Host,
-			Port:         in.Port,
-			Database:     "postgres",
-			SSLEnabled:   in.SSLEnabled,
-			SkipCAVerify: in.SkipCAVerify,
-		}
-		return db, nil
-	case "mysql":
-		db := kcidb.Mysql{
-			Host:         in.Host,
-			Port:         in.Port,
-			Database:     "mysql",
-			SSLEnabled:   in.SSLEnabled,
-			SkipCAVerify: in.SkipCAVerify,
-		}
-		return db, nil
-	default:
-		return nil, errors.New("not supported engine type")
+	engine, err := kcidb.GetEngine(in.Engine)
+	if err != nil {
+		return nil, err
 	}
+
+	return engine.New(context.Background(), kcidb.EngineConfig{
+		Host:           in.Host,
+		Port:           in.Port,
+		Database:       engine.AdminDatabase,
+		SSLEnabled:     in.SSLEnabled,
+		SkipCAVerify:   in.SkipCAVerify,
+		Annotations:    in.Annotations,
+		PluginEndpoint: in.PluginEndpoint,
+		PluginTLS:      in.PluginTLS,
+	})
 }
 
 func (ins *Generic) state() (string, error) {
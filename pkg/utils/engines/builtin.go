@@ -0,0 +1,207 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import "github.com/db-operator/db-operator/pkg/consts"
+
+// Built-in engines, their drivers are registered by the database package
+var (
+	Postgres = Engine{
+		Name:     consts.ENGINE_POSTGRES,
+		Protocol: "postgresql",
+		SecretKeys: SecretKeys{
+			Database: consts.POSTGRES_DB,
+			User:     consts.POSTGRES_USER,
+			Password: consts.POSTGRES_PASSWORD,
+		},
+		SSLModes: map[string]string{
+			consts.SSL_DISABLED:  "disable",
+			consts.SSL_REQUIRED:  "require",
+			consts.SSL_VERIFY_CA: "verify-ca",
+		},
+		AdminDatabase:  "postgres",
+		AccessProfiles: true,
+	}
+
+	Mysql = Engine{
+		Name:     consts.ENGINE_MYSQL,
+		Protocol: "mysql",
+		SecretKeys: SecretKeys{
+			Database: consts.MYSQL_DB,
+			User:     consts.MYSQL_USER,
+			Password: consts.MYSQL_PASSWORD,
+		},
+		SSLModes: map[string]string{
+			consts.SSL_DISABLED:  "disabled",
+			consts.SSL_REQUIRED:  "required",
+			consts.SSL_VERIFY_CA: "verify_ca",
+		},
+		// https://dev.mysql.com/doc/refman/5.7/en/identifier-length.html
+		DatabaseNameLengthLimit: 63,
+		// https://dev.mysql.com/doc/refman/5.7/en/replication-features-user-names.html
+		UserNameLengthLimit: 32,
+		AdminDatabase:       "mysql",
+		AccessProfiles:      true,
+	}
+
+	ClickHouse = Engine{
+		Name:     consts.ENGINE_CLICKHOUSE,
+		Protocol: "clickhouse",
+		SecretKeys: SecretKeys{
+			Database: consts.CLICKHOUSE_DB,
+			User:     consts.CLICKHOUSE_USER,
+			Password: consts.CLICKHOUSE_PASSWORD,
+		},
+		// clickhouse-go is configured with the secure and skip_verify connection string options
+		SSLModes: map[string]string{
+			consts.SSL_DISABLED:  "secure=false",
+			consts.SSL_REQUIRED:  "secure=true&skip_verify=true",
+			consts.SSL_VERIFY_CA: "secure=true",
+		},
+		// ClickHouse databases are stored in directories, so names are limited by the filesystem
+		DatabaseNameLengthLimit: 255,
+		AdminDatabase:           "default",
+		AccessProfiles:          true,
+	}
+
+	SQLServer = Engine{
+		Name:     consts.ENGINE_SQLSERVER,
+		Protocol: "sqlserver",
+		SecretKeys: SecretKeys{
+			Database: consts.SQLSERVER_DB,
+			User:     consts.SQLSERVER_USER,
+			Password: consts.SQLSERVER_PASSWORD,
+		},
+		// SQL Server is configured with the encrypt and TrustServerCertificate connection string options
+		SSLModes: map[string]string{
+			consts.SSL_DISABLED:  "encrypt=disable",
+			consts.SSL_REQUIRED:  "encrypt=true;TrustServerCertificate=true",
+			consts.SSL_VERIFY_CA: "encrypt=true",
+		},
+		// https://learn.microsoft.com/en-us/sql/relational-databases/databases/database-identifiers
+		DatabaseNameLengthLimit: 128,
+		UserNameLengthLimit:     128,
+		AdminDatabase:           "master",
+	}
+
+	Oracle = Engine{
+		Name:     consts.ENGINE_ORACLE,
+		Protocol: "oracle",
+		SecretKeys: SecretKeys{
+			Database: consts.ORACLE_DB,
+			User:     consts.ORACLE_USER,
+			Password: consts.ORACLE_PASSWORD,
+		},
+		// go-ora is configured with the SSL and SSL VERIFY connection string options
+		SSLModes: map[string]string{
+			consts.SSL_DISABLED:  "SSL=false",
+			consts.SSL_REQUIRED:  "SSL=true&SSL VERIFY=false",
+			consts.SSL_VERIFY_CA: "SSL=true",
+		},
+		// https://docs.oracle.com/en/database/oracle/oracle-database/19/sqlrf/Database-Object-Names-and-Qualifiers.html
+		// PDB names are still limited to 30 characters
+		DatabaseNameLengthLimit: 30,
+		UserNameLengthLimit:     30,
+		// Quoted identifiers are case sensitive, so names are upper-cased
+		// to let users refer to them without quotes
+		UpperCaseIdentifiers: true,
+	}
+
+	MongoDB = Engine{
+		Name:     consts.ENGINE_MONGODB,
+		Protocol: "mongodb",
+		SecretKeys: SecretKeys{
+			Database: consts.MONGODB_DB,
+			User:     consts.MONGODB_USER,
+			Password: consts.MONGODB_PASSWORD,
+		},
+		// MongoDB is configured with the tls and tlsInsecure connection string options
+		SSLModes: map[string]string{
+			consts.SSL_DISABLED:  "false",
+			consts.SSL_REQUIRED:  "insecure",
+			consts.SSL_VERIFY_CA: "true",
+		},
+		// https://www.mongodb.com/docs/manual/reference/limits/#mongodb-limit-Length-of-Database-Names
+		DatabaseNameLengthLimit: 63,
+		AdminDatabase:           "admin",
+	}
+
+	Redis = Engine{
+		Name:     consts.ENGINE_REDIS,
+		Protocol: "redis",
+		SecretKeys: SecretKeys{
+			Database: consts.REDIS_DB,
+			User:     consts.REDIS_USER,
+			Password: consts.REDIS_PASSWORD,
+		},
+		// TLS is enabled by the rediss scheme, that is used by clients when the option is not false
+		SSLModes: map[string]string{
+			consts.SSL_DISABLED:  "false",
+			consts.SSL_REQUIRED:  "insecure",
+			consts.SSL_VERIFY_CA: "true",
+		},
+	}
+
+	Plugin = Engine{
+		Name:     consts.ENGINE_PLUGIN,
+		Protocol: "plugin",
+		SecretKeys: SecretKeys{
+			Database: consts.PLUGIN_DB,
+			User:     consts.PLUGIN_USER,
+			Password: consts.PLUGIN_PASSWORD,
+		},
+		// Plugins get the ssl options of the instance with every request,
+		// so generic modes are used in connection strings
+		SSLModes: map[string]string{
+			consts.SSL_DISABLED:  consts.SSL_DISABLED,
+			consts.SSL_REQUIRED:  consts.SSL_REQUIRED,
+			consts.SSL_VERIFY_CA: consts.SSL_VERIFY_CA,
+		},
+		// Custom profiles are sent to plugins, that can reject them
+		AccessProfiles: true,
+	}
+
+	// Recorder is a hidden engine, that records calls for controller tests
+	Recorder = Engine{
+		Name:     consts.ENGINE_RECORDER,
+		Protocol: "recorder",
+		SecretKeys: SecretKeys{
+			Database: consts.RECORDER_DB,
+			User:     consts.RECORDER_USER,
+			Password: consts.RECORDER_PASSWORD,
+		},
+		SSLModes: map[string]string{
+			consts.SSL_DISABLED:  consts.SSL_DISABLED,
+			consts.SSL_REQUIRED:  consts.SSL_REQUIRED,
+			consts.SSL_VERIFY_CA: consts.SSL_VERIFY_CA,
+		},
+		AccessProfiles: true,
+		Hidden:         true,
+	}
+
+	// Dummy is a hidden engine for unit tests
+	Dummy = Engine{
+		Name:   "dummy",
+		Hidden: true,
+	}
+)
+
+func init() {
+	for _, engine := range []Engine{Postgres, Mysql, ClickHouse, SQLServer, Oracle, MongoDB, Redis, Plugin, Recorder, Dummy} {
+		Register(engine)
+	}
+}
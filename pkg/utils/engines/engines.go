@@ -0,0 +1,127 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package engines describes database engines without their drivers, so API types
+// and webhooks can validate resources without linking database clients. Drivers
+// of the engines are registered by the database package.
+package engines
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	enginesMu sync.RWMutex
+	engines   = map[string]Engine{}
+	// Hidden engines are only allowed by tests
+	hiddenEnginesAllowed bool
+)
+
+// SecretKeys are keys of the secret that is created for a Database or a DbUser
+type SecretKeys struct {
+	Database string
+	User     string
+	Password string
+}
+
+// Engine describes a database engine that can be used by db-operator
+type Engine struct {
+	// Name is used as the engine of a DbInstance
+	Name string
+	// Protocol that is used in connection strings
+	Protocol   string
+	SecretKeys SecretKeys
+	// Values of the connection string SSL option per generic
+	// SSL mode (consts.SSL_DISABLED, consts.SSL_REQUIRED, consts.SSL_VERIFY_CA)
+	SSLModes map[string]string
+	// Generated names are sanitized and cut to the limit if it's set
+	DatabaseNameLengthLimit int
+	UserNameLengthLimit     int
+	// Upper-case generated names (e.g. Oracle treats quoted identifiers as case sensitive)
+	UpperCaseIdentifiers bool
+	// A database that is used to check the instance
+	AdminDatabase string
+	// Custom access profiles can be granted to users,
+	// otherwise only built-in access types are supported
+	AccessProfiles bool
+	// Hidden engines can't be used by DbInstances, e.g. the dummy engine for unit tests
+	Hidden bool
+}
+
+// Register makes an engine available by its name,
+// it panics if the name is empty or is already registered
+func Register(engine Engine) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+	if len(engine.Name) == 0 {
+		panic("engines: engine name is empty")
+	}
+	if _, ok := engines[engine.Name]; ok {
+		panic("engines: Register called twice for engine " + engine.Name)
+	}
+	engines[engine.Name] = engine
+}
+
+// Lookup returns a registered engine, hidden ones too
+func Lookup(name string) (Engine, bool) {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	engine, ok := engines[name]
+	return engine, ok
+}
+
+// Get returns a registered engine that can be used by DbInstances
+func Get(name string) (Engine, error) {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	engine, ok := engines[name]
+	if !ok || (engine.Hidden && !hiddenEnginesAllowed) {
+		return Engine{}, fmt.Errorf("not supported engine type: %s", name)
+	}
+	return engine, nil
+}
+
+// AllowHidden makes hidden engines usable by DbInstances, so controllers
+// can be tested with the recorder engine, it must never be called by the operator
+func AllowHidden(allow bool) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+	hiddenEnginesAllowed = allow
+}
+
+// Names returns sorted names of engines that can be used by DbInstances
+func Names() []string {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	names := []string{}
+	for name, engine := range engines {
+		if !engine.Hidden {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SSLMode returns the value of the connection string SSL option for a generic SSL mode
+func (e Engine) SSLMode(genericSSL string) (string, error) {
+	if mode, ok := e.SSLModes[genericSSL]; ok {
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown ssl mode %s for the engine %s", genericSSL, e.Name)
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"testing"
+
+	"github.com/db-operator/db-operator/pkg/consts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnginesBuiltin(t *testing.T) {
+	assert.Equal(t, []string{"clickhouse", "mongodb", "mysql", "oracle", "plugin", "postgres", "redis", "sqlserver"}, Names())
+
+	engine, err := Get(consts.ENGINE_MYSQL)
+	assert.NoError(t, err)
+	assert.Equal(t, Mysql, engine)
+
+	_, err = Get("unknown")
+	assert.EqualError(t, err, "not supported engine type: unknown")
+
+	assert.Panics(t, func() { Register(Engine{Name: consts.ENGINE_POSTGRES}) })
+	assert.Panics(t, func() { Register(Engine{}) })
+}
+
+func TestEnginesHidden(t *testing.T) {
+	_, err := Get(consts.ENGINE_RECORDER)
+	assert.Error(t, err)
+	_, ok := Lookup(consts.ENGINE_RECORDER)
+	assert.True(t, ok)
+
+	AllowHidden(true)
+	defer AllowHidden(false)
+	engine, err := Get(consts.ENGINE_RECORDER)
+	assert.NoError(t, err)
+	assert.Equal(t, "recorder", engine.Protocol)
+	assert.NotContains(t, Names(), consts.ENGINE_RECORDER)
+}
+
+func TestEnginesSSLMode(t *testing.T) {
+	mode, err := ClickHouse.SSLMode(consts.SSL_REQUIRED)
+	assert.NoError(t, err)
+	assert.Equal(t, "secure=true&skip_verify=true", mode)
+
+	_, err = ClickHouse.SSLMode("unknown")
+	assert.EqualError(t, err, "unknown ssl mode unknown for the engine clickhouse")
+}
+
+func TestEnginesValidatePostgresSettings(t *testing.T) {
+	assert.NoError(t, ValidatePostgresSettings(map[string]string{"work_mem": "64MB", "pg_stat_statements.track": "all"}))
+	assert.Error(t, ValidatePostgresSettings(map[string]string{"work_mem = 1; --": "64MB"}))
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2024 Datacosmos
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	ACCESS_TYPE_READONLY  = "readOnly"
+	ACCESS_TYPE_READWRITE = "readWrite"
+	ACCESS_TYPE_MAINUSER  = "main"
+)
+
+// CLICKHOUSE_ENGINE_REPLICATED is the database engine of clickhouse that replicates
+// the database to all hosts of the cluster, other engines are used as defaults of tables
+const CLICKHOUSE_ENGINE_REPLICATED = "Replicated"
+
+// AccessProfile describes privileges that are granted to a user per kind of objects.
+// Built-in access types (readOnly and readWrite) are access profiles that are defined
+// by engines, custom ones are coming from DbAccessProfile resources
+type AccessProfile struct {
+	Name      string
+	Database  []string
+	Schemas   []string
+	Tables    []string
+	Sequences []string
+	Functions []string
+	// Privileges on objects that are created by the main user later
+	DefaultPrivileges DefaultPrivileges
+}
+
+// DefaultPrivileges are granted on objects that don't exist yet
+type DefaultPrivileges struct {
+	Tables    []string
+	Sequences []string
+	Functions []string
+}
+
+// Privileges are keywords that can't be quoted, so only letters and spaces
+// are allowed, e.g. "SELECT" or "CREATE TEMPORARY TABLES"
+var privilegeRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z ]*$`)
+
+// ValidatePrivileges checks if privileges can be put into a statement without quoting
+func ValidatePrivileges(privileges []string) error {
+	for _, privilege := range privileges {
+		if !privilegeRegexp.MatchString(privilege) {
+			return fmt.Errorf("invalid privilege: %s", privilege)
+		}
+	}
+	return nil
+}
+
+// Validate checks all privileges of the profile
+func (p AccessProfile) Validate() error {
+	for _, privileges := range [][]string{
+		p.Database, p.Schemas, p.Tables, p.Sequences, p.Functions,
+		p.DefaultPrivileges.Tables, p.DefaultPrivileges.Sequences, p.DefaultPrivileges.Functions,
+	} {
+		if err := ValidatePrivileges(privileges); err != nil {
+			return fmt.Errorf("access profile %s: %v", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// Names of parameters can't be quoted, custom ones are prefixed by an extension name
+var postgresSettingRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// IsPostgresSettingName checks if a name of a parameter can be put into a statement
+func IsPostgresSettingName(name string) bool {
+	return postgresSettingRegexp.MatchString(name)
+}
+
+// ValidatePostgresSettings checks if names of parameters can be put into a statement
+func ValidatePostgresSettings(settings map[string]string) error {
+	for name := range settings {
+		if !IsPostgresSettingName(name) {
+			return fmt.Errorf("invalid setting name: %s", name)
+		}
+	}
+	return nil
+}
@@ -23,17 +23,30 @@ import (
 
 	kindav1beta1 "github.com/db-operator/db-operator/api/v1beta1"
 	"github.com/db-operator/db-operator/pkg/utils/database"
+	"github.com/db-operator/db-operator/pkg/utils/engines"
 	"github.com/sirupsen/logrus"
 	"k8s.io/utils/strings/slices"
 )
 
 const (
-	FieldPostgresDB        = "POSTGRES_DB"
-	FieldPostgresUser      = "POSTGRES_USER"
-	FieldPostgressPassword = "POSTGRES_PASSWORD"
-	FieldMysqlDB           = "DB"
-	FieldMysqlUser         = "USER"
-	FieldMysqlPassword     = "PASSWORD"
+	FieldPostgresDB         = "POSTGRES_DB"
+	FieldPostgresUser       = "POSTGRES_USER"
+	FieldPostgressPassword  = "POSTGRES_PASSWORD"
+	FieldMysqlDB            = "DB"
+	FieldMysqlUser          = "USER"
+	FieldMysqlPassword      = "PASSWORD"
+	FieldMongodbDB          = "MONGODB_DB"
+	FieldMongodbUser        = "MONGODB_USER"
+	FieldMongodbPassword    = "MONGODB_PASSWORD"
+	FieldClickhouseDB       = "CLICKHOUSE_DB"
+	FieldClickhouseUser     = "CLICKHOUSE_USER"
+	FieldClickhousePassword = "CLICKHOUSE_PASSWORD"
+	FieldOracleDB           = "ORACLE_DB"
+	FieldOracleUser         = "ORACLE_USER"
+	FieldOraclePassword     = "ORACLE_PASSWORD"
+	FieldSqlserverDB        = "SQLSERVER_DB"
+	FieldSqlserverUser      = "SQLSERVER_USER"
+	FieldSqlserverPassword  = "SQLSERVER_PASSWORD"
 )
 
 // SecretsTemplatesFields defines default fields that can be used to generate secrets with db creds
@@ -50,7 +63,15 @@ type SecretsTemplatesFields struct {
 //
 //	templating fields that are created by the operator by default
 func getBlockedTempatedKeys() []string {
-	return []string{FieldMysqlDB, FieldMysqlPassword, FieldMysqlUser, FieldPostgresDB, FieldPostgresUser, FieldPostgressPassword}
+	keys := []string{}
+	for _, name := range engines.Names() {
+		engine, err := engines.Get(name)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, engine.SecretKeys.Database, engine.SecretKeys.User, engine.SecretKeys.Password)
+	}
+	return keys
 }
 
 func ParseTemplatedSecretsData(dbcr *kindav1beta1.Database, cred database.Credentials, data map[string][]byte) (database.Credentials, error) {
@@ -91,9 +112,9 @@ func GenerateTemplatedSecrets(dbcr *kindav1beta1.Database, databaseCred database
 		dbData.DatabaseHost = dbAddress.Host
 		dbData.DatabasePort = int32(dbAddress.Port)
 	}
-	// If engine is 'postgres', the protocol should be postgresql
-	if dbcr.Status.Engine == "postgres" {
-		dbData.Protocol = "postgresql"
+	// The protocol is defined by the engine, e.g. postgresql for postgres
+	if protocol, err := dbcr.GetProtocol(); err == nil {
+		dbData.Protocol = protocol
 	} else {
 		dbData.Protocol = dbcr.Status.Engine
 	}
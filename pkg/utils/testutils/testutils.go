@@ -97,3 +97,84 @@ func NewMysqlTestDbCr() *kindav1beta1.Database {
 
 	return &db
 }
+
+func NewMongodbTestDbCr() *kindav1beta1.Database {
+	o := metav1.ObjectMeta{Namespace: TestNamespace}
+	s := kindav1beta1.DatabaseSpec{SecretName: TestSecretName}
+
+	db := kindav1beta1.Database{
+		ObjectMeta: o,
+		Spec:       s,
+		Status: kindav1beta1.DatabaseStatus{
+			Engine: consts.ENGINE_MONGODB,
+		},
+	}
+
+	return &db
+}
+
+func NewSqlserverTestDbCr() *kindav1beta1.Database {
+	o := metav1.ObjectMeta{Namespace: TestNamespace}
+	s := kindav1beta1.DatabaseSpec{
+		SecretName: TestSecretName,
+		SQLServer: kindav1beta1.SQLServer{
+			Schemas: []string{"app"},
+			Roles:   []string{"db_ddladmin"},
+		},
+	}
+
+	db := kindav1beta1.Database{
+		ObjectMeta: o,
+		Spec:       s,
+		Status: kindav1beta1.DatabaseStatus{
+			Engine: consts.ENGINE_SQLSERVER,
+		},
+	}
+
+	return &db
+}
+
+func NewOracleTestDbCr() *kindav1beta1.Database {
+	o := metav1.ObjectMeta{Namespace: TestNamespace}
+	s := kindav1beta1.DatabaseSpec{
+		SecretName: TestSecretName,
+		Oracle: kindav1beta1.Oracle{
+			Tablespaces: []string{"APP_DATA"},
+			Profiles:    []string{"APP_PROFILE"},
+		},
+	}
+
+	db := kindav1beta1.Database{
+		ObjectMeta: o,
+		Spec:       s,
+		Status: kindav1beta1.DatabaseStatus{
+			Engine: consts.ENGINE_ORACLE,
+		},
+	}
+
+	return &db
+}
+
+func NewClickhouseTestDbCr() *kindav1beta1.Database {
+	o := metav1.ObjectMeta{Namespace: TestNamespace}
+	s := kindav1beta1.DatabaseSpec{
+		SecretName: TestSecretName,
+		Clickhouse: kindav1beta1.Clickhouse{
+			Cluster:           "test_cluster",
+			Shard:             "1",
+			ReplicationFactor: 2,
+			Engine:            "ReplicatedMergeTree",
+			Settings:          map[string]string{"max_threads": "8"},
+		},
+	}
+
+	db := kindav1beta1.Database{
+		ObjectMeta: o,
+		Spec:       s,
+		Status: kindav1beta1.DatabaseStatus{
+			Engine: consts.ENGINE_CLICKHOUSE,
+		},
+	}
+
+	return &db
+}